@@ -34,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
 
 	"kubevirt.io/containerized-data-importer/pkg/apiserver"
+	cdiclient "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
 	"kubevirt.io/containerized-data-importer/pkg/version/verflag"
 )
 
@@ -86,6 +87,11 @@ func main() {
 		klog.Fatalf("Unable to get kube client: %v\n", errors.WithStack(err))
 	}
 
+	cdiClient, err := cdiclient.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Unable to get cdi client: %v\n", errors.WithStack(err))
+	}
+
 	aggregatorClient := aggregatorclient.NewForConfigOrDie(cfg)
 
 	ch := signals.SetupSignalHandler()
@@ -100,6 +106,7 @@ func main() {
 	uploadApp, err := apiserver.NewCdiAPIServer(defaultHost,
 		defaultPort,
 		client,
+		cdiClient,
 		aggregatorClient,
 		authorizor,
 		authConfigWatcher)
@@ -6,17 +6,24 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
+	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/klog"
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 	prometheusutil "kubevirt.io/containerized-data-importer/pkg/util/prometheus"
 )
 
+const blockdeviceCloneContentType = "blockdevice-clone"
+
 var (
 	contentType string
 	uploadBytes uint64
@@ -28,22 +35,36 @@ func init() {
 	klog.InitFlags(nil)
 }
 
+// fatalf writes the failure to the termination message and exits.
+func fatalf(reason string, transient bool, format string, args ...interface{}) {
+	message := &util.TerminationMessage{Message: fmt.Sprintf(format, args...), Reason: reason, Transient: transient}
+	if err := message.Write(); err != nil {
+		klog.Errorf("%+v", err)
+	}
+	klog.Fatalf(format, args...)
+}
+
 func getEnvVarOrDie(name string) string {
 	value := os.Getenv(name)
 	if value == "" {
-		klog.Fatalf("Error geting env var %s", name)
+		fatalf(util.ReasonInvalidConfiguration, false, "Error geting env var %s", name)
 	}
 	return value
 }
 
-func createHTTPClient(clientKey, clientCert, serverCert []byte) *http.Client {
+// createHTTPClient returns the client posting to the upload server, the PEM encoded proxy CA is trusted in addition to
+// the server CA, so the upload server can be reached through a TLS intercepting proxy.
+func createHTTPClient(clientKey, clientCert, serverCert, proxyCA []byte) *http.Client {
 	clientKeyPair, err := tls.X509KeyPair(clientCert, clientKey)
 	if err != nil {
-		klog.Fatalf("Error %s creating client keypair", err)
+		fatalf(util.ReasonInvalidConfiguration, false, "Error %s creating client keypair", err)
 	}
 
 	caCertPool := x509.NewCertPool()
 	caCertPool.AppendCertsFromPEM(serverCert)
+	if len(proxyCA) > 0 && !caCertPool.AppendCertsFromPEM(proxyCA) {
+		klog.Warningf("No certs in %s", common.ProxyCACertVar)
+	}
 
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{clientKeyPair},
@@ -51,7 +72,7 @@ func createHTTPClient(clientKey, clientCert, serverCert []byte) *http.Client {
 	}
 	tlsConfig.BuildNameToCertificate()
 
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	transport := &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}
 	client := &http.Client{Transport: transport}
 
 	return client
@@ -60,7 +81,7 @@ func createHTTPClient(clientKey, clientCert, serverCert []byte) *http.Client {
 func startPrometheus() {
 	certsDirectory, err := ioutil.TempDir("", "certsdir")
 	if err != nil {
-		klog.Fatalf("Error %s creating temp dir", err)
+		fatalf(util.ReasonProcessingFailed, true, "Error %s creating temp dir", err)
 	}
 
 	prometheusutil.StartPrometheusEndpoint(certsDirectory)
@@ -74,24 +95,33 @@ func createProgressReader(readCloser io.ReadCloser, ownerUID string, totalBytes
 		},
 		[]string{"ownerUID"},
 	)
-	prometheus.MustRegister(progress)
+	transferStats := prometheusutil.NewTransferStatsVec("clone_transfer_stats", "The statistics of the clone transfer")
+	prometheus.MustRegister(progress, transferStats)
 
 	promReader := prometheusutil.NewProgressReader(readCloser, totalBytes, progress, ownerUID)
+	promReader.ReportTransferStats(transferStats)
+	reporter, err := util.NewProgressReporterFromEnv()
+	if err != nil {
+		klog.Warningf("Not pushing progress reports: %v", err)
+	}
+	promReader.PushReports(reporter)
 	promReader.StartTimedUpdate()
 
 	return promReader
 }
 
-func pipeToGzip(reader io.ReadCloser) io.ReadCloser {
+// pipeToGzip compresses reader, done is called with the number of bytes read before the compressed stream ends.
+func pipeToGzip(reader io.Reader, done func(int64)) io.ReadCloser {
 	pr, pw := io.Pipe()
 	gzw := gzip.NewWriter(pw)
 
 	go func() {
 		n, err := io.Copy(gzw, reader)
 		if err != nil {
-			klog.Fatalf("Error %s piping to gzip", err)
+			fatalf(util.ReasonTransferFailed, true, "Error %s piping to gzip", err)
 		}
 		gzw.Close()
+		done(n)
 		pw.Close()
 		klog.Infof("Wrote %d bytes\n", n)
 	}()
@@ -99,6 +129,26 @@ func pipeToGzip(reader io.ReadCloser) io.ReadCloser {
 	return pr
 }
 
+// setChecksumTrailer sets the content checksum of the source in the request trailer, so the target can verify the
+// data it wrote. A block device is hashed while it is streamed, for a file system the disk image is hashed once the
+// archive is sent. The checksum is returned, or an empty string if there is none.
+func setChecksumTrailer(trailer http.Header, streamHash hash.Hash, streamed int64) string {
+	if contentType == blockdeviceCloneContentType {
+		checksum := util.FormatContentChecksum(streamHash)
+		trailer.Set(common.ContentChecksumTrailer, checksum)
+		trailer.Set(common.ContentSizeTrailer, strconv.FormatInt(streamed, 10))
+		return checksum
+	}
+	checksum, err := util.ContentChecksum(filepath.Join(os.Getenv("MOUNT_POINT"), common.DiskImageName), -1)
+	if err != nil {
+		// not a disk image, there is nothing to verify
+		klog.Warningf("Not sending a content checksum: %v", err)
+		return ""
+	}
+	trailer.Set(common.ContentChecksumTrailer, checksum)
+	return checksum
+}
+
 func main() {
 	flag.Parse()
 	defer klog.Flush()
@@ -111,18 +161,35 @@ func main() {
 	clientKey := []byte(getEnvVarOrDie("CLIENT_KEY"))
 	clientCert := []byte(getEnvVarOrDie("CLIENT_CERT"))
 	serverCert := []byte(getEnvVarOrDie("SERVER_CA_CERT"))
+	proxyCA := []byte(os.Getenv(common.ProxyCACertVar))
 
 	url := getEnvVarOrDie("UPLOAD_URL")
 
 	klog.V(1).Infoln("Starting cloner target")
 
-	reader := pipeToGzip(createProgressReader(os.Stdin, ownerUID, uploadBytes))
+	streamHash := util.NewContentHash()
+	trailer := http.Header{common.ContentChecksumTrailer: nil, common.ContentSizeTrailer: nil}
+	transferRateLimit, err := util.TransferRateLimitFromEnv()
+	if err != nil {
+		fatalf(util.ReasonInvalidConfiguration, false, "Error %s parsing the transfer rate limit", err)
+	}
+	limitedStdin := util.NewRateLimitedReader(os.Stdin, util.NewRateLimiter(transferRateLimit))
+	var source io.Reader = createProgressReader(limitedStdin, ownerUID, uploadBytes)
+	if contentType == blockdeviceCloneContentType {
+		source = io.TeeReader(source, streamHash)
+	}
+	result := &util.TerminationMessage{Message: "Clone Complete"}
+	reader := pipeToGzip(source, func(n int64) {
+		result.BytesTransferred = n
+		result.Digest = setChecksumTrailer(trailer, streamHash, n)
+	})
 
 	startPrometheus()
 
-	client := createHTTPClient(clientKey, clientCert, serverCert)
+	client := createHTTPClient(clientKey, clientCert, serverCert, proxyCA)
 
 	req, _ := http.NewRequest("POST", url, reader)
+	req.Trailer = trailer
 
 	if contentType != "" {
 		req.Header.Set("x-cdi-content-type", contentType)
@@ -131,20 +198,25 @@ func main() {
 
 	response, err := client.Do(req)
 	if err != nil {
-		klog.Fatalf("Error %s POSTing to %s", err, url)
-	}
-
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		klog.Fatalf("Unexpected status code %d", response.StatusCode)
+		fatalf(util.ReasonTransferFailed, true, "Error %s POSTing to %s", err, url)
 	}
 
 	var buf bytes.Buffer
 	_, err = io.Copy(&buf, response.Body)
 	if err != nil {
-		klog.Fatalf("Error %s copying response body", err)
+		fatalf(util.ReasonTransferFailed, true, "Error %s copying response body", err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		// the target rejects data it cannot process with a client error, retrying will not help
+		fatalf(util.ReasonTransferFailed, response.StatusCode >= 500, "Unexpected status code %d: %s", response.StatusCode, buf.String())
 	}
 
 	klog.V(1).Infof("Response body:\n%s", buf.String())
 
+	if err := result.Write(); err != nil {
+		klog.Errorf("%+v", err)
+	}
+
 	klog.V(1).Infoln("clone complete")
 }
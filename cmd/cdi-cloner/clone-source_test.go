@@ -3,12 +3,16 @@ package main
 import (
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"k8s.io/client-go/util/cert"
+
+	"kubevirt.io/containerized-data-importer/pkg/util/cert/triple"
 	prometheusutil "kubevirt.io/containerized-data-importer/pkg/util/prometheus"
 )
 
@@ -29,6 +33,34 @@ var _ = Describe("Prometheus Endpoint", func() {
 	})
 })
 
+var _ = Describe("Upload client", func() {
+	It("Should trust the server CA and the proxy CA", func() {
+		serverCA, err := triple.NewCA("server-ca")
+		Expect(err).NotTo(HaveOccurred())
+		proxyCA, err := triple.NewCA("proxy-ca")
+		Expect(err).NotTo(HaveOccurred())
+		clientKeyPair, err := triple.NewClientKeyPair(serverCA, "client", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		client := createHTTPClient(cert.EncodePrivateKeyPEM(clientKeyPair.Key), cert.EncodeCertPEM(clientKeyPair.Cert),
+			cert.EncodeCertPEM(serverCA.Cert), cert.EncodeCertPEM(proxyCA.Cert))
+		rootCAs := client.Transport.(*http.Transport).TLSClientConfig.RootCAs
+		Expect(rootCAs.Subjects()).To(ConsistOf(serverCA.Cert.RawSubject, proxyCA.Cert.RawSubject))
+	})
+
+	It("Should only trust the server CA without a proxy CA", func() {
+		serverCA, err := triple.NewCA("server-ca")
+		Expect(err).NotTo(HaveOccurred())
+		clientKeyPair, err := triple.NewClientKeyPair(serverCA, "client", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		client := createHTTPClient(cert.EncodePrivateKeyPEM(clientKeyPair.Key), cert.EncodeCertPEM(clientKeyPair.Cert),
+			cert.EncodeCertPEM(serverCA.Cert), nil)
+		rootCAs := client.Transport.(*http.Transport).TLSClientConfig.RootCAs
+		Expect(rootCAs.Subjects()).To(Equal([][]byte{serverCA.Cert.RawSubject}))
+	})
+})
+
 func isDirEmpty(dirName string) (bool, error) {
 	f, err := os.Open(dirName)
 	if err != nil {
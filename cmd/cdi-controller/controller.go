@@ -146,22 +146,39 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 	routeInformer := routeInformerFactory.Route().V1().Routes()
 	dataVolumeInformer := cdiInformerFactory.Cdi().V1alpha1().DataVolumes()
 	configInformer := cdiInformerFactory.Cdi().V1alpha1().CDIConfigs()
+	storageProfileInformer := cdiInformerFactory.Cdi().V1alpha1().StorageProfiles()
+	storageClassInformer := pvcInformerFactory.Storage().V1().StorageClasses()
 	snapshotInformer := csiInformerFactory.Snapshot().V1alpha1().VolumeSnapshots()
 	crdInformer := crdInformerFactory.Apiextensions().V1beta1().CustomResourceDefinitions().Informer()
 
+	progressServer := controller.NewProgressServer(client, fmt.Sprintf(":%d", controller.ProgressServerPort))
+
+	workerScheduler, err := controller.NewWorkerScheduler(pvcInformer, podInformer, dataVolumeInformer)
+	if err != nil {
+		klog.Fatalf("Error creating worker scheduler: %+v", err)
+	}
+
 	dataVolumeController := controller.NewDataVolumeController(
 		client,
 		cdiClient,
 		csiClient,
 		extClient,
 		pvcInformer,
-		dataVolumeInformer)
+		dataVolumeInformer,
+		configInformer,
+		workerScheduler,
+		importerImage,
+		pullPolicy,
+		verbose,
+		progressServer)
 
 	importController := controller.NewImportController(
 		client,
 		cdiClient,
 		pvcInformer,
 		podInformer,
+		configInformer,
+		workerScheduler,
 		importerImage,
 		pullPolicy,
 		verbose)
@@ -169,6 +186,7 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 	cloneController := controller.NewCloneController(client,
 		pvcInformer,
 		podInformer,
+		configInformer,
 		clonerImage,
 		pullPolicy,
 		verbose,
@@ -187,6 +205,8 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 		pvcInformer,
 		podInformer,
 		serviceInformer,
+		configInformer,
+		workerScheduler,
 		uploadServerImage,
 		uploadProxyServiceName,
 		pullPolicy,
@@ -202,6 +222,14 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 		pullPolicy,
 		verbose)
 
+	storageProfileController := controller.NewStorageProfileController(client,
+		cdiClient,
+		csiClient,
+		extClient,
+		storageClassInformer,
+		storageProfileInformer,
+		configInformer)
+
 	klog.V(1).Infoln("created cdi controllers")
 
 	err = uploadController.Init()
@@ -214,6 +242,11 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 		klog.Fatalf("Error initializing config controller: %+v", err)
 	}
 
+	err = progressServer.Init()
+	if err != nil {
+		klog.Fatalf("Error initializing progress server: %+v", err)
+	}
+
 	go cdiInformerFactory.Start(stopCh)
 	go pvcInformerFactory.Start(stopCh)
 	go podInformerFactory.Start(stopCh)
@@ -228,6 +261,13 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 
 	klog.V(1).Infoln("started informers")
 
+	go func() {
+		err = progressServer.Run(stopCh)
+		if err != nil {
+			klog.Fatalf("Error running progress server: %+v", err)
+		}
+	}()
+
 	go func() {
 		err = dataVolumeController.Run(3, stopCh)
 		if err != nil {
@@ -263,6 +303,13 @@ func start(cfg *rest.Config, stopCh <-chan struct{}) {
 		}
 	}()
 
+	go func() {
+		err = storageProfileController.Run(1, stopCh)
+		if err != nil {
+			klog.Fatalf("Error running storage profile controller: %+v", err)
+		}
+	}()
+
 	startSmartController(extClient, csiInformerFactory, smartCloneController, stopCh)
 }
 
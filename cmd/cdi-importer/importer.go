@@ -11,18 +11,25 @@ package main
 //    ImporterAccessKeyID  Optional. Access key is the user ID that uniquely identifies your
 //			      account.
 //    ImporterSecretKey     Optional. Secret key is the password to your account.
+//    ImporterToken         Optional. Bearer token sent to http endpoints.
+//    ImporterExtraHeaders  Optional. Extra "Name: value" headers, one per line, sent to http endpoints.
+//    ProxyCACertVar        Optional. PEM encoded CA of the proxy configured in HTTP_PROXY and HTTPS_PROXY.
+//    ImageValidationPolicyVar Optional. JSON encoded policy the image is validated against.
+//    ImporterSizeProbe     Optional. Only determine the virtual size of the source, nothing is imported.
+//    FilesystemOverheadVar Optional. Fraction of a Filesystem mode volume reserved for file system metadata.
 
 import (
 	"flag"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/common"
@@ -33,6 +40,9 @@ import (
 	prometheusutil "kubevirt.io/containerized-data-importer/pkg/util/prometheus"
 )
 
+// proxyCertDir is the temporary directory holding the proxy CA, removed by exit since os.Exit skips deferred calls.
+var proxyCertDir string
+
 func init() {
 	klog.InitFlags(nil)
 	flag.Parse()
@@ -52,16 +62,56 @@ func main() {
 	ep, _ := util.ParseEnvVar(common.ImporterEndpoint, false)
 	acc, _ := util.ParseEnvVar(common.ImporterAccessKeyID, false)
 	sec, _ := util.ParseEnvVar(common.ImporterSecretKey, false)
+	token, _ := util.ParseEnvVar(common.ImporterToken, false)
+	extraHeaders, _ := util.ParseEnvVar(common.ImporterExtraHeaders, false)
 	source, _ := util.ParseEnvVar(common.ImporterSource, false)
 	contentType, _ := util.ParseEnvVar(common.ImporterContentType, false)
 	imageSize, _ := util.ParseEnvVar(common.ImporterImageSize, false)
 	certDir, _ := util.ParseEnvVar(common.ImporterCertDirVar, false)
+	clientCertDir, _ := util.ParseEnvVar(common.ImporterClientCertDirVar, false)
+	checksumURL, _ := util.ParseEnvVar(common.ImporterChecksumURL, false)
+	checksumSignatureURL, _ := util.ParseEnvVar(common.ImporterChecksumSignatureURL, false)
+	checksumKeyDir, _ := util.ParseEnvVar(common.ImporterChecksumKeyDirVar, false)
+	archiveSubPath, _ := util.ParseEnvVar(common.ImporterArchiveSubPath, false)
+	archiveStripComponents, _ := util.ParseEnvVar(common.ImporterArchiveStripComponents, false)
+	archiveOwnership, _ := util.ParseEnvVar(common.ImporterArchiveOwnership, false)
 	insecureTLS, _ := strconv.ParseBool(os.Getenv(common.InsecureTLSVar))
+	verify, _ := strconv.ParseBool(os.Getenv(common.ImporterVerify))
+	sizeProbe, _ := strconv.ParseBool(os.Getenv(common.ImporterSizeProbe))
+	proxyCA, _ := util.ParseEnvVar(common.ProxyCACertVar, false)
+	validationPolicy, _ := util.ParseEnvVar(common.ImageValidationPolicyVar, false)
+
+	if validationPolicy != "" {
+		policy, err := importer.ParseValidationPolicy(validationPolicy)
+		if err != nil {
+			exitWithError(util.ReasonInvalidConfiguration, false, errors.WithMessage(err, "Unable to set image validation policy"))
+		}
+		image.SetValidationPolicy(policy)
+	}
+
+	transferRateLimit, err := util.TransferRateLimitFromEnv()
+	if err != nil {
+		exitWithError(util.ReasonInvalidConfiguration, false, err)
+	}
+	importer.SetTransferRateLimit(transferRateLimit)
+
+	filesystemOverhead, err := util.FilesystemOverheadFromEnv()
+	if err != nil {
+		exitWithError(util.ReasonInvalidConfiguration, false, err)
+	}
+
+	if proxyCA != "" {
+		certDir, err = importer.AddProxyCA(certDir, []byte(proxyCA))
+		if err != nil {
+			exitWithError(util.ReasonInvalidConfiguration, false, errors.WithMessage(err, "Unable to add proxy CA"))
+		}
+		proxyCertDir = certDir
+		defer os.RemoveAll(proxyCertDir)
+	}
 
 	//Registry import currently support kubevirt content type only
 	if contentType != string(cdiv1.DataVolumeKubeVirt) && source == controller.SourceRegistry {
-		klog.Errorf("Unsupported content type %s when importing from registry", contentType)
-		os.Exit(1)
+		exitWithError(util.ReasonInvalidConfiguration, false, errors.Errorf("Unsupported content type %s when importing from registry", contentType))
 	}
 
 	volumeMode := v1.PersistentVolumeBlock
@@ -80,6 +130,8 @@ func main() {
 
 	dataDir := common.ImporterDataDir
 	availableDestSpace := util.GetAvailableSpaceByVolumeMode(volumeMode)
+	completeMessage := &util.TerminationMessage{Message: "Import Complete"}
+	startTime := metav1.Now()
 	if source == controller.SourceNone && contentType == string(cdiv1.DataVolumeKubeVirt) {
 		requestImageSizeQuantity := resource.MustParse(imageSize)
 		minSizeQuantity := util.MinQuantity(resource.NewScaledQuantity(availableDestSpace, 0), &requestImageSizeQuantity)
@@ -87,75 +139,130 @@ func main() {
 			// Available dest space is smaller than the size we want to create
 			klog.Warningf("Available space less than requested size, creating blank image sized to available space: %s.\n", minSizeQuantity.String())
 		}
+		if volumeMode == v1.PersistentVolumeFilesystem && filesystemOverhead > 0 {
+			minSizeQuantity = *resource.NewScaledQuantity(util.UsableSpace(minSizeQuantity.Value(), filesystemOverhead), 0)
+			klog.V(1).Infof("Reserving the filesystem overhead, creating blank image of size %s.\n", minSizeQuantity.String())
+		}
 		err := image.CreateBlankImage(common.ImporterWritePath, minSizeQuantity)
 		if err != nil {
-			klog.Errorf("%+v", err)
-			err = util.WriteTerminationMessage(fmt.Sprintf("Unable to create blank image: %+v", err))
-			if err != nil {
-				klog.Errorf("%+v", err)
-			}
-			os.Exit(1)
+			exitWithError(util.ReasonProcessingFailed, true, errors.WithMessage(err, "Unable to create blank image"))
 		}
 	} else if source == controller.SourceNone && contentType == string(cdiv1.DataVolumeArchive) {
-		klog.Errorf("%+v", errors.New("Cannot create empty disk with content type archive"))
-		err = util.WriteTerminationMessage("Cannot create empty disk with content type archive")
-		if err != nil {
-			klog.Errorf("%+v", err)
-		}
-		os.Exit(1)
+		exitWithError(util.ReasonInvalidConfiguration, false, errors.New("Cannot create empty disk with content type archive"))
 	} else {
 		klog.V(1).Infoln("begin import process")
 		var dp importer.DataSourceInterface
 		switch source {
 		case controller.SourceHTTP:
-			dp, err = importer.NewHTTPDataSource(ep, acc, sec, certDir, cdiv1.DataVolumeContentType(contentType))
-			if err != nil {
-				klog.Errorf("%+v", err)
-				err = util.WriteTerminationMessage(fmt.Sprintf("Unable to connect to http data source: %+v", err))
-				if err != nil {
-					klog.Errorf("%+v", err)
+			var checksum *importer.HTTPChecksum
+			if checksumURL != "" {
+				checksum = &importer.HTTPChecksum{
+					URL:          checksumURL,
+					SignatureURL: checksumSignatureURL,
+					KeyDir:       checksumKeyDir,
+				}
+			}
+			var archive *importer.ArchiveOptions
+			if contentType == string(cdiv1.DataVolumeArchive) {
+				stripComponents, _ := strconv.Atoi(archiveStripComponents)
+				archive = &importer.ArchiveOptions{
+					SubPath:           archiveSubPath,
+					StripComponents:   stripComponents,
+					PreserveOwnership: archiveOwnership != string(cdiv1.ArchiveOwnershipDrop),
 				}
-				os.Exit(1)
+			}
+			dp, err = importer.NewHTTPDataSource(ep, acc, sec, token, certDir, clientCertDir, strings.Split(extraHeaders, "\n"), cdiv1.DataVolumeContentType(contentType), checksum, archive)
+			if err != nil {
+				reason, transient := importer.ClassifyError(err)
+				exitWithError(reason, transient, errors.WithMessage(err, "Unable to connect to http data source"))
 			}
 		case controller.SourceRegistry:
 			dp = importer.NewRegistryDataSource(ep, acc, sec, certDir, insecureTLS)
 		case controller.SourceS3:
-			dp, err = importer.NewS3DataSource(ep, acc, sec)
+			dp, err = importer.NewS3DataSource(ep, acc, sec, certDir)
 			if err != nil {
-				klog.Errorf("%+v", err)
-				err = util.WriteTerminationMessage(fmt.Sprintf("Unable to connect to s3 data source: %+v", err))
-				if err != nil {
-					klog.Errorf("%+v", err)
-				}
-				os.Exit(1)
+				reason, transient := importer.ClassifyError(err)
+				exitWithError(reason, transient, errors.WithMessage(err, "Unable to connect to s3 data source"))
 			}
 		default:
-			klog.Errorf("Unknown source type %s\n", source)
-			err = util.WriteTerminationMessage(fmt.Sprintf("Unknown data source: %s", source))
-			if err != nil {
-				klog.Errorf("%+v", err)
-			}
-			os.Exit(1)
+			exitWithError(util.ReasonInvalidConfiguration, false, errors.Errorf("Unknown data source: %s", source))
 		}
 		defer dp.Close()
+		if sizeProbe {
+			probeSize(dp)
+			return
+		}
 		processor := importer.NewDataProcessor(dp, dest, dataDir, common.ScratchDataDir, imageSize)
+		processor.SetVerify(verify)
+		processor.SetFilesystemOverhead(filesystemOverhead)
 		err = processor.ProcessData()
 		if err != nil {
 			klog.Errorf("%+v", err)
 			if err == importer.ErrRequiresScratchSpace {
-				os.Exit(common.ScratchSpaceNeededExitCode)
+				exit(common.ScratchSpaceNeededExitCode)
 			}
-			err = util.WriteTerminationMessage(fmt.Sprintf("Unable to process data: %+v", err))
-			if err != nil {
+			reason, transient := importer.ClassifyError(err)
+			message := util.NewFailureMessage(reason, transient, errors.WithMessage(err, "Unable to process data"))
+			message.BytesTransferred = processor.BytesTransferred()
+			if validationErr, ok := errors.Cause(err).(*image.ValidationError); ok {
+				message.Message = validationErr.Message
+				message.ValidationRule = validationErr.Rule
+			}
+			if err = message.Write(); err != nil {
 				klog.Errorf("%+v", err)
 			}
-			os.Exit(1)
+			exit(1)
+		}
+		completeMessage.BytesTransferred = processor.BytesTransferred()
+		completeMessage.Format = processor.SourceFormat()
+		completeMessage.VirtualSize = processor.VirtualSize()
+		completeMessage.Digest = processor.Digest()
+		completeMessage.ISOVolumeLabel = processor.ISOVolumeLabel()
+		if layout := processor.DiskLayout(); layout != nil {
+			completeMessage.DiskLayout = &cdiv1.DataVolumeDiskLayout{
+				PartitionTable:     layout.PartitionTable,
+				EFISystemPartition: layout.EFISystemPartition,
+				Filesystems:        layout.Filesystems,
+			}
 		}
 	}
-	err = util.WriteTerminationMessage("Import Complete")
+	completeMessage.SetTransferTimes(startTime)
+	err = completeMessage.Write()
 	if err != nil {
 		klog.Errorf("%+v", err)
-		os.Exit(1)
+		exit(1)
 	}
 	klog.V(1).Infoln("Import complete")
 }
+
+// probeSize writes the virtual size of the source to the termination message, instead of importing it.
+func probeSize(dp importer.DataSourceInterface) {
+	size, err := importer.ProbeVirtualSize(dp, common.ScratchDataDir)
+	if err != nil {
+		reason, transient := importer.ClassifyError(err)
+		exitWithError(reason, transient, errors.WithMessage(err, "Unable to determine the virtual size"))
+	}
+	klog.V(1).Infof("Virtual size is %d", size)
+	message := &util.TerminationMessage{Message: "Size Probe Complete", VirtualSize: size}
+	if err := message.Write(); err != nil {
+		klog.Errorf("%+v", err)
+		exit(1)
+	}
+}
+
+// exitWithError logs err, writes it to the termination message together with the reason of the failure, and exits.
+func exitWithError(reason string, transient bool, err error) {
+	klog.Errorf("%+v", err)
+	if err := util.NewFailureMessage(reason, transient, err).Write(); err != nil {
+		klog.Errorf("%+v", err)
+	}
+	exit(1)
+}
+
+// exit removes the temporary proxy CA directory and exits with code.
+func exit(code int) {
+	if proxyCertDir != "" {
+		os.RemoveAll(proxyCertDir)
+	}
+	os.Exit(code)
+}
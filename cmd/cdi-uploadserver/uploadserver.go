@@ -24,9 +24,13 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/pkg/errors"
 	"k8s.io/klog"
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/image"
+	"kubevirt.io/containerized-data-importer/pkg/importer"
 	"kubevirt.io/containerized-data-importer/pkg/uploadserver"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 const (
@@ -48,6 +52,19 @@ func main() {
 
 	destination := getDestination()
 
+	if val := os.Getenv(common.ImageValidationPolicyVar); val != "" {
+		policy, err := importer.ParseValidationPolicy(val)
+		if err != nil {
+			exitWithError(util.ReasonInvalidConfiguration, false, errors.WithMessage(err, "Invalid image validation policy"))
+		}
+		image.SetValidationPolicy(policy)
+	}
+
+	transferRateLimit, err := util.TransferRateLimitFromEnv()
+	if err != nil {
+		exitWithError(util.ReasonInvalidConfiguration, false, err)
+	}
+
 	server := uploadserver.NewUploadServer(
 		listenAddress,
 		listenPort,
@@ -57,16 +74,20 @@ func main() {
 		os.Getenv("CLIENT_CERT"),
 		os.Getenv("CLIENT_NAME"),
 		os.Getenv(common.UploadImageSize),
+		transferRateLimit,
 	)
 
 	klog.Infof("Upload destination: %s", destination)
 
 	klog.Infof("Running server on %s:%d", listenAddress, listenPort)
 
-	err := server.Run()
+	err = server.Run()
 	if err != nil {
-		klog.Errorf("UploadServer failed: %s", err)
-		os.Exit(1)
+		exitWithError(util.ReasonProcessingFailed, true, errors.WithMessage(err, "UploadServer failed"))
+	}
+
+	if err := server.TerminationMessage().Write(); err != nil {
+		klog.Errorf("%+v", err)
 	}
 
 	klog.Info("UploadServer successfully exited")
@@ -100,3 +121,12 @@ func getDestination() string {
 
 	return destination
 }
+
+// exitWithError logs err, writes it to the termination message together with the reason of the failure, and exits.
+func exitWithError(reason string, transient bool, err error) {
+	klog.Errorf("%+v", err)
+	if err := util.NewFailureMessage(reason, transient, err).Write(); err != nil {
+		klog.Errorf("%+v", err)
+	}
+	os.Exit(1)
+}
@@ -21,8 +21,9 @@ limitations under the License.
 package v1alpha1
 
 import (
-	v1 "github.com/openshift/custom-resource-status/conditions/v1"
-	corev1 "k8s.io/api/core/v1"
+	conditionsv1 "github.com/openshift/custom-resource-status/conditions/v1"
+	v1 "k8s.io/api/core/v1"
+	resource "k8s.io/apimachinery/pkg/api/resource"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -128,6 +129,46 @@ func (in *CDIConfigSpec) DeepCopyInto(out *CDIConfigSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ImportProxy != nil {
+		in, out := &in.ImportProxy, &out.ImportProxy
+		*out = new(ImportProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageValidation != nil {
+		in, out := &in.ImageValidation, &out.ImageValidation
+		*out = new(ImageValidationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TransferRateLimit != nil {
+		in, out := &in.TransferRateLimit, &out.TransferRateLimit
+		*out = new(TransferRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FilesystemOverhead != nil {
+		in, out := &in.FilesystemOverhead, &out.FilesystemOverhead
+		*out = new(FilesystemOverhead)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = new(NodePlacement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodResourceRequirements != nil {
+		in, out := &in.PodResourceRequirements, &out.PodResourceRequirements
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConcurrencyLimits != nil {
+		in, out := &in.ConcurrencyLimits, &out.ConcurrencyLimits
+		*out = new(ConcurrencyLimits)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -149,6 +190,31 @@ func (in *CDIConfigStatus) DeepCopyInto(out *CDIConfigStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ImportProxy != nil {
+		in, out := &in.ImportProxy, &out.ImportProxy
+		*out = new(ImportProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageValidation != nil {
+		in, out := &in.ImageValidation, &out.ImageValidation
+		*out = new(ImageValidationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TransferRateLimit != nil {
+		in, out := &in.TransferRateLimit, &out.TransferRateLimit
+		*out = new(TransferRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FilesystemOverhead != nil {
+		in, out := &in.FilesystemOverhead, &out.FilesystemOverhead
+		*out = new(FilesystemOverhead)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodResourceRequirements != nil {
+		in, out := &in.PodResourceRequirements, &out.PodResourceRequirements
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -216,7 +282,7 @@ func (in *CDIStatus) DeepCopyInto(out *CDIStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]conditionsv1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -234,13 +300,39 @@ func (in *CDIStatus) DeepCopy() *CDIStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConcurrencyLimits) DeepCopyInto(out *ConcurrencyLimits) {
+	*out = *in
+	if in.Global != nil {
+		in, out := &in.Global, &out.Global
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Namespace != nil {
+		in, out := &in.Namespace, &out.Namespace
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyLimits.
+func (in *ConcurrencyLimits) DeepCopy() *ConcurrencyLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ConcurrencyLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataVolume) DeepCopyInto(out *DataVolume) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -262,6 +354,22 @@ func (in *DataVolume) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeArchiveOptions) DeepCopyInto(out *DataVolumeArchiveOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeArchiveOptions.
+func (in *DataVolumeArchiveOptions) DeepCopy() *DataVolumeArchiveOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeArchiveOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataVolumeBlankImage) DeepCopyInto(out *DataVolumeBlankImage) {
 	*out = *in
@@ -278,6 +386,75 @@ func (in *DataVolumeBlankImage) DeepCopy() *DataVolumeBlankImage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeDiskLayout) DeepCopyInto(out *DataVolumeDiskLayout) {
+	*out = *in
+	if in.Filesystems != nil {
+		in, out := &in.Filesystems, &out.Filesystems
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeDiskLayout.
+func (in *DataVolumeDiskLayout) DeepCopy() *DataVolumeDiskLayout {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeDiskLayout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeError) DeepCopyInto(out *DataVolumeError) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeError.
+func (in *DataVolumeError) DeepCopy() *DataVolumeError {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeImageInfo) DeepCopyInto(out *DataVolumeImageInfo) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeImageInfo.
+func (in *DataVolumeImageInfo) DeepCopy() *DataVolumeImageInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeImageInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeImageValidationFailure) DeepCopyInto(out *DataVolumeImageValidationFailure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeImageValidationFailure.
+func (in *DataVolumeImageValidationFailure) DeepCopy() *DataVolumeImageValidationFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeImageValidationFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataVolumeList) DeepCopyInto(out *DataVolumeList) {
 	*out = *in
@@ -311,6 +488,22 @@ func (in *DataVolumeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeRetryPolicy) DeepCopyInto(out *DataVolumeRetryPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeRetryPolicy.
+func (in *DataVolumeRetryPolicy) DeepCopy() *DataVolumeRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataVolumeSource) DeepCopyInto(out *DataVolumeSource) {
 	*out = *in
@@ -443,9 +636,19 @@ func (in *DataVolumeSpec) DeepCopyInto(out *DataVolumeSpec) {
 	in.Source.DeepCopyInto(&out.Source)
 	if in.PVC != nil {
 		in, out := &in.PVC, &out.PVC
-		*out = new(corev1.PersistentVolumeClaimSpec)
+		*out = new(v1.PersistentVolumeClaimSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ArchiveOptions != nil {
+		in, out := &in.ArchiveOptions, &out.ArchiveOptions
+		*out = new(DataVolumeArchiveOptions)
+		**out = **in
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(DataVolumeRetryPolicy)
+		**out = **in
+	}
 	return
 }
 
@@ -462,6 +665,38 @@ func (in *DataVolumeSpec) DeepCopy() *DataVolumeSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataVolumeStatus) DeepCopyInto(out *DataVolumeStatus) {
 	*out = *in
+	if in.ImageValidationFailure != nil {
+		in, out := &in.ImageValidationFailure, &out.ImageValidationFailure
+		*out = new(DataVolumeImageValidationFailure)
+		**out = **in
+	}
+	if in.DiskLayout != nil {
+		in, out := &in.DiskLayout, &out.DiskLayout
+		*out = new(DataVolumeDiskLayout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageInfo != nil {
+		in, out := &in.ImageInfo, &out.ImageInfo
+		*out = new(DataVolumeImageInfo)
+		**out = **in
+	}
+	if in.Transfer != nil {
+		in, out := &in.Transfer, &out.Transfer
+		*out = new(DataVolumeTransferStats)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(DataVolumeError)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]conditionsv1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -474,3 +709,333 @@ func (in *DataVolumeStatus) DeepCopy() *DataVolumeStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolumeTransferStats) DeepCopyInto(out *DataVolumeTransferStats) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EstimatedSecondsRemaining != nil {
+		in, out := &in.EstimatedSecondsRemaining, &out.EstimatedSecondsRemaining
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolumeTransferStats.
+func (in *DataVolumeTransferStats) DeepCopy() *DataVolumeTransferStats {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolumeTransferStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemOverhead) DeepCopyInto(out *FilesystemOverhead) {
+	*out = *in
+	if in.StorageClass != nil {
+		in, out := &in.StorageClass, &out.StorageClass
+		*out = make(map[string]Percent, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemOverhead.
+func (in *FilesystemOverhead) DeepCopy() *FilesystemOverhead {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemOverhead)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageValidationPolicy) DeepCopyInto(out *ImageValidationPolicy) {
+	*out = *in
+	if in.MaxVirtualSize != nil {
+		in, out := &in.MaxVirtualSize, &out.MaxVirtualSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AllowedFormats != nil {
+		in, out := &in.AllowedFormats, &out.AllowedFormats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedFormats != nil {
+		in, out := &in.DeniedFormats, &out.DeniedFormats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InfoMemoryLimit != nil {
+		in, out := &in.InfoMemoryLimit, &out.InfoMemoryLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.InfoCPUTimeLimit != nil {
+		in, out := &in.InfoCPUTimeLimit, &out.InfoCPUTimeLimit
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageValidationPolicy.
+func (in *ImageValidationPolicy) DeepCopy() *ImageValidationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageValidationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImportProxy) DeepCopyInto(out *ImportProxy) {
+	*out = *in
+	if in.HTTPProxy != nil {
+		in, out := &in.HTTPProxy, &out.HTTPProxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.HTTPSProxy != nil {
+		in, out := &in.HTTPSProxy, &out.HTTPSProxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.TrustedCAProxy != nil {
+		in, out := &in.TrustedCAProxy, &out.TrustedCAProxy
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImportProxy.
+func (in *ImportProxy) DeepCopy() *ImportProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImportProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePlacement) DeepCopyInto(out *NodePlacement) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePlacement.
+func (in *NodePlacement) DeepCopy() *NodePlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageProfile) DeepCopyInto(out *StorageProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageProfile.
+func (in *StorageProfile) DeepCopy() *StorageProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StorageProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageProfileList) DeepCopyInto(out *StorageProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StorageProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageProfileList.
+func (in *StorageProfileList) DeepCopy() *StorageProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StorageProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageProfileSpec) DeepCopyInto(out *StorageProfileSpec) {
+	*out = *in
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeMode != nil {
+		in, out := &in.VolumeMode, &out.VolumeMode
+		*out = new(v1.PersistentVolumeMode)
+		**out = **in
+	}
+	if in.CloneStrategy != nil {
+		in, out := &in.CloneStrategy, &out.CloneStrategy
+		*out = new(CDICloneStrategy)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageProfileSpec.
+func (in *StorageProfileSpec) DeepCopy() *StorageProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageProfileStatus) DeepCopyInto(out *StorageProfileStatus) {
+	*out = *in
+	if in.StorageClass != nil {
+		in, out := &in.StorageClass, &out.StorageClass
+		*out = new(string)
+		**out = **in
+	}
+	if in.Provisioner != nil {
+		in, out := &in.Provisioner, &out.Provisioner
+		*out = new(string)
+		**out = **in
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeMode != nil {
+		in, out := &in.VolumeMode, &out.VolumeMode
+		*out = new(v1.PersistentVolumeMode)
+		**out = **in
+	}
+	if in.CloneStrategy != nil {
+		in, out := &in.CloneStrategy, &out.CloneStrategy
+		*out = new(CDICloneStrategy)
+		**out = **in
+	}
+	if in.FilesystemOverhead != nil {
+		in, out := &in.FilesystemOverhead, &out.FilesystemOverhead
+		*out = new(Percent)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageProfileStatus.
+func (in *StorageProfileStatus) DeepCopy() *StorageProfileStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageProfileStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TransferRateLimit) DeepCopyInto(out *TransferRateLimit) {
+	*out = *in
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make(map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransferRateLimit.
+func (in *TransferRateLimit) DeepCopy() *TransferRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(TransferRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
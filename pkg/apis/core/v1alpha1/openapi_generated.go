@@ -29,25 +29,42 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDI":                      schema_pkg_apis_core_v1alpha1_CDI(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfig":                schema_pkg_apis_core_v1alpha1_CDIConfig(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfigList":            schema_pkg_apis_core_v1alpha1_CDIConfigList(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfigSpec":            schema_pkg_apis_core_v1alpha1_CDIConfigSpec(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfigStatus":          schema_pkg_apis_core_v1alpha1_CDIConfigStatus(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIList":                  schema_pkg_apis_core_v1alpha1_CDIList(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDISpec":                  schema_pkg_apis_core_v1alpha1_CDISpec(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIStatus":                schema_pkg_apis_core_v1alpha1_CDIStatus(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolume":               schema_pkg_apis_core_v1alpha1_DataVolume(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeBlankImage":     schema_pkg_apis_core_v1alpha1_DataVolumeBlankImage(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeList":           schema_pkg_apis_core_v1alpha1_DataVolumeList(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSource":         schema_pkg_apis_core_v1alpha1_DataVolumeSource(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceHTTP":     schema_pkg_apis_core_v1alpha1_DataVolumeSourceHTTP(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourcePVC":      schema_pkg_apis_core_v1alpha1_DataVolumeSourcePVC(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceRegistry": schema_pkg_apis_core_v1alpha1_DataVolumeSourceRegistry(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceS3":       schema_pkg_apis_core_v1alpha1_DataVolumeSourceS3(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceUpload":   schema_pkg_apis_core_v1alpha1_DataVolumeSourceUpload(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSpec":           schema_pkg_apis_core_v1alpha1_DataVolumeSpec(ref),
-		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeStatus":         schema_pkg_apis_core_v1alpha1_DataVolumeStatus(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDI":                              schema_pkg_apis_core_v1alpha1_CDI(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfig":                        schema_pkg_apis_core_v1alpha1_CDIConfig(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfigList":                    schema_pkg_apis_core_v1alpha1_CDIConfigList(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfigSpec":                    schema_pkg_apis_core_v1alpha1_CDIConfigSpec(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIConfigStatus":                  schema_pkg_apis_core_v1alpha1_CDIConfigStatus(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIList":                          schema_pkg_apis_core_v1alpha1_CDIList(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDISpec":                          schema_pkg_apis_core_v1alpha1_CDISpec(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.CDIStatus":                        schema_pkg_apis_core_v1alpha1_CDIStatus(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ConcurrencyLimits":                schema_pkg_apis_core_v1alpha1_ConcurrencyLimits(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolume":                       schema_pkg_apis_core_v1alpha1_DataVolume(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeArchiveOptions":         schema_pkg_apis_core_v1alpha1_DataVolumeArchiveOptions(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeBlankImage":             schema_pkg_apis_core_v1alpha1_DataVolumeBlankImage(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeDiskLayout":             schema_pkg_apis_core_v1alpha1_DataVolumeDiskLayout(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeError":                  schema_pkg_apis_core_v1alpha1_DataVolumeError(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeImageInfo":              schema_pkg_apis_core_v1alpha1_DataVolumeImageInfo(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeImageValidationFailure": schema_pkg_apis_core_v1alpha1_DataVolumeImageValidationFailure(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeList":                   schema_pkg_apis_core_v1alpha1_DataVolumeList(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeRetryPolicy":            schema_pkg_apis_core_v1alpha1_DataVolumeRetryPolicy(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSource":                 schema_pkg_apis_core_v1alpha1_DataVolumeSource(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceHTTP":             schema_pkg_apis_core_v1alpha1_DataVolumeSourceHTTP(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourcePVC":              schema_pkg_apis_core_v1alpha1_DataVolumeSourcePVC(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceRegistry":         schema_pkg_apis_core_v1alpha1_DataVolumeSourceRegistry(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceS3":               schema_pkg_apis_core_v1alpha1_DataVolumeSourceS3(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSourceUpload":           schema_pkg_apis_core_v1alpha1_DataVolumeSourceUpload(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSpec":                   schema_pkg_apis_core_v1alpha1_DataVolumeSpec(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeStatus":                 schema_pkg_apis_core_v1alpha1_DataVolumeStatus(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeTransferStats":          schema_pkg_apis_core_v1alpha1_DataVolumeTransferStats(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.FilesystemOverhead":               schema_pkg_apis_core_v1alpha1_FilesystemOverhead(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImageValidationPolicy":            schema_pkg_apis_core_v1alpha1_ImageValidationPolicy(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImportProxy":                      schema_pkg_apis_core_v1alpha1_ImportProxy(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.NodePlacement":                    schema_pkg_apis_core_v1alpha1_NodePlacement(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfile":                   schema_pkg_apis_core_v1alpha1_StorageProfile(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileList":               schema_pkg_apis_core_v1alpha1_StorageProfileList(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileSpec":               schema_pkg_apis_core_v1alpha1_StorageProfileSpec(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileStatus":             schema_pkg_apis_core_v1alpha1_StorageProfileStatus(ref),
+		"kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.TransferRateLimit":                schema_pkg_apis_core_v1alpha1_TransferRateLimit(ref),
 	}
 }
 
@@ -208,9 +225,67 @@ func schema_pkg_apis_core_v1alpha1_CDIConfigSpec(ref common.ReferenceCallback) c
 							Format: "",
 						},
 					},
+					"importProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImportProxy is the proxy configuration used by the importer, upload server and cloner pods",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImportProxy"),
+						},
+					},
+					"imageValidation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImageValidation is the policy imported and uploaded images are validated against",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImageValidationPolicy"),
+						},
+					},
+					"transferRateLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TransferRateLimit limits the bandwidth of the importer, upload server and clone source pods",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.TransferRateLimit"),
+						},
+					},
+					"filesystemOverhead": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FilesystemOverhead is the fraction of Filesystem mode volumes reserved for file system metadata",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.FilesystemOverhead"),
+						},
+					},
+					"featureGates": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FeatureGates are the names of the optional CDI features that are enabled, such as HonorWaitForFirstConsumer",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"workloads": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Workloads is the node placement of the importer, upload server, cloner and size probe pods",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.NodePlacement"),
+						},
+					},
+					"podResourceRequirements": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PodResourceRequirements are the CPU and memory requests and limits of the importer, upload server, cloner and size probe pods, they replace the default requests of the resources they set, the pods are not limited by default",
+							Ref:         ref("k8s.io/api/core/v1.ResourceRequirements"),
+						},
+					},
+					"concurrencyLimits": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConcurrencyLimits are the maximum numbers of importer and upload server pods running at the same time",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ConcurrencyLimits"),
+						},
+					},
 				},
 			},
 		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.ResourceRequirements", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ConcurrencyLimits", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.FilesystemOverhead", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImageValidationPolicy", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImportProxy", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.NodePlacement", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.TransferRateLimit"},
 	}
 }
 
@@ -233,9 +308,40 @@ func schema_pkg_apis_core_v1alpha1_CDIConfigStatus(ref common.ReferenceCallback)
 							Format: "",
 						},
 					},
+					"importProxy": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImportProxy"),
+						},
+					},
+					"imageValidation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImageValidation is the validation policy in effect, with the defaults filled in",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImageValidationPolicy"),
+						},
+					},
+					"transferRateLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TransferRateLimit is the bandwidth limit in effect for the worker pods",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.TransferRateLimit"),
+						},
+					},
+					"filesystemOverhead": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FilesystemOverhead is the file system overhead in effect, for every storage class",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.FilesystemOverhead"),
+						},
+					},
+					"podResourceRequirements": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PodResourceRequirements are the requests and limits in effect for the worker pods, with the defaults filled in",
+							Ref:         ref("k8s.io/api/core/v1.ResourceRequirements"),
+						},
+					},
 				},
 			},
 		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.ResourceRequirements", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.FilesystemOverhead", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImageValidationPolicy", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.ImportProxy", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.TransferRateLimit"},
 	}
 }
 
@@ -369,6 +475,33 @@ func schema_pkg_apis_core_v1alpha1_CDIStatus(ref common.ReferenceCallback) commo
 	}
 }
 
+func schema_pkg_apis_core_v1alpha1_ConcurrencyLimits(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ConcurrencyLimits limits the number of worker pods populating volumes at the same time, a clone counts once",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"global": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Global is the maximum number of worker pods running in the cluster, unlimited if unset",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace is the maximum number of worker pods running in each namespace, unlimited if unset",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_core_v1alpha1_DataVolume(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -414,6 +547,40 @@ func schema_pkg_apis_core_v1alpha1_DataVolume(ref common.ReferenceCallback) comm
 	}
 }
 
+func schema_pkg_apis_core_v1alpha1_DataVolumeArchiveOptions(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeArchiveOptions controls which entries of an archive are extracted, and how",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"subPath": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SubPath is a directory or file inside the archive, only it is extracted, to the root of the volume",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"stripComponents": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StripComponents removes the given number of leading path components from the extracted entries",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"ownership": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Ownership options: \"Preserve\", \"Drop\", defaults to \"Preserve\"",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_core_v1alpha1_DataVolumeBlankImage(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -425,6 +592,151 @@ func schema_pkg_apis_core_v1alpha1_DataVolumeBlankImage(ref common.ReferenceCall
 	}
 }
 
+func schema_pkg_apis_core_v1alpha1_DataVolumeDiskLayout(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeDiskLayout describes the partitioning and file systems of an imported disk image",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"partitionTable": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PartitionTable is the type of the partition table: none, mbr or gpt",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"efiSystemPartition": {
+						SchemaProps: spec.SchemaProps{
+							Description: "EFISystemPartition is true if the disk contains an EFI system partition, so it boots with UEFI",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"filesystems": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Filesystems are the file systems detected on the disk, in partition order",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_DataVolumeError(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeError describes a failure reported by the pod populating a data volume",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"reason": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Reason is the cause of the failure, such as NotFound, Unauthorized or TransferFailed",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message describes the failure in human readable form",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"transient": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Transient is true if the failure may not happen again when the operation is retried",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"reason"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_DataVolumeImageInfo(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeImageInfo describes the disk image a data volume was populated with",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"format": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Format is the detected format of the source image",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"virtualSize": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VirtualSize is the virtual size of the source image in bytes",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"bytesTransferred": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BytesTransferred is the number of bytes read from the source",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"digest": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Digest is the content checksum of the disk image written to the volume",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_DataVolumeImageValidationFailure(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeImageValidationFailure describes the image validation policy rule an image violates",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"rule": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Rule is the violated rule, such as MaxVirtualSize or DeniedFormats",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message describes the violation in human readable form",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"rule"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_core_v1alpha1_DataVolumeList(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -473,6 +785,40 @@ func schema_pkg_apis_core_v1alpha1_DataVolumeList(ref common.ReferenceCallback)
 	}
 }
 
+func schema_pkg_apis_core_v1alpha1_DataVolumeRetryPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeRetryPolicy controls how often and when a failed import is retried",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"maxAttempts": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MaxAttempts is the number of times the import is attempted before the data volume fails, 0 means no limit",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"backoffSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BackoffSeconds is the delay before the first retry, it doubles with every further retry up to five minutes, defaults to 10",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"restartOnTransientOnly": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RestartOnTransientOnly stops retrying as soon as the import fails for a reason that will not go away on a retry, like a missing source or an invalid image",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_core_v1alpha1_DataVolumeSource(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -546,6 +892,34 @@ func schema_pkg_apis_core_v1alpha1_DataVolumeSourceHTTP(ref common.ReferenceCall
 							Format:      "",
 						},
 					},
+					"clientCertSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClientCertSecret provides a reference to a kubernetes.io/tls Secret holding the client certificate presented to the HTTP source",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"checksumURL": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ChecksumURL is the URL of a SHA256SUMS style file containing the digest of the http source",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"checksumSignatureURL": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ChecksumSignatureURL is the URL of a detached GPG signature of the checksum file, the checksum file may also be clearsigned",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"checksumKeyConfigMap": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ChecksumKeyConfigMap provides a reference to a ConfigMap containing the GPG public keys used to verify the checksum file",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
 			},
 		},
@@ -670,17 +1044,29 @@ func schema_pkg_apis_core_v1alpha1_DataVolumeSpec(ref common.ReferenceCallback)
 					},
 					"contentType": {
 						SchemaProps: spec.SchemaProps{
-							Description: "DataVolumeContentType options: \"kubevirt\", \"archive\"",
+							Description: "DataVolumeContentType options: \"kubevirt\", \"archive\", \"iso\"",
 							Type:        []string{"string"},
 							Format:      "",
 						},
 					},
+					"archiveOptions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ArchiveOptions control how \"archive\" content is extracted",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeArchiveOptions"),
+						},
+					},
+					"retryPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RetryPolicy controls if and when a failed import is retried, without it the importer pod is restarted on every failure",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeRetryPolicy"),
+						},
+					},
 				},
 				Required: []string{"source", "pvc"},
 			},
 		},
 		Dependencies: []string{
-			"k8s.io/api/core/v1.PersistentVolumeClaimSpec", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSource"},
+			"k8s.io/api/core/v1.PersistentVolumeClaimSpec", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeArchiveOptions", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeRetryPolicy", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeSource"},
 	}
 }
 
@@ -704,8 +1090,561 @@ func schema_pkg_apis_core_v1alpha1_DataVolumeStatus(ref common.ReferenceCallback
 							Format: "",
 						},
 					},
-				},
-			},
-		},
+					"imageValidationFailure": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImageValidationFailure describes why the image validation policy rejected the imported image",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeImageValidationFailure"),
+						},
+					},
+					"diskLayout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DiskLayout is the partitioning and file systems detected on the imported disk image",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeDiskLayout"),
+						},
+					},
+					"imageInfo": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImageInfo describes the disk image the data volume was populated with",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeImageInfo"),
+						},
+					},
+					"transfer": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Transfer describes the progress of the transfer populating the data volume",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeTransferStats"),
+						},
+					},
+					"lastError": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastError is the last failure reported by the pod populating the data volume",
+							Ref:         ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeError"),
+						},
+					},
+					"restartCount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RestartCount is the number of times the pod populating the data volume was restarted after a failure",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"queuePosition": {
+						SchemaProps: spec.SchemaProps{
+							Description: "QueuePosition is the position of the data volume in the queue of the volumes waiting for a worker pod, starting at 1, while the data volume is Queued",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"conditions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Conditions are the latest observations of the data volume",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/openshift/custom-resource-status/conditions/v1.Condition"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/openshift/custom-resource-status/conditions/v1.Condition", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeDiskLayout", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeError", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeImageInfo", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeImageValidationFailure", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.DataVolumeTransferStats"},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_DataVolumeTransferStats(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DataVolumeTransferStats describes the progress of the transfer populating a data volume",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"bytesTransferred": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BytesTransferred is the number of bytes read from the source so far",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"totalBytes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TotalBytes is the number of bytes to read from the source, if it is known",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"throughput": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Throughput is the current transfer rate in bytes per second",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"startTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StartTime is the time the transfer started",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CompletionTime is the time the transfer completed",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"estimatedSecondsRemaining": {
+						SchemaProps: spec.SchemaProps{
+							Description: "EstimatedSecondsRemaining is the estimated time until the transfer completes, based on the average transfer rate",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_FilesystemOverhead(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "FilesystemOverhead defines the fraction of a Filesystem mode volume that is not available to the disk image",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"global": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Global is the overhead of the storage classes without an overhead of their own, 0.055 if not set",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"storageClass": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageClass are the overheads of the named storage classes, they take precedence over the global overhead",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_ImageValidationPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ImageValidationPolicy defines which images the importer and upload server accept",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"maxVirtualSize": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MaxVirtualSize is the largest virtual size of an accepted image",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"allowedFormats": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllowedFormats are the qemu-img formats of the accepted images, raw and qcow2 if empty",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"deniedFormats": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeniedFormats are the qemu-img formats of rejected images, it takes precedence over AllowedFormats",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"rejectEncrypted": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RejectEncrypted rejects encrypted images",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"rejectCompressed": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RejectCompressed rejects qcow2 images with compressed clusters",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"infoMemoryLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InfoMemoryLimit is the address space limit of the qemu-img process inspecting images, 1Gi if not set",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"infoCPUTimeLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InfoCPUTimeLimit is the CPU time limit in seconds of the qemu-img process inspecting images, 30 if not set",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity"},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_ImportProxy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ImportProxy provides the proxy configuration for the CDI worker pods",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"httpProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "HTTPProxy is the URL of the proxy used for http requests",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"httpsProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "HTTPSProxy is the URL of the proxy used for https requests",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"noProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NoProxy is a comma separated list of hosts, domains and CIDRs that are accessed without the proxy",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"trustedCAProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TrustedCAProxy is the name of a ConfigMap in the CDI namespace containing the CA certificates of the proxy",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_NodePlacement(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NodePlacement describes the nodes pods are scheduled onto",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"nodeSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeSelector are the labels a node must have for the pods to run on it",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"affinity": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Affinity are the scheduling constraints of the pods",
+							Ref:         ref("k8s.io/api/core/v1.Affinity"),
+						},
+					},
+					"tolerations": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Tolerations are the taints of the nodes the pods tolerate",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/api/core/v1.Toleration"),
+									},
+								},
+							},
+						},
+					},
+					"priorityClassName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PriorityClassName is the name of the priority class of the pods",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Toleration"},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_StorageProfile(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StorageProfile provides the recommended PVC settings of the StorageClass of the same name",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileSpec"),
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileStatus"),
+						},
+					},
+				},
+				Required: []string{"spec"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileSpec", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfileStatus"},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_StorageProfileList(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StorageProfileList provides the needed parameters to request a list of StorageProfiles from the system",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"),
+						},
+					},
+					"items": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Items provides a list of StorageProfiles",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfile"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"metadata", "items"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta", "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1.StorageProfile"},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_StorageProfileSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StorageProfileSpec defines the PVC settings overriding the ones detected for the StorageClass",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"accessModes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AccessModes are the access modes of PVCs of the storage class",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"volumeMode": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VolumeMode is the volume mode of PVCs of the storage class",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"cloneStrategy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CloneStrategy is how PVCs of the storage class are cloned",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_StorageProfileStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StorageProfileStatus provides the recommended PVC settings in effect for the StorageClass",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"storageClass": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageClass is the name of the storage class",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"provisioner": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Provisioner is the provisioner of the storage class",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"accessModes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AccessModes are the recommended access modes, empty if there is no recommendation",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"volumeMode": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VolumeMode is the recommended volume mode, nil if there is no recommendation",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"cloneStrategy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CloneStrategy is how PVCs of the storage class are cloned",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"filesystemOverhead": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FilesystemOverhead is the fraction of Filesystem mode volumes of the storage class reserved for file system metadata",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_core_v1alpha1_TransferRateLimit(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TransferRateLimit limits the bytes per second a single import, upload or clone transfers",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"default": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Default is the limit of the transfers into PVCs of namespaces that have no limit of their own, unlimited if not set",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+					"namespaces": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespaces are the limits of the transfers into PVCs of the named namespaces, they take precedence over the default",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
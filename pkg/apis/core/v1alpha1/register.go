@@ -38,6 +38,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&DataVolumeList{},
 		&CDIConfig{},
 		&CDIConfigList{},
+		&StorageProfile{},
+		&StorageProfileList{},
 		&CDI{},
 		&CDIList{},
 	)
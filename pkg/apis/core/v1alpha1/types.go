@@ -22,6 +22,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	conditions "github.com/openshift/custom-resource-status/conditions/v1"
@@ -44,8 +45,14 @@ type DataVolumeSpec struct {
 	Source DataVolumeSource `json:"source"`
 	//PVC is a pointer to the PVC Spec we want to use
 	PVC *corev1.PersistentVolumeClaimSpec `json:"pvc"`
-	//DataVolumeContentType options: "kubevirt", "archive"
+	//DataVolumeContentType options: "kubevirt", "archive", "iso"
 	ContentType DataVolumeContentType `json:"contentType,omitempty"`
+	//ArchiveOptions control how "archive" content is extracted
+	// +optional
+	ArchiveOptions *DataVolumeArchiveOptions `json:"archiveOptions,omitempty"`
+	//RetryPolicy controls if and when a failed import is retried, without it the importer pod is restarted on every failure
+	// +optional
+	RetryPolicy *DataVolumeRetryPolicy `json:"retryPolicy,omitempty"`
 }
 
 // DataVolumeContentType represents the types of the imported data
@@ -56,6 +63,45 @@ const (
 	DataVolumeKubeVirt DataVolumeContentType = "kubevirt"
 	// DataVolumeArchive is the content-type to specify if there is a need to extract the imported archive
 	DataVolumeArchive DataVolumeContentType = "archive"
+	// DataVolumeISO is the content-type of an ISO 9660 or UDF image, which is written as is without conversion or resize
+	DataVolumeISO DataVolumeContentType = "iso"
+)
+
+// DataVolumeArchiveOptions controls which entries of an archive are extracted, and how
+type DataVolumeArchiveOptions struct {
+	// SubPath is a directory or file inside the archive, only it is extracted, to the root of the volume
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
+	// StripComponents removes the given number of leading path components from the extracted entries
+	// +optional
+	StripComponents int32 `json:"stripComponents,omitempty"`
+	// Ownership options: "Preserve", "Drop", defaults to "Preserve"
+	// +optional
+	Ownership ArchiveOwnership `json:"ownership,omitempty"`
+}
+
+// DataVolumeRetryPolicy controls how often and when a failed import is retried
+type DataVolumeRetryPolicy struct {
+	// MaxAttempts is the number of times the import is attempted before the data volume fails, 0 means no limit
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+	// BackoffSeconds is the delay before the first retry, it doubles with every further retry up to five minutes, defaults to 10
+	// +optional
+	BackoffSeconds int32 `json:"backoffSeconds,omitempty"`
+	// RestartOnTransientOnly stops retrying as soon as the import fails for a reason that will not go away on a retry,
+	// like a missing source or an invalid image
+	// +optional
+	RestartOnTransientOnly bool `json:"restartOnTransientOnly,omitempty"`
+}
+
+// ArchiveOwnership defines what happens to the owner of the extracted files
+type ArchiveOwnership string
+
+const (
+	// ArchiveOwnershipPreserve keeps the user and group recorded in the archive
+	ArchiveOwnershipPreserve ArchiveOwnership = "Preserve"
+	// ArchiveOwnershipDrop leaves the extracted files owned by the importer
+	ArchiveOwnershipDrop ArchiveOwnership = "Drop"
 )
 
 // DataVolumeSource represents the source for our Data Volume, this can be HTTP, S3, Registry or an existing PVC
@@ -108,6 +154,14 @@ type DataVolumeSourceHTTP struct {
 	SecretRef string `json:"secretRef,omitempty"`
 	//CertConfigMap provides a reference to the Registry certs
 	CertConfigMap string `json:"certConfigMap,omitempty"`
+	//ClientCertSecret provides a reference to a kubernetes.io/tls Secret holding the client certificate presented to the HTTP source
+	ClientCertSecret string `json:"clientCertSecret,omitempty"`
+	//ChecksumURL is the URL of a SHA256SUMS style file containing the digest of the http source
+	ChecksumURL string `json:"checksumURL,omitempty"`
+	//ChecksumSignatureURL is the URL of a detached GPG signature of the checksum file, the checksum file may also be clearsigned
+	ChecksumSignatureURL string `json:"checksumSignatureURL,omitempty"`
+	//ChecksumKeyConfigMap provides a reference to a ConfigMap containing the GPG public keys used to verify the checksum file
+	ChecksumKeyConfigMap string `json:"checksumKeyConfigMap,omitempty"`
 }
 
 // DataVolumeStatus provides the parameters to store the phase of the Data Volume
@@ -115,8 +169,92 @@ type DataVolumeStatus struct {
 	//Phase is the current phase of the data volume
 	Phase    DataVolumePhase    `json:"phase,omitempty"`
 	Progress DataVolumeProgress `json:"progress,omitempty"`
+	//ImageValidationFailure describes why the image validation policy rejected the imported image
+	ImageValidationFailure *DataVolumeImageValidationFailure `json:"imageValidationFailure,omitempty"`
+	//DiskLayout is the partitioning and file systems detected on the imported disk image
+	DiskLayout *DataVolumeDiskLayout `json:"diskLayout,omitempty"`
+	//ImageInfo describes the disk image the data volume was populated with
+	ImageInfo *DataVolumeImageInfo `json:"imageInfo,omitempty"`
+	//Transfer describes the progress of the transfer populating the data volume
+	Transfer *DataVolumeTransferStats `json:"transfer,omitempty"`
+	//LastError is the last failure reported by the pod populating the data volume
+	LastError *DataVolumeError `json:"lastError,omitempty"`
+	//RestartCount is the number of times the pod populating the data volume was restarted after a failure
+	RestartCount int32 `json:"restartCount,omitempty"`
+	//QueuePosition is the position of the data volume in the queue of the volumes waiting for a worker pod, starting
+	//at 1, while the data volume is Queued
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+	//Conditions are the latest observations of the data volume
+	Conditions []conditions.Condition `json:"conditions,omitempty" optional:"true"`
+}
+
+//DataVolumeImageValidationFailure describes the image validation policy rule an image violates
+type DataVolumeImageValidationFailure struct {
+	//Rule is the violated rule, such as MaxVirtualSize or DeniedFormats
+	Rule string `json:"rule"`
+	//Message describes the violation in human readable form
+	Message string `json:"message,omitempty"`
+}
+
+//DataVolumeDiskLayout describes the partitioning and file systems of an imported disk image
+type DataVolumeDiskLayout struct {
+	//PartitionTable is the type of the partition table: none, mbr or gpt
+	PartitionTable string `json:"partitionTable,omitempty"`
+	//EFISystemPartition is true if the disk contains an EFI system partition, so it boots with UEFI
+	EFISystemPartition bool `json:"efiSystemPartition,omitempty"`
+	//Filesystems are the file systems detected on the disk, in partition order
+	Filesystems []string `json:"filesystems,omitempty"`
+}
+
+//DataVolumeImageInfo describes the disk image a data volume was populated with
+type DataVolumeImageInfo struct {
+	//Format is the detected format of the source image
+	Format string `json:"format,omitempty"`
+	//VirtualSize is the virtual size of the source image in bytes
+	VirtualSize int64 `json:"virtualSize,omitempty"`
+	//BytesTransferred is the number of bytes read from the source
+	BytesTransferred int64 `json:"bytesTransferred,omitempty"`
+	//Digest is the content checksum of the disk image written to the volume
+	Digest string `json:"digest,omitempty"`
+}
+
+//DataVolumeTransferStats describes the progress of the transfer populating a data volume
+type DataVolumeTransferStats struct {
+	//BytesTransferred is the number of bytes read from the source so far
+	BytesTransferred int64 `json:"bytesTransferred,omitempty"`
+	//TotalBytes is the number of bytes to read from the source, if it is known
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+	//Throughput is the current transfer rate in bytes per second
+	Throughput int64 `json:"throughput,omitempty"`
+	//StartTime is the time the transfer started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	//CompletionTime is the time the transfer completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	//EstimatedSecondsRemaining is the estimated time until the transfer completes, based on the average transfer rate
+	EstimatedSecondsRemaining *int64 `json:"estimatedSecondsRemaining,omitempty"`
 }
 
+//DataVolumeError describes a failure reported by the pod populating a data volume
+type DataVolumeError struct {
+	//Reason is the cause of the failure, such as NotFound, Unauthorized or TransferFailed
+	Reason string `json:"reason"`
+	//Message describes the failure in human readable form
+	Message string `json:"message,omitempty"`
+	//Transient is true if the failure may not happen again when the operation is retried
+	Transient bool `json:"transient,omitempty"`
+}
+
+const (
+	// DataVolumeBound is the condition type reporting whether the PVC of the data volume is bound
+	DataVolumeBound conditions.ConditionType = "Bound"
+	// DataVolumeRunning is the condition type reporting whether the data volume is being populated, usually by a
+	// worker pod. If it is not the reason tells if it did not start yet, completed or failed.
+	DataVolumeRunning conditions.ConditionType = "Running"
+	// DataVolumeReady is the condition type reporting whether the data volume is populated and can be used. The reason
+	// is the phase of the data volume.
+	DataVolumeReady conditions.ConditionType = "Ready"
+)
+
 //DataVolumeList provides the needed parameters to do request a list of Data Volumes from the system
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type DataVolumeList struct {
@@ -142,6 +280,17 @@ const (
 	// PVCBound represents a data volume with a current phase of PVCBound
 	PVCBound DataVolumePhase = "PVCBound"
 
+	// SizeProbeInProgress represents a data volume with a current phase of SizeProbeInProgress
+	SizeProbeInProgress DataVolumePhase = "SizeProbeInProgress"
+
+	// WaitForFirstConsumer represents a data volume with a current phase of WaitForFirstConsumer, its PVC is in a
+	// storage class binding volumes only once a pod uses them, and no pod except the CDI ones uses it yet
+	WaitForFirstConsumer DataVolumePhase = "WaitForFirstConsumer"
+
+	// Queued represents a data volume with a current phase of Queued, its worker pod waits for the number of running
+	// worker pods to drop below the concurrency limits
+	Queued DataVolumePhase = "Queued"
+
 	// ImportScheduled represents a data volume with a current phase of ImportScheduled
 	ImportScheduled DataVolumePhase = "ImportScheduled"
 
@@ -262,12 +411,113 @@ type CDIConfig struct {
 type CDIConfigSpec struct {
 	UploadProxyURLOverride   *string `json:"uploadProxyURLOverride,omitempty"`
 	ScratchSpaceStorageClass *string `json:"scratchSpaceStorageClass,omitempty"`
+	// ImportProxy is the proxy configuration used by the importer, upload server and cloner pods
+	ImportProxy *ImportProxy `json:"importProxy,omitempty"`
+	// ImageValidation is the policy imported and uploaded images are validated against
+	ImageValidation *ImageValidationPolicy `json:"imageValidation,omitempty"`
+	// TransferRateLimit limits the bandwidth of the importer, upload server and clone source pods
+	TransferRateLimit *TransferRateLimit `json:"transferRateLimit,omitempty"`
+	// FilesystemOverhead is the fraction of Filesystem mode volumes reserved for file system metadata
+	FilesystemOverhead *FilesystemOverhead `json:"filesystemOverhead,omitempty"`
+	// FeatureGates are the names of the optional CDI features that are enabled, such as HonorWaitForFirstConsumer
+	FeatureGates []string `json:"featureGates,omitempty"`
+	// Workloads is the node placement of the importer, upload server, cloner and size probe pods
+	Workloads *NodePlacement `json:"workloads,omitempty"`
+	// PodResourceRequirements are the CPU and memory requests and limits of the importer, upload server, cloner and
+	// size probe pods, they replace the default requests of the resources they set, the pods are not limited by default
+	PodResourceRequirements *corev1.ResourceRequirements `json:"podResourceRequirements,omitempty"`
+	// ConcurrencyLimits are the maximum numbers of importer and upload server pods running at the same time
+	ConcurrencyLimits *ConcurrencyLimits `json:"concurrencyLimits,omitempty"`
 }
 
 //CDIConfigStatus provides
 type CDIConfigStatus struct {
-	UploadProxyURL           *string `json:"uploadProxyURL,omitempty"`
-	ScratchSpaceStorageClass string  `json:"scratchSpaceStorageClass,omitempty"`
+	UploadProxyURL           *string      `json:"uploadProxyURL,omitempty"`
+	ScratchSpaceStorageClass string       `json:"scratchSpaceStorageClass,omitempty"`
+	ImportProxy              *ImportProxy `json:"importProxy,omitempty"`
+	// ImageValidation is the validation policy in effect, with the defaults filled in
+	ImageValidation *ImageValidationPolicy `json:"imageValidation,omitempty"`
+	// TransferRateLimit is the bandwidth limit in effect for the worker pods
+	TransferRateLimit *TransferRateLimit `json:"transferRateLimit,omitempty"`
+	// FilesystemOverhead is the file system overhead in effect, for every storage class
+	FilesystemOverhead *FilesystemOverhead `json:"filesystemOverhead,omitempty"`
+	// PodResourceRequirements are the requests and limits in effect for the worker pods, with the defaults filled in
+	PodResourceRequirements *corev1.ResourceRequirements `json:"podResourceRequirements,omitempty"`
+}
+
+//Percent is a fraction between 0 (inclusive) and 1 (exclusive), such as "0.055"
+type Percent string
+
+//FilesystemOverhead defines the fraction of a Filesystem mode volume that is not available to the disk image
+type FilesystemOverhead struct {
+	// Global is the overhead of the storage classes without an overhead of their own, 0.055 if not set
+	Global Percent `json:"global,omitempty"`
+	// StorageClass are the overheads of the named storage classes, they take precedence over the global overhead
+	StorageClass map[string]Percent `json:"storageClass,omitempty"`
+}
+
+//TransferRateLimit limits the bytes per second a single import, upload or clone transfers
+type TransferRateLimit struct {
+	// Default is the limit of the transfers into PVCs of namespaces that have no limit of their own, unlimited if not set
+	Default *resource.Quantity `json:"default,omitempty"`
+	// Namespaces are the limits of the transfers into PVCs of the named namespaces, they take precedence over the default
+	Namespaces map[string]resource.Quantity `json:"namespaces,omitempty"`
+}
+
+//ImageValidationPolicy defines which images the importer and upload server accept
+type ImageValidationPolicy struct {
+	// MaxVirtualSize is the largest virtual size of an accepted image
+	MaxVirtualSize *resource.Quantity `json:"maxVirtualSize,omitempty"`
+	// AllowedFormats are the qemu-img formats of the accepted images, raw and qcow2 if empty
+	AllowedFormats []string `json:"allowedFormats,omitempty"`
+	// DeniedFormats are the qemu-img formats of rejected images, it takes precedence over AllowedFormats
+	DeniedFormats []string `json:"deniedFormats,omitempty"`
+	// RejectEncrypted rejects encrypted images
+	RejectEncrypted bool `json:"rejectEncrypted,omitempty"`
+	// RejectCompressed rejects qcow2 images with compressed clusters
+	RejectCompressed bool `json:"rejectCompressed,omitempty"`
+	// InfoMemoryLimit is the address space limit of the qemu-img process inspecting images, 1Gi if not set
+	InfoMemoryLimit *resource.Quantity `json:"infoMemoryLimit,omitempty"`
+	// InfoCPUTimeLimit is the CPU time limit in seconds of the qemu-img process inspecting images, 30 if not set
+	InfoCPUTimeLimit *int64 `json:"infoCPUTimeLimit,omitempty"`
+}
+
+//ConcurrencyLimits limits the number of worker pods populating volumes at the same time, a clone counts once
+type ConcurrencyLimits struct {
+	// Global is the maximum number of worker pods running in the cluster, unlimited if unset
+	// +optional
+	Global *int32 `json:"global,omitempty"`
+	// Namespace is the maximum number of worker pods running in each namespace, unlimited if unset
+	// +optional
+	Namespace *int32 `json:"namespace,omitempty"`
+}
+
+//NodePlacement describes the nodes pods are scheduled onto
+type NodePlacement struct {
+	// NodeSelector are the labels a node must have for the pods to run on it
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Affinity are the scheduling constraints of the pods
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Tolerations are the taints of the nodes the pods tolerate
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// PriorityClassName is the name of the priority class of the pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+//ImportProxy provides the proxy configuration for the CDI worker pods
+type ImportProxy struct {
+	// HTTPProxy is the URL of the proxy used for http requests
+	HTTPProxy *string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the URL of the proxy used for https requests
+	HTTPSProxy *string `json:"httpsProxy,omitempty"`
+	// NoProxy is a comma separated list of hosts, domains and CIDRs that are accessed without the proxy
+	NoProxy *string `json:"noProxy,omitempty"`
+	// TrustedCAProxy is the name of a ConfigMap in the CDI namespace containing the CA certificates of the proxy
+	TrustedCAProxy *string `json:"trustedCAProxy,omitempty"`
 }
 
 //CDIConfigList provides the needed parameters to do request a list of CDIConfigs from the system
@@ -279,3 +529,64 @@ type CDIConfigList struct {
 	// Items provides a list of CDIConfigs
 	Items []CDIConfig `json:"items"`
 }
+
+// this has to be here otherwise informer-gen doesn't recognize it
+// see https://github.com/kubernetes/code-generator/issues/59
+// +genclient:nonNamespaced
+
+// StorageProfile provides the recommended PVC settings of the StorageClass of the same name
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StorageProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StorageProfileSpec   `json:"spec"`
+	Status StorageProfileStatus `json:"status,omitempty"`
+}
+
+//StorageProfileSpec defines the PVC settings overriding the ones detected for the StorageClass
+type StorageProfileSpec struct {
+	// AccessModes are the access modes of PVCs of the storage class
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// VolumeMode is the volume mode of PVCs of the storage class
+	VolumeMode *corev1.PersistentVolumeMode `json:"volumeMode,omitempty"`
+	// CloneStrategy is how PVCs of the storage class are cloned
+	CloneStrategy *CDICloneStrategy `json:"cloneStrategy,omitempty"`
+}
+
+//StorageProfileStatus provides the recommended PVC settings in effect for the StorageClass
+type StorageProfileStatus struct {
+	// StorageClass is the name of the storage class
+	StorageClass *string `json:"storageClass,omitempty"`
+	// Provisioner is the provisioner of the storage class
+	Provisioner *string `json:"provisioner,omitempty"`
+	// AccessModes are the recommended access modes, empty if there is no recommendation
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// VolumeMode is the recommended volume mode, nil if there is no recommendation
+	VolumeMode *corev1.PersistentVolumeMode `json:"volumeMode,omitempty"`
+	// CloneStrategy is how PVCs of the storage class are cloned
+	CloneStrategy *CDICloneStrategy `json:"cloneStrategy,omitempty"`
+	// FilesystemOverhead is the fraction of Filesystem mode volumes of the storage class reserved for file system metadata
+	FilesystemOverhead *Percent `json:"filesystemOverhead,omitempty"`
+}
+
+//CDICloneStrategy defines how a PVC is cloned
+type CDICloneStrategy string
+
+const (
+	// CloneStrategySnapshot clones a PVC by creating a PVC from a snapshot of the source
+	CloneStrategySnapshot CDICloneStrategy = "snapshot"
+	// CloneStrategyHostAssisted clones a PVC by copying the source with a pair of worker pods
+	CloneStrategyHostAssisted CDICloneStrategy = "copy"
+)
+
+//StorageProfileList provides the needed parameters to request a list of StorageProfiles from the system
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StorageProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	// Items provides a list of StorageProfiles
+	Items []StorageProfile `json:"items"`
+}
@@ -10,10 +10,30 @@ func (DataVolume) SwaggerDoc() map[string]string {
 
 func (DataVolumeSpec) SwaggerDoc() map[string]string {
 	return map[string]string{
-		"":            "DataVolumeSpec defines our specification for a DataVolume type",
-		"source":      "Source is the src of the data for the requested DataVolume",
-		"pvc":         "PVC is a pointer to the PVC Spec we want to use",
-		"contentType": "DataVolumeContentType options: \"kubevirt\", \"archive\"",
+		"":               "DataVolumeSpec defines our specification for a DataVolume type",
+		"source":         "Source is the src of the data for the requested DataVolume",
+		"pvc":            "PVC is a pointer to the PVC Spec we want to use",
+		"contentType":    "DataVolumeContentType options: \"kubevirt\", \"archive\", \"iso\"",
+		"archiveOptions": "ArchiveOptions control how \"archive\" content is extracted\n+optional",
+		"retryPolicy":    "RetryPolicy controls if and when a failed import is retried, without it the importer pod is restarted on every failure\n+optional",
+	}
+}
+
+func (DataVolumeArchiveOptions) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                "DataVolumeArchiveOptions controls which entries of an archive are extracted, and how",
+		"subPath":         "SubPath is a directory or file inside the archive, only it is extracted, to the root of the volume\n+optional",
+		"stripComponents": "StripComponents removes the given number of leading path components from the extracted entries\n+optional",
+		"ownership":       "Ownership options: \"Preserve\", \"Drop\", defaults to \"Preserve\"\n+optional",
+	}
+}
+
+func (DataVolumeRetryPolicy) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                       "DataVolumeRetryPolicy controls how often and when a failed import is retried",
+		"maxAttempts":            "MaxAttempts is the number of times the import is attempted before the data volume fails, 0 means no limit\n+optional",
+		"backoffSeconds":         "BackoffSeconds is the delay before the first retry, it doubles with every further retry up to five minutes, defaults to 10\n+optional",
+		"restartOnTransientOnly": "RestartOnTransientOnly stops retrying as soon as the import fails for a reason that will not go away on a retry,\nlike a missing source or an invalid image\n+optional",
 	}
 }
 
@@ -60,17 +80,77 @@ func (DataVolumeSourceRegistry) SwaggerDoc() map[string]string {
 
 func (DataVolumeSourceHTTP) SwaggerDoc() map[string]string {
 	return map[string]string{
-		"":              "DataVolumeSourceHTTP provides the parameters to create a Data Volume from an HTTP source",
-		"url":           "URL is the URL of the http source",
-		"secretRef":     "SecretRef provides the secret reference needed to access the HTTP source",
-		"certConfigMap": "CertConfigMap provides a reference to the Registry certs",
+		"":                     "DataVolumeSourceHTTP provides the parameters to create a Data Volume from an HTTP source",
+		"url":                  "URL is the URL of the http source",
+		"secretRef":            "SecretRef provides the secret reference needed to access the HTTP source",
+		"certConfigMap":        "CertConfigMap provides a reference to the Registry certs",
+		"clientCertSecret":     "ClientCertSecret provides a reference to a kubernetes.io/tls Secret holding the client certificate presented to the HTTP source",
+		"checksumURL":          "ChecksumURL is the URL of a SHA256SUMS style file containing the digest of the http source",
+		"checksumSignatureURL": "ChecksumSignatureURL is the URL of a detached GPG signature of the checksum file, the checksum file may also be clearsigned",
+		"checksumKeyConfigMap": "ChecksumKeyConfigMap provides a reference to a ConfigMap containing the GPG public keys used to verify the checksum file",
 	}
 }
 
 func (DataVolumeStatus) SwaggerDoc() map[string]string {
 	return map[string]string{
-		"":      "DataVolumeStatus provides the parameters to store the phase of the Data Volume",
-		"phase": "Phase is the current phase of the data volume",
+		"":                       "DataVolumeStatus provides the parameters to store the phase of the Data Volume",
+		"phase":                  "Phase is the current phase of the data volume",
+		"imageValidationFailure": "ImageValidationFailure describes why the image validation policy rejected the imported image",
+		"diskLayout":             "DiskLayout is the partitioning and file systems detected on the imported disk image",
+		"imageInfo":              "ImageInfo describes the disk image the data volume was populated with",
+		"transfer":               "Transfer describes the progress of the transfer populating the data volume",
+		"lastError":              "LastError is the last failure reported by the pod populating the data volume",
+		"restartCount":           "RestartCount is the number of times the pod populating the data volume was restarted after a failure",
+		"queuePosition":          "QueuePosition is the position of the data volume in the queue of the volumes waiting for a worker pod, starting\nat 1, while the data volume is Queued",
+		"conditions":             "Conditions are the latest observations of the data volume",
+	}
+}
+
+func (DataVolumeImageValidationFailure) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":        "DataVolumeImageValidationFailure describes the image validation policy rule an image violates",
+		"rule":    "Rule is the violated rule, such as MaxVirtualSize or DeniedFormats",
+		"message": "Message describes the violation in human readable form",
+	}
+}
+
+func (DataVolumeDiskLayout) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                   "DataVolumeDiskLayout describes the partitioning and file systems of an imported disk image",
+		"partitionTable":     "PartitionTable is the type of the partition table: none, mbr or gpt",
+		"efiSystemPartition": "EFISystemPartition is true if the disk contains an EFI system partition, so it boots with UEFI",
+		"filesystems":        "Filesystems are the file systems detected on the disk, in partition order",
+	}
+}
+
+func (DataVolumeImageInfo) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                 "DataVolumeImageInfo describes the disk image a data volume was populated with",
+		"format":           "Format is the detected format of the source image",
+		"virtualSize":      "VirtualSize is the virtual size of the source image in bytes",
+		"bytesTransferred": "BytesTransferred is the number of bytes read from the source",
+		"digest":           "Digest is the content checksum of the disk image written to the volume",
+	}
+}
+
+func (DataVolumeTransferStats) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                          "DataVolumeTransferStats describes the progress of the transfer populating a data volume",
+		"bytesTransferred":          "BytesTransferred is the number of bytes read from the source so far",
+		"totalBytes":                "TotalBytes is the number of bytes to read from the source, if it is known",
+		"throughput":                "Throughput is the current transfer rate in bytes per second",
+		"startTime":                 "StartTime is the time the transfer started",
+		"completionTime":            "CompletionTime is the time the transfer completed",
+		"estimatedSecondsRemaining": "EstimatedSecondsRemaining is the estimated time until the transfer completes, based on the average transfer rate",
+	}
+}
+
+func (DataVolumeError) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":          "DataVolumeError describes a failure reported by the pod populating a data volume",
+		"reason":    "Reason is the cause of the failure, such as NotFound, Unauthorized or TransferFailed",
+		"message":   "Message describes the failure in human readable form",
+		"transient": "Transient is true if the failure may not happen again when the operation is retried",
 	}
 }
 
@@ -114,13 +194,82 @@ func (CDIConfig) SwaggerDoc() map[string]string {
 
 func (CDIConfigSpec) SwaggerDoc() map[string]string {
 	return map[string]string{
-		"": "CDIConfigSpec defines specification for user configuration",
+		"":                        "CDIConfigSpec defines specification for user configuration",
+		"importProxy":             "ImportProxy is the proxy configuration used by the importer, upload server and cloner pods",
+		"imageValidation":         "ImageValidation is the policy imported and uploaded images are validated against",
+		"transferRateLimit":       "TransferRateLimit limits the bandwidth of the importer, upload server and clone source pods",
+		"filesystemOverhead":      "FilesystemOverhead is the fraction of Filesystem mode volumes reserved for file system metadata",
+		"featureGates":            "FeatureGates are the names of the optional CDI features that are enabled, such as HonorWaitForFirstConsumer",
+		"workloads":               "Workloads is the node placement of the importer, upload server, cloner and size probe pods",
+		"podResourceRequirements": "PodResourceRequirements are the CPU and memory requests and limits of the importer, upload server, cloner and\nsize probe pods, they replace the default requests of the resources they set, the pods are not limited by default",
+		"concurrencyLimits":       "ConcurrencyLimits are the maximum numbers of importer and upload server pods running at the same time",
 	}
 }
 
 func (CDIConfigStatus) SwaggerDoc() map[string]string {
 	return map[string]string{
-		"": "CDIConfigStatus provides",
+		"":                        "CDIConfigStatus provides",
+		"imageValidation":         "ImageValidation is the validation policy in effect, with the defaults filled in",
+		"transferRateLimit":       "TransferRateLimit is the bandwidth limit in effect for the worker pods",
+		"filesystemOverhead":      "FilesystemOverhead is the file system overhead in effect, for every storage class",
+		"podResourceRequirements": "PodResourceRequirements are the requests and limits in effect for the worker pods, with the defaults filled in",
+	}
+}
+
+func (FilesystemOverhead) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":             "FilesystemOverhead defines the fraction of a Filesystem mode volume that is not available to the disk image",
+		"global":       "Global is the overhead of the storage classes without an overhead of their own, 0.055 if not set",
+		"storageClass": "StorageClass are the overheads of the named storage classes, they take precedence over the global overhead",
+	}
+}
+
+func (TransferRateLimit) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":           "TransferRateLimit limits the bytes per second a single import, upload or clone transfers",
+		"default":    "Default is the limit of the transfers into PVCs of namespaces that have no limit of their own, unlimited if not set",
+		"namespaces": "Namespaces are the limits of the transfers into PVCs of the named namespaces, they take precedence over the default",
+	}
+}
+
+func (ImageValidationPolicy) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                 "ImageValidationPolicy defines which images the importer and upload server accept",
+		"maxVirtualSize":   "MaxVirtualSize is the largest virtual size of an accepted image",
+		"allowedFormats":   "AllowedFormats are the qemu-img formats of the accepted images, raw and qcow2 if empty",
+		"deniedFormats":    "DeniedFormats are the qemu-img formats of rejected images, it takes precedence over AllowedFormats",
+		"rejectEncrypted":  "RejectEncrypted rejects encrypted images",
+		"rejectCompressed": "RejectCompressed rejects qcow2 images with compressed clusters",
+		"infoMemoryLimit":  "InfoMemoryLimit is the address space limit of the qemu-img process inspecting images, 1Gi if not set",
+		"infoCPUTimeLimit": "InfoCPUTimeLimit is the CPU time limit in seconds of the qemu-img process inspecting images, 30 if not set",
+	}
+}
+
+func (ConcurrencyLimits) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":          "ConcurrencyLimits limits the number of worker pods populating volumes at the same time, a clone counts once",
+		"global":    "Global is the maximum number of worker pods running in the cluster, unlimited if unset\n+optional",
+		"namespace": "Namespace is the maximum number of worker pods running in each namespace, unlimited if unset\n+optional",
+	}
+}
+
+func (NodePlacement) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                  "NodePlacement describes the nodes pods are scheduled onto",
+		"nodeSelector":      "NodeSelector are the labels a node must have for the pods to run on it\n+optional",
+		"affinity":          "Affinity are the scheduling constraints of the pods\n+optional",
+		"tolerations":       "Tolerations are the taints of the nodes the pods tolerate\n+optional",
+		"priorityClassName": "PriorityClassName is the name of the priority class of the pods\n+optional",
+	}
+}
+
+func (ImportProxy) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":               "ImportProxy provides the proxy configuration for the CDI worker pods",
+		"httpProxy":      "HTTPProxy is the URL of the proxy used for http requests",
+		"httpsProxy":     "HTTPSProxy is the URL of the proxy used for https requests",
+		"noProxy":        "NoProxy is a comma separated list of hosts, domains and CIDRs that are accessed without the proxy",
+		"trustedCAProxy": "TrustedCAProxy is the name of a ConfigMap in the CDI namespace containing the CA certificates of the proxy",
 	}
 }
 
@@ -130,3 +279,37 @@ func (CDIConfigList) SwaggerDoc() map[string]string {
 		"items": "Items provides a list of CDIConfigs",
 	}
 }
+
+func (StorageProfile) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"": "StorageProfile provides the recommended PVC settings of the StorageClass of the same name\n+genclient\n+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object",
+	}
+}
+
+func (StorageProfileSpec) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":              "StorageProfileSpec defines the PVC settings overriding the ones detected for the StorageClass",
+		"accessModes":   "AccessModes are the access modes of PVCs of the storage class",
+		"volumeMode":    "VolumeMode is the volume mode of PVCs of the storage class",
+		"cloneStrategy": "CloneStrategy is how PVCs of the storage class are cloned",
+	}
+}
+
+func (StorageProfileStatus) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":                   "StorageProfileStatus provides the recommended PVC settings in effect for the StorageClass",
+		"storageClass":       "StorageClass is the name of the storage class",
+		"provisioner":        "Provisioner is the provisioner of the storage class",
+		"accessModes":        "AccessModes are the recommended access modes, empty if there is no recommendation",
+		"volumeMode":         "VolumeMode is the recommended volume mode, nil if there is no recommendation",
+		"cloneStrategy":      "CloneStrategy is how PVCs of the storage class are cloned",
+		"filesystemOverhead": "FilesystemOverhead is the fraction of Filesystem mode volumes of the storage class reserved for file system metadata",
+	}
+}
+
+func (StorageProfileList) SwaggerDoc() map[string]string {
+	return map[string]string{
+		"":      "StorageProfileList provides the needed parameters to request a list of StorageProfiles from the system\n+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object",
+		"items": "Items provides a list of StorageProfiles",
+	}
+}
@@ -49,6 +49,7 @@ import (
 	cdicorev1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	cdiuploadv1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/upload/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/apiserver/webhooks"
+	cdiclient "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/controller"
 	"kubevirt.io/containerized-data-importer/pkg/keys"
@@ -91,6 +92,7 @@ type cdiAPIApp struct {
 	bindPort    uint
 
 	client           kubernetes.Interface
+	cdiClient        cdiclient.Interface
 	aggregatorClient aggregatorclient.Interface
 
 	serverCACertBytes []byte
@@ -123,6 +125,7 @@ func UploadTokenRequestAPI() []*restful.WebService {
 func NewCdiAPIServer(bindAddress string,
 	bindPort uint,
 	client kubernetes.Interface,
+	cdiClient cdiclient.Interface,
 	aggregatorClient aggregatorclient.Interface,
 	authorizor CdiAPIAuthorizer,
 	authConfigWatcher AuthConfigWatcher) (CdiAPIServer, error) {
@@ -131,6 +134,7 @@ func NewCdiAPIServer(bindAddress string,
 		bindAddress:       bindAddress,
 		bindPort:          bindPort,
 		client:            client,
+		cdiClient:         cdiClient,
 		aggregatorClient:  aggregatorClient,
 		authorizer:        authorizor,
 		uploadPossible:    controller.UploadPossibleForPVC,
@@ -719,6 +723,6 @@ func (app *cdiAPIApp) createMutatingWebhook() error {
 		}
 	}
 
-	app.container.ServeMux.Handle(path, webhooks.NewDataVolumeMutatingWebhook(app.client, app.privateSigningKey))
+	app.container.ServeMux.Handle(path, webhooks.NewDataVolumeMutatingWebhook(app.client, app.cdiClient, app.privateSigningKey))
 	return nil
 }
@@ -33,6 +33,7 @@ import (
 	"k8s.io/client-go/util/cert"
 	aggregatorapifake "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/fake"
 
+	cdifake "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 	"kubevirt.io/containerized-data-importer/pkg/util/cert/triple"
 )
@@ -94,7 +95,7 @@ func TestNewCdiAPIServer(t *testing.T) {
 	authorizer := &testAuthorizer{}
 	authConfigWatcher := NewAuthConfigWatcher(client, ch)
 
-	server, err := NewCdiAPIServer("0.0.0.0", 0, client, aggregatorClient, authorizer, authConfigWatcher)
+	server, err := NewCdiAPIServer("0.0.0.0", 0, client, cdifake.NewSimpleClientset(), aggregatorClient, authorizer, authConfigWatcher)
 	if err != nil {
 		t.Errorf("Upload api server creation failed: %+v", err)
 	}
@@ -122,7 +123,7 @@ func TestAuthConfigUpdate(t *testing.T) {
 	authorizer := &testAuthorizer{}
 	acw := NewAuthConfigWatcher(client, ch).(*authConfigWatcher)
 
-	server, err := NewCdiAPIServer("0.0.0.0", 0, client, aggregatorClient, authorizer, acw)
+	server, err := NewCdiAPIServer("0.0.0.0", 0, client, cdifake.NewSimpleClientset(), aggregatorClient, authorizer, acw)
 	if err != nil {
 		t.Errorf("Upload api server creation failed: %+v", err)
 	}
@@ -154,7 +155,7 @@ func TestGetTLSConfig(t *testing.T) {
 	authorizer := &testAuthorizer{}
 	acw := NewAuthConfigWatcher(client, ch).(*authConfigWatcher)
 
-	server, err := NewCdiAPIServer("0.0.0.0", 0, client, aggregatorClient, authorizer, acw)
+	server, err := NewCdiAPIServer("0.0.0.0", 0, client, cdifake.NewSimpleClientset(), aggregatorClient, authorizer, acw)
 	if err != nil {
 		t.Errorf("Upload api server creation failed: %+v", err)
 	}
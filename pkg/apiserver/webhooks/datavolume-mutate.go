@@ -20,13 +20,20 @@
 package webhooks
 
 import (
+	"reflect"
+
+	"github.com/pkg/errors"
+
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sfield "k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 
 	cdiv1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	cdiclient "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
 	"kubevirt.io/containerized-data-importer/pkg/clone"
 	"kubevirt.io/containerized-data-importer/pkg/controller"
 	"kubevirt.io/containerized-data-importer/pkg/token"
@@ -34,6 +41,7 @@ import (
 
 type dataVolumeMutatingWebhook struct {
 	client         kubernetes.Interface
+	cdiClient      cdiclient.Interface
 	tokenGenerator token.Generator
 }
 
@@ -69,9 +77,19 @@ func (wh *dataVolumeMutatingWebhook) Admit(ar admissionv1beta1.AdmissionReview)
 		targetName = ar.Request.Name
 	}
 
+	modifiedDataVolume := dataVolume.DeepCopy()
+	if ar.Request.Operation == admissionv1beta1.Create {
+		if err := wh.applyStorageProfile(modifiedDataVolume); err != nil {
+			return toAdmissionResponseError(err)
+		}
+	}
+
 	if pvcSource == nil {
 		klog.V(3).Infof("DataVolume %s/%s not cloning", targetNamespace, targetName)
-		return allowedAdmissionResponse()
+		if reflect.DeepEqual(dataVolume, *modifiedDataVolume) {
+			return allowedAdmissionResponse()
+		}
+		return toPatchResponse(dataVolume, modifiedDataVolume)
 	}
 
 	sourceNamespace, sourceName := pvcSource.Namespace, pvcSource.Name
@@ -123,7 +141,6 @@ func (wh *dataVolumeMutatingWebhook) Admit(ar admissionv1beta1.AdmissionReview)
 		return toAdmissionResponseError(err)
 	}
 
-	modifiedDataVolume := dataVolume.DeepCopy()
 	if modifiedDataVolume.Annotations == nil {
 		modifiedDataVolume.Annotations = make(map[string]string)
 	}
@@ -134,3 +151,32 @@ func (wh *dataVolumeMutatingWebhook) Admit(ar admissionv1beta1.AdmissionReview)
 
 	return toPatchResponse(dataVolume, modifiedDataVolume)
 }
+
+// applyStorageProfile fills in the access modes and volume mode missing in the PVC spec of the data volume, with the
+// ones recommended by the StorageProfile of its storage class.
+func (wh *dataVolumeMutatingWebhook) applyStorageProfile(dataVolume *cdiv1alpha1.DataVolume) error {
+	pvcSpec := dataVolume.Spec.PVC
+	if pvcSpec == nil || (len(pvcSpec.AccessModes) > 0 && pvcSpec.VolumeMode != nil) {
+		return nil
+	}
+	storageClass, err := controller.GetStorageClassName(wh.client, pvcSpec.StorageClassName)
+	if err != nil || storageClass == "" {
+		return err
+	}
+	profile, err := wh.cdiClient.CdiV1alpha1().StorageProfiles().Get(storageClass, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.V(3).Infof("No storage profile for storage class %s", storageClass)
+			return nil
+		}
+		return errors.Wrapf(err, "error getting storage profile %s", storageClass)
+	}
+	if len(pvcSpec.AccessModes) == 0 && len(profile.Status.AccessModes) > 0 {
+		pvcSpec.AccessModes = append([]corev1.PersistentVolumeAccessMode(nil), profile.Status.AccessModes...)
+	}
+	if pvcSpec.VolumeMode == nil && profile.Status.VolumeMode != nil {
+		volumeMode := *profile.Status.VolumeMode
+		pvcSpec.VolumeMode = &volumeMode
+	}
+	return nil
+}
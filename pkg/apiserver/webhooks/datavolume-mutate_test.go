@@ -33,12 +33,14 @@ import (
 
 	"k8s.io/api/admission/v1beta1"
 	authorization "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	fakeclient "k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 
 	cdicorev1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	cdifake "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	"kubevirt.io/containerized-data-importer/pkg/controller"
 )
 
@@ -164,9 +166,91 @@ var _ = Describe("Mutating DataVolume Webhook", func() {
 			Entry("succeed with empty namespace", ""),
 		)
 	})
+
+	Context("with a StorageProfile", func() {
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+		storageClass := "test-sc"
+		volumeMode := corev1.PersistentVolumeBlock
+		profile := &cdicorev1alpha1.StorageProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: storageClass},
+			Status: cdicorev1alpha1.StorageProfileStatus{
+				StorageClass: &storageClass,
+				AccessModes:  []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				VolumeMode:   &volumeMode,
+			},
+		}
+
+		createReview := func(dataVolume *cdicorev1alpha1.DataVolume) *v1beta1.AdmissionReview {
+			dvBytes, _ := json.Marshal(dataVolume)
+			return &v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Operation: v1beta1.Create,
+					Resource: metav1.GroupVersionResource{
+						Group:    cdicorev1alpha1.SchemeGroupVersion.Group,
+						Version:  cdicorev1alpha1.SchemeGroupVersion.Version,
+						Resource: "datavolumes",
+					},
+					Object: runtime.RawExtension{
+						Raw: dvBytes,
+					},
+				},
+			}
+		}
+
+		patchPaths := func(resp *v1beta1.AdmissionResponse) []string {
+			var patchObjs []jsonpatch.Operation
+			err := json.Unmarshal(resp.Patch, &patchObjs)
+			Expect(err).ToNot(HaveOccurred())
+			var paths []string
+			for _, patch := range patchObjs {
+				paths = append(paths, patch.Path)
+			}
+			return paths
+		}
+
+		It("should fill in the missing access modes and volume mode", func() {
+			dataVolume := newHTTPDataVolume("testDV", "http://www.example.com")
+			dataVolume.Spec.PVC.AccessModes = nil
+			dataVolume.Spec.PVC.StorageClassName = &storageClass
+
+			resp := mutateDVs(key, createReview(dataVolume), true, profile)
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(patchPaths(resp)).To(ConsistOf("/spec/pvc/accessModes", "/spec/pvc/volumeMode"))
+		})
+
+		It("should keep the access modes set in the DataVolume", func() {
+			dataVolume := newHTTPDataVolume("testDV", "http://www.example.com")
+			dataVolume.Spec.PVC.StorageClassName = &storageClass
+
+			resp := mutateDVs(key, createReview(dataVolume), true, profile)
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(patchPaths(resp)).To(ConsistOf("/spec/pvc/volumeMode"))
+		})
+
+		It("should leave a DataVolume of a storage class without a StorageProfile alone", func() {
+			dataVolume := newHTTPDataVolume("testDV", "http://www.example.com")
+			dataVolume.Spec.PVC.AccessModes = nil
+			otherClass := "other-sc"
+			dataVolume.Spec.PVC.StorageClassName = &otherClass
+
+			resp := mutateDVs(key, createReview(dataVolume), true, profile)
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Patch).To(BeNil())
+		})
+
+		It("should fill in a clone DataVolume along with the clone token", func() {
+			dataVolume := newPVCDataVolume("testDV", "testNamespace", "test")
+			dataVolume.Spec.PVC.AccessModes = nil
+			dataVolume.Spec.PVC.StorageClassName = &storageClass
+
+			resp := mutateDVs(key, createReview(dataVolume), true, profile)
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(patchPaths(resp)).To(ConsistOf("/metadata/annotations", "/spec/pvc/accessModes", "/spec/pvc/volumeMode"))
+		})
+	})
 })
 
-func mutateDVs(key *rsa.PrivateKey, ar *v1beta1.AdmissionReview, isAuthorized bool) *v1beta1.AdmissionResponse {
+func mutateDVs(key *rsa.PrivateKey, ar *v1beta1.AdmissionReview, isAuthorized bool, cdiObjects ...runtime.Object) *v1beta1.AdmissionResponse {
 	client := fakeclient.NewSimpleClientset()
 	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
 		if action.GetResource().Resource != "subjectaccessreviews" {
@@ -181,6 +265,6 @@ func mutateDVs(key *rsa.PrivateKey, ar *v1beta1.AdmissionReview, isAuthorized bo
 		}
 		return true, sar, nil
 	})
-	wh := NewDataVolumeMutatingWebhook(client, key)
+	wh := NewDataVolumeMutatingWebhook(client, cdifake.NewSimpleClientset(cdiObjects...), key)
 	return serve(ar, wh)
 }
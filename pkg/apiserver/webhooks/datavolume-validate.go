@@ -23,7 +23,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path"
 	"reflect"
+	"strings"
 
 	"k8s.io/api/admission/v1beta1"
 	v1 "k8s.io/api/core/v1"
@@ -68,6 +70,75 @@ func validateDataVolumeName(name string) []metav1.StatusCause {
 	return causes
 }
 
+func validateArchiveOptions(field *k8sfield.Path, spec *cdicorev1alpha1.DataVolumeSpec) []metav1.StatusCause {
+	var causes []metav1.StatusCause
+	options := spec.ArchiveOptions
+	if spec.ContentType != cdicorev1alpha1.DataVolumeArchive {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s requires contentType %s", field.String(), cdicorev1alpha1.DataVolumeArchive),
+			Field:   field.String(),
+		})
+		return causes
+	}
+	subPath := path.Clean(options.SubPath)
+	if options.SubPath != "" && (path.IsAbs(subPath) || subPath == ".." || strings.HasPrefix(subPath, "../")) {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s must be a relative path inside the archive", field.Child("subPath").String()),
+			Field:   field.Child("subPath").String(),
+		})
+		return causes
+	}
+	if options.StripComponents < 0 {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s can't be less than zero", field.Child("stripComponents").String()),
+			Field:   field.Child("stripComponents").String(),
+		})
+		return causes
+	}
+	if options.Ownership != "" && options.Ownership != cdicorev1alpha1.ArchiveOwnershipPreserve && options.Ownership != cdicorev1alpha1.ArchiveOwnershipDrop {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("Ownership not one of: %s, %s", cdicorev1alpha1.ArchiveOwnershipPreserve, cdicorev1alpha1.ArchiveOwnershipDrop),
+			Field:   field.Child("ownership").String(),
+		})
+		return causes
+	}
+	return causes
+}
+
+func validateRetryPolicy(field *k8sfield.Path, spec *cdicorev1alpha1.DataVolumeSpec) []metav1.StatusCause {
+	var causes []metav1.StatusCause
+	policy := spec.RetryPolicy
+	if spec.Source.PVC != nil || spec.Source.Upload != nil {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s is only supported for imports", field.String()),
+			Field:   field.String(),
+		})
+		return causes
+	}
+	if policy.MaxAttempts < 0 {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s can't be less than zero", field.Child("maxAttempts").String()),
+			Field:   field.Child("maxAttempts").String(),
+		})
+		return causes
+	}
+	if policy.BackoffSeconds < 0 {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("%s can't be less than zero", field.Child("backoffSeconds").String()),
+			Field:   field.Child("backoffSeconds").String(),
+		})
+		return causes
+	}
+	return causes
+}
+
 func (wh *dataVolumeValidatingWebhook) validateDataVolumeSpec(request *v1beta1.AdmissionRequest, field *k8sfield.Path, spec *cdicorev1alpha1.DataVolumeSpec) []metav1.StatusCause {
 	var causes []metav1.StatusCause
 	var url string
@@ -115,12 +186,12 @@ func (wh *dataVolumeValidatingWebhook) validateDataVolumeSpec(request *v1beta1.A
 		}
 	}
 
-	// Make sure contentType is either empty (kubevirt), or kubevirt or archive
-	if spec.ContentType != "" && string(spec.ContentType) != string(cdicorev1alpha1.DataVolumeKubeVirt) && string(spec.ContentType) != string(cdicorev1alpha1.DataVolumeArchive) {
+	// Make sure contentType is either empty (kubevirt), or kubevirt, archive or iso
+	if spec.ContentType != "" && string(spec.ContentType) != string(cdicorev1alpha1.DataVolumeKubeVirt) && string(spec.ContentType) != string(cdicorev1alpha1.DataVolumeArchive) && string(spec.ContentType) != string(cdicorev1alpha1.DataVolumeISO) {
 		sourceType = field.Child("contentType").String()
 		causes = append(causes, metav1.StatusCause{
 			Type:    metav1.CauseTypeFieldValueInvalid,
-			Message: fmt.Sprintf("ContentType not one of: %s, %s", cdicorev1alpha1.DataVolumeKubeVirt, cdicorev1alpha1.DataVolumeArchive),
+			Message: fmt.Sprintf("ContentType not one of: %s, %s, %s", cdicorev1alpha1.DataVolumeKubeVirt, cdicorev1alpha1.DataVolumeArchive, cdicorev1alpha1.DataVolumeISO),
 			Field:   sourceType,
 		})
 		return causes
@@ -136,6 +207,16 @@ func (wh *dataVolumeValidatingWebhook) validateDataVolumeSpec(request *v1beta1.A
 		return causes
 	}
 
+	if spec.Source.HTTP == nil && string(spec.ContentType) == string(cdicorev1alpha1.DataVolumeISO) {
+		sourceType = field.Child("contentType").String()
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: fmt.Sprintf("ContentType %s requires an http source", cdicorev1alpha1.DataVolumeISO),
+			Field:   sourceType,
+		})
+		return causes
+	}
+
 	if spec.Source.Registry != nil && spec.ContentType != "" && string(spec.ContentType) != string(cdicorev1alpha1.DataVolumeKubeVirt) {
 		sourceType = field.Child("contentType").String()
 		causes = append(causes, metav1.StatusCause{
@@ -146,6 +227,18 @@ func (wh *dataVolumeValidatingWebhook) validateDataVolumeSpec(request *v1beta1.A
 		return causes
 	}
 
+	if spec.ArchiveOptions != nil {
+		if causes = validateArchiveOptions(field.Child("archiveOptions"), spec); len(causes) > 0 {
+			return causes
+		}
+	}
+
+	if spec.RetryPolicy != nil {
+		if causes = validateRetryPolicy(field.Child("retryPolicy"), spec); len(causes) > 0 {
+			return causes
+		}
+	}
+
 	if spec.Source.PVC != nil {
 		if spec.Source.PVC.Namespace == "" || spec.Source.PVC.Name == "" {
 			causes = append(causes, metav1.StatusCause{
@@ -197,7 +290,7 @@ func (wh *dataVolumeValidatingWebhook) validateDataVolumeSpec(request *v1beta1.A
 			})
 			return causes
 		}
-	} else {
+	} else if !controller.CanProbeSize(spec) {
 		causes = append(causes, metav1.StatusCause{
 			Type:    metav1.CauseTypeFieldValueInvalid,
 			Message: fmt.Sprintf("PVC size is missing"),
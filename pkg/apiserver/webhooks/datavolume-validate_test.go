@@ -27,6 +27,7 @@ import (
 	"net/http/httptest"
 
 	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	"k8s.io/api/admission/v1beta1"
@@ -353,6 +354,112 @@ var _ = Describe("Validating Webhook", func() {
 			Expect(resp.Allowed).To(Equal(true))
 
 		})
+		table.DescribeTable("should validate archive options", func(contentType cdicorev1alpha1.DataVolumeContentType, options *cdicorev1alpha1.DataVolumeArchiveOptions, allowed bool) {
+			dataVolume := newHTTPDataVolume("testDV", "http://www.example.com")
+			dataVolume.Spec.ContentType = contentType
+			dataVolume.Spec.ArchiveOptions = options
+
+			dvBytes, _ := json.Marshal(&dataVolume)
+			ar := &v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Resource: metav1.GroupVersionResource{
+						Group:    cdicorev1alpha1.SchemeGroupVersion.Group,
+						Version:  cdicorev1alpha1.SchemeGroupVersion.Version,
+						Resource: "datavolumes",
+					},
+					Object: runtime.RawExtension{
+						Raw: dvBytes,
+					},
+				},
+			}
+
+			resp := validateDVs(ar)
+			Expect(resp.Allowed).To(Equal(allowed))
+		},
+			table.Entry("accept all options", cdicorev1alpha1.DataVolumeArchive, &cdicorev1alpha1.DataVolumeArchiveOptions{SubPath: "dir/sub", StripComponents: 1, Ownership: cdicorev1alpha1.ArchiveOwnershipDrop}, true),
+			table.Entry("reject options without archive contentType", cdicorev1alpha1.DataVolumeKubeVirt, &cdicorev1alpha1.DataVolumeArchiveOptions{SubPath: "dir"}, false),
+			table.Entry("reject absolute subPath", cdicorev1alpha1.DataVolumeArchive, &cdicorev1alpha1.DataVolumeArchiveOptions{SubPath: "/dir"}, false),
+			table.Entry("reject subPath outside the archive", cdicorev1alpha1.DataVolumeArchive, &cdicorev1alpha1.DataVolumeArchiveOptions{SubPath: "dir/../../etc"}, false),
+			table.Entry("reject negative stripComponents", cdicorev1alpha1.DataVolumeArchive, &cdicorev1alpha1.DataVolumeArchiveOptions{StripComponents: -1}, false),
+			table.Entry("reject invalid ownership", cdicorev1alpha1.DataVolumeArchive, &cdicorev1alpha1.DataVolumeArchiveOptions{Ownership: "Keep"}, false),
+		)
+		table.DescribeTable("should validate iso contentType", func(dataVolume *cdicorev1alpha1.DataVolume, allowed bool) {
+			dataVolume.Spec.ContentType = cdicorev1alpha1.DataVolumeISO
+
+			dvBytes, _ := json.Marshal(&dataVolume)
+			ar := &v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Resource: metav1.GroupVersionResource{
+						Group:    cdicorev1alpha1.SchemeGroupVersion.Group,
+						Version:  cdicorev1alpha1.SchemeGroupVersion.Version,
+						Resource: "datavolumes",
+					},
+					Object: runtime.RawExtension{
+						Raw: dvBytes,
+					},
+				},
+			}
+
+			resp := validateDVs(ar)
+			Expect(resp.Allowed).To(Equal(allowed))
+		},
+			table.Entry("accept http source", newHTTPDataVolume("testDV", "http://www.example.com"), true),
+			table.Entry("reject blank source", newBlankDataVolume("blank"), false),
+			table.Entry("reject registry source", newRegistryDataVolume("testDV", "docker://registry:5000/test"), false),
+		)
+		table.DescribeTable("should validate the retry policy", func(dataVolume *cdicorev1alpha1.DataVolume, policy *cdicorev1alpha1.DataVolumeRetryPolicy, allowed bool) {
+			dataVolume.Spec.RetryPolicy = policy
+
+			dvBytes, _ := json.Marshal(&dataVolume)
+			ar := &v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Resource: metav1.GroupVersionResource{
+						Group:    cdicorev1alpha1.SchemeGroupVersion.Group,
+						Version:  cdicorev1alpha1.SchemeGroupVersion.Version,
+						Resource: "datavolumes",
+					},
+					Object: runtime.RawExtension{
+						Raw: dvBytes,
+					},
+				},
+			}
+
+			resp := validateDVs(ar)
+			Expect(resp.Allowed).To(Equal(allowed))
+		},
+			table.Entry("accept a policy for an http import", newHTTPDataVolume("testDV", "http://www.example.com"), &cdicorev1alpha1.DataVolumeRetryPolicy{MaxAttempts: 3, BackoffSeconds: 30, RestartOnTransientOnly: true}, true),
+			table.Entry("accept a policy for a registry import", newRegistryDataVolume("testDV", "docker://registry:5000/test"), &cdicorev1alpha1.DataVolumeRetryPolicy{}, true),
+			table.Entry("reject a policy for a clone", newPVCDataVolume("testDV", "testNamespace", "test"), &cdicorev1alpha1.DataVolumeRetryPolicy{MaxAttempts: 3}, false),
+			table.Entry("reject negative maxAttempts", newHTTPDataVolume("testDV", "http://www.example.com"), &cdicorev1alpha1.DataVolumeRetryPolicy{MaxAttempts: -1}, false),
+			table.Entry("reject negative backoffSeconds", newHTTPDataVolume("testDV", "http://www.example.com"), &cdicorev1alpha1.DataVolumeRetryPolicy{BackoffSeconds: -1}, false),
+		)
+		table.DescribeTable("should validate a missing PVC size", func(dataVolume *cdicorev1alpha1.DataVolume, contentType cdicorev1alpha1.DataVolumeContentType, allowed bool) {
+			dataVolume.Spec.ContentType = contentType
+			dataVolume.Spec.PVC.Resources.Requests = nil
+
+			dvBytes, _ := json.Marshal(&dataVolume)
+			ar := &v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Resource: metav1.GroupVersionResource{
+						Group:    cdicorev1alpha1.SchemeGroupVersion.Group,
+						Version:  cdicorev1alpha1.SchemeGroupVersion.Version,
+						Resource: "datavolumes",
+					},
+					Object: runtime.RawExtension{
+						Raw: dvBytes,
+					},
+				},
+			}
+
+			resp := validateDVs(ar)
+			Expect(resp.Allowed).To(Equal(allowed))
+		},
+			table.Entry("accept http source", newHTTPDataVolume("testDV", "http://www.example.com"), cdicorev1alpha1.DataVolumeKubeVirt, true),
+			table.Entry("reject registry source", newRegistryDataVolume("testDV", "docker://registry:5000/test"), cdicorev1alpha1.DataVolumeKubeVirt, false),
+			table.Entry("reject archive contentType", newHTTPDataVolume("testDV", "http://www.example.com"), cdicorev1alpha1.DataVolumeArchive, false),
+			table.Entry("reject blank source", newBlankDataVolume("blank"), cdicorev1alpha1.DataVolumeKubeVirt, false),
+			table.Entry("reject pvc source", newPVCDataVolume("testDV", "testNamespace", "test"), cdicorev1alpha1.DataVolumeKubeVirt, false),
+		)
 		It("should reject invalid DataVolume spec update", func() {
 			newDataVolume := newPVCDataVolume("testDV", "newNamespace", "testName")
 			newBytes, _ := json.Marshal(&newDataVolume)
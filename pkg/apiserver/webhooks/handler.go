@@ -36,6 +36,7 @@ import (
 	"k8s.io/klog"
 
 	cdicorev1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	cdiclient "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/token"
 )
@@ -55,9 +56,9 @@ func NewDataVolumeValidatingWebhook(client kubernetes.Interface) http.Handler {
 }
 
 // NewDataVolumeMutatingWebhook creates a new DataVolumeMutation webhook
-func NewDataVolumeMutatingWebhook(client kubernetes.Interface, key *rsa.PrivateKey) http.Handler {
+func NewDataVolumeMutatingWebhook(client kubernetes.Interface, cdiClient cdiclient.Interface, key *rsa.PrivateKey) http.Handler {
 	generator := newCloneTokenGenerator(key)
-	return newAdmissionHandler(&dataVolumeMutatingWebhook{client: client, tokenGenerator: generator})
+	return newAdmissionHandler(&dataVolumeMutatingWebhook{client: client, cdiClient: cdiClient, tokenGenerator: generator})
 }
 
 func newCloneTokenGenerator(key *rsa.PrivateKey) token.Generator {
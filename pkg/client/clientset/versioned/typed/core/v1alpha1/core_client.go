@@ -30,6 +30,7 @@ type CdiV1alpha1Interface interface {
 	CDIsGetter
 	CDIConfigsGetter
 	DataVolumesGetter
+	StorageProfilesGetter
 }
 
 // CdiV1alpha1Client is used to interact with features provided by the cdi.kubevirt.io group.
@@ -49,6 +50,10 @@ func (c *CdiV1alpha1Client) DataVolumes(namespace string) DataVolumeInterface {
 	return newDataVolumes(c, namespace)
 }
 
+func (c *CdiV1alpha1Client) StorageProfiles() StorageProfileInterface {
+	return newStorageProfiles(c)
+}
+
 // NewForConfig creates a new CdiV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*CdiV1alpha1Client, error) {
 	config := *c
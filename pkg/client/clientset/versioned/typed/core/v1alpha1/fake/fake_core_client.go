@@ -40,6 +40,10 @@ func (c *FakeCdiV1alpha1) DataVolumes(namespace string) v1alpha1.DataVolumeInter
 	return &FakeDataVolumes{c, namespace}
 }
 
+func (c *FakeCdiV1alpha1) StorageProfiles() v1alpha1.StorageProfileInterface {
+	return &FakeStorageProfiles{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeCdiV1alpha1) RESTClient() rest.Interface {
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	v1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+// FakeStorageProfiles implements StorageProfileInterface
+type FakeStorageProfiles struct {
+	Fake *FakeCdiV1alpha1
+}
+
+var storageprofilesResource = schema.GroupVersionResource{Group: "cdi.kubevirt.io", Version: "v1alpha1", Resource: "storageprofiles"}
+
+var storageprofilesKind = schema.GroupVersionKind{Group: "cdi.kubevirt.io", Version: "v1alpha1", Kind: "StorageProfile"}
+
+// Get takes name of the storageProfile, and returns the corresponding storageProfile object, and an error if there is any.
+func (c *FakeStorageProfiles) Get(name string, options v1.GetOptions) (result *v1alpha1.StorageProfile, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(storageprofilesResource, name), &v1alpha1.StorageProfile{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.StorageProfile), err
+}
+
+// List takes label and field selectors, and returns the list of StorageProfiles that match those selectors.
+func (c *FakeStorageProfiles) List(opts v1.ListOptions) (result *v1alpha1.StorageProfileList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(storageprofilesResource, storageprofilesKind, opts), &v1alpha1.StorageProfileList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.StorageProfileList{ListMeta: obj.(*v1alpha1.StorageProfileList).ListMeta}
+	for _, item := range obj.(*v1alpha1.StorageProfileList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested storageProfiles.
+func (c *FakeStorageProfiles) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(storageprofilesResource, opts))
+}
+
+// Create takes the representation of a storageProfile and creates it.  Returns the server's representation of the storageProfile, and an error, if there is any.
+func (c *FakeStorageProfiles) Create(storageProfile *v1alpha1.StorageProfile) (result *v1alpha1.StorageProfile, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(storageprofilesResource, storageProfile), &v1alpha1.StorageProfile{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.StorageProfile), err
+}
+
+// Update takes the representation of a storageProfile and updates it. Returns the server's representation of the storageProfile, and an error, if there is any.
+func (c *FakeStorageProfiles) Update(storageProfile *v1alpha1.StorageProfile) (result *v1alpha1.StorageProfile, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(storageprofilesResource, storageProfile), &v1alpha1.StorageProfile{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.StorageProfile), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeStorageProfiles) UpdateStatus(storageProfile *v1alpha1.StorageProfile) (*v1alpha1.StorageProfile, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(storageprofilesResource, "status", storageProfile), &v1alpha1.StorageProfile{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.StorageProfile), err
+}
+
+// Delete takes name of the storageProfile and deletes it. Returns an error if one occurs.
+func (c *FakeStorageProfiles) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(storageprofilesResource, name), &v1alpha1.StorageProfile{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeStorageProfiles) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(storageprofilesResource, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.StorageProfileList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched storageProfile.
+func (c *FakeStorageProfiles) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.StorageProfile, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(storageprofilesResource, name, pt, data, subresources...), &v1alpha1.StorageProfile{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.StorageProfile), err
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	v1alpha1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	scheme "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/scheme"
+)
+
+// StorageProfilesGetter has a method to return a StorageProfileInterface.
+// A group's client should implement this interface.
+type StorageProfilesGetter interface {
+	StorageProfiles() StorageProfileInterface
+}
+
+// StorageProfileInterface has methods to work with StorageProfile resources.
+type StorageProfileInterface interface {
+	Create(*v1alpha1.StorageProfile) (*v1alpha1.StorageProfile, error)
+	Update(*v1alpha1.StorageProfile) (*v1alpha1.StorageProfile, error)
+	UpdateStatus(*v1alpha1.StorageProfile) (*v1alpha1.StorageProfile, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.StorageProfile, error)
+	List(opts v1.ListOptions) (*v1alpha1.StorageProfileList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.StorageProfile, err error)
+	StorageProfileExpansion
+}
+
+// storageProfiles implements StorageProfileInterface
+type storageProfiles struct {
+	client rest.Interface
+}
+
+// newStorageProfiles returns a StorageProfiles
+func newStorageProfiles(c *CdiV1alpha1Client) *storageProfiles {
+	return &storageProfiles{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the storageProfile, and returns the corresponding storageProfile object, and an error if there is any.
+func (c *storageProfiles) Get(name string, options v1.GetOptions) (result *v1alpha1.StorageProfile, err error) {
+	result = &v1alpha1.StorageProfile{}
+	err = c.client.Get().
+		Resource("storageprofiles").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of StorageProfiles that match those selectors.
+func (c *storageProfiles) List(opts v1.ListOptions) (result *v1alpha1.StorageProfileList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.StorageProfileList{}
+	err = c.client.Get().
+		Resource("storageprofiles").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested storageProfiles.
+func (c *storageProfiles) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("storageprofiles").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a storageProfile and creates it.  Returns the server's representation of the storageProfile, and an error, if there is any.
+func (c *storageProfiles) Create(storageProfile *v1alpha1.StorageProfile) (result *v1alpha1.StorageProfile, err error) {
+	result = &v1alpha1.StorageProfile{}
+	err = c.client.Post().
+		Resource("storageprofiles").
+		Body(storageProfile).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a storageProfile and updates it. Returns the server's representation of the storageProfile, and an error, if there is any.
+func (c *storageProfiles) Update(storageProfile *v1alpha1.StorageProfile) (result *v1alpha1.StorageProfile, err error) {
+	result = &v1alpha1.StorageProfile{}
+	err = c.client.Put().
+		Resource("storageprofiles").
+		Name(storageProfile.Name).
+		Body(storageProfile).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *storageProfiles) UpdateStatus(storageProfile *v1alpha1.StorageProfile) (result *v1alpha1.StorageProfile, err error) {
+	result = &v1alpha1.StorageProfile{}
+	err = c.client.Put().
+		Resource("storageprofiles").
+		Name(storageProfile.Name).
+		SubResource("status").
+		Body(storageProfile).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the storageProfile and deletes it. Returns an error if one occurs.
+func (c *storageProfiles) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("storageprofiles").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *storageProfiles) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("storageprofiles").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched storageProfile.
+func (c *storageProfiles) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.StorageProfile, err error) {
+	result = &v1alpha1.StorageProfile{}
+	err = c.client.Patch(pt).
+		Resource("storageprofiles").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -33,8 +33,18 @@ const (
 	ImporterWriteBlockPath = "/dev/cdi-block-volume"
 	// PodTerminationMessageFile is the name of the file to write the termination message to.
 	PodTerminationMessageFile = "/dev/termination-log"
+	// ContentChecksumAlgorithm is the hash algorithm of content checksums, it prefixes the hex encoded digest.
+	ContentChecksumAlgorithm = "sha256"
+	// ContentChecksumTrailer is the HTTP trailer a clone source uses to send the content checksum of the source volume
+	ContentChecksumTrailer = "X-Cdi-Content-Checksum"
+	// ContentSizeTrailer is the HTTP trailer a clone source uses to send the number of bytes covered by the content checksum
+	ContentSizeTrailer = "X-Cdi-Content-Size"
+	// ImageValidationFailedMessage precedes the violated rule and the reason in the response to a rejected upload.
+	ImageValidationFailedMessage = "Image validation failed, rule "
 	// ImporterPodName provides a constant to use as a prefix for Pods created by CDI (controller only)
 	ImporterPodName = "importer"
+	// SizeProbePodName provides a constant to use as a prefix for the pods determining the size of a DataVolume (controller only)
+	SizeProbePodName = "cdi-size-probe"
 	// ImporterDataDir provides a constant for the controller pkg to use as a hardcoded path to where content is transferred to/from (controller only)
 	ImporterDataDir = "/data"
 	// ScratchDataDir provides a constant for the controller pkg to use as a hardcoded path to where scratch space is located.
@@ -43,6 +53,10 @@ const (
 	ImporterS3Host = "s3.amazonaws.com"
 	// ImporterCertDir is where the configmap containing certs will be mounted
 	ImporterCertDir = "/certs"
+	// ImporterClientCertDir is where the secret containing the tls client certificate will be mounted
+	ImporterClientCertDir = "/client-certs"
+	// ImporterChecksumKeyDir is where the configmap containing the checksum file GPG keys will be mounted
+	ImporterChecksumKeyDir = "/checksum-keys"
 	// DefaultPullPolicy imports k8s "IfNotPresent" string for the import_controller_gingko_test and the cdi-controller executable
 	DefaultPullPolicy = string(v1.PullIfNotPresent)
 
@@ -58,10 +72,33 @@ const (
 	ImporterAccessKeyID = "IMPORTER_ACCESS_KEY_ID"
 	// ImporterSecretKey provides a constant to capture our env variable "IMPORTER_SECRET_KEY"
 	ImporterSecretKey = "IMPORTER_SECRET_KEY"
+	// ImporterToken provides a constant to capture our env variable "IMPORTER_TOKEN"
+	ImporterToken = "IMPORTER_TOKEN"
+	// ImporterExtraHeaders provides a constant to capture our env variable "IMPORTER_EXTRA_HEADERS"
+	ImporterExtraHeaders = "IMPORTER_EXTRA_HEADERS"
 	// ImporterImageSize provides a constant to capture our env variable "IMPORTER_IMAGE_SIZE"
 	ImporterImageSize = "IMPORTER_IMAGE_SIZE"
 	// ImporterCertDirVar provides a constant to capture our env variable "IMPORTER_CERT_DIR"
 	ImporterCertDirVar = "IMPORTER_CERT_DIR"
+	// ImporterClientCertDirVar provides a constant to capture our env variable "IMPORTER_CLIENT_CERT_DIR"
+	ImporterClientCertDirVar = "IMPORTER_CLIENT_CERT_DIR"
+	// ImporterChecksumURL provides a constant to capture our env variable "IMPORTER_CHECKSUM_URL"
+	ImporterChecksumURL = "IMPORTER_CHECKSUM_URL"
+	// ImporterChecksumSignatureURL provides a constant to capture our env variable "IMPORTER_CHECKSUM_SIGNATURE_URL"
+	ImporterChecksumSignatureURL = "IMPORTER_CHECKSUM_SIGNATURE_URL"
+	// ImporterChecksumKeyDirVar provides a constant to capture our env variable "IMPORTER_CHECKSUM_KEY_DIR"
+	ImporterChecksumKeyDirVar = "IMPORTER_CHECKSUM_KEY_DIR"
+	// ImporterArchiveSubPath provides a constant to capture our env variable "IMPORTER_ARCHIVE_SUB_PATH"
+	ImporterArchiveSubPath = "IMPORTER_ARCHIVE_SUB_PATH"
+	// ImporterArchiveStripComponents provides a constant to capture our env variable "IMPORTER_ARCHIVE_STRIP_COMPONENTS"
+	ImporterArchiveStripComponents = "IMPORTER_ARCHIVE_STRIP_COMPONENTS"
+	// ImporterArchiveOwnership provides a constant to capture our env variable "IMPORTER_ARCHIVE_OWNERSHIP"
+	ImporterArchiveOwnership = "IMPORTER_ARCHIVE_OWNERSHIP"
+	// ImporterVerify provides a constant to capture our env variable "IMPORTER_VERIFY"
+	ImporterVerify = "IMPORTER_VERIFY"
+	// ImporterSizeProbe provides a constant to capture our env variable "IMPORTER_SIZE_PROBE", set when the importer
+	// only determines the virtual size of the source
+	ImporterSizeProbe = "IMPORTER_SIZE_PROBE"
 	// InsecureTLSVar provides a constant to capture our env variable "INSECURE_TLS"
 	InsecureTLSVar = "INSECURE_TLS"
 
@@ -94,10 +131,42 @@ const (
 	// OwnerUID provides the UID of the owner entity (either PVC or DV)
 	OwnerUID = "OWNER_UID"
 
+	// ProxyCACertVar provides a constant to capture our env variable "PROXY_CA_CERT", holding the PEM encoded proxy CA
+	ProxyCACertVar = "PROXY_CA_CERT"
+
+	// ImageValidationPolicyVar provides a constant to capture our env variable "IMAGE_VALIDATION_POLICY", holding the JSON
+	// encoded image validation policy
+	ImageValidationPolicyVar = "IMAGE_VALIDATION_POLICY"
+
+	// TransferRateLimitVar provides a constant to capture our env variable "TRANSFER_RATE_LIMIT", holding the maximum
+	// number of bytes per second a worker pod transfers
+	TransferRateLimitVar = "TRANSFER_RATE_LIMIT"
+
+	// FilesystemOverheadVar provides a constant to capture our env variable "FILESYSTEM_OVERHEAD", holding the fraction
+	// of a Filesystem mode volume a worker pod leaves for file system metadata
+	FilesystemOverheadVar = "FILESYSTEM_OVERHEAD"
+
+	// ProgressURLVar provides a constant to capture our env variable "PROGRESS_URL", holding the URL worker pods push
+	// their progress reports to
+	ProgressURLVar = "PROGRESS_URL"
+	// ProgressClientKeyVar provides a constant to capture our env variable "PROGRESS_CLIENT_KEY", holding the PEM
+	// encoded key of the client certificate used to push progress reports
+	ProgressClientKeyVar = "PROGRESS_CLIENT_KEY"
+	// ProgressClientCertVar provides a constant to capture our env variable "PROGRESS_CLIENT_CERT", holding the PEM
+	// encoded client certificate used to push progress reports
+	ProgressClientCertVar = "PROGRESS_CLIENT_CERT"
+	// ProgressServerCACertVar provides a constant to capture our env variable "PROGRESS_SERVER_CA_CERT", holding the
+	// PEM encoded CA certificate of the progress server
+	ProgressServerCACertVar = "PROGRESS_SERVER_CA_CERT"
+
 	// KeyAccess provides a constant to the accessKeyId label using in controller pkg and transport_test.go
 	KeyAccess = "accessKeyId"
 	// KeySecret provides a constant to the secretKey label using in controller pkg and transport_test.go
 	KeySecret = "secretKey"
+	// KeyToken provides a constant to the bearer token label in the endpoint secret
+	KeyToken = "token"
+	// KeyExtraHeaders provides a constant to the label in the endpoint secret holding extra "Name: value" http headers, one per line
+	KeyExtraHeaders = "extraHeaders"
 
 	// DefaultResyncPeriod sets a 10 minute resync period, used in the controller pkg and the controller cmd executable
 	DefaultResyncPeriod = 10 * time.Minute
@@ -115,4 +184,8 @@ const (
 
 	// UploadPath is the path to POST CDI uploads
 	UploadPath = "/v1alpha1/upload"
+
+	// HonorWaitForFirstConsumer is the feature gate delaying the worker pods of PVCs in WaitForFirstConsumer storage
+	// classes until another pod uses the PVC, the worker pods then run on the node selected for that pod
+	HonorWaitForFirstConsumer = "HonorWaitForFirstConsumer"
 )
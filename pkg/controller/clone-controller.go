@@ -3,6 +3,7 @@ package controller
 import (
 	"crypto/rsa"
 	"fmt"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -18,6 +19,8 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
+	listers "kubevirt.io/containerized-data-importer/pkg/client/listers/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/token"
 )
@@ -52,6 +55,7 @@ const (
 // CloneController represents the CDI Clone Controller
 type CloneController struct {
 	Controller
+	configLister   listers.CDIConfigLister
 	recorder       record.EventRecorder
 	tokenValidator token.Validator
 }
@@ -61,6 +65,7 @@ type CloneController struct {
 func NewCloneController(client kubernetes.Interface,
 	pvcInformer coreinformers.PersistentVolumeClaimInformer,
 	podInformer coreinformers.PodInformer,
+	configInformer informers.CDIConfigInformer,
 	image string,
 	pullPolicy string,
 	verbose string,
@@ -75,6 +80,7 @@ func NewCloneController(client kubernetes.Interface,
 
 	c := &CloneController{
 		Controller:     *NewController(client, pvcInformer, podInformer, image, pullPolicy, verbose),
+		configLister:   configInformer.Lister(),
 		recorder:       recorder,
 		tokenValidator: newCloneTokenValidator(apiServerKey),
 	}
@@ -160,8 +166,13 @@ func (cc *CloneController) processPvcItem(pvc *v1.PersistentVolumeClaim) error {
 			return err
 		}
 
+		config, err := getCDIConfig(cc.configLister)
+		if err != nil {
+			return err
+		}
+
 		cc.raisePodCreate(pvcKey)
-		sourcePod, err = CreateCloneSourcePod(cc.clientset, cc.image, cc.pullPolicy, clientName, pvc)
+		sourcePod, err = CreateCloneSourcePod(cc.clientset, config, cc.image, cc.pullPolicy, clientName, pvc)
 		if err != nil {
 			cc.observePodCreate(pvcKey)
 			return err
@@ -172,6 +183,18 @@ func (cc *CloneController) processPvcItem(pvc *v1.PersistentVolumeClaim) error {
 
 	klog.V(3).Infof("Pod phase for PVC %s/%s is %s", pvc.Namespace, pvc.Name, pvc.Annotations[AnnPodPhase])
 
+	if message := podFailure(sourcePod); message != nil {
+		pvcCopy := pvc.DeepCopy()
+		addFailureAnnotations(pvcCopy.Annotations, message)
+		if !reflect.DeepEqual(pvc, pvcCopy) {
+			klog.V(1).Infof("Clone source pod %s/%s failed: %s", sourcePod.Namespace, sourcePod.Name, message.Message)
+			pvc, err = cc.clientset.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(pvcCopy)
+			if err != nil {
+				return errors.Wrap(err, "error updating pvc")
+			}
+		}
+	}
+
 	if podSucceededFromPVC(pvc) && pvc.Annotations[AnnCloneOf] != "true" {
 		klog.V(1).Infof("Adding CloneOf annotation to PVC %s/%s", pvc.Namespace, pvc.Name)
 		pvc.Annotations[AnnCloneOf] = "true"
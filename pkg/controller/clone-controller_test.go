@@ -11,6 +11,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 
+	cdifake "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	"kubevirt.io/containerized-data-importer/pkg/util/cert/triple"
 )
 
@@ -45,6 +46,7 @@ func (f *CloneFixture) newCloneController() *CloneController {
 	v := newCloneTokenValidator(&getAPIServerKey().PublicKey)
 	return &CloneController{
 		Controller:     *f.newController("test/mycloneimage", "Always", "5"),
+		configLister:   newConfigInformer(cdifake.NewSimpleClientset(), nil).Lister(),
 		recorder:       &record.FakeRecorder{},
 		tokenValidator: v,
 	}
@@ -165,6 +167,37 @@ func TestAddsCloneOfAnnotation(t *testing.T) {
 	f.run(getPvcKey(pvc, t))
 }
 
+func TestRecordsSourcePodFailure(t *testing.T) {
+	f := newCloneFixture(t)
+	pvc := createClonePvc("source-ns", "golden-pvc", "target-ns", "target-pvc", nil, nil)
+	pvc.Annotations[AnnPodReady] = "true"
+	pvc.Annotations[AnnPodPhase] = string(corev1.PodRunning)
+	id := string(pvc.GetUID())
+	pod := createSourcePod(pvc, id)
+	pod.Namespace = "source-ns"
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 255,
+					Message:  `{"message":"Unexpected status code 503: ","reason":"TransferFailed","transient":true}`,
+				},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc, pod)
+
+	updatedPVC := pvc.DeepCopy()
+	updatedPVC.Annotations[AnnFailureReason] = "TransferFailed"
+	updatedPVC.Annotations[AnnFailureMessage] = "Unexpected status code 503: "
+	updatedPVC.Annotations[AnnFailureTransient] = "true"
+	f.expectUpdatePvcAction(updatedPVC)
+	f.run(getPvcKey(pvc, t))
+}
+
 func TestDeletesSourcePodAndFinalizer(t *testing.T) {
 	f := newCloneFixture(t)
 	pvc := createClonePvc("source-ns", "golden-pvc", "target-ns", "target-pvc", nil, nil)
@@ -2,13 +2,16 @@ package controller
 
 import (
 	"fmt"
+	"reflect"
 	"time"
 
 	routev1 "github.com/openshift/api/route/v1"
 	routeinformers "github.com/openshift/client-go/route/informers/externalversions/route/v1"
 	routelisters "github.com/openshift/client-go/route/listers/route/v1"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -23,6 +26,17 @@ import (
 	cdiclientset "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
 	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
 	listers "kubevirt.io/containerized-data-importer/pkg/client/listers/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/util"
+)
+
+const (
+	// the qemu-img info limits used if the image validation policy does not set them, the values are from OpenStack Nova
+	defaultInfoMemoryLimit  = "1Gi"
+	defaultInfoCPUTimeLimit = 30
+
+	// the requests of the worker pods if the CDI config does not set them, the pods are not limited by default
+	defaultPodCPURequest    = "100m"
+	defaultPodMemoryRequest = "60M"
 )
 
 // ConfigController members
@@ -287,6 +301,35 @@ func (c *ConfigController) syncHandler(key string) error {
 		updateConfig = true
 	}
 
+	importProxy := c.importProxyStatus(config)
+	if !reflect.DeepEqual(importProxy, config.Status.ImportProxy) {
+		newConfig.Status.ImportProxy = importProxy
+		updateConfig = true
+	}
+
+	imageValidation := imageValidationStatus(config)
+	if !reflect.DeepEqual(imageValidation, config.Status.ImageValidation) {
+		newConfig.Status.ImageValidation = imageValidation
+		updateConfig = true
+	}
+
+	if !reflect.DeepEqual(config.Spec.TransferRateLimit, config.Status.TransferRateLimit) {
+		newConfig.Status.TransferRateLimit = config.Spec.TransferRateLimit.DeepCopy()
+		updateConfig = true
+	}
+
+	filesystemOverhead := filesystemOverheadStatus(config)
+	if !reflect.DeepEqual(filesystemOverhead, config.Status.FilesystemOverhead) {
+		newConfig.Status.FilesystemOverhead = filesystemOverhead
+		updateConfig = true
+	}
+
+	podResourceRequirements := podResourceRequirementsStatus(config)
+	if !resourceRequirementsEqual(podResourceRequirements, config.Status.PodResourceRequirements) {
+		newConfig.Status.PodResourceRequirements = podResourceRequirements
+		updateConfig = true
+	}
+
 	if updateConfig {
 		err = updateCDIConfig(c.cdiClientSet, newConfig)
 		if err != nil {
@@ -321,6 +364,125 @@ func (c *ConfigController) scratchSpaceStorageClassStatus(config *cdiv1.CDIConfi
 	return "", nil
 }
 
+// importProxyStatus returns the proxy configuration used by the worker pods. A trusted CA ConfigMap which does not
+// exist in the CDI namespace is left out.
+func (c *ConfigController) importProxyStatus(config *cdiv1.CDIConfig) *cdiv1.ImportProxy {
+	if config.Spec.ImportProxy == nil {
+		return nil
+	}
+	proxy := config.Spec.ImportProxy.DeepCopy()
+	if proxy.TrustedCAProxy != nil {
+		_, err := c.client.CoreV1().ConfigMaps(util.GetNamespace()).Get(*proxy.TrustedCAProxy, metav1.GetOptions{})
+		if err != nil {
+			klog.Warningf("Unable to find proxy CA ConfigMap %s, %v\n", *proxy.TrustedCAProxy, err)
+			proxy.TrustedCAProxy = nil
+		}
+	}
+	return proxy
+}
+
+// imageValidationStatus returns the image validation policy in the spec, with the defaults of the unset fields filled in.
+func imageValidationStatus(config *cdiv1.CDIConfig) *cdiv1.ImageValidationPolicy {
+	if config.Spec.ImageValidation == nil {
+		return nil
+	}
+	policy := config.Spec.ImageValidation.DeepCopy()
+	if len(policy.AllowedFormats) == 0 {
+		policy.AllowedFormats = []string{"raw", "qcow2"}
+	}
+	if policy.InfoMemoryLimit == nil {
+		memoryLimit := resource.MustParse(defaultInfoMemoryLimit)
+		policy.InfoMemoryLimit = &memoryLimit
+	}
+	if policy.InfoCPUTimeLimit == nil {
+		cpuTimeLimit := int64(defaultInfoCPUTimeLimit)
+		policy.InfoCPUTimeLimit = &cpuTimeLimit
+	}
+	return policy
+}
+
+// filesystemOverheadStatus returns the filesystem overhead in effect, the valid values of the spec with the global
+// overhead defaulted. An invalid value is left out, so the global overhead applies instead.
+func filesystemOverheadStatus(config *cdiv1.CDIConfig) *cdiv1.FilesystemOverhead {
+	status := &cdiv1.FilesystemOverhead{
+		Global: util.DefaultFilesystemOverhead,
+	}
+	spec := config.Spec.FilesystemOverhead
+	if spec == nil {
+		return status
+	}
+	if spec.Global != "" {
+		if _, err := util.ParseFilesystemOverhead(spec.Global); err != nil {
+			klog.Warningf("Ignoring the global filesystem overhead, %v\n", err)
+		} else {
+			status.Global = spec.Global
+		}
+	}
+	for storageClass, percent := range spec.StorageClass {
+		if _, err := util.ParseFilesystemOverhead(percent); err != nil {
+			klog.Warningf("Ignoring the filesystem overhead of storage class %s, %v\n", storageClass, err)
+			continue
+		}
+		if status.StorageClass == nil {
+			status.StorageClass = map[string]cdiv1.Percent{}
+		}
+		status.StorageClass[storageClass] = percent
+	}
+	return status
+}
+
+// podResourceRequirementsStatus returns the resource requirements of the worker pods, the requests and limits of the
+// spec with the default requests of the unset resources filled in. There are no default limits, so qemu-img and the
+// decompression of large images are not killed by a limit nobody chose. A request above its limit is lowered to the
+// limit.
+func podResourceRequirementsStatus(config *cdiv1.CDIConfig) *v1.ResourceRequirements {
+	status := &v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(defaultPodCPURequest),
+			v1.ResourceMemory: resource.MustParse(defaultPodMemoryRequest),
+		},
+	}
+	spec := config.Spec.PodResourceRequirements
+	if spec == nil {
+		return status
+	}
+	if len(spec.Limits) > 0 {
+		status.Limits = spec.Limits.DeepCopy()
+	}
+	for name, quantity := range spec.Requests {
+		status.Requests[name] = quantity.DeepCopy()
+	}
+	for name, request := range status.Requests {
+		if limit, ok := status.Limits[name]; ok && request.Cmp(limit) > 0 {
+			klog.Warningf("Lowering the %s request of the worker pods to the limit %s", name, limit.String())
+			status.Requests[name] = limit
+		}
+	}
+	return status
+}
+
+// resourceRequirementsEqual returns true if a and b request and limit the same amounts of the same resources. The
+// quantities are compared by value, as a parsed quantity may not keep the form it was written in.
+func resourceRequirementsEqual(a, b *v1.ResourceRequirements) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return resourceListsEqual(a.Limits, b.Limits) && resourceListsEqual(a.Requests, b.Requests)
+}
+
+func resourceListsEqual(a, b v1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, quantity := range a {
+		other, ok := b[name]
+		if !ok || quantity.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // Init is meant to be called synchroniously when the the controller is starting
 func (c *ConfigController) Init() error {
 	klog.V(3).Infoln("Creating CDI config if necessary")
@@ -8,7 +8,9 @@ import (
 	routefake "github.com/openshift/client-go/route/clientset/versioned/fake"
 	routeinformers "github.com/openshift/client-go/route/informers/externalversions"
 
+	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -20,6 +22,7 @@ import (
 	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 type configFixture struct {
@@ -281,6 +284,11 @@ func (f *configFixture) expectListStorageClass() {
 		core.NewRootListAction(schema.GroupVersionResource{Resource: "storageclasses", Version: "v1"}, schema.GroupVersionKind{Group: "storage.k8s.io", Version: "v1", Kind: "StorageClass"}, metav1.ListOptions{}))
 }
 
+func (f *configFixture) expectGetConfigMap(name string) {
+	f.kubeactions = append(f.kubeactions,
+		core.NewGetAction(schema.GroupVersionResource{Resource: "configmaps", Version: "v1"}, util.GetNamespace(), name))
+}
+
 // very flaky
 /*
 func TestCreatesCDIConfig(t *testing.T) {
@@ -461,6 +469,184 @@ func TestCreatesScratchStorageClassOverrideExists(t *testing.T) {
 	f.run(getConfigKey(config, t))
 }
 
+func TestImportProxyStatus(t *testing.T) {
+	f := newConfigFixture(t)
+
+	caConfigMap := "proxy-ca"
+	f.kubeobjects = append(f.kubeobjects, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      caConfigMap,
+			Namespace: util.GetNamespace(),
+		},
+	})
+
+	config := createCDIConfig("testConfig")
+	httpProxy := "http://proxy:3128"
+	config.Spec.ImportProxy = &cdiv1.ImportProxy{
+		HTTPProxy:      &httpProxy,
+		TrustedCAProxy: &caConfigMap,
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	result := config.DeepCopy()
+	result.Status.ImportProxy = config.Spec.ImportProxy.DeepCopy()
+	f.expectListStorageClass()
+	f.expectGetConfigMap(caConfigMap)
+	f.expectUpdateConfigAction(result)
+
+	f.run(getConfigKey(config, t))
+}
+
+func TestImportProxyStatusMissingCA(t *testing.T) {
+	f := newConfigFixture(t)
+
+	config := createCDIConfig("testConfig")
+	httpProxy := "http://proxy:3128"
+	caConfigMap := "proxy-ca"
+	config.Spec.ImportProxy = &cdiv1.ImportProxy{
+		HTTPProxy:      &httpProxy,
+		TrustedCAProxy: &caConfigMap,
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	result := config.DeepCopy()
+	result.Status.ImportProxy = &cdiv1.ImportProxy{
+		HTTPProxy: &httpProxy,
+	}
+	f.expectListStorageClass()
+	f.expectGetConfigMap(caConfigMap)
+	f.expectUpdateConfigAction(result)
+
+	f.run(getConfigKey(config, t))
+}
+
+func TestImageValidationStatus(t *testing.T) {
+	f := newConfigFixture(t)
+
+	config := createCDIConfig("testConfig")
+	maxSize := resource.MustParse("10Gi")
+	config.Spec.ImageValidation = &cdiv1.ImageValidationPolicy{
+		MaxVirtualSize:  &maxSize,
+		RejectEncrypted: true,
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	memoryLimit := resource.MustParse("1Gi")
+	cpuTimeLimit := int64(30)
+	result := config.DeepCopy()
+	result.Status.ImageValidation = &cdiv1.ImageValidationPolicy{
+		MaxVirtualSize:   &maxSize,
+		AllowedFormats:   []string{"raw", "qcow2"},
+		RejectEncrypted:  true,
+		InfoMemoryLimit:  &memoryLimit,
+		InfoCPUTimeLimit: &cpuTimeLimit,
+	}
+	f.expectListStorageClass()
+	f.expectUpdateConfigAction(result)
+
+	f.run(getConfigKey(config, t))
+}
+
+func TestTransferRateLimitStatus(t *testing.T) {
+	f := newConfigFixture(t)
+
+	config := createCDIConfig("testConfig")
+	defaultLimit := resource.MustParse("100Mi")
+	config.Spec.TransferRateLimit = &cdiv1.TransferRateLimit{
+		Default:    &defaultLimit,
+		Namespaces: map[string]resource.Quantity{"backup": resource.MustParse("10Mi")},
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	result := config.DeepCopy()
+	result.Status.TransferRateLimit = config.Spec.TransferRateLimit.DeepCopy()
+	f.expectListStorageClass()
+	f.expectUpdateConfigAction(result)
+
+	f.run(getConfigKey(config, t))
+}
+
+func TestFilesystemOverheadStatus(t *testing.T) {
+	f := newConfigFixture(t)
+
+	config := createCDIConfig("testConfig")
+	config.Spec.FilesystemOverhead = &cdiv1.FilesystemOverhead{
+		Global:       "0.1",
+		StorageClass: map[string]cdiv1.Percent{"local": "0", "nfs": "0.2", "broken": "1.5"},
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	result := config.DeepCopy()
+	result.Status.FilesystemOverhead = &cdiv1.FilesystemOverhead{
+		Global:       "0.1",
+		StorageClass: map[string]cdiv1.Percent{"local": "0", "nfs": "0.2"},
+	}
+	f.expectListStorageClass()
+	f.expectUpdateConfigAction(result)
+
+	f.run(getConfigKey(config, t))
+}
+
+func TestPodResourceRequirementsStatus(t *testing.T) {
+	f := newConfigFixture(t)
+
+	config := createCDIConfig("testConfig")
+	config.Spec.PodResourceRequirements = &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("4Gi"),
+		},
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	result := config.DeepCopy()
+	result.Status.PodResourceRequirements = &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+	}
+	f.expectListStorageClass()
+	f.expectUpdateConfigAction(result)
+
+	f.run(getConfigKey(config, t))
+}
+
+func TestPodResourceRequirementsStatusUpToDate(t *testing.T) {
+	f := newConfigFixture(t)
+
+	config := createCDIConfig("testConfig")
+	config.Spec.PodResourceRequirements = &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("0.1"),
+		},
+	}
+
+	f.configLister = append(f.configLister, config)
+	f.objects = append(f.objects, config)
+
+	f.expectListStorageClass()
+	f.run(getConfigKey(config, t))
+}
+
 // TODO Enable me when we refactor the controller.
 //func TestCreatesScratchStorageClassOverrideMissing(t *testing.T) {
 //	f := newConfigFixture(t)
@@ -30,6 +30,47 @@ const (
 	AnnPodReady = AnnAPIGroup + "/storage.pod.ready"
 	// AnnOwnerRef is used when owner is in a different namespace
 	AnnOwnerRef = AnnAPIGroup + "/storage.ownerRef"
+	// AnnSourceFormat is a PVC annotation holding the detected format of the image the PVC was populated with
+	AnnSourceFormat = AnnAPIGroup + "/storage.sourceFormat"
+	// AnnVirtualSize is a PVC annotation holding the virtual size in bytes of the image the PVC was populated with
+	AnnVirtualSize = AnnAPIGroup + "/storage.virtualSize"
+	// AnnBytesTransferred is a PVC annotation holding the number of bytes read from the source
+	AnnBytesTransferred = AnnAPIGroup + "/storage.bytesTransferred"
+	// AnnTransferStartTime is a PVC annotation holding the RFC3339 time the transfer populating the PVC started
+	AnnTransferStartTime = AnnAPIGroup + "/storage.transferStartTime"
+	// AnnTransferCompletionTime is a PVC annotation holding the RFC3339 time the transfer populating the PVC completed
+	AnnTransferCompletionTime = AnnAPIGroup + "/storage.transferCompletionTime"
+	// AnnFailureReason is a PVC annotation holding the reason of the last failure reported by the worker pod
+	AnnFailureReason = AnnAPIGroup + "/storage.failure.reason"
+	// AnnFailureMessage is a PVC annotation describing the last failure reported by the worker pod
+	AnnFailureMessage = AnnAPIGroup + "/storage.failure.message"
+	// AnnFailureTransient is a PVC annotation telling whether retrying may overcome the last failure
+	AnnFailureTransient = AnnAPIGroup + "/storage.failure.transient"
+	// AnnTransferRateLimit is a PVC annotation overriding the bytes per second the worker pod populating it may transfer,
+	// on the worker pod it holds the limit in effect
+	AnnTransferRateLimit = AnnAPIGroup + "/storage.transferRateLimit"
+	// AnnWaitForFirstConsumer is a PVC annotation telling that the worker pod populating it waits for the first pod
+	// consuming the PVC to be scheduled
+	AnnWaitForFirstConsumer = AnnAPIGroup + "/storage.waitForFirstConsumer"
+	// AnnWorkloadNodeSelector is a PVC annotation overriding the node selector of the worker pods populating it, a JSON
+	// object of node labels
+	AnnWorkloadNodeSelector = AnnAPIGroup + "/storage.workloads.nodeSelector"
+	// AnnWorkloadTolerations is a PVC annotation overriding the tolerations of the worker pods populating it, a JSON
+	// list of tolerations
+	AnnWorkloadTolerations = AnnAPIGroup + "/storage.workloads.tolerations"
+	// AnnWorkloadAffinity is a PVC annotation overriding the affinity of the worker pods populating it, a JSON affinity
+	AnnWorkloadAffinity = AnnAPIGroup + "/storage.workloads.affinity"
+	// AnnWorkloadPriorityClassName is a PVC annotation overriding the priority class of the worker pods populating it
+	AnnWorkloadPriorityClassName = AnnAPIGroup + "/storage.workloads.priorityClassName"
+	// AnnQueuePosition is a PVC annotation holding the position of the PVC in the queue of the PVCs whose worker pod
+	// waits for the number of running worker pods to drop below the concurrency limits
+	AnnQueuePosition = AnnAPIGroup + "/storage.queue.position"
+	// AnnQueuePriority is a PVC annotation holding the priority of the PVC in the queue, PVCs with a higher priority
+	// get a worker pod first
+	AnnQueuePriority = AnnAPIGroup + "/storage.queue.priority"
+	// AnnSelectedNode is the PVC annotation the scheduler sets to the node selected for the first pod consuming a PVC
+	// in a WaitForFirstConsumer storage class
+	AnnSelectedNode = "volume.kubernetes.io/selected-node"
 )
 
 //Controller is a struct that contains common information and functionality used by all CDI controllers.
@@ -13,6 +13,11 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	cdiclientset "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions"
+	cdiinformers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
 )
 
 type ControllerFixture struct {
@@ -74,6 +79,22 @@ func (f *ControllerFixture) newController(image, pullPolicy, verbose string) *Co
 	return c
 }
 
+// newConfigInformer returns a CDIConfig informer whose cache holds the CDI configs among objects.
+func newConfigInformer(client cdiclientset.Interface, objects []runtime.Object) cdiinformers.CDIConfigInformer {
+	configInformer := informers.NewSharedInformerFactory(client, noResyncPeriodFunc()).Cdi().V1alpha1().CDIConfigs()
+	for _, object := range objects {
+		if config, ok := object.(*cdiv1.CDIConfig); ok {
+			configInformer.Informer().GetIndexer().Add(config)
+		}
+	}
+	return configInformer
+}
+
+// newDataVolumeInformer returns a DataVolume informer the worker scheduler of the controller under test can index.
+func newDataVolumeInformer(client cdiclientset.Interface) cdiinformers.DataVolumeInformer {
+	return informers.NewSharedInformerFactory(client, noResyncPeriodFunc()).Cdi().V1alpha1().DataVolumes()
+}
+
 // checkAction verifies that expected and actual actions are equal and both have
 // same attached resources
 func checkAction(expected, actual core.Action, t *testing.T) {
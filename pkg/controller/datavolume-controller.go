@@ -17,24 +17,22 @@ limitations under the License.
 package controller
 
 import (
-	"crypto/tls"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"math"
 	"reflect"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	csisnapshotv1 "github.com/kubernetes-csi/external-snapshotter/pkg/apis/volumesnapshot/v1alpha1"
+	conditions "github.com/openshift/custom-resource-status/conditions/v1"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	extclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformers "k8s.io/client-go/informers/core/v1"
@@ -54,6 +52,7 @@ import (
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	expectations "kubevirt.io/containerized-data-importer/pkg/expectations"
 	csiclientset "kubevirt.io/containerized-data-importer/pkg/snapshot-client/clientset/versioned"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 const controllerAgentName = "datavolume-controller"
@@ -135,9 +134,45 @@ const (
 	MessageUploadFailed = "Upload into %s failed"
 	// MessageUploadSucceeded provides a const to form upload has succeeded message
 	MessageUploadSucceeded = "Successfully uploaded into %s"
+	// MessageClaimBound provides a const to form the message of a bound PVC
+	MessageClaimBound = "PVC %s Bound"
+	// MessageClaimPending provides a const to form the message of a pending PVC
+	MessageClaimPending = "PVC %s Pending"
+	// MessageClaimNotFound provides a const to form the message of a PVC that does not exist
+	MessageClaimNotFound = "PVC %s not found"
+	// SizeProbeInProgress provides a const to indicate the size of the PVC is being determined
+	SizeProbeInProgress = "SizeProbeInProgress"
+	// SizeProbeFailed provides a const to indicate the size of the PVC could not be determined
+	SizeProbeFailed = "SizeProbeFailed"
+	// MessageSizeProbeInProgress provides a const to form size probe is in progress message
+	MessageSizeProbeInProgress = "Determining the size of PVC %s"
+	// MessageSizeProbeFailed provides a const to form size probe has failed message
+	MessageSizeProbeFailed = "Failed to determine the size of PVC %s"
 )
 
-var httpClient *http.Client
+// sizeProbePollInterval is how often the size probe pod is checked while it runs
+const sizeProbePollInterval = 5 * time.Second
+
+// The reasons of the Bound and Running conditions of a DataVolume. The reason of the Ready condition is the phase of the
+// DataVolume.
+const (
+	// ReasonClaimBound provides a const to indicate the PVC is bound
+	ReasonClaimBound = "Bound"
+	// ReasonClaimPending provides a const to indicate the PVC is not bound yet
+	ReasonClaimPending = "Pending"
+	// ReasonClaimLost provides a const to indicate the PVC lost its volume
+	ReasonClaimLost = "Lost"
+	// ReasonClaimNotFound provides a const to indicate the PVC does not exist
+	ReasonClaimNotFound = "NotFound"
+	// ReasonPodPending provides a const to indicate the worker pod is not running yet
+	ReasonPodPending = "Pending"
+	// ReasonPodRunning provides a const to indicate the worker pod is running
+	ReasonPodRunning = "Running"
+	// ReasonPodCompleted provides a const to indicate the worker pod completed
+	ReasonPodCompleted = "Completed"
+	// ReasonPodFailed provides a const to indicate the worker pod failed without reporting a reason
+	ReasonPodFailed = "Failed"
+)
 
 // DataVolumeController represents the CDI Data Volume Controller
 type DataVolumeController struct {
@@ -154,10 +189,22 @@ type DataVolumeController struct {
 	dataVolumesLister listers.DataVolumeLister
 	dataVolumesSynced cache.InformerSynced
 
+	configLister listers.CDIConfigLister
+	// scheduler admits the size probe pods under the concurrency limits
+	scheduler *WorkerScheduler
+
 	workqueue workqueue.RateLimitingInterface
 	recorder  record.EventRecorder
 
 	pvcExpectations *expectations.UIDTrackingControllerExpectations
+
+	// progressServer receives the progress reports of the worker pods
+	progressServer *ProgressServer
+
+	// the importer image, pull policy and verbosity of the size probe pods
+	importerImage string
+	pullPolicy    string
+	verbose       string
 }
 
 // DataVolumeEvent reoresents event
@@ -175,7 +222,13 @@ func NewDataVolumeController(
 	csiClientSet csiclientset.Interface,
 	extClientSet extclientset.Interface,
 	pvcInformer coreinformers.PersistentVolumeClaimInformer,
-	dataVolumeInformer informers.DataVolumeInformer) *DataVolumeController {
+	dataVolumeInformer informers.DataVolumeInformer,
+	configInformer informers.CDIConfigInformer,
+	scheduler *WorkerScheduler,
+	importerImage string,
+	pullPolicy string,
+	verbose string,
+	progressServer *ProgressServer) *DataVolumeController {
 
 	// Create event broadcaster
 	// Add datavolume-controller types to the default Kubernetes Scheme so Events can be
@@ -196,9 +249,15 @@ func NewDataVolumeController(
 		pvcsSynced:        pvcInformer.Informer().HasSynced,
 		dataVolumesLister: dataVolumeInformer.Lister(),
 		dataVolumesSynced: dataVolumeInformer.Informer().HasSynced,
+		configLister:      configInformer.Lister(),
+		scheduler:         scheduler,
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DataVolumes"),
 		recorder:          recorder,
 		pvcExpectations:   expectations.NewUIDTrackingControllerExpectations(expectations.NewControllerExpectations()),
+		progressServer:    progressServer,
+		importerImage:     importerImage,
+		pullPolicy:        pullPolicy,
+		verbose:           verbose,
 	}
 	klog.V(2).Info("Setting up event handlers")
 
@@ -391,6 +450,19 @@ func (c *DataVolumeController) syncHandler(key string) error {
 			if err != nil {
 				return err
 			}
+			if needsSizeProbe(dataVolume) {
+				size, err := c.probeSize(key, dataVolume, newPvc)
+				if err != nil || size == nil {
+					return err
+				}
+				// the requests are shared with the data volume in the cache
+				requests := corev1.ResourceList{}
+				for name, quantity := range newPvc.Spec.Resources.Requests {
+					requests[name] = quantity
+				}
+				requests[corev1.ResourceStorage] = *size
+				newPvc.Spec.Resources.Requests = requests
+			}
 			c.pvcExpectations.ExpectCreations(key, 1)
 			pvc, err = c.kubeclientset.CoreV1().PersistentVolumeClaims(dataVolume.Namespace).Create(newPvc)
 			if err != nil {
@@ -398,7 +470,7 @@ func (c *DataVolumeController) syncHandler(key string) error {
 				return err
 			}
 
-			c.scheduleProgressUpdate(dataVolume, pvc.GetUID())
+			c.scheduleProgressUpdate(dataVolume)
 		}
 	}
 
@@ -413,17 +485,140 @@ func (c *DataVolumeController) syncHandler(key string) error {
 	return nil
 }
 
-func (c *DataVolumeController) scheduleProgressUpdate(dataVolume *cdiv1.DataVolume, pvcUID types.UID) {
-	var podNamespace string
-	if dataVolume.Spec.Source.HTTP != nil {
-		podNamespace = dataVolume.Namespace
-	} else if dataVolume.Spec.Source.PVC != nil {
-		podNamespace = dataVolume.Spec.Source.PVC.Namespace
-	} else {
+// CanProbeSize returns true if the size of the PVC of a data volume with the spec can be determined from the virtual
+// size of its source, so the storage request may be omitted. Registry images are not probed, the whole image would have
+// to be pulled.
+func CanProbeSize(spec *cdiv1.DataVolumeSpec) bool {
+	if spec.ContentType == cdiv1.DataVolumeArchive {
+		return false
+	}
+	return spec.Source.HTTP != nil || spec.Source.S3 != nil
+}
+
+// needsSizeProbe returns true if the data volume does not request a size for its PVC.
+func needsSizeProbe(dataVolume *cdiv1.DataVolume) bool {
+	if dataVolume.Spec.PVC == nil {
+		return false
+	}
+	_, ok := dataVolume.Spec.PVC.Resources.Requests[corev1.ResourceStorage]
+	return !ok && CanProbeSize(&dataVolume.Spec)
+}
+
+// probeSize returns the size of the PVC of a data volume without a storage request, computed from the virtual size
+// of the source the size probe pod determined. The pod is created if it does not exist. It returns nil while the pod
+// runs, or if it failed, and the data volume status reflects that. The filesystem overhead in effect for pvc is added
+// to the virtual size.
+func (c *DataVolumeController) probeSize(key string, dataVolume *cdiv1.DataVolume, pvc *corev1.PersistentVolumeClaim) (*resource.Quantity, error) {
+	config, err := getCDIConfig(c.configLister)
+	if err != nil {
+		return nil, err
+	}
+	pod, err := c.kubeclientset.CoreV1().Pods(dataVolume.Namespace).Get(sizeProbePodName(dataVolume), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		position, err := c.scheduler.admit(sizeProbeQueueEntry(dataVolume), config)
+		if err != nil {
+			return nil, err
+		}
+		if position > 0 {
+			return nil, c.queueSizeProbe(key, dataVolume, position)
+		}
+		pod, err = CreateSizeProbePod(c.kubeclientset, config, c.importerImage, c.verbose, c.pullPolicy, dataVolume)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(pod, dataVolume) {
+		msg := fmt.Sprintf(MessageResourceExists, pod.Name)
+		c.recorder.Event(dataVolume, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return nil, errors.New(msg)
+	}
+
+	dataVolumeCopy := dataVolume.DeepCopy()
+	dataVolumeCopy.Status.QueuePosition = 0
+	var event DataVolumeEvent
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		result := podResult(pod)
+		if result != nil && result.VirtualSize > 0 {
+			overhead, err := GetFilesystemOverhead(c.kubeclientset, config, pvc)
+			if err != nil {
+				return nil, err
+			}
+			size := sizeForVirtualSize(result.VirtualSize, overhead)
+			klog.V(1).Infof("Virtual size of the source of data volume %s is %d, requesting %d", key, result.VirtualSize, size.Value())
+			err = c.kubeclientset.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return nil, err
+			}
+			return size, nil
+		}
+		dataVolumeCopy.Status.Phase = cdiv1.Failed
+		dataVolumeCopy.Status.LastError = &cdiv1.DataVolumeError{
+			Reason:  SizeProbeFailed,
+			Message: "the size probe pod did not report a virtual size",
+		}
+		event.eventType = corev1.EventTypeWarning
+		event.reason = SizeProbeFailed
+		event.message = fmt.Sprintf(MessageSizeProbeFailed, dataVolume.Name)
+	case corev1.PodFailed:
+		dataVolumeCopy.Status.Phase = cdiv1.Failed
+		if message := podFailure(pod); message != nil {
+			dataVolumeCopy.Status.LastError = &cdiv1.DataVolumeError{
+				Reason:    message.Reason,
+				Message:   message.Message,
+				Transient: message.Transient,
+			}
+		}
+		event.eventType = corev1.EventTypeWarning
+		event.reason = SizeProbeFailed
+		event.message = fmt.Sprintf(MessageSizeProbeFailed, dataVolume.Name)
+	default:
+		dataVolumeCopy.Status.Phase = cdiv1.SizeProbeInProgress
+		event.eventType = corev1.EventTypeNormal
+		event.reason = SizeProbeInProgress
+		event.message = fmt.Sprintf(MessageSizeProbeInProgress, dataVolume.Name)
+		// pods are not watched, check on the probe until it completes
+		c.workqueue.AddAfter(key, sizeProbePollInterval)
+	}
+	updateConditions(dataVolumeCopy, nil)
+
+	return nil, c.emitEvent(dataVolume, dataVolumeCopy, &event)
+}
+
+// queueSizeProbe sets the Queued phase of a data volume whose size probe pod exceeds the concurrency limits, and checks
+// on the queue until the pod may be created.
+func (c *DataVolumeController) queueSizeProbe(key string, dataVolume *cdiv1.DataVolume, position int) error {
+	klog.V(3).Infof("size probe of data volume %s is queued at position %d", key, position)
+	c.workqueue.AddAfter(key, queueRecheckInterval)
+	dataVolumeCopy := dataVolume.DeepCopy()
+	dataVolumeCopy.Status.Phase = cdiv1.Queued
+	dataVolumeCopy.Status.QueuePosition = int32(position)
+	updateConditions(dataVolumeCopy, nil)
+	return c.emitEvent(dataVolume, dataVolumeCopy, &DataVolumeEvent{})
+}
+
+// sizeForVirtualSize returns the storage request of a PVC fitting a disk image of the virtual size. The filesystem
+// overhead is the fraction of the volume not available to the image. The size is rounded up to whole MiB.
+func sizeForVirtualSize(virtualSize int64, filesystemOverhead float64) *resource.Quantity {
+	const mebibyte = 1024 * 1024
+
+	size := int64(math.Ceil(float64(virtualSize) / (1 - filesystemOverhead)))
+	size = (size + mebibyte - 1) / mebibyte * mebibyte
+	return resource.NewQuantity(size, resource.BinarySI)
+}
+
+// scheduleProgressUpdate copies the progress the worker pod of the data volume pushes to the progress server into the
+// status of the data volume, until the data volume completes.
+func (c *DataVolumeController) scheduleProgressUpdate(dataVolume *cdiv1.DataVolume) {
+	if c.progressServer == nil || dataVolume.Spec.Source.Blank != nil {
 		return
 	}
 
 	go func() {
+		defer c.progressServer.Forget(dataVolume.UID)
+		var applied *util.ProgressReport
 		for {
 			time.Sleep(2 * time.Second)
 			dataVolume, err := c.dataVolumesLister.DataVolumes(dataVolume.Namespace).Get(dataVolume.Name)
@@ -433,20 +628,26 @@ func (c *DataVolumeController) scheduleProgressUpdate(dataVolume *cdiv1.DataVolu
 				return
 			} else if err != nil {
 				klog.Errorf("error retrieving data volume %+v", err)
+				continue
 			}
 			if dataVolume.Status.Phase == cdiv1.Succeeded || dataVolume.Status.Phase == cdiv1.Failed {
 				// Data volume completed progress, or failed, either way stop queueing the data volume.
 				klog.V(3).Infof("DV %s/%s phase is %s, no longer updating progress", dataVolume.Namespace, dataVolume.Name, dataVolume.Status.Phase)
 				return
 			}
-			pod, err := c.getPodFromPvc(podNamespace, pvcUID)
-			if err == nil {
-				c.updateProgressUsingPod(dataVolume, pod)
-				_, err = c.cdiClientSet.CdiV1alpha1().DataVolumes(dataVolume.Namespace).Update(dataVolume)
-				if err != nil {
-					klog.Errorf("Unable to update data volume %s progress %+v", dataVolume.Name, err)
-				}
+			report := c.progressServer.Report(dataVolume.UID)
+			if report == nil || report == applied {
+				// nothing new was reported since the last update
+				continue
 			}
+			dataVolumeCopy := dataVolume.DeepCopy()
+			updateProgressFromReport(dataVolumeCopy, report, time.Now())
+			_, err = c.cdiClientSet.CdiV1alpha1().DataVolumes(dataVolume.Namespace).Update(dataVolumeCopy)
+			if err != nil {
+				klog.Errorf("Unable to update data volume %s progress %+v", dataVolume.Name, err)
+				continue
+			}
+			applied = report
 		}
 	}()
 }
@@ -523,6 +724,17 @@ func (c *DataVolumeController) getSnapshotClassForSmartClone(dataVolume *cdiv1.D
 		return ""
 	}
 
+	// Honor the clone strategy of the storage profile
+	profile, err := c.cdiClientSet.CdiV1alpha1().StorageProfiles().Get(storageclass.Name, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		runtime.HandleError(err)
+		return ""
+	}
+	if err == nil && profile.Status.CloneStrategy != nil && *profile.Status.CloneStrategy == cdiv1.CloneStrategyHostAssisted {
+		klog.V(3).Infof("Storage profile %s requests host assisted clones", storageclass.Name)
+		return ""
+	}
+
 	// List the snapshot classes
 	scs, err := c.csiClientSet.SnapshotV1alpha1().VolumeSnapshotClasses().List(metav1.ListOptions{})
 	if err != nil {
@@ -600,9 +812,13 @@ func (c *DataVolumeController) updateImportStatusPhase(pvc *corev1.PersistentVol
 			event.eventType = corev1.EventTypeWarning
 			event.reason = ImportFailed
 			event.message = fmt.Sprintf(MessageImportFailed, pvc.Name)
+			dataVolumeCopy.Status.ImageValidationFailure = imageValidationFailureFromAnnotations(pvc)
 		case string(corev1.PodSucceeded):
 			dataVolumeCopy.Status.Phase = cdiv1.Succeeded
 			dataVolumeCopy.Status.Progress = cdiv1.DataVolumeProgress("100.0%")
+			dataVolumeCopy.Status.DiskLayout = diskLayoutFromAnnotations(pvc)
+			dataVolumeCopy.Status.ImageInfo = imageInfoFromAnnotations(pvc)
+			completeTransferStats(pvc, dataVolumeCopy)
 			event.eventType = corev1.EventTypeNormal
 			event.reason = ImportSucceeded
 			event.message = fmt.Sprintf(MessageImportSucceeded, pvc.Name)
@@ -610,6 +826,137 @@ func (c *DataVolumeController) updateImportStatusPhase(pvc *corev1.PersistentVol
 	}
 }
 
+// diskLayoutFromAnnotations returns the disk layout the import controller recorded on the PVC, or nil if it was not
+// inspected.
+func diskLayoutFromAnnotations(pvc *corev1.PersistentVolumeClaim) *cdiv1.DataVolumeDiskLayout {
+	partitionTable, ok := pvc.Annotations[AnnPartitionTable]
+	if !ok {
+		return nil
+	}
+	layout := &cdiv1.DataVolumeDiskLayout{
+		PartitionTable:     partitionTable,
+		EFISystemPartition: pvc.Annotations[AnnEFISystemPartition] == "true",
+	}
+	if filesystems := pvc.Annotations[AnnFilesystems]; filesystems != "" {
+		layout.Filesystems = strings.Split(filesystems, ",")
+	}
+	return layout
+}
+
+// imageInfoFromAnnotations returns the description of the disk image the worker pod recorded on the PVC, or nil if
+// it reported none.
+func imageInfoFromAnnotations(pvc *corev1.PersistentVolumeClaim) *cdiv1.DataVolumeImageInfo {
+	info := &cdiv1.DataVolumeImageInfo{
+		Format: pvc.Annotations[AnnSourceFormat],
+		Digest: pvc.Annotations[AnnContentChecksum],
+	}
+	info.VirtualSize, _ = strconv.ParseInt(pvc.Annotations[AnnVirtualSize], 10, 64)
+	info.BytesTransferred, _ = strconv.ParseInt(pvc.Annotations[AnnBytesTransferred], 10, 64)
+	if *info == (cdiv1.DataVolumeImageInfo{}) {
+		return nil
+	}
+	return info
+}
+
+// lastErrorFromAnnotations returns the last failure the worker pod recorded on the PVC, or nil if it did not fail.
+func lastErrorFromAnnotations(pvc *corev1.PersistentVolumeClaim) *cdiv1.DataVolumeError {
+	reason, ok := pvc.Annotations[AnnFailureReason]
+	if !ok {
+		return nil
+	}
+	return &cdiv1.DataVolumeError{
+		Reason:    reason,
+		Message:   pvc.Annotations[AnnFailureMessage],
+		Transient: pvc.Annotations[AnnFailureTransient] == "true",
+	}
+}
+
+// imageValidationFailureFromAnnotations returns the image validation policy rule the import controller recorded on the
+// PVC, or nil if the image was not rejected.
+func imageValidationFailureFromAnnotations(pvc *corev1.PersistentVolumeClaim) *cdiv1.DataVolumeImageValidationFailure {
+	rule, ok := pvc.Annotations[AnnImageValidationRule]
+	if !ok {
+		return nil
+	}
+	return &cdiv1.DataVolumeImageValidationFailure{
+		Rule:    rule,
+		Message: pvc.Annotations[AnnImageValidationMessage],
+	}
+}
+
+// setCondition sets the condition of the passed in type. The condition is left alone if its status, reason and message
+// did not change, so its heartbeat does not cause an update on every sync.
+func setCondition(dataVolume *cdiv1.DataVolume, conditionType conditions.ConditionType, status corev1.ConditionStatus, reason, message string) {
+	condition := conditions.FindStatusCondition(dataVolume.Status.Conditions, conditionType)
+	if condition != nil && condition.Status == status && condition.Reason == reason && condition.Message == message {
+		return
+	}
+	conditions.SetStatusCondition(&dataVolume.Status.Conditions, conditions.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// updateConditions maintains the Bound, Running and Ready conditions of the DataVolume from the phase of the PVC, the
+// phase of the worker pod and the failure it reported. The phase of the DataVolume has to be updated first. Nothing is
+// set before the PVC or the snapshot of a smart-clone is created.
+func updateConditions(dataVolume *cdiv1.DataVolume, pvc *corev1.PersistentVolumeClaim) {
+	phase := dataVolume.Status.Phase
+	if pvc == nil && phase == cdiv1.PhaseUnset {
+		return
+	}
+
+	switch {
+	case pvc == nil && (phase == cdiv1.SnapshotForSmartCloneInProgress || phase == cdiv1.SmartClonePVCInProgress || phase == cdiv1.SizeProbeInProgress || phase == cdiv1.Queued):
+		setCondition(dataVolume, cdiv1.DataVolumeBound, corev1.ConditionFalse, string(phase), "")
+	case pvc == nil:
+		setCondition(dataVolume, cdiv1.DataVolumeBound, corev1.ConditionFalse, ReasonClaimNotFound, fmt.Sprintf(MessageClaimNotFound, dataVolume.Name))
+	case pvc.Status.Phase == corev1.ClaimBound:
+		setCondition(dataVolume, cdiv1.DataVolumeBound, corev1.ConditionTrue, ReasonClaimBound, fmt.Sprintf(MessageClaimBound, pvc.Name))
+	case pvc.Status.Phase == corev1.ClaimLost:
+		setCondition(dataVolume, cdiv1.DataVolumeBound, corev1.ConditionFalse, ReasonClaimLost, fmt.Sprintf(MessageErrClaimLost, pvc.Name))
+	default:
+		setCondition(dataVolume, cdiv1.DataVolumeBound, corev1.ConditionFalse, ReasonClaimPending, fmt.Sprintf(MessageClaimPending, pvc.Name))
+	}
+
+	podPhase := ""
+	if pvc != nil {
+		podPhase = pvc.Annotations[AnnPodPhase]
+	}
+	lastError := dataVolume.Status.LastError
+	switch {
+	case phase == cdiv1.SnapshotForSmartCloneInProgress || phase == cdiv1.SmartClonePVCInProgress:
+		// a smart-clone has no worker pod, the volume is populated while the snapshot and PVC are created
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionTrue, string(phase), "")
+	case phase == cdiv1.WaitForFirstConsumer || phase == cdiv1.Queued:
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionFalse, string(phase), "")
+	case podPhase == string(corev1.PodRunning):
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionTrue, ReasonPodRunning, "")
+	case podPhase == string(corev1.PodSucceeded) || phase == cdiv1.Succeeded:
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionFalse, ReasonPodCompleted, "")
+	case lastError != nil:
+		// a failed pod, or a pending one that is retried after a failure
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionFalse, lastError.Reason, lastError.Message)
+	case podPhase == string(corev1.PodFailed):
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionFalse, ReasonPodFailed, "")
+	default:
+		setCondition(dataVolume, cdiv1.DataVolumeRunning, corev1.ConditionFalse, ReasonPodPending, "")
+	}
+
+	switch {
+	case phase == cdiv1.Succeeded:
+		setCondition(dataVolume, cdiv1.DataVolumeReady, corev1.ConditionTrue, string(phase), "")
+	case phase == cdiv1.Failed && lastError != nil:
+		setCondition(dataVolume, cdiv1.DataVolumeReady, corev1.ConditionFalse, string(phase), lastError.Message)
+	case phase == cdiv1.PhaseUnset:
+		setCondition(dataVolume, cdiv1.DataVolumeReady, corev1.ConditionFalse, string(cdiv1.Pending), "")
+	default:
+		setCondition(dataVolume, cdiv1.DataVolumeReady, corev1.ConditionFalse, string(phase), "")
+	}
+}
+
 func (c *DataVolumeController) updateSmartCloneStatusPhase(phase cdiv1.DataVolumePhase, dataVolume *cdiv1.DataVolume) error {
 	var dataVolumeCopy = dataVolume.DeepCopy()
 	var event DataVolumeEvent
@@ -621,6 +968,7 @@ func (c *DataVolumeController) updateSmartCloneStatusPhase(phase cdiv1.DataVolum
 		event.reason = SnapshotForSmartCloneInProgress
 		event.message = fmt.Sprintf(MessageSmartCloneInProgress, dataVolumeCopy.Spec.Source.PVC.Namespace, dataVolumeCopy.Spec.Source.PVC.Name)
 	}
+	updateConditions(dataVolumeCopy, nil)
 
 	return c.emitEvent(dataVolume, dataVolumeCopy, &event)
 }
@@ -649,6 +997,8 @@ func (c *DataVolumeController) updateCloneStatusPhase(pvc *corev1.PersistentVolu
 		case string(corev1.PodSucceeded):
 			dataVolumeCopy.Status.Phase = cdiv1.Succeeded
 			dataVolumeCopy.Status.Progress = cdiv1.DataVolumeProgress("100.0%")
+			dataVolumeCopy.Status.ImageInfo = imageInfoFromAnnotations(pvc)
+			completeTransferStats(pvc, dataVolumeCopy)
 			event.eventType = corev1.EventTypeNormal
 			event.reason = CloneSucceeded
 			event.message = fmt.Sprintf(MessageCloneSucceeded, dataVolumeCopy.Spec.Source.PVC.Namespace, dataVolumeCopy.Spec.Source.PVC.Name, pvc.Namespace, pvc.Name)
@@ -680,6 +1030,8 @@ func (c *DataVolumeController) updateUploadStatusPhase(pvc *corev1.PersistentVol
 			event.message = fmt.Sprintf(MessageUploadFailed, pvc.Name)
 		case string(corev1.PodSucceeded):
 			dataVolumeCopy.Status.Phase = cdiv1.Succeeded
+			dataVolumeCopy.Status.ImageInfo = imageInfoFromAnnotations(pvc)
+			completeTransferStats(pvc, dataVolumeCopy)
 			event.eventType = corev1.EventTypeNormal
 			event.reason = UploadSucceeded
 			event.message = fmt.Sprintf(MessageUploadSucceeded, pvc.Name)
@@ -688,13 +1040,28 @@ func (c *DataVolumeController) updateUploadStatusPhase(pvc *corev1.PersistentVol
 	}
 }
 
+// updateQueuedPhase sets the Queued phase and the queue position of a data volume whose PVC is queued for a worker pod.
+func updateQueuedPhase(pvc *corev1.PersistentVolumeClaim, dataVolume *cdiv1.DataVolume) {
+	value, queued := pvc.Annotations[AnnQueuePosition]
+	if !queued {
+		return
+	}
+	position, err := strconv.Atoi(value)
+	if err != nil {
+		klog.V(3).Infof("Ignoring invalid queue position %q of pvc %s/%s", value, pvc.Namespace, pvc.Name)
+		return
+	}
+	dataVolume.Status.Phase = cdiv1.Queued
+	dataVolume.Status.QueuePosition = int32(position)
+}
+
 func (c *DataVolumeController) updateDataVolumeStatus(dataVolume *cdiv1.DataVolume, pvc *corev1.PersistentVolumeClaim) error {
 	dataVolumeCopy := dataVolume.DeepCopy()
 	var event DataVolumeEvent
 
 	curPhase := dataVolumeCopy.Status.Phase
 	if pvc == nil {
-		if curPhase != cdiv1.PhaseUnset && curPhase != cdiv1.Pending && curPhase != cdiv1.SnapshotForSmartCloneInProgress {
+		if curPhase != cdiv1.PhaseUnset && curPhase != cdiv1.Pending && curPhase != cdiv1.SnapshotForSmartCloneInProgress && curPhase != cdiv1.SizeProbeInProgress {
 
 			// if pvc doesn't exist and we're not still initializing, then
 			// something has gone wrong. Perhaps the PVC was deleted out from
@@ -706,10 +1073,15 @@ func (c *DataVolumeController) updateDataVolumeStatus(dataVolume *cdiv1.DataVolu
 		}
 
 	} else {
+		dataVolumeCopy.Status.QueuePosition = 0
 
 		switch pvc.Status.Phase {
 		case corev1.ClaimPending:
 			dataVolumeCopy.Status.Phase = cdiv1.Pending
+			if checkIfAnnoExists(pvc, AnnWaitForFirstConsumer, "true") && pvc.Annotations[AnnSelectedNode] == "" {
+				dataVolumeCopy.Status.Phase = cdiv1.WaitForFirstConsumer
+			}
+			updateQueuedPhase(pvc, dataVolumeCopy)
 			// the following check is for a case where the request is to create a blank disk for a block device.
 			// in that case, we do not create a pod as there is no need to create a blank image.
 			// instead, we just mark the DV phase as 'Succeeded' so any consumer will be able to use it.
@@ -741,6 +1113,10 @@ func (c *DataVolumeController) updateDataVolumeStatus(dataVolume *cdiv1.DataVolu
 				dataVolumeCopy.Status.Phase = cdiv1.UploadScheduled
 				c.updateUploadStatusPhase(pvc, dataVolumeCopy, &event)
 			}
+			updateQueuedPhase(pvc, dataVolumeCopy)
+			dataVolumeCopy.Status.LastError = lastErrorFromAnnotations(pvc)
+			restarts, _ := strconv.Atoi(pvc.Annotations[AnnRestartCount])
+			dataVolumeCopy.Status.RestartCount = int32(restarts)
 
 		case corev1.ClaimLost:
 			dataVolumeCopy.Status.Phase = cdiv1.Failed
@@ -753,6 +1129,7 @@ func (c *DataVolumeController) updateDataVolumeStatus(dataVolume *cdiv1.DataVolu
 			}
 		}
 	}
+	updateConditions(dataVolumeCopy, pvc)
 
 	return c.emitEvent(dataVolume, dataVolumeCopy, &event)
 }
@@ -770,95 +1147,52 @@ func (c *DataVolumeController) emitEvent(dataVolume *cdiv1.DataVolume, dataVolum
 	return nil
 }
 
-// getPodFromPvc determines the pod associated with the pvc UID passed in.
-func (c *DataVolumeController) getPodFromPvc(namespace string, pvcUID types.UID) (*corev1.Pod, error) {
-	l, _ := labels.Parse(common.PrometheusLabel)
-	pods, err := c.kubeclientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: l.String()})
-	if err != nil {
-		return nil, err
+// updateProgressFromReport sets the progress and the transfer statistics of the data volume from the progress report
+// of its worker pod. The remaining time is estimated from the average transfer rate.
+func updateProgressFromReport(dataVolume *cdiv1.DataVolume, report *util.ProgressReport, now time.Time) {
+	if report.Progress != nil {
+		klog.V(3).Infof("Setting progress to: %.2f", *report.Progress)
+		dataVolume.Status.Progress = cdiv1.DataVolumeProgress(fmt.Sprintf("%.2f%%", *report.Progress))
 	}
-
-	for _, pod := range pods.Items {
-		for _, or := range pod.OwnerReferences {
-			if or.UID == pvcUID {
-				return &pod, nil
-			}
-		}
-
-		val, exists := pod.Labels[CloneUniqueID]
-		if exists && val == string(pvcUID)+"-source-pod" {
-			return &pod, nil
-		}
+	stats := &cdiv1.DataVolumeTransferStats{
+		BytesTransferred: report.BytesTransferred,
+		TotalBytes:       report.TotalBytes,
+		Throughput:       report.Throughput,
+		StartTime:        report.StartTime,
 	}
-	return nil, errors.Errorf("Unable to find pod owned by UID: %s, in namespace: %s", string(pvcUID), namespace)
-}
-
-func (c *DataVolumeController) updateProgressUsingPod(dataVolumeCopy *cdiv1.DataVolume, pod *corev1.Pod) {
-	httpClient := buildHTTPClient()
-	// Example value: import_progress{ownerUID="b856691e-1038-11e9-a5ab-525500d15501"} 13.45
-	var importRegExp = regexp.MustCompile("progress\\{ownerUID\\=\"" + string(dataVolumeCopy.UID) + "\"\\} (\\d{1,3}\\.?\\d*)")
-
-	port, err := c.getPodMetricsPort(pod)
-	if err == nil {
-		url := fmt.Sprintf("https://%s:%d/metrics", pod.Status.PodIP, port)
-		klog.V(3).Info("Connecting to URL: " + url)
-		resp, err := httpClient.Get(url)
-		if err != nil {
-			klog.Errorf("%+v", err)
-			return
-		}
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return
-		}
-
-		match := importRegExp.FindStringSubmatch(string(body))
-		if match == nil {
-			klog.V(3).Info("No match found")
-			// No match
-			return
-		}
-		if f, err := strconv.ParseFloat(match[1], 64); err == nil {
-			klog.V(3).Info("Setting progress to: " + match[1])
-			dataVolumeCopy.Status.Progress = cdiv1.DataVolumeProgress(fmt.Sprintf("%.2f%%", f))
+	if stats.StartTime != nil && stats.BytesTransferred > 0 && stats.TotalBytes > stats.BytesTransferred {
+		if elapsed := now.Sub(stats.StartTime.Time).Seconds(); elapsed > 0 {
+			remaining := int64(float64(stats.TotalBytes-stats.BytesTransferred) * elapsed / float64(stats.BytesTransferred))
+			stats.EstimatedSecondsRemaining = &remaining
 		}
 	}
+	dataVolume.Status.Transfer = stats
 }
 
-func (c *DataVolumeController) getPodMetricsPort(pod *corev1.Pod) (int, error) {
-	for _, container := range pod.Spec.Containers {
-		for _, port := range container.Ports {
-			if port.Name == "metrics" {
-				return int(port.ContainerPort), nil
-			}
-		}
+// completeTransferStats records the final transfer statistics once the worker pod succeeded, from the result it
+// recorded on the PVC. What it did not record is kept from the last statistics reported while the transfer ran.
+func completeTransferStats(pvc *corev1.PersistentVolumeClaim, dataVolume *cdiv1.DataVolume) {
+	stats := &cdiv1.DataVolumeTransferStats{}
+	if dataVolume.Status.Transfer != nil {
+		stats = dataVolume.Status.Transfer.DeepCopy()
 	}
-	klog.V(3).Infof("Unable to find metrics port on pod: %s", pod.Name)
-	return 0, errors.New("Metrics port not found in pod")
-}
-
-// buildHTTPClient generates an http client that accepts any certificate, since we are using
-// it to get prometheus data it doesn't matter if someone can intercept the data. Once we have
-// a mechanism to properly sign the server, we can update this method to get a proper client.
-func buildHTTPClient() *http.Client {
-	if httpClient == nil {
-		defaultTransport := http.DefaultTransport.(*http.Transport)
-		// Create new Transport that ignores self-signed SSL
-		tr := &http.Transport{
-			Proxy:                 defaultTransport.Proxy,
-			DialContext:           defaultTransport.DialContext,
-			MaxIdleConns:          defaultTransport.MaxIdleConns,
-			IdleConnTimeout:       defaultTransport.IdleConnTimeout,
-			ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
-			TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-		}
-		httpClient = &http.Client{
-			Transport: tr,
-		}
+	stats.Throughput = 0
+	stats.EstimatedSecondsRemaining = nil
+	if bytes, err := strconv.ParseInt(pvc.Annotations[AnnBytesTransferred], 10, 64); err == nil {
+		stats.BytesTransferred = bytes
+	}
+	if t, err := time.Parse(time.RFC3339, pvc.Annotations[AnnTransferStartTime]); err == nil {
+		startTime := metav1.NewTime(t)
+		stats.StartTime = &startTime
+	}
+	if t, err := time.Parse(time.RFC3339, pvc.Annotations[AnnTransferCompletionTime]); err == nil {
+		completionTime := metav1.NewTime(t)
+		stats.CompletionTime = &completionTime
 	}
-	return httpClient
+	if *stats == (cdiv1.DataVolumeTransferStats{}) {
+		stats = nil
+	}
+	dataVolume.Status.Transfer = stats
 }
 
 // enqueueDataVolume takes a DataVolume resource and converts it into a namespace/name
@@ -958,8 +1292,8 @@ func newPersistentVolumeClaim(dataVolume *cdiv1.DataVolume) (*corev1.PersistentV
 	if dataVolume.Spec.Source.HTTP != nil {
 		annotations[AnnEndpoint] = dataVolume.Spec.Source.HTTP.URL
 		annotations[AnnSource] = SourceHTTP
-		if dataVolume.Spec.ContentType == cdiv1.DataVolumeArchive {
-			annotations[AnnContentType] = string(cdiv1.DataVolumeArchive)
+		if dataVolume.Spec.ContentType == cdiv1.DataVolumeArchive || dataVolume.Spec.ContentType == cdiv1.DataVolumeISO {
+			annotations[AnnContentType] = string(dataVolume.Spec.ContentType)
 		} else {
 			annotations[AnnContentType] = string(cdiv1.DataVolumeKubeVirt)
 		}
@@ -969,6 +1303,30 @@ func newPersistentVolumeClaim(dataVolume *cdiv1.DataVolume) (*corev1.PersistentV
 		if dataVolume.Spec.Source.HTTP.CertConfigMap != "" {
 			annotations[AnnCertConfigMap] = dataVolume.Spec.Source.HTTP.CertConfigMap
 		}
+		if dataVolume.Spec.Source.HTTP.ClientCertSecret != "" {
+			annotations[AnnClientCertSecret] = dataVolume.Spec.Source.HTTP.ClientCertSecret
+		}
+		if dataVolume.Spec.Source.HTTP.ChecksumURL != "" {
+			annotations[AnnChecksumURL] = dataVolume.Spec.Source.HTTP.ChecksumURL
+		}
+		if dataVolume.Spec.Source.HTTP.ChecksumSignatureURL != "" {
+			annotations[AnnChecksumSignatureURL] = dataVolume.Spec.Source.HTTP.ChecksumSignatureURL
+		}
+		if dataVolume.Spec.Source.HTTP.ChecksumKeyConfigMap != "" {
+			annotations[AnnChecksumKeyConfigMap] = dataVolume.Spec.Source.HTTP.ChecksumKeyConfigMap
+		}
+		if dataVolume.Spec.ContentType == cdiv1.DataVolumeArchive && dataVolume.Spec.ArchiveOptions != nil {
+			options := dataVolume.Spec.ArchiveOptions
+			if options.SubPath != "" {
+				annotations[AnnArchiveSubPath] = options.SubPath
+			}
+			if options.StripComponents > 0 {
+				annotations[AnnArchiveStripComponents] = strconv.Itoa(int(options.StripComponents))
+			}
+			if options.Ownership != "" {
+				annotations[AnnArchiveOwnership] = string(options.Ownership)
+			}
+		}
 	} else if dataVolume.Spec.Source.S3 != nil {
 		annotations[AnnEndpoint] = dataVolume.Spec.Source.S3.URL
 		if dataVolume.Spec.Source.S3.SecretRef != "" {
@@ -1004,6 +1362,16 @@ func newPersistentVolumeClaim(dataVolume *cdiv1.DataVolume) (*corev1.PersistentV
 		return nil, errors.Errorf("no source set for datavolume")
 	}
 
+	if policy := dataVolume.Spec.RetryPolicy; policy != nil {
+		annotations[AnnRetryMaxAttempts] = strconv.Itoa(int(policy.MaxAttempts))
+		if policy.BackoffSeconds > 0 {
+			annotations[AnnRetryBackoffSeconds] = strconv.Itoa(int(policy.BackoffSeconds))
+		}
+		if policy.RestartOnTransientOnly {
+			annotations[AnnRetryTransientOnly] = "true"
+		}
+	}
+
 	return &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        dataVolume.Name,
@@ -21,7 +21,9 @@ import (
 	"testing"
 	"time"
 
+	conditions "github.com/openshift/custom-resource-status/conditions/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -36,7 +38,9 @@ import (
 	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions"
+	"kubevirt.io/containerized-data-importer/pkg/common"
 	csifake "kubevirt.io/containerized-data-importer/pkg/snapshot-client/clientset/versioned/fake"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 var (
@@ -55,6 +59,7 @@ type fixture struct {
 	// Objects to put in the store.
 	dataVolumeLister []*cdiv1.DataVolume
 	pvcLister        []*corev1.PersistentVolumeClaim
+	podLister        []*corev1.Pod
 
 	// Actions expected to happen on the client.
 	kubeactions []core.Action
@@ -96,7 +101,13 @@ func newImportDataVolume(name string) *cdiv1.DataVolume {
 					URL: "http://example.com/data",
 				},
 			},
-			PVC: &corev1.PersistentVolumeClaimSpec{},
+			PVC: &corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse("1G"),
+					},
+				},
+			},
 		},
 	}
 }
@@ -167,6 +178,10 @@ func (f *fixture) newController() (*DataVolumeController, informers.SharedInform
 
 	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
 	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
+	scheduler, err := NewWorkerScheduler(k8sI.Core().V1().PersistentVolumeClaims(), k8sI.Core().V1().Pods(), i.Cdi().V1alpha1().DataVolumes())
+	if err != nil {
+		f.t.Fatalf("Error creating worker scheduler: %v", err)
+	}
 
 	for _, f := range f.dataVolumeLister {
 		i.Cdi().V1alpha1().DataVolumes().Informer().GetIndexer().Add(f)
@@ -176,12 +191,22 @@ func (f *fixture) newController() (*DataVolumeController, informers.SharedInform
 		k8sI.Core().V1().PersistentVolumeClaims().Informer().GetIndexer().Add(d)
 	}
 
+	for _, p := range f.podLister {
+		k8sI.Core().V1().Pods().Informer().GetIndexer().Add(p)
+	}
+
 	c := NewDataVolumeController(f.kubeclient,
 		f.client,
 		f.csiclient,
 		f.extclient,
 		k8sI.Core().V1().PersistentVolumeClaims(),
-		i.Cdi().V1alpha1().DataVolumes())
+		i.Cdi().V1alpha1().DataVolumes(),
+		newConfigInformer(f.client, f.objects),
+		scheduler,
+		"test/image",
+		"Always",
+		"5",
+		NewProgressServer(f.kubeclient, ""))
 
 	c.dataVolumesSynced = alwaysReady
 	c.pvcsSynced = alwaysReady
@@ -261,8 +286,8 @@ func checkDVAction(expected, actual core.Action, t *testing.T) {
 	switch a := actual.(type) {
 	case core.CreateAction:
 		e, _ := expected.(core.CreateAction)
-		expObject := e.GetObject()
-		object := a.GetObject()
+		expObject := withoutConditions(e.GetObject())
+		object := withoutConditions(a.GetObject())
 
 		if !reflect.DeepEqual(expObject, object) {
 			t.Errorf("Action %s %s has wrong object\nDiff:\n %s",
@@ -270,8 +295,8 @@ func checkDVAction(expected, actual core.Action, t *testing.T) {
 		}
 	case core.UpdateAction:
 		e, _ := expected.(core.UpdateAction)
-		expObject := e.GetObject()
-		object := a.GetObject()
+		expObject := withoutConditions(e.GetObject())
+		object := withoutConditions(a.GetObject())
 
 		if !reflect.DeepEqual(expObject, object) {
 			t.Errorf("Action %s %s has wrong object\nDiff:\n %s",
@@ -289,6 +314,18 @@ func checkDVAction(expected, actual core.Action, t *testing.T) {
 	}
 }
 
+// withoutConditions returns a copy of a DataVolume without its conditions, the timestamps of the conditions differ on
+// every run, so they are checked by TestDataVolumeConditions instead.
+func withoutConditions(object runtime.Object) runtime.Object {
+	dataVolume, ok := object.(*cdiv1.DataVolume)
+	if !ok {
+		return object
+	}
+	dataVolume = dataVolume.DeepCopy()
+	dataVolume.Status.Conditions = nil
+	return dataVolume
+}
+
 // filterInformerActions filters list and watch actions for testing resources.
 // Since list and watch don't change resource state we can filter it to lower
 // nose level in our tests.
@@ -342,10 +379,150 @@ func TestCreatesPersistentVolumeClaim(t *testing.T) {
 	expPersistentVolumeClaim, _ := newPersistentVolumeClaim(dataVolume)
 
 	f.expectCreatePersistentVolumeClaimAction(expPersistentVolumeClaim)
+	// the conditions report the PVC is not bound yet
+	f.expectUpdateDataVolumeStatusAction(dataVolume.DeepCopy())
 
 	f.run(getKey(dataVolume, t))
 }
 
+func newSizeProbeDataVolume(name string) *cdiv1.DataVolume {
+	dataVolume := newImportDataVolume(name)
+	dataVolume.Spec.PVC.Resources.Requests = nil
+	return dataVolume
+}
+
+func newSizeProbePod(dataVolume *cdiv1.DataVolume, phase corev1.PodPhase, terminated *corev1.ContainerStateTerminated) *corev1.Pod {
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            sizeProbePodName(dataVolume),
+			Namespace:       dataVolume.Namespace,
+			OwnerReferences: pvc.OwnerReferences,
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Terminated: terminated}},
+			},
+		},
+	}
+}
+
+func (f *fixture) expectGetPodAction(namespace, name string) {
+	f.kubeactions = append(f.kubeactions, core.NewGetAction(schema.GroupVersionResource{Resource: "pods", Version: "v1"}, namespace, name))
+}
+
+func TestSizeProbeInProgress(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newSizeProbeDataVolume("test")
+	pod := newSizeProbePod(dataVolume, corev1.PodRunning, nil)
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	f.expectGetPodAction(pod.Namespace, pod.Name)
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.SizeProbeInProgress
+	f.expectUpdateDataVolumeStatusAction(result)
+
+	f.run(getKey(dataVolume, t))
+}
+
+func TestSizeProbeQueued(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newSizeProbeDataVolume("test")
+	runningPod := createRunningImporterPod(createPvc("running", dataVolume.Namespace, nil, nil))
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume, createConcurrencyLimitsConfig(int32Ptr(1), nil))
+	f.podLister = append(f.podLister, runningPod)
+	f.kubeobjects = append(f.kubeobjects, runningPod)
+
+	f.expectGetPodAction(dataVolume.Namespace, sizeProbePodName(dataVolume))
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.Queued
+	result.Status.QueuePosition = 1
+	f.expectUpdateDataVolumeStatusAction(result)
+
+	f.run(getKey(dataVolume, t))
+}
+
+func TestSizeProbeSucceeded(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newSizeProbeDataVolume("test")
+	pod := newSizeProbePod(dataVolume, corev1.PodSucceeded, &corev1.ContainerStateTerminated{
+		Message: `{"message": "Size Probe Complete", "virtualSize": 10737418240}`,
+	})
+
+	config := createCDIConfig(common.ConfigName)
+	config.Status.FilesystemOverhead = &cdiv1.FilesystemOverhead{Global: "0.2"}
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume, config)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPersistentVolumeClaim, _ := newPersistentVolumeClaim(dataVolume)
+	expPersistentVolumeClaim.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("12800Mi"),
+	}
+
+	f.expectGetPodAction(pod.Namespace, pod.Name)
+	f.kubeactions = append(f.kubeactions, core.NewDeleteAction(schema.GroupVersionResource{Resource: "pods", Version: "v1"}, pod.Namespace, pod.Name))
+	f.expectCreatePersistentVolumeClaimAction(expPersistentVolumeClaim)
+	f.expectUpdateDataVolumeStatusAction(dataVolume.DeepCopy())
+
+	f.run(getKey(dataVolume, t))
+
+	if dataVolume.Spec.PVC.Resources.Requests != nil {
+		t.Errorf("Expected the data volume to be left alone, got requests %+v", dataVolume.Spec.PVC.Resources.Requests)
+	}
+}
+
+func TestSizeProbeFailed(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newSizeProbeDataVolume("test")
+	pod := newSizeProbePod(dataVolume, corev1.PodFailed, &corev1.ContainerStateTerminated{
+		ExitCode: 1,
+		Message:  `{"message": "Unable to determine the virtual size: 404 Not Found", "reason": "NotFound"}`,
+	})
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	f.expectGetPodAction(pod.Namespace, pod.Name)
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.Failed
+	result.Status.LastError = &cdiv1.DataVolumeError{
+		Reason:  "NotFound",
+		Message: "Unable to determine the virtual size: 404 Not Found",
+	}
+	f.expectUpdateDataVolumeStatusAction(result)
+
+	f.run(getKey(dataVolume, t))
+}
+
+func TestSizeForVirtualSize(t *testing.T) {
+	tests := []struct {
+		virtualSize int64
+		overhead    float64
+		want        string
+	}{
+		{10 * 1024 * 1024 * 1024, 0, "10Gi"},
+		{10*1024*1024*1024 - 1, 0, "10Gi"},
+		{1024*1024 + 1, 0, "2Mi"},
+		{1000 * 1024 * 1024, 0.2, "1250Mi"},
+		{1000*1024*1024 + 1, 0.2, "1251Mi"},
+	}
+	for _, test := range tests {
+		got := sizeForVirtualSize(test.virtualSize, test.overhead)
+		if got.Cmp(resource.MustParse(test.want)) != 0 {
+			t.Errorf("sizeForVirtualSize(%d, %v) = %s, want %s", test.virtualSize, test.overhead, got.String(), test.want)
+		}
+	}
+}
+
 func TestDoNothing(t *testing.T) {
 	f := newFixture(t)
 	dataVolume := newImportDataVolume("test")
@@ -353,6 +530,7 @@ func TestDoNothing(t *testing.T) {
 
 	dataVolume.Status.Phase = cdiv1.PVCBound
 	pvc.Status.Phase = corev1.ClaimBound
+	updateConditions(dataVolume, pvc)
 
 	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
 	f.objects = append(f.objects, dataVolume)
@@ -480,6 +658,425 @@ func TestImportPodFailed(t *testing.T) {
 	f.run(getKey(dataVolume, t))
 }
 
+func TestImportSucceededWithDiskLayout(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newImportDataVolume("test")
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+
+	dataVolume.Status.Phase = cdiv1.Pending
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Annotations[AnnImportPod] = "somepod"
+	pvc.Annotations[AnnPodPhase] = "Succeeded"
+	pvc.Annotations[AnnPartitionTable] = "mbr"
+	pvc.Annotations[AnnEFISystemPartition] = "false"
+	pvc.Annotations[AnnFilesystems] = "ext4,ntfs"
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume)
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.Succeeded
+	result.Status.Progress = "100.0%"
+	result.Status.DiskLayout = &cdiv1.DataVolumeDiskLayout{
+		PartitionTable: "mbr",
+		Filesystems:    []string{"ext4", "ntfs"},
+	}
+	f.expectUpdateDataVolumeStatusAction(result)
+	f.run(getKey(dataVolume, t))
+}
+
+func TestImportSucceededWithImageInfo(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newImportDataVolume("test")
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+
+	dataVolume.Status.Phase = cdiv1.Pending
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Annotations[AnnImportPod] = "somepod"
+	pvc.Annotations[AnnPodPhase] = "Succeeded"
+	pvc.Annotations[AnnSourceFormat] = "qcow2"
+	pvc.Annotations[AnnVirtualSize] = "4096"
+	pvc.Annotations[AnnBytesTransferred] = "1024"
+	pvc.Annotations[AnnContentChecksum] = "sha256:1234"
+	pvc.Annotations[AnnTransferStartTime] = "2019-10-02T10:00:00Z"
+	pvc.Annotations[AnnTransferCompletionTime] = "2019-10-02T10:01:00Z"
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume)
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.Succeeded
+	result.Status.Progress = "100.0%"
+	result.Status.ImageInfo = &cdiv1.DataVolumeImageInfo{
+		Format:           "qcow2",
+		VirtualSize:      4096,
+		BytesTransferred: 1024,
+		Digest:           "sha256:1234",
+	}
+	startTime := metav1.NewTime(time.Date(2019, 10, 2, 10, 0, 0, 0, time.UTC))
+	completionTime := metav1.NewTime(time.Date(2019, 10, 2, 10, 1, 0, 0, time.UTC))
+	result.Status.Transfer = &cdiv1.DataVolumeTransferStats{
+		BytesTransferred: 1024,
+		StartTime:        &startTime,
+		CompletionTime:   &completionTime,
+	}
+	f.expectUpdateDataVolumeStatusAction(result)
+	f.run(getKey(dataVolume, t))
+}
+
+func TestUpdateProgressFromReport(t *testing.T) {
+	dataVolume := newImportDataVolume("test-dv")
+	startTime := metav1.NewTime(time.Unix(1570000000, 0))
+	progress := 25.0
+	report := &util.ProgressReport{
+		Progress:         &progress,
+		BytesTransferred: 1048576,
+		TotalBytes:       4194304,
+		Throughput:       65536,
+		StartTime:        &startTime,
+	}
+	updateProgressFromReport(dataVolume, report, time.Unix(1570000010, 0))
+	remaining := int64(30)
+	expected := &cdiv1.DataVolumeTransferStats{
+		BytesTransferred:          1048576,
+		TotalBytes:                4194304,
+		Throughput:                65536,
+		StartTime:                 &startTime,
+		EstimatedSecondsRemaining: &remaining,
+	}
+	if !reflect.DeepEqual(dataVolume.Status.Transfer, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, dataVolume.Status.Transfer)
+	}
+	if dataVolume.Status.Progress != "25.00%" {
+		t.Errorf("Expected progress 25.00%%, got %s", dataVolume.Status.Progress)
+	}
+
+	// without a total there is neither progress nor an estimate
+	dataVolume = newImportDataVolume("test-dv")
+	updateProgressFromReport(dataVolume, &util.ProgressReport{BytesTransferred: 1024, StartTime: &startTime}, time.Unix(1570000010, 0))
+	if dataVolume.Status.Progress != "" || dataVolume.Status.Transfer.EstimatedSecondsRemaining != nil {
+		t.Errorf("Expected no progress and no estimate, got %+v", dataVolume.Status)
+	}
+}
+
+func TestCompleteTransferStats(t *testing.T) {
+	dataVolume := newUploadDataVolume("upload-datavolume")
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+	pvc.Annotations[AnnBytesTransferred] = "4194304"
+	remaining := int64(30)
+	dataVolume.Status.Transfer = &cdiv1.DataVolumeTransferStats{
+		BytesTransferred:          1048576,
+		TotalBytes:                4194304,
+		Throughput:                65536,
+		EstimatedSecondsRemaining: &remaining,
+	}
+
+	completeTransferStats(pvc, dataVolume)
+	expected := &cdiv1.DataVolumeTransferStats{
+		BytesTransferred: 4194304,
+		TotalBytes:       4194304,
+	}
+	if !reflect.DeepEqual(dataVolume.Status.Transfer, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, dataVolume.Status.Transfer)
+	}
+
+	dataVolume.Status.Transfer = nil
+	delete(pvc.Annotations, AnnBytesTransferred)
+	completeTransferStats(pvc, dataVolume)
+	if dataVolume.Status.Transfer != nil {
+		t.Errorf("Expected no statistics, got %+v", dataVolume.Status.Transfer)
+	}
+}
+
+func TestImportFailedWithLastError(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newImportDataVolume("test")
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+
+	dataVolume.Status.Phase = cdiv1.Pending
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Annotations[AnnImportPod] = "somepod"
+	pvc.Annotations[AnnPodPhase] = "Failed"
+	pvc.Annotations[AnnFailureReason] = "NotFound"
+	pvc.Annotations[AnnFailureMessage] = "expected status code 200, got 404. Status: 404 Not Found"
+	pvc.Annotations[AnnFailureTransient] = "false"
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume)
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.Failed
+	result.Status.LastError = &cdiv1.DataVolumeError{
+		Reason:  "NotFound",
+		Message: "expected status code 200, got 404. Status: 404 Not Found",
+	}
+	f.expectUpdateDataVolumeStatusAction(result)
+	f.run(getKey(dataVolume, t))
+}
+
+func TestImportRetryPolicy(t *testing.T) {
+	f := newFixture(t)
+	dataVolume := newImportDataVolume("test")
+	dataVolume.Spec.RetryPolicy = &cdiv1.DataVolumeRetryPolicy{MaxAttempts: 3, BackoffSeconds: 30, RestartOnTransientOnly: true}
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+
+	for key, expected := range map[string]string{AnnRetryMaxAttempts: "3", AnnRetryBackoffSeconds: "30", AnnRetryTransientOnly: "true"} {
+		if value := pvc.Annotations[key]; value != expected {
+			t.Errorf("Expected annotation %s to be %q, got %q", key, expected, value)
+		}
+	}
+
+	dataVolume.Status.Phase = cdiv1.Pending
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Annotations[AnnImportPod] = "somepod"
+	pvc.Annotations[AnnPodPhase] = "Pending"
+	pvc.Annotations[AnnRestartCount] = "2"
+	pvc.Annotations[AnnFailureReason] = "TransferFailed"
+	pvc.Annotations[AnnFailureMessage] = "expected status code 200, got 503. Status: 503 Service Unavailable"
+	pvc.Annotations[AnnFailureTransient] = "true"
+
+	f.dataVolumeLister = append(f.dataVolumeLister, dataVolume)
+	f.objects = append(f.objects, dataVolume)
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+
+	result := dataVolume.DeepCopy()
+	result.Status.Phase = cdiv1.ImportScheduled
+	result.Status.RestartCount = 2
+	result.Status.LastError = &cdiv1.DataVolumeError{
+		Reason:    "TransferFailed",
+		Message:   "expected status code 200, got 503. Status: 503 Service Unavailable",
+		Transient: true,
+	}
+	f.expectUpdateDataVolumeStatusAction(result)
+	f.run(getKey(dataVolume, t))
+}
+
+func TestImportPodFailedValidation(t *testing.T) {
+	dataVolume := newImportDataVolume("test")
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+	pvc.Annotations[AnnPodPhase] = "Failed"
+	pvc.Annotations[AnnImageValidationRule] = "MaxVirtualSize"
+	pvc.Annotations[AnnImageValidationMessage] = "Virtual size 2 of image http://test is larger than the maximum 1"
+
+	controller := &DataVolumeController{}
+	controller.updateImportStatusPhase(pvc, dataVolume, &DataVolumeEvent{})
+	if dataVolume.Status.Phase != cdiv1.Failed {
+		t.Errorf("Expected phase %s, got %s", cdiv1.Failed, dataVolume.Status.Phase)
+	}
+	expected := &cdiv1.DataVolumeImageValidationFailure{
+		Rule:    "MaxVirtualSize",
+		Message: pvc.Annotations[AnnImageValidationMessage],
+	}
+	if !reflect.DeepEqual(dataVolume.Status.ImageValidationFailure, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, dataVolume.Status.ImageValidationFailure)
+	}
+}
+
+func TestDataVolumeConditions(t *testing.T) {
+	type expectedCondition struct {
+		status corev1.ConditionStatus
+		reason string
+	}
+	tests := []struct {
+		name          string
+		claimPhase    corev1.PersistentVolumeClaimPhase
+		annotations   map[string]string
+		noClaim       bool
+		phase         cdiv1.DataVolumePhase
+		queuePosition int32
+		bound         expectedCondition
+		running       expectedCondition
+		ready         expectedCondition
+	}{
+		{
+			name:       "claim pending",
+			claimPhase: corev1.ClaimPending,
+			phase:      cdiv1.Pending,
+			bound:      expectedCondition{corev1.ConditionFalse, ReasonClaimPending},
+			running:    expectedCondition{corev1.ConditionFalse, ReasonPodPending},
+			ready:      expectedCondition{corev1.ConditionFalse, string(cdiv1.Pending)},
+		},
+		{
+			name:        "waiting for first consumer",
+			claimPhase:  corev1.ClaimPending,
+			annotations: map[string]string{AnnWaitForFirstConsumer: "true"},
+			phase:       cdiv1.WaitForFirstConsumer,
+			bound:       expectedCondition{corev1.ConditionFalse, ReasonClaimPending},
+			running:     expectedCondition{corev1.ConditionFalse, string(cdiv1.WaitForFirstConsumer)},
+			ready:       expectedCondition{corev1.ConditionFalse, string(cdiv1.WaitForFirstConsumer)},
+		},
+		{
+			name:        "first consumer scheduled",
+			claimPhase:  corev1.ClaimPending,
+			annotations: map[string]string{AnnWaitForFirstConsumer: "true", AnnSelectedNode: "node01"},
+			phase:       cdiv1.Pending,
+			bound:       expectedCondition{corev1.ConditionFalse, ReasonClaimPending},
+			running:     expectedCondition{corev1.ConditionFalse, ReasonPodPending},
+			ready:       expectedCondition{corev1.ConditionFalse, string(cdiv1.Pending)},
+		},
+		{
+			name:          "queued",
+			claimPhase:    corev1.ClaimBound,
+			annotations:   map[string]string{AnnQueuePosition: "3"},
+			phase:         cdiv1.Queued,
+			queuePosition: 3,
+			bound:         expectedCondition{corev1.ConditionTrue, ReasonClaimBound},
+			running:       expectedCondition{corev1.ConditionFalse, string(cdiv1.Queued)},
+			ready:         expectedCondition{corev1.ConditionFalse, string(cdiv1.Queued)},
+		},
+		{
+			name:          "queued claim pending",
+			claimPhase:    corev1.ClaimPending,
+			annotations:   map[string]string{AnnQueuePosition: "1"},
+			phase:         cdiv1.Queued,
+			queuePosition: 1,
+			bound:         expectedCondition{corev1.ConditionFalse, ReasonClaimPending},
+			running:       expectedCondition{corev1.ConditionFalse, string(cdiv1.Queued)},
+			ready:         expectedCondition{corev1.ConditionFalse, string(cdiv1.Queued)},
+		},
+		{
+			name:        "pod pending",
+			claimPhase:  corev1.ClaimBound,
+			annotations: map[string]string{AnnImportPod: "importer", AnnPodPhase: "Pending"},
+			phase:       cdiv1.ImportScheduled,
+			bound:       expectedCondition{corev1.ConditionTrue, ReasonClaimBound},
+			running:     expectedCondition{corev1.ConditionFalse, ReasonPodPending},
+			ready:       expectedCondition{corev1.ConditionFalse, string(cdiv1.ImportScheduled)},
+		},
+		{
+			name:        "pod running",
+			claimPhase:  corev1.ClaimBound,
+			annotations: map[string]string{AnnImportPod: "importer", AnnPodPhase: "Running"},
+			phase:       cdiv1.ImportInProgress,
+			bound:       expectedCondition{corev1.ConditionTrue, ReasonClaimBound},
+			running:     expectedCondition{corev1.ConditionTrue, ReasonPodRunning},
+			ready:       expectedCondition{corev1.ConditionFalse, string(cdiv1.ImportInProgress)},
+		},
+		{
+			name:        "pod succeeded",
+			claimPhase:  corev1.ClaimBound,
+			annotations: map[string]string{AnnImportPod: "importer", AnnPodPhase: "Succeeded"},
+			phase:       cdiv1.Succeeded,
+			bound:       expectedCondition{corev1.ConditionTrue, ReasonClaimBound},
+			running:     expectedCondition{corev1.ConditionFalse, ReasonPodCompleted},
+			ready:       expectedCondition{corev1.ConditionTrue, string(cdiv1.Succeeded)},
+		},
+		{
+			name:       "pod failed",
+			claimPhase: corev1.ClaimBound,
+			annotations: map[string]string{AnnImportPod: "importer", AnnPodPhase: "Failed",
+				AnnFailureReason: "TransferFailed", AnnFailureMessage: "connection reset"},
+			phase:   cdiv1.Failed,
+			bound:   expectedCondition{corev1.ConditionTrue, ReasonClaimBound},
+			running: expectedCondition{corev1.ConditionFalse, "TransferFailed"},
+			ready:   expectedCondition{corev1.ConditionFalse, string(cdiv1.Failed)},
+		},
+		{
+			name:        "pod failed without termination message",
+			claimPhase:  corev1.ClaimBound,
+			annotations: map[string]string{AnnUploadRequest: "", AnnPodPhase: "Failed"},
+			phase:       cdiv1.Failed,
+			bound:       expectedCondition{corev1.ConditionTrue, ReasonClaimBound},
+			running:     expectedCondition{corev1.ConditionFalse, ReasonPodFailed},
+			ready:       expectedCondition{corev1.ConditionFalse, string(cdiv1.Failed)},
+		},
+		{
+			name:       "claim lost",
+			claimPhase: corev1.ClaimLost,
+			phase:      cdiv1.Failed,
+			bound:      expectedCondition{corev1.ConditionFalse, ReasonClaimLost},
+			running:    expectedCondition{corev1.ConditionFalse, ReasonPodPending},
+			ready:      expectedCondition{corev1.ConditionFalse, string(cdiv1.Failed)},
+		},
+		{
+			name:    "claim deleted",
+			noClaim: true,
+			phase:   cdiv1.Failed,
+			bound:   expectedCondition{corev1.ConditionFalse, ReasonClaimNotFound},
+			running: expectedCondition{corev1.ConditionFalse, ReasonPodPending},
+			ready:   expectedCondition{corev1.ConditionFalse, string(cdiv1.Failed)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			dataVolume := newImportDataVolume("test")
+			dataVolume.Status.Phase = cdiv1.ImportInProgress
+			pvc, _ := newPersistentVolumeClaim(dataVolume)
+			pvc.Status.Phase = test.claimPhase
+			for k, v := range test.annotations {
+				pvc.Annotations[k] = v
+			}
+			if test.noClaim {
+				pvc = nil
+			}
+
+			f.objects = append(f.objects, dataVolume)
+			controller, _, _ := f.newController()
+			if err := controller.updateDataVolumeStatus(dataVolume, pvc); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			updated, err := f.client.CdiV1alpha1().DataVolumes(dataVolume.Namespace).Get(dataVolume.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if updated.Status.Phase != test.phase {
+				t.Errorf("Expected phase %s, got %s", test.phase, updated.Status.Phase)
+			}
+			if updated.Status.QueuePosition != test.queuePosition {
+				t.Errorf("Expected queue position %d, got %d", test.queuePosition, updated.Status.QueuePosition)
+			}
+			for conditionType, expected := range map[conditions.ConditionType]expectedCondition{
+				cdiv1.DataVolumeBound: test.bound, cdiv1.DataVolumeRunning: test.running, cdiv1.DataVolumeReady: test.ready,
+			} {
+				condition := conditions.FindStatusCondition(updated.Status.Conditions, conditionType)
+				if condition == nil {
+					t.Fatalf("Expected condition %s", conditionType)
+				}
+				if condition.Status != expected.status || condition.Reason != expected.reason {
+					t.Errorf("Expected condition %s %s with reason %s, got %+v", conditionType, expected.status, expected.reason, condition)
+				}
+			}
+
+			// The conditions do not change on the next sync, so the data volume is not updated again
+			f.client.ClearActions()
+			if err := controller.updateDataVolumeStatus(updated, pvc); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			for _, action := range f.client.Actions() {
+				if action.GetVerb() == "update" {
+					t.Errorf("Unexpected update of unchanged data volume")
+				}
+			}
+		})
+	}
+}
+
+func TestDataVolumeConditionsUnset(t *testing.T) {
+	dataVolume := newImportDataVolume("test")
+	updateConditions(dataVolume, nil)
+	if len(dataVolume.Status.Conditions) != 0 {
+		t.Errorf("Expected no conditions before the PVC is created, got %+v", dataVolume.Status.Conditions)
+	}
+
+	dataVolume.Status.Phase = cdiv1.SnapshotForSmartCloneInProgress
+	updateConditions(dataVolume, nil)
+	if !conditions.IsStatusConditionTrue(dataVolume.Status.Conditions, cdiv1.DataVolumeRunning) {
+		t.Errorf("Expected condition %s during smart-clone, got %+v", cdiv1.DataVolumeRunning, dataVolume.Status.Conditions)
+	}
+	condition := conditions.FindStatusCondition(dataVolume.Status.Conditions, cdiv1.DataVolumeBound)
+	if condition == nil || condition.Reason != SnapshotForSmartCloneInProgress {
+		t.Errorf("Unexpected condition %+v", condition)
+	}
+}
+
 func TestImportClaimLost(t *testing.T) {
 	f := newFixture(t)
 	dataVolume := newImportDataVolume("test")
@@ -948,3 +1545,27 @@ func TestSmartCloneMatchingStorageClass(t *testing.T) {
 		t.Errorf("Should be expected SnapshotClass")
 	}
 }
+
+func TestSmartCloneStorageProfileHostAssisted(t *testing.T) {
+	f := newFixtureCsiCrds(t)
+	scName := "test"
+	sc := createStorageClassWithProvisioner(scName, map[string]string{
+		AnnDefaultStorageClass: "true",
+	}, "csi-plugin")
+	f.kubeobjects = append(f.kubeobjects, sc)
+	f.csiobjects = append(f.csiobjects, createSnapshotClass("snap-class", nil, "csi-plugin"))
+	cloneStrategy := cdiv1.CloneStrategyHostAssisted
+	f.objects = append(f.objects, &cdiv1.StorageProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: scName},
+		Status:     cdiv1.StorageProfileStatus{CloneStrategy: &cloneStrategy},
+	})
+	dataVolume := newCloneDataVolume("test")
+	pvc := createPvcInStorageClass("test", "default", &scName, nil, nil)
+	f.pvcLister = append(f.pvcLister, pvc)
+
+	c, _, _ := f.newController()
+
+	if snapClassName := c.getSnapshotClassForSmartClone(dataVolume); snapClassName != "" {
+		t.Errorf("Should not be smart-clone applicable, storage profile requests host assisted clones")
+	}
+}
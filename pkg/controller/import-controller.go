@@ -3,6 +3,7 @@ package controller
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
@@ -15,8 +16,12 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	clientset "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
+	listers "kubevirt.io/containerized-data-importer/pkg/client/listers/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 const (
@@ -30,12 +35,54 @@ const (
 	AnnSecret = AnnAPIGroup + "/storage.import.secretName"
 	// AnnCertConfigMap is the name of a configmap containing tls certs
 	AnnCertConfigMap = AnnAPIGroup + "/storage.import.certConfigMap"
+	// AnnClientCertSecret is the name of a secret containing the tls client certificate
+	AnnClientCertSecret = AnnAPIGroup + "/storage.import.clientCertSecret"
+	// AnnChecksumURL provides a const for the URL of the checksum file used to verify the import
+	AnnChecksumURL = AnnAPIGroup + "/storage.import.checksumURL"
+	// AnnChecksumSignatureURL provides a const for the URL of the detached signature of the checksum file
+	AnnChecksumSignatureURL = AnnAPIGroup + "/storage.import.checksumSignatureURL"
+	// AnnChecksumKeyConfigMap is the name of a configmap containing the GPG keys used to verify the checksum file
+	AnnChecksumKeyConfigMap = AnnAPIGroup + "/storage.import.checksumKeyConfigMap"
+	// AnnArchiveSubPath provides a const for the path inside an archive to extract
+	AnnArchiveSubPath = AnnAPIGroup + "/storage.import.archiveSubPath"
+	// AnnArchiveStripComponents provides a const for the number of leading path components to strip from archive entries
+	AnnArchiveStripComponents = AnnAPIGroup + "/storage.import.archiveStripComponents"
+	// AnnArchiveOwnership provides a const for keeping or dropping the owner of extracted archive entries
+	AnnArchiveOwnership = AnnAPIGroup + "/storage.import.archiveOwnership"
+	// AnnVerify provides a const for comparing the converted disk image with its source
+	AnnVerify = AnnAPIGroup + "/storage.import.verify"
+	// AnnISOVolumeLabel provides a const for the volume label of an imported ISO image
+	AnnISOVolumeLabel = AnnAPIGroup + "/storage.import.isoVolumeLabel"
+	// AnnPartitionTable provides a const for the partition table type of an imported disk image: none, mbr or gpt
+	AnnPartitionTable = AnnAPIGroup + "/storage.import.partitionTable"
+	// AnnEFISystemPartition provides a const for whether an imported disk image contains an EFI system partition
+	AnnEFISystemPartition = AnnAPIGroup + "/storage.import.efiSystemPartition"
+	// AnnFilesystems provides a const for the comma separated file systems detected on an imported disk image
+	AnnFilesystems = AnnAPIGroup + "/storage.import.filesystems"
+	// AnnImageValidationRule provides a const for the image validation policy rule the imported image violates
+	AnnImageValidationRule = AnnAPIGroup + "/storage.import.imageValidationRule"
+	// AnnImageValidationMessage provides a const for the description of the image validation policy violation
+	AnnImageValidationMessage = AnnAPIGroup + "/storage.import.imageValidationMessage"
 	// AnnContentType provides a const for the PVC content-type
 	AnnContentType = AnnAPIGroup + "/storage.contentType"
+	// AnnContentChecksum provides a const for the checksum of the disk image the PVC was populated with
+	AnnContentChecksum = AnnAPIGroup + "/storage.contentChecksum"
 	// AnnImportPod provides a const for our PVC importPodName annotation
 	AnnImportPod = AnnAPIGroup + "/storage.import.importPodName"
 	// AnnRequiresScratch provides a const for our PVC requires scratch annotation
 	AnnRequiresScratch = AnnAPIGroup + "/storage.import.requiresScratch"
+	// AnnRetryMaxAttempts provides a const for the number of import attempts, its presence enables the retry policy
+	AnnRetryMaxAttempts = AnnAPIGroup + "/storage.import.retryMaxAttempts"
+	// AnnRetryBackoffSeconds provides a const for the delay before the first retry of a failed import
+	AnnRetryBackoffSeconds = AnnAPIGroup + "/storage.import.retryBackoffSeconds"
+	// AnnRetryTransientOnly provides a const for only retrying imports that failed for a transient reason
+	AnnRetryTransientOnly = AnnAPIGroup + "/storage.import.retryTransientOnly"
+	// AnnRetryAfter provides a const for the time the next importer pod is created after a failure
+	AnnRetryAfter = AnnAPIGroup + "/storage.import.retryAfter"
+	// AnnRetriedPod provides a const for the name of the failed importer pod the last retry was scheduled for
+	AnnRetriedPod = AnnAPIGroup + "/storage.import.retriedPod"
+	// AnnRestartCount provides a const for the number of times the import was restarted after a failure
+	AnnRestartCount = AnnAPIGroup + "/storage.import.restartCount"
 
 	//LabelImportPvc is a pod label used to find the import pod that was created by the relevant PVC
 	LabelImportPvc = AnnAPIGroup + "/storage.import.importPvcName"
@@ -44,18 +91,35 @@ const (
 
 	// ErrImportFailedPVC provides a const to indicate an import to the PVC failed
 	ErrImportFailedPVC = "ErrImportFailed"
+
+	// defaultRetryBackoff is the delay before the first retry of a failed import if the retry policy sets none
+	defaultRetryBackoff = 10 * time.Second
+	// maxRetryBackoff caps the delay between retries of a failed import
+	maxRetryBackoff = 5 * time.Minute
 )
 
 // ImportController represents a CDI Import Controller
 type ImportController struct {
 	cdiClient clientset.Interface
 	Controller
-	recorder record.EventRecorder
+	configLister listers.CDIConfigLister
+	recorder     record.EventRecorder
+	scheduler    *WorkerScheduler
+}
+
+// importRetryPolicy controls how often and when a failed import is retried.
+type importRetryPolicy struct {
+	maxAttempts   int
+	backoff       time.Duration
+	transientOnly bool
 }
 
 type importPodEnvVar struct {
 	ep, secretName, source, contentType, imageSize, certConfigMap string
-	insecureTLS                                                   bool
+	clientCertSecret                                              string
+	checksumURL, checksumSignatureURL, checksumKeyConfigMap       string
+	archiveSubPath, archiveStripComponents, archiveOwnership      string
+	insecureTLS, verify                                           bool
 }
 
 // NewImportController sets up an Import Controller, and returns a pointer to
@@ -64,6 +128,8 @@ func NewImportController(client kubernetes.Interface,
 	cdiClientSet clientset.Interface,
 	pvcInformer coreinformers.PersistentVolumeClaimInformer,
 	podInformer coreinformers.PodInformer,
+	configInformer informers.CDIConfigInformer,
+	scheduler *WorkerScheduler,
 	image string,
 	pullPolicy string,
 	verbose string) *ImportController {
@@ -76,9 +142,11 @@ func NewImportController(client kubernetes.Interface,
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: importControllerAgentName})
 
 	c := &ImportController{
-		cdiClient:  cdiClientSet,
-		Controller: *NewController(client, pvcInformer, podInformer, image, pullPolicy, verbose),
-		recorder:   recorder,
+		cdiClient:    cdiClientSet,
+		Controller:   *NewController(client, pvcInformer, podInformer, image, pullPolicy, verbose),
+		configLister: configInformer.Lister(),
+		recorder:     recorder,
+		scheduler:    scheduler,
 	}
 	return c
 }
@@ -129,6 +197,71 @@ func (ic *ImportController) requiresScratchSpace(pvc *v1.PersistentVolumeClaim)
 	return scratchRequired
 }
 
+// getRetryPolicy returns the retry policy the data volume set on the pvc, or nil if the importer pod is simply restarted
+// on failure.
+func getRetryPolicy(pvc *v1.PersistentVolumeClaim) *importRetryPolicy {
+	value, ok := pvc.Annotations[AnnRetryMaxAttempts]
+	if !ok {
+		return nil
+	}
+	policy := &importRetryPolicy{backoff: defaultRetryBackoff}
+	policy.maxAttempts, _ = strconv.Atoi(value)
+	if seconds, err := strconv.Atoi(pvc.Annotations[AnnRetryBackoffSeconds]); err == nil && seconds > 0 {
+		policy.backoff = time.Duration(seconds) * time.Second
+	}
+	policy.transientOnly, _ = strconv.ParseBool(pvc.Annotations[AnnRetryTransientOnly])
+	return policy
+}
+
+// delay returns the time to wait before the next attempt after the import was restarted restarts times.
+func (p *importRetryPolicy) delay(restarts int) time.Duration {
+	delay := p.backoff
+	for i := 0; i < restarts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// scheduleRetry decides if the import is retried after the importer pod failed with message. If so, the restart count
+// and the time of the next attempt are recorded in anno.
+func scheduleRetry(pvc *v1.PersistentVolumeClaim, pod *v1.Pod, policy *importRetryPolicy, message *util.TerminationMessage,
+	terminated *v1.ContainerStateTerminated, anno map[string]string) bool {
+	if pvc.Annotations[AnnRetriedPod] == pod.Name {
+		// the retry is already scheduled, the failed pod is being deleted
+		return true
+	}
+	// a pod killed before it could report the reason, e.g. when running out of memory, is retried
+	if policy.transientOnly && message.Failed() && !message.Transient {
+		klog.V(1).Infof("import into pvc %s/%s failed permanently: %s", pvc.Namespace, pvc.Name, message.Reason)
+		return false
+	}
+	restarts, _ := strconv.Atoi(pvc.Annotations[AnnRestartCount])
+	if policy.maxAttempts > 0 && restarts+1 >= policy.maxAttempts {
+		klog.V(1).Infof("import into pvc %s/%s failed after %d attempts", pvc.Namespace, pvc.Name, restarts+1)
+		return false
+	}
+	failedAt := terminated.FinishedAt.Time
+	if failedAt.IsZero() {
+		failedAt = time.Now()
+	}
+	anno[AnnRestartCount] = strconv.Itoa(restarts + 1)
+	anno[AnnRetriedPod] = pod.Name
+	anno[AnnRetryAfter] = failedAt.Add(policy.delay(restarts)).Format(time.RFC3339)
+	return true
+}
+
+// retryDelay returns the time left until the importer pod of a failed import is created again.
+func retryDelay(pvc *v1.PersistentVolumeClaim) time.Duration {
+	retryAfter, err := time.Parse(time.RFC3339, pvc.Annotations[AnnRetryAfter])
+	if err != nil {
+		return 0
+	}
+	return time.Until(retryAfter)
+}
+
 // Create the importer pod based the pvc. The endpoint and optional secret are available to
 // the importer pod as env vars. The pvc is checked (again) to ensure that we are not already
 // processing this pvc, which would result in multiple importer pods for the same pvc.
@@ -178,25 +311,73 @@ func (ic *ImportController) processPvcItem(pvc *v1.PersistentVolumeClaim) error
 	}
 
 	if pod == nil && needsSync {
-		return ic.createImporterPod(pvc, pvcKey)
+		if delay := retryDelay(pvc); delay > 0 {
+			klog.V(3).Infof("retrying import into pvc %s in %v", pvcKey, delay)
+			ic.queue.AddAfter(pvcKey, delay)
+			return nil
+		}
+		config, err := getCDIConfig(ic.configLister)
+		if err != nil {
+			return err
+		}
+		waiting, err := waitsForFirstConsumer(ic.clientset, config, pvc)
+		if err != nil {
+			return err
+		}
+		if waiting {
+			klog.V(3).Infof("pvc %s waits for its first consumer", pvcKey)
+			if !checkIfAnnoExists(pvc, AnnWaitForFirstConsumer, "true") {
+				anno[AnnWaitForFirstConsumer] = "true"
+				_, err = updatePVC(ic.clientset, pvc, anno, lab)
+			}
+			return err
+		}
+		position, err := ic.scheduler.admit(pvc, config)
+		if err != nil {
+			return err
+		}
+		if position > 0 {
+			klog.V(3).Infof("pvc %s is queued at position %d", pvcKey, position)
+			ic.queue.AddAfter(pvcKey, queueRecheckInterval)
+			if pvc.Annotations[AnnQueuePosition] != strconv.Itoa(position) {
+				anno[AnnQueuePosition] = strconv.Itoa(position)
+				_, err = updatePVC(ic.clientset, pvc, anno, lab)
+			}
+			return err
+		}
+		if pvc, err = removeQueuePosition(ic.clientset, pvc); err != nil {
+			return err
+		}
+		return ic.createImporterPod(pvc, pvcKey, config)
 	}
 
 	// update pvc with importer pod name and optional cdi label
 	if pod != nil {
 		scratchExitCode := false
-		if pod.Status.ContainerStatuses != nil && pod.Status.ContainerStatuses[0].LastTerminationState.Terminated != nil &&
-			pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.ExitCode > 0 {
-			klog.V(3).Infof("Pod %s termination code: %d\n", pod.Name, pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.ExitCode)
-			if pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.ExitCode == common.ScratchSpaceNeededExitCode {
+		retry := false
+		if terminated := podTermination(pod); terminated != nil {
+			klog.V(3).Infof("Pod %s termination code: %d\n", pod.Name, terminated.ExitCode)
+			if terminated.ExitCode == common.ScratchSpaceNeededExitCode {
 				klog.V(3).Infof("Pod %s requires scratch space, terminating pod, and restarting with scratch space\n", pod.Name)
 				scratchExitCode = true
 				anno[AnnRequiresScratch] = "true"
 			} else {
-				ic.recorder.Event(pvc, v1.EventTypeWarning, ErrImportFailedPVC, pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.Message)
+				message := util.ParseTerminationMessage(terminated.Message)
+				ic.recorder.Event(pvc, v1.EventTypeWarning, ErrImportFailedPVC, message.Message)
+				addFailureAnnotations(anno, message)
+				if policy := getRetryPolicy(pvc); policy != nil && pod.Status.Phase == v1.PodFailed {
+					retry = scheduleRetry(pvc, pod, policy, message, terminated, anno)
+				}
 			}
 		}
+		if !scratchExitCode && len(pod.Status.ContainerStatuses) > 0 && pod.Status.ContainerStatuses[0].RestartCount > 0 {
+			anno[AnnRestartCount] = strconv.Itoa(int(pod.Status.ContainerStatuses[0].RestartCount))
+		}
 		anno[AnnImportPod] = string(pod.Name)
-		if !scratchExitCode {
+		if retry {
+			// the failed pod is replaced once the backoff expired, until then the import is scheduled again
+			anno[AnnPodPhase] = string(v1.PodPending)
+		} else if !scratchExitCode {
 			anno[AnnPodPhase] = string(pod.Status.Phase)
 			//this is for a case where the import container is failing and the restartPolicy is OnFailure. In such case
 			//the pod phase is "Running" although the container state is Waiting. When the container recovers, its state
@@ -206,7 +387,13 @@ func (ic *ImportController) processPvcItem(pvc *v1.PersistentVolumeClaim) error
 			}
 		}
 
-		if pod.Status.Phase == v1.PodSucceeded || scratchExitCode {
+		if pod.Status.Phase == v1.PodSucceeded {
+			if message := podResult(pod); message != nil {
+				addResultAnnotations(anno, message)
+			}
+		}
+
+		if pod.Status.Phase == v1.PodSucceeded || scratchExitCode || retry {
 			dReq := podDeleteRequest{
 				namespace: pod.Namespace,
 				podName:   pod.Name,
@@ -235,7 +422,7 @@ func (ic *ImportController) processPvcItem(pvc *v1.PersistentVolumeClaim) error
 	return nil
 }
 
-func (ic *ImportController) createImporterPod(pvc *v1.PersistentVolumeClaim, pvcKey string) error {
+func (ic *ImportController) createImporterPod(pvc *v1.PersistentVolumeClaim, pvcKey string, config *cdiv1.CDIConfig) error {
 	var scratchPvcName *string
 	var err error
 
@@ -252,7 +439,7 @@ func (ic *ImportController) createImporterPod(pvc *v1.PersistentVolumeClaim, pvc
 
 	// all checks passed, let's create the importer pod!
 	ic.expectPodCreate(pvcKey)
-	pod, err := CreateImporterPod(ic.clientset, ic.image, ic.verbose, ic.pullPolicy, podEnvVar, pvc, scratchPvcName)
+	pod, err := CreateImporterPod(ic.clientset, config, ic.image, ic.verbose, ic.pullPolicy, podEnvVar, pvc, scratchPvcName)
 	if err != nil {
 		ic.observePodCreate(pvcKey)
 		return err
@@ -4,16 +4,26 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	cdifake "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	. "kubevirt.io/containerized-data-importer/pkg/common"
 )
 
 type ImportFixture struct {
 	ControllerFixture
+
+	// Objects from here preloaded into the CDI NewSimpleFake.
+	cdiobjects []runtime.Object
 }
 
 func newImportFixture(t *testing.T) *ImportFixture {
@@ -24,8 +34,14 @@ func newImportFixture(t *testing.T) *ImportFixture {
 }
 
 func (f *ImportFixture) newImportController() *ImportController {
+	c := f.newController("test/myimage", "Always", "5")
+	cdiClient := cdifake.NewSimpleClientset(f.cdiobjects...)
 	return &ImportController{
-		Controller: *f.newController("test/myimage", "Always", "5"),
+		Controller:   *c,
+		cdiClient:    cdiClient,
+		configLister: newConfigInformer(cdiClient, f.cdiobjects).Lister(),
+		recorder:     record.NewFakeRecorder(10),
+		scheduler:    createWorkerScheduler(f.pvcLister, f.podLister, nil),
 	}
 }
 
@@ -96,6 +112,99 @@ func TestCreatesImportPodForEndpoint(t *testing.T) {
 	f.run(getPvcKey(pvc, t))
 }
 
+// Verifies the importer pod is placed according to the CDI config and the PVC annotations
+func TestCreatesImportPodWithWorkloadPlacement(t *testing.T) {
+	f := newImportFixture(t)
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnEndpoint: "http://test", AnnWorkloadPriorityClassName: "import"}, nil)
+	config := createCDIConfig(ConfigName)
+	config.Spec.Workloads = &cdiv1.NodePlacement{
+		NodeSelector:      map[string]string{"disktype": "ssd"},
+		Tolerations:       []corev1.Toleration{{Key: "storage", Operator: corev1.TolerationOpExists}},
+		PriorityClassName: "cdi-workloads",
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.cdiobjects = append(f.cdiobjects, config)
+
+	expPod := createPod(pvc, DataVolName, nil)
+	expPod.Spec.Containers[0].Env = append(expPod.Spec.Containers[0].Env, corev1.EnvVar{Name: FilesystemOverheadVar, Value: "0.055"})
+	expPod.Spec.NodeSelector = config.Spec.Workloads.NodeSelector
+	expPod.Spec.Tolerations = config.Spec.Workloads.Tolerations
+	expPod.Spec.PriorityClassName = "import"
+	expPod.Spec.Containers[0].Resources = *createDefaultPodResourceRequirements()
+	f.expectCreatePodAction(expPod)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+// Verifies no pod is created for a PVC waiting for its first consumer
+func TestImportWaitsForFirstConsumer(t *testing.T) {
+	f := newImportFixture(t)
+	storageClass := createWaitForFirstConsumerStorageClass("wffc")
+	pvc := createPvcInStorageClass("testPvc1", "default", &storageClass.Name, map[string]string{AnnEndpoint: "http://test"}, nil)
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc, storageClass)
+	f.cdiobjects = append(f.cdiobjects, createHonorWaitForFirstConsumerConfig())
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Labels = map[string]string{CDILabelKey: CDILabelValue}
+	expPvc.Annotations[AnnWaitForFirstConsumer] = "true"
+	f.kubeactions = append(f.kubeactions, core.NewRootGetAction(schema.GroupVersionResource{Resource: "storageclasses", Version: "v1"}, storageClass.Name))
+	f.expectUpdatePvcAction(expPvc)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+// Verifies no pod is created for a PVC while the concurrency limits are reached
+func TestImportQueued(t *testing.T) {
+	f := newImportFixture(t)
+	running := createPvc("running", "default", map[string]string{AnnEndpoint: "http://test"}, nil)
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnEndpoint: "http://test"}, nil)
+	pod := createPod(running, DataVolName, nil)
+	pod.Name = "importer-running"
+	pod.Namespace = running.Namespace
+	limit := int32(1)
+	config := createCDIConfig(ConfigName)
+	config.Spec.ConcurrencyLimits = &cdiv1.ConcurrencyLimits{Global: &limit}
+
+	f.pvcLister = append(f.pvcLister, running, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, running, pvc, pod)
+	f.cdiobjects = append(f.cdiobjects, config)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Labels = map[string]string{CDILabelKey: CDILabelValue}
+	expPvc.Annotations[AnnQueuePosition] = "1"
+	f.expectUpdatePvcAction(expPvc)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+// Verifies a queued PVC leaves the queue before its pod is created
+func TestImportDequeued(t *testing.T) {
+	f := newImportFixture(t)
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnEndpoint: "http://test", AnnQueuePosition: "1"}, nil)
+	limit := int32(1)
+	config := createCDIConfig(ConfigName)
+	config.Spec.ConcurrencyLimits = &cdiv1.ConcurrencyLimits{Global: &limit}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.cdiobjects = append(f.cdiobjects, config)
+
+	expPvc := pvc.DeepCopy()
+	delete(expPvc.Annotations, AnnQueuePosition)
+	f.expectUpdatePvcAction(expPvc)
+	expPod := createPod(expPvc, DataVolName, nil)
+	expPod.Spec.Containers[0].Env = append(expPod.Spec.Containers[0].Env, corev1.EnvVar{Name: FilesystemOverheadVar, Value: "0.055"})
+	expPod.Spec.Containers[0].Resources = *createDefaultPodResourceRequirements()
+	f.expectCreatePodAction(expPod)
+
+	f.run(getPvcKey(pvc, t))
+}
+
 // Verifies basic pod creation when new PVC (VolumeMode:Block) with endpoint annotation is discovered
 func TestCreatesImportPodForEndpointBlockPV(t *testing.T) {
 	f := newImportFixture(t)
@@ -336,6 +445,307 @@ func TestControllerImporterPodSuccessWithScratch(t *testing.T) {
 	f.run(getPvcKey(pvc, t))
 }
 
+func TestControllerImporterPodSuccessWithISOVolumeLabel(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP, AnnContentType: "iso"}, map[string]string{CDILabelKey: CDILabelValue})
+
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "madeup-name"
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Namespace = pvc.Namespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"message":"Import Complete","format":"iso","isoVolumeLabel":"CENTOS 7 X86_64"}`,
+				},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(pod.Status.Phase), AnnSource: SourceHTTP, AnnContentType: "iso", AnnSourceFormat: "iso", AnnISOVolumeLabel: "CENTOS 7 X86_64"}
+
+	f.expectUpdatePvcAction(expPvc)
+	f.expectDeletePodAction(pod)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodSuccessWithDiskLayout(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP}, map[string]string{CDILabelKey: CDILabelValue})
+
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "madeup-name"
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Namespace = pvc.Namespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"message":"Import Complete","diskLayout":{"partitionTable":"gpt","efiSystemPartition":true,"filesystems":["vfat","xfs"]}}`,
+				},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(pod.Status.Phase), AnnSource: SourceHTTP,
+		AnnPartitionTable: "gpt", AnnEFISystemPartition: "true", AnnFilesystems: "vfat,xfs"}
+
+	f.expectUpdatePvcAction(expPvc)
+	f.expectDeletePodAction(pod)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodSuccessWithImageInfo(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP}, map[string]string{CDILabelKey: CDILabelValue})
+
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "madeup-name"
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Namespace = pvc.Namespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"message":"Import Complete","bytesTransferred":1024,"format":"qcow2","virtualSize":4096,"digest":"sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824","diskLayout":{"partitionTable":"none"},"startTime":"2019-10-02T10:00:00Z","completionTime":"2019-10-02T10:01:00Z"}`,
+				},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(pod.Status.Phase), AnnSource: SourceHTTP,
+		AnnContentChecksum: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		AnnSourceFormat:    "qcow2", AnnVirtualSize: "4096", AnnBytesTransferred: "1024",
+		AnnPartitionTable: "none", AnnEFISystemPartition: "false", AnnFilesystems: "",
+		AnnTransferStartTime: "2019-10-02T10:00:00Z", AnnTransferCompletionTime: "2019-10-02T10:01:00Z"}
+
+	f.expectUpdatePvcAction(expPvc)
+	f.expectDeletePodAction(pod)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodFailedValidation(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP}, map[string]string{CDILabelKey: CDILabelValue})
+
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "madeup-name"
+	pod.Status.Phase = corev1.PodRunning
+	pod.Namespace = pvc.Namespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  `{"message":"Format vmdk of image http://test is denied","reason":"ValidationFailed","validationRule":"DeniedFormats"}`,
+				},
+			},
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodFailed), AnnSource: SourceHTTP,
+		AnnImageValidationRule: "DeniedFormats", AnnImageValidationMessage: "Format vmdk of image http://test is denied",
+		AnnFailureReason: "ValidationFailed", AnnFailureMessage: "Format vmdk of image http://test is denied", AnnFailureTransient: "false"}
+
+	f.expectUpdatePvcAction(expPvc)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodFailedTransfer(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP}, map[string]string{CDILabelKey: CDILabelValue})
+
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "madeup-name"
+	pod.Status.Phase = corev1.PodRunning
+	pod.Namespace = pvc.Namespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  `{"message":"Unable to connect to http data source: expected status code 200, got 503. Status: 503 Service Unavailable","reason":"TransferFailed","transient":true}`,
+				},
+			},
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodFailed), AnnSource: SourceHTTP,
+		AnnFailureReason:    "TransferFailed",
+		AnnFailureMessage:   "Unable to connect to http data source: expected status code 200, got 503. Status: 503 Service Unavailable",
+		AnnFailureTransient: "true"}
+
+	f.expectUpdatePvcAction(expPvc)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+// createFailedImportPod creates an importer pod that failed with the passed in termination message, as it is reported
+// when the pod does not restart
+func createFailedImportPod(pvc *corev1.PersistentVolumeClaim, message string) *corev1.Pod {
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "madeup-name"
+	pod.Status.Phase = corev1.PodFailed
+	pod.Namespace = pvc.Namespace
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					ExitCode:   1,
+					Message:    message,
+					FinishedAt: metav1.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+	}
+	return pod
+}
+
+func TestControllerImporterPodRetry(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "3", AnnRetryBackoffSeconds: "30", AnnRestartCount: "1"}, map[string]string{CDILabelKey: CDILabelValue})
+	pod := createFailedImportPod(pvc, `{"message":"Unable to connect to http data source","reason":"TransferFailed","transient":true}`)
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	// the second retry waits twice the backoff
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodPending), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "3", AnnRetryBackoffSeconds: "30", AnnRestartCount: "2",
+		AnnRetriedPod: "madeup-name", AnnRetryAfter: "2019-06-01T12:01:00Z",
+		AnnFailureReason: "TransferFailed", AnnFailureMessage: "Unable to connect to http data source", AnnFailureTransient: "true"}
+
+	f.expectUpdatePvcAction(expPvc)
+	f.expectDeletePodAction(pod)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodRetriesExhausted(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "2", AnnRestartCount: "1"}, map[string]string{CDILabelKey: CDILabelValue})
+	pod := createFailedImportPod(pvc, `{"message":"Unable to connect to http data source","reason":"TransferFailed","transient":true}`)
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	// the failed pod is kept
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodFailed), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "2", AnnRestartCount: "1",
+		AnnFailureReason: "TransferFailed", AnnFailureMessage: "Unable to connect to http data source", AnnFailureTransient: "true"}
+
+	f.expectUpdatePvcAction(expPvc)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodNoRetryOnPermanentFailure(t *testing.T) {
+	f := newImportFixture(t)
+
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodRunning), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "0", AnnRetryTransientOnly: "true"}, map[string]string{CDILabelKey: CDILabelValue})
+	pod := createFailedImportPod(pvc, `{"message":"Unable to connect to http data source: expected status code 200, got 404. Status: 404 Not Found","reason":"NotFound"}`)
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	expPvc := pvc.DeepCopy()
+	expPvc.ObjectMeta.Annotations = map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodFailed), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "0", AnnRetryTransientOnly: "true",
+		AnnFailureReason: "NotFound", AnnFailureMessage: "Unable to connect to http data source: expected status code 200, got 404. Status: 404 Not Found", AnnFailureTransient: "false"}
+
+	f.expectUpdatePvcAction(expPvc)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestControllerImporterPodRetryBackoff(t *testing.T) {
+	f := newImportFixture(t)
+
+	retryAfter := time.Now().Add(time.Hour).Format(time.RFC3339)
+	pvc := createPvc("testPvc1", "default", map[string]string{AnnImportPod: "madeup-name", AnnEndpoint: "http://test", AnnPodPhase: string(corev1.PodPending), AnnSource: SourceHTTP,
+		AnnRetryMaxAttempts: "3", AnnRestartCount: "1", AnnRetriedPod: "madeup-name", AnnRetryAfter: retryAfter}, map[string]string{CDILabelKey: CDILabelValue})
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+
+	// no importer pod is created before the backoff expired
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := getRetryPolicy(createPvc("testPvc1", "default", map[string]string{AnnRetryMaxAttempts: "0"}, nil))
+	for restarts, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		if delay := policy.delay(restarts); delay != expected {
+			t.Errorf("expected delay %v after %d restarts, got %v", expected, restarts, delay)
+		}
+	}
+	if delay := policy.delay(100); delay != maxRetryBackoff {
+		t.Errorf("expected delay to be capped at %v, got %v", maxRetryBackoff, delay)
+	}
+	if policy := getRetryPolicy(createPvc("testPvc1", "default", nil, nil)); policy != nil {
+		t.Errorf("expected no retry policy without annotation, got %+v", policy)
+	}
+}
+
 func TestControllerCreateImporterPodWithScratch(t *testing.T) {
 	f := newImportFixture(t)
 
@@ -350,7 +760,7 @@ func TestControllerCreateImporterPodWithScratch(t *testing.T) {
 	controller := f.newImportController()
 
 	f.expectCreatePodAction(pod)
-	if err := controller.createImporterPod(pvc, "testkey"); err != nil {
+	if err := controller.createImporterPod(pvc, "testkey", nil); err != nil {
 		t.Errorf("Error creating importer pod for http %v", err)
 	}
 }
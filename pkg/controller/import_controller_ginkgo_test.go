@@ -15,6 +15,7 @@ import (
 	k8stesting "k8s.io/client-go/tools/cache/testing"
 
 	cdifake "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
+	cdiinformers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions"
 
 	. "kubevirt.io/containerized-data-importer/pkg/common"
 	. "kubevirt.io/containerized-data-importer/pkg/controller"
@@ -59,8 +60,12 @@ var _ = Describe("Import Controller", func() {
 
 		pvcInformer := pvcInformerFactory.Core().V1().PersistentVolumeClaims()
 		podInformer := podInformerFactory.Core().V1().Pods()
+		cdiInformerFactory := cdiinformers.NewSharedInformerFactory(fakeCdiClient, DefaultResyncPeriod)
+		configInformer := cdiInformerFactory.Cdi().V1alpha1().CDIConfigs()
+		scheduler, err := NewWorkerScheduler(pvcInformer, podInformer, cdiInformerFactory.Cdi().V1alpha1().DataVolumes())
+		Expect(err).NotTo(HaveOccurred())
 
-		controller = NewImportController(fakeClient, fakeCdiClient, pvcInformer, podInformer, IMPORTER_DEFAULT_IMAGE, DefaultPullPolicy, verboseDebug)
+		controller = NewImportController(fakeClient, fakeCdiClient, pvcInformer, podInformer, configInformer, scheduler, IMPORTER_DEFAULT_IMAGE, DefaultPullPolicy, verboseDebug)
 
 		go pvcInformerFactory.Start(stop)
 		go podInformerFactory.Start(stop)
@@ -0,0 +1,301 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/klog"
+
+	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/keys"
+	"kubevirt.io/containerized-data-importer/pkg/util"
+)
+
+const (
+	// ProgressServiceName is the name of the service worker pods reach the progress server at
+	ProgressServiceName = "cdi-progress"
+	// ProgressServerPort is the port the progress server listens on
+	ProgressServerPort = 8443
+
+	progressPath = "/v1alpha1/progress"
+
+	// maxProgressReportSize is the largest progress report body accepted
+	maxProgressReportSize = 4096
+
+	progressServerCASecret = "cdi-progress-server-ca-key"
+	progressServerCAName   = "server.progress.cdi.kubevirt.io"
+
+	progressClientCASecret = "cdi-progress-client-ca-key"
+	progressClientCAName   = "client.progress.cdi.kubevirt.io"
+
+	progressServerSecret = "cdi-progress-server-key"
+
+	// progressClientSecretPrefix prefixes the names of the secrets holding the progress client key and cert of a pod
+	progressClientSecretPrefix = "cdi-progress-client-"
+)
+
+// ProgressServer receives the progress reports importer, upload server and clone source pods push while they transfer
+// data. A pod authenticates with a client certificate signed by the progress client CA, the common name of the
+// certificate is the UID of the DataVolume the pod works for, so a pod can only report the progress of its own
+// DataVolume.
+type ProgressServer struct {
+	client      kubernetes.Interface
+	bindAddress string
+	tlsConfig   *tls.Config
+
+	mutex   sync.RWMutex
+	reports map[types.UID]*util.ProgressReport
+}
+
+// NewProgressServer returns a progress server listening on bindAddress once it runs.
+func NewProgressServer(client kubernetes.Interface, bindAddress string) *ProgressServer {
+	return &ProgressServer{
+		client:      client,
+		bindAddress: bindAddress,
+		reports:     make(map[types.UID]*util.ProgressReport),
+	}
+}
+
+// Init gets or creates the CAs and the server certificate of the progress server.
+func (s *ProgressServer) Init() error {
+	klog.V(2).Infoln("Getting/creating progress server certs")
+
+	if err := s.initCerts(); err != nil {
+		runtime.HandleError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *ProgressServer) initCerts() error {
+	namespace := util.GetNamespace()
+
+	serverCAKeyPair, err := keys.GetOrCreateCA(s.client, namespace, progressServerCASecret, progressServerCAName)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get/create progress server CA")
+	}
+
+	clientCAKeyPair, err := keys.GetOrCreateCA(s.client, namespace, progressClientCASecret, progressClientCAName)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get/create progress client CA")
+	}
+
+	serverKeyPair, err := keys.GetOrCreateServerKeyPairAndCert(s.client,
+		namespace,
+		progressServerSecret,
+		serverCAKeyPair,
+		clientCAKeyPair.Cert,
+		ProgressServiceName+"."+namespace,
+		ProgressServiceName,
+		nil,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error creating progress server key pair")
+	}
+
+	serverCert, err := tls.X509KeyPair(cert.EncodeCertPEM(serverKeyPair.KeyPair.Cert),
+		cert.EncodePrivateKeyPEM(serverKeyPair.KeyPair.Key))
+	if err != nil {
+		return errors.Wrap(err, "invalid progress server key pair")
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCAKeyPair.Cert)
+
+	s.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	return nil
+}
+
+// Run serves progress reports until stopCh is closed.
+func (s *ProgressServer) Run(stopCh <-chan struct{}) error {
+	if s.tlsConfig == nil {
+		return errors.New("progress server is not initialized")
+	}
+
+	server := &http.Server{
+		Addr:      s.bindAddress,
+		Handler:   s,
+		TLSConfig: s.tlsConfig,
+	}
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	klog.V(1).Infof("Serving progress reports on %s", s.bindAddress)
+	if err := server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+		return errors.Wrap(err, "error serving progress reports")
+	}
+	return nil
+}
+
+func (s *ProgressServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != progressPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	uid := types.UID(r.TLS.VerifiedChains[0][0].Subject.CommonName)
+	if uid == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	report := &util.ProgressReport{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxProgressReportSize)).Decode(report); err != nil {
+		klog.V(3).Infof("Invalid progress report for %s: %v", uid, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	klog.V(3).Infof("Received progress report for %s", uid)
+	s.mutex.Lock()
+	s.reports[uid] = report
+	s.mutex.Unlock()
+}
+
+// Report returns the latest progress report of the DataVolume with the passed in UID, nil if there is none.
+func (s *ProgressServer) Report(uid types.UID) *util.ProgressReport {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.reports[uid]
+}
+
+// Forget drops the progress reports of the DataVolume with the passed in UID.
+func (s *ProgressServer) Forget(uid types.UID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.reports, uid)
+}
+
+// addProgressReporting configures the worker pod for the pvc to push its progress to the progress server, with a
+// client certificate for the DataVolume owning the pvc. The client key and certificate are passed in the returned
+// secret, which the pod references but which is not created yet, createProgressSecret creates it once the pod exists.
+// Only the progress of DataVolumes is reported, and nothing is configured while the progress server is not
+// initialized, nil is returned then.
+func addProgressReporting(client kubernetes.Interface, pod *v1.Pod, pvc *v1.PersistentVolumeClaim) (*v1.Secret, error) {
+	owner := metav1.GetControllerOf(pvc)
+	if owner == nil || owner.Kind != "DataVolume" {
+		return nil, nil
+	}
+
+	namespace := util.GetNamespace()
+	serverCACertBytes, err := keys.GetKeyPairAndCertBytes(client, namespace, progressServerCASecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting progress server CA cert")
+	}
+
+	clientCAKeyPair, err := keys.GetKeyPairAndCert(client, namespace, progressClientCASecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting progress client CA cert")
+	}
+
+	if serverCACertBytes == nil || clientCAKeyPair == nil {
+		klog.V(3).Infof("Progress server is not initialized, pod for pvc \"%s/%s\" does not report progress", pvc.Namespace, pvc.Name)
+		return nil, nil
+	}
+
+	clientKeyBytes, clientCertBytes, err := createClientKeyAndCertFunc(&clientCAKeyPair.KeyPair, string(owner.UID), []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: progressClientSecretPrefix + string(uuid.NewUUID()),
+			Labels: map[string]string{
+				common.CDILabelKey: common.CDILabelValue,
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			keys.KeyStoreTLSKeyFile:  clientKeyBytes,
+			keys.KeyStoreTLSCertFile: clientCertBytes,
+			keys.KeyStoreTLSCAFile:   serverCACertBytes.Cert,
+		},
+	}
+	secretEnv := func(name, key string) v1.EnvVar {
+		return v1.EnvVar{
+			Name: name,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: secret.Name,
+					},
+					Key: key,
+				},
+			},
+		}
+	}
+
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env,
+		v1.EnvVar{
+			Name:  common.ProgressURLVar,
+			Value: fmt.Sprintf("https://%s.%s.svc%s", ProgressServiceName, namespace, progressPath),
+		},
+		secretEnv(common.ProgressClientKeyVar, keys.KeyStoreTLSKeyFile),
+		secretEnv(common.ProgressClientCertVar, keys.KeyStoreTLSCertFile),
+		secretEnv(common.ProgressServerCACertVar, keys.KeyStoreTLSCAFile),
+	)
+	return secret, nil
+}
+
+// createProgressSecret creates the progress client secret addProgressReporting returned for the pod in the namespace
+// of the pod, owned by the pod so it is garbage collected with it. The pod is deleted if the secret can't be created.
+func createProgressSecret(client kubernetes.Interface, namespace string, pod *v1.Pod, secret *v1.Secret) error {
+	if secret == nil {
+		return nil
+	}
+	secret.OwnerReferences = []metav1.OwnerReference{MakePodOwnerReference(pod)}
+	if _, err := client.CoreV1().Secrets(namespace).Create(secret); err != nil {
+		// try to clean up
+		client.CoreV1().Pods(namespace).Delete(pod.Name, &metav1.DeleteOptions{})
+
+		return errors.Wrap(err, "error creating progress client secret")
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/cert"
+
+	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/keys"
+)
+
+func newProgressTestPod() *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "worker"}},
+		},
+	}
+}
+
+func podEnv(pod *corev1.Pod) map[string]string {
+	env := map[string]string{}
+	for _, e := range pod.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	return env
+}
+
+// progressEnv returns the environment of the pod, with the values of secret key references resolved from the secret.
+func progressEnv(t *testing.T, pod *corev1.Pod, secret *corev1.Secret) map[string]string {
+	env := map[string]string{}
+	for _, e := range pod.Spec.Containers[0].Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+			continue
+		}
+		ref := e.ValueFrom.SecretKeyRef
+		if ref == nil || secret == nil || ref.Name != secret.Name {
+			t.Fatalf("Expected %s to reference the progress secret, got %+v", e.Name, e.ValueFrom)
+		}
+		env[e.Name] = string(secret.Data[ref.Key])
+	}
+	return env
+}
+
+func TestAddProgressReportingNotInitialized(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pvc, _ := newPersistentVolumeClaim(newImportDataVolume("test-dv"))
+	pod := newProgressTestPod()
+	secret, err := addProgressReporting(client, pod, pvc)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if secret != nil {
+		t.Errorf("Expected no progress secret, got %+v", secret)
+	}
+	if len(pod.Spec.Containers[0].Env) != 0 {
+		t.Errorf("Expected no progress env, got %+v", pod.Spec.Containers[0].Env)
+	}
+}
+
+func TestProgressServer(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	server := NewProgressServer(client, "")
+	if err := server.Init(); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+
+	dataVolume := newImportDataVolume("test-dv")
+	dataVolume.UID = "b856691e-1038-11e9-a5ab-525500d15501"
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+	pod := newProgressTestPod()
+	secret, err := addProgressReporting(client, pod, pvc)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if secret == nil {
+		t.Fatalf("Expected a progress secret")
+	}
+	env := progressEnv(t, pod, secret)
+	if !strings.HasPrefix(env[common.ProgressURLVar], "https://"+ProgressServiceName+".") {
+		t.Errorf("Unexpected progress URL %q", env[common.ProgressURLVar])
+	}
+	certs, err := cert.ParseCertsPEM([]byte(env[common.ProgressClientCertVar]))
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if certs[0].Subject.CommonName != string(dataVolume.UID) {
+		t.Errorf("Expected the client cert for %s, got %s", dataVolume.UID, certs[0].Subject.CommonName)
+	}
+	if _, err := tls.X509KeyPair([]byte(env[common.ProgressClientCertVar]), []byte(env[common.ProgressClientKeyVar])); err != nil {
+		t.Errorf("Invalid client key pair %+v", err)
+	}
+	if _, err := cert.ParseCertsPEM([]byte(env[common.ProgressServerCACertVar])); err != nil {
+		t.Errorf("Invalid server CA cert %+v", err)
+	}
+
+	post := func(chain []*x509.Certificate, body string) int {
+		req := httptest.NewRequest(http.MethodPost, progressPath, strings.NewReader(body))
+		if chain != nil {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{chain}}
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(nil, `{"bytesTransferred": 1024}`); code != http.StatusUnauthorized {
+		t.Errorf("Expected an unauthenticated report to be rejected, got %d", code)
+	}
+	if code := post(certs, `{"bytesTransferred": `); code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid report to be rejected, got %d", code)
+	}
+	if report := server.Report(dataVolume.UID); report != nil {
+		t.Errorf("Expected no report, got %+v", report)
+	}
+
+	if code := post(certs, `{"progress": 25, "bytesTransferred": 1024, "totalBytes": 4096}`); code != http.StatusOK {
+		t.Errorf("Expected the report to be accepted, got %d", code)
+	}
+	report := server.Report(dataVolume.UID)
+	if report == nil || report.BytesTransferred != 1024 || report.TotalBytes != 4096 || *report.Progress != 25 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	server.Forget(dataVolume.UID)
+	if report := server.Report(dataVolume.UID); report != nil {
+		t.Errorf("Expected the report to be forgotten, got %+v", report)
+	}
+}
+
+func TestAddProgressReportingNotOwnedByDataVolume(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	if err := NewProgressServer(client, "").Init(); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	pvc := createPvc("testPvc1", "default", nil, nil)
+	pvc.OwnerReferences = []metav1.OwnerReference{}
+	pod := newProgressTestPod()
+	secret, err := addProgressReporting(client, pod, pvc)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if secret != nil {
+		t.Errorf("Expected no progress secret, got %+v", secret)
+	}
+	if len(pod.Spec.Containers[0].Env) != 0 {
+		t.Errorf("Expected no progress env, got %+v", pod.Spec.Containers[0].Env)
+	}
+}
+
+func TestCreateProgressSecret(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	if err := NewProgressServer(client, "").Init(); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	dataVolume := newImportDataVolume("test-dv")
+	dataVolume.UID = "b856691e-1038-11e9-a5ab-525500d15501"
+	pvc, _ := newPersistentVolumeClaim(dataVolume)
+	pod := newProgressTestPod()
+	pod.Name = "worker-pod"
+	pod.UID = "c856691e-1038-11e9-a5ab-525500d15501"
+	secret, err := addProgressReporting(client, pod, pvc)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if err := createProgressSecret(client, "default", pod, secret); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	created, err := client.CoreV1().Secrets("default").Get(secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if owner := metav1.GetControllerOf(created); owner == nil || owner.Kind != "Pod" || owner.UID != pod.UID {
+		t.Errorf("Expected the secret to be owned by the pod, got %+v", created.OwnerReferences)
+	}
+	if len(created.Data[keys.KeyStoreTLSKeyFile]) == 0 {
+		t.Errorf("Expected the secret to hold the client key")
+	}
+	for _, e := range pod.Spec.Containers[0].Env {
+		if e.Value == string(created.Data[keys.KeyStoreTLSKeyFile]) {
+			t.Errorf("Expected the client key not to be in the pod spec")
+		}
+	}
+}
@@ -334,6 +334,7 @@ func (c *SmartCloneController) updateSmartCloneStatusPhase(phase cdiv1.DataVolum
 		event.reason = CloneSucceeded
 		event.message = fmt.Sprintf(MessageCloneSucceeded, dataVolumeCopy.Spec.Source.PVC.Namespace, dataVolumeCopy.Spec.Source.PVC.Name, newPVC.Namespace, newPVC.Name)
 	}
+	updateConditions(dataVolumeCopy, newPVC)
 
 	return c.emitEvent(dataVolume, dataVolumeCopy, &event)
 }
@@ -0,0 +1,349 @@
+package controller
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	extclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	storageinformers "k8s.io/client-go/informers/storage/v1"
+	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	cdiclientset "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
+	listers "kubevirt.io/containerized-data-importer/pkg/client/listers/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+	csiclientset "kubevirt.io/containerized-data-importer/pkg/snapshot-client/clientset/versioned"
+)
+
+// claimProperties are the recommended access modes and volume mode of the PVCs of a provisioner
+type claimProperties struct {
+	accessModes []corev1.PersistentVolumeAccessMode
+	volumeMode  corev1.PersistentVolumeMode
+}
+
+var (
+	rwo = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	rwx = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+
+	// provisionerClaimProperties are the recommendations for the provisioners known to CDI, the storage classes of other
+	// provisioners get a StorageProfile without recommended access modes and volume mode
+	provisionerClaimProperties = map[string]claimProperties{
+		// Ceph RBD
+		"kubernetes.io/rbd":                  {rwx, corev1.PersistentVolumeBlock},
+		"rbd.csi.ceph.com":                   {rwx, corev1.PersistentVolumeBlock},
+		"openshift-storage.rbd.csi.ceph.com": {rwx, corev1.PersistentVolumeBlock},
+		// CephFS
+		"cephfs.csi.ceph.com":                   {rwx, corev1.PersistentVolumeFilesystem},
+		"openshift-storage.cephfs.csi.ceph.com": {rwx, corev1.PersistentVolumeFilesystem},
+		// NFS
+		"nfs.csi.k8s.io": {rwx, corev1.PersistentVolumeFilesystem},
+		// AWS EBS
+		"kubernetes.io/aws-ebs": {rwo, corev1.PersistentVolumeBlock},
+		"ebs.csi.aws.com":       {rwo, corev1.PersistentVolumeBlock},
+		// GCE PD
+		"kubernetes.io/gce-pd":  {rwo, corev1.PersistentVolumeBlock},
+		"pd.csi.storage.gke.io": {rwo, corev1.PersistentVolumeBlock},
+		// OpenStack Cinder
+		"kubernetes.io/cinder":     {rwo, corev1.PersistentVolumeBlock},
+		"cinder.csi.openstack.org": {rwo, corev1.PersistentVolumeBlock},
+		// Local volumes
+		"kubernetes.io/no-provisioner":     {rwo, corev1.PersistentVolumeFilesystem},
+		"kubevirt.io/hostpath-provisioner": {rwo, corev1.PersistentVolumeFilesystem},
+	}
+)
+
+// StorageProfileController creates a StorageProfile for every StorageClass and keeps its status up to date
+type StorageProfileController struct {
+	client                kubernetes.Interface
+	cdiClientSet          cdiclientset.Interface
+	csiClientSet          csiclientset.Interface
+	extClientSet          extclientset.Interface
+	queue                 workqueue.RateLimitingInterface
+	storageClassLister    storagelisters.StorageClassLister
+	storageProfileLister  listers.StorageProfileLister
+	configLister          listers.CDIConfigLister
+	storageClassesSynced  cache.InformerSynced
+	storageProfilesSynced cache.InformerSynced
+	configsSynced         cache.InformerSynced
+}
+
+// NewStorageProfileController creates a new StorageProfileController
+func NewStorageProfileController(client kubernetes.Interface,
+	cdiClientSet cdiclientset.Interface,
+	csiClientSet csiclientset.Interface,
+	extClientSet extclientset.Interface,
+	storageClassInformer storageinformers.StorageClassInformer,
+	storageProfileInformer informers.StorageProfileInformer,
+	configInformer informers.CDIConfigInformer) *StorageProfileController {
+	c := &StorageProfileController{
+		client:                client,
+		cdiClientSet:          cdiClientSet,
+		csiClientSet:          csiClientSet,
+		extClientSet:          extClientSet,
+		queue:                 workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		storageClassLister:    storageClassInformer.Lister(),
+		storageProfileLister:  storageProfileInformer.Lister(),
+		configLister:          configInformer.Lister(),
+		storageClassesSynced:  storageClassInformer.Informer().HasSynced,
+		storageProfilesSynced: storageProfileInformer.Informer().HasSynced,
+		configsSynced:         configInformer.Informer().HasSynced,
+	}
+
+	// A StorageProfile has the name of its StorageClass, so both are queued by name. The periodic resync picks up
+	// VolumeSnapshotClasses created after the StorageProfile.
+	storageClassInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueObject,
+		UpdateFunc: func(old, new interface{}) {
+			c.enqueueObject(new)
+		},
+		DeleteFunc: c.enqueueObject,
+	})
+
+	storageProfileInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueObject,
+		UpdateFunc: func(old, new interface{}) {
+			c.enqueueObject(new)
+		},
+		DeleteFunc: c.enqueueObject,
+	})
+
+	// The filesystem overhead of every StorageProfile comes from the CDI config
+	configInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueAll,
+		UpdateFunc: func(old, new interface{}) {
+			newConfig := new.(*cdiv1.CDIConfig)
+			oldConfig := old.(*cdiv1.CDIConfig)
+			if newConfig.ResourceVersion == oldConfig.ResourceVersion {
+				return
+			}
+			c.enqueueAll(new)
+		},
+	})
+
+	return c
+}
+
+func (c *StorageProfileController) enqueueObject(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.AddRateLimited(key)
+}
+
+func (c *StorageProfileController) enqueueAll(obj interface{}) {
+	storageClasses, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, storageClass := range storageClasses {
+		c.enqueueObject(storageClass)
+	}
+}
+
+func (c *StorageProfileController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *StorageProfileController) processNextWorkItem() bool {
+	obj, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+
+	err := func(obj interface{}) error {
+		defer c.queue.Done(obj)
+
+		key, ok := obj.(string)
+		if !ok {
+			c.queue.Forget(obj)
+			runtime.HandleError(errors.Errorf("expected string in workqueue but got %#v", obj))
+			return nil
+		}
+
+		if err := c.syncHandler(key); err != nil {
+			c.queue.AddRateLimited(key)
+			return errors.Errorf("error syncing '%s': %s", key, err.Error())
+		}
+
+		c.queue.Forget(obj)
+		klog.V(3).Infof("Successfully synced storage profile '%s'", key)
+		return nil
+	}(obj)
+
+	if err != nil {
+		runtime.HandleError(err)
+	}
+
+	return true
+}
+
+func (c *StorageProfileController) syncHandler(key string) error {
+	storageClass, err := c.storageClassLister.Get(key)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// the StorageProfile is garbage collected along with its StorageClass
+			return nil
+		}
+		return err
+	}
+
+	profile, err := c.storageProfileLister.Get(key)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	newProfile := newStorageProfile(storageClass)
+	if profile != nil {
+		newProfile = profile.DeepCopy()
+	}
+
+	status, err := c.storageProfileStatus(storageClass, &newProfile.Spec)
+	if err != nil {
+		return err
+	}
+	newProfile.Status = *status
+
+	if profile == nil {
+		klog.V(3).Infof("Creating storage profile %s", newProfile.Name)
+		if _, err := c.cdiClientSet.CdiV1alpha1().StorageProfiles().Create(newProfile); err != nil {
+			return errors.Wrapf(err, "error creating storage profile %s", newProfile.Name)
+		}
+		return nil
+	}
+
+	if !reflect.DeepEqual(profile.Status, newProfile.Status) {
+		klog.V(3).Infof("Updating storage profile %s", newProfile.Name)
+		if _, err := c.cdiClientSet.CdiV1alpha1().StorageProfiles().Update(newProfile); err != nil {
+			return errors.Wrapf(err, "error updating storage profile %s", newProfile.Name)
+		}
+	}
+	return nil
+}
+
+// newStorageProfile returns an empty StorageProfile of the storage class, which is deleted along with the storage
+// class.
+func newStorageProfile(storageClass *storagev1.StorageClass) *cdiv1.StorageProfile {
+	return &cdiv1.StorageProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: storageClass.Name,
+			Labels: map[string]string{
+				common.CDILabelKey:       common.CDILabelValue,
+				common.CDIComponentLabel: "",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "storage.k8s.io/v1",
+					Kind:       "StorageClass",
+					Name:       storageClass.Name,
+					UID:        storageClass.UID,
+				},
+			},
+		},
+	}
+}
+
+// storageProfileStatus returns the PVC settings recommended for the storage class, the ones in the spec of its
+// StorageProfile take precedence over the detected ones.
+func (c *StorageProfileController) storageProfileStatus(storageClass *storagev1.StorageClass, spec *cdiv1.StorageProfileSpec) (*cdiv1.StorageProfileStatus, error) {
+	name, provisioner := storageClass.Name, storageClass.Provisioner
+	status := &cdiv1.StorageProfileStatus{
+		StorageClass: &name,
+		Provisioner:  &provisioner,
+	}
+
+	if properties, ok := provisionerClaimProperties[provisioner]; ok {
+		status.AccessModes = append([]corev1.PersistentVolumeAccessMode(nil), properties.accessModes...)
+		volumeMode := properties.volumeMode
+		status.VolumeMode = &volumeMode
+	}
+	if len(spec.AccessModes) > 0 {
+		status.AccessModes = append([]corev1.PersistentVolumeAccessMode(nil), spec.AccessModes...)
+	}
+	if spec.VolumeMode != nil {
+		volumeMode := *spec.VolumeMode
+		status.VolumeMode = &volumeMode
+	}
+
+	if spec.CloneStrategy != nil {
+		cloneStrategy := *spec.CloneStrategy
+		status.CloneStrategy = &cloneStrategy
+	} else {
+		cloneStrategy, err := c.detectCloneStrategy(provisioner)
+		if err != nil {
+			return nil, err
+		}
+		status.CloneStrategy = &cloneStrategy
+	}
+
+	config, err := c.configLister.Get(common.ConfigName)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		overhead := storageClassFilesystemOverhead(config, name)
+		status.FilesystemOverhead = &overhead
+	}
+
+	return status, nil
+}
+
+// detectCloneStrategy returns the snapshot clone strategy if there is a VolumeSnapshotClass of the provisioner, and
+// the host assisted one otherwise.
+func (c *StorageProfileController) detectCloneStrategy(provisioner string) (cdiv1.CDICloneStrategy, error) {
+	if !IsCsiCrdsDeployed(c.extClientSet) {
+		return cdiv1.CloneStrategyHostAssisted, nil
+	}
+	snapshotClasses, err := c.csiClientSet.SnapshotV1alpha1().VolumeSnapshotClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "error listing volume snapshot classes")
+	}
+	for _, snapshotClass := range snapshotClasses.Items {
+		if snapshotClass.Snapshotter == provisioner {
+			return cdiv1.CloneStrategySnapshot, nil
+		}
+	}
+	return cdiv1.CloneStrategyHostAssisted, nil
+}
+
+// Run sets up StorageProfileController state and executes main event loop
+func (c *StorageProfileController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer func() {
+		c.queue.ShutDown()
+	}()
+
+	klog.V(3).Infoln("Starting storage profile controller Run loop")
+	if threadiness < 1 {
+		return errors.Errorf("expected >0 threads, got %d", threadiness)
+	}
+
+	if ok := cache.WaitForCacheSync(stopCh, c.storageClassesSynced, c.storageProfilesSynced, c.configsSynced); !ok {
+		return errors.New("failed to wait for caches to sync")
+	}
+
+	klog.V(3).Infoln("StorageProfileController cache has synced")
+
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+
+	klog.Info("Started workers")
+	<-stopCh
+	klog.Info("Shutting down workers")
+	return nil
+}
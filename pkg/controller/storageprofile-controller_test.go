@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	extfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/diff"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+	csifake "kubevirt.io/containerized-data-importer/pkg/snapshot-client/clientset/versioned/fake"
+)
+
+type storageProfileFixture struct {
+	t *testing.T
+
+	client *fake.Clientset
+
+	// Objects to put in the store.
+	storageClasses  []*storagev1.StorageClass
+	storageProfiles []*cdiv1.StorageProfile
+	configs         []*cdiv1.CDIConfig
+
+	// Objects from here preloaded into NewSimpleFake.
+	extobjects []runtime.Object
+	csiobjects []runtime.Object
+
+	// Actions expected to happen on the client.
+	actions []core.Action
+}
+
+func newStorageProfileFixture(t *testing.T) *storageProfileFixture {
+	return &storageProfileFixture{t: t}
+}
+
+func (f *storageProfileFixture) newController() *StorageProfileController {
+	var objects []runtime.Object
+	for _, profile := range f.storageProfiles {
+		objects = append(objects, profile)
+	}
+	f.client = fake.NewSimpleClientset(objects...)
+	kubeclient := k8sfake.NewSimpleClientset()
+
+	i := informers.NewSharedInformerFactory(f.client, noResyncPeriodFunc())
+	k8sI := kubeinformers.NewSharedInformerFactory(kubeclient, noResyncPeriodFunc())
+
+	for _, storageClass := range f.storageClasses {
+		k8sI.Storage().V1().StorageClasses().Informer().GetIndexer().Add(storageClass)
+	}
+	for _, profile := range f.storageProfiles {
+		i.Cdi().V1alpha1().StorageProfiles().Informer().GetIndexer().Add(profile)
+	}
+	for _, config := range f.configs {
+		i.Cdi().V1alpha1().CDIConfigs().Informer().GetIndexer().Add(config)
+	}
+
+	return NewStorageProfileController(kubeclient,
+		f.client,
+		csifake.NewSimpleClientset(f.csiobjects...),
+		extfake.NewSimpleClientset(f.extobjects...),
+		k8sI.Storage().V1().StorageClasses(),
+		i.Cdi().V1alpha1().StorageProfiles(),
+		i.Cdi().V1alpha1().CDIConfigs())
+}
+
+func (f *storageProfileFixture) run(key string) {
+	c := f.newController()
+	if err := c.syncHandler(key); err != nil {
+		f.t.Errorf("error syncing storage profile: %v", err)
+	}
+
+	actions := f.client.Actions()
+	if len(actions) != len(f.actions) {
+		f.t.Fatalf("Expected %d actions, got %d: %+v", len(f.actions), len(actions), actions)
+	}
+	for i, action := range actions {
+		checkAction(f.actions[i], action, f.t)
+	}
+}
+
+func (f *storageProfileFixture) expectCreateStorageProfileAction(profile *cdiv1.StorageProfile) {
+	f.actions = append(f.actions, core.NewRootCreateAction(cdiv1.SchemeGroupVersion.WithResource("storageprofiles"), profile))
+}
+
+func (f *storageProfileFixture) expectUpdateStorageProfileAction(profile *cdiv1.StorageProfile) {
+	f.actions = append(f.actions, core.NewRootUpdateAction(cdiv1.SchemeGroupVersion.WithResource("storageprofiles"), profile))
+}
+
+func createStorageProfileStatus(storageClass *storagev1.StorageClass, accessModes []corev1.PersistentVolumeAccessMode, volumeMode *corev1.PersistentVolumeMode, cloneStrategy cdiv1.CDICloneStrategy, overhead cdiv1.Percent) cdiv1.StorageProfileStatus {
+	name, provisioner := storageClass.Name, storageClass.Provisioner
+	return cdiv1.StorageProfileStatus{
+		StorageClass:       &name,
+		Provisioner:        &provisioner,
+		AccessModes:        accessModes,
+		VolumeMode:         volumeMode,
+		CloneStrategy:      &cloneStrategy,
+		FilesystemOverhead: &overhead,
+	}
+}
+
+func TestCreatesStorageProfile(t *testing.T) {
+	f := newStorageProfileFixture(t)
+	storageClass := createStorageClassWithProvisioner("rbd", nil, "rbd.csi.ceph.com")
+	config := createCDIConfig(common.ConfigName)
+	config.Status.FilesystemOverhead = &cdiv1.FilesystemOverhead{Global: "0.1", StorageClass: map[string]cdiv1.Percent{"rbd": "0.2"}}
+	f.storageClasses = append(f.storageClasses, storageClass)
+	f.configs = append(f.configs, config)
+
+	block := corev1.PersistentVolumeBlock
+	profile := newStorageProfile(storageClass)
+	profile.Status = createStorageProfileStatus(storageClass, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, &block, cdiv1.CloneStrategyHostAssisted, "0.2")
+	f.expectCreateStorageProfileAction(profile)
+
+	f.run(storageClass.Name)
+}
+
+func TestCreatesStorageProfileUnknownProvisioner(t *testing.T) {
+	f := newStorageProfileFixture(t)
+	storageClass := createStorageClassWithProvisioner("unknown", nil, "example.com/unknown")
+	f.storageClasses = append(f.storageClasses, storageClass)
+	f.configs = append(f.configs, createCDIConfig(common.ConfigName))
+
+	profile := newStorageProfile(storageClass)
+	profile.Status = createStorageProfileStatus(storageClass, nil, nil, cdiv1.CloneStrategyHostAssisted, "0.055")
+	f.expectCreateStorageProfileAction(profile)
+
+	f.run(storageClass.Name)
+}
+
+func TestStorageProfileSnapshotCloneStrategy(t *testing.T) {
+	f := newStorageProfileFixture(t)
+	storageClass := createStorageClassWithProvisioner("csi", nil, "csi-plugin")
+	f.storageClasses = append(f.storageClasses, storageClass)
+	f.configs = append(f.configs, createCDIConfig(common.ConfigName))
+	f.extobjects = append(f.extobjects, createVolumeSnapshotContentCrd(), createVolumeSnapshotClassCrd(), createVolumeSnapshotCrd())
+	f.csiobjects = append(f.csiobjects, createSnapshotClass("snap-class", nil, "csi-plugin"))
+
+	profile := newStorageProfile(storageClass)
+	profile.Status = createStorageProfileStatus(storageClass, nil, nil, cdiv1.CloneStrategySnapshot, "0.055")
+	f.expectCreateStorageProfileAction(profile)
+
+	f.run(storageClass.Name)
+}
+
+func TestUpdatesStorageProfileFromSpec(t *testing.T) {
+	f := newStorageProfileFixture(t)
+	storageClass := createStorageClassWithProvisioner("rbd", nil, "rbd.csi.ceph.com")
+	filesystem := corev1.PersistentVolumeFilesystem
+	cloneStrategy := cdiv1.CloneStrategySnapshot
+	profile := newStorageProfile(storageClass)
+	profile.Spec = cdiv1.StorageProfileSpec{
+		AccessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		VolumeMode:    &filesystem,
+		CloneStrategy: &cloneStrategy,
+	}
+	f.storageClasses = append(f.storageClasses, storageClass)
+	f.storageProfiles = append(f.storageProfiles, profile)
+	f.configs = append(f.configs, createCDIConfig(common.ConfigName))
+
+	result := profile.DeepCopy()
+	result.Status = createStorageProfileStatus(storageClass, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, &filesystem, cdiv1.CloneStrategySnapshot, "0.055")
+	f.expectUpdateStorageProfileAction(result)
+
+	f.run(storageClass.Name)
+}
+
+func TestStorageProfileUpToDate(t *testing.T) {
+	f := newStorageProfileFixture(t)
+	storageClass := createStorageClassWithProvisioner("local", nil, "kubernetes.io/no-provisioner")
+	filesystem := corev1.PersistentVolumeFilesystem
+	profile := newStorageProfile(storageClass)
+	profile.Status = createStorageProfileStatus(storageClass, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, &filesystem, cdiv1.CloneStrategyHostAssisted, "0.055")
+	f.storageClasses = append(f.storageClasses, storageClass)
+	f.storageProfiles = append(f.storageProfiles, profile)
+	f.configs = append(f.configs, createCDIConfig(common.ConfigName))
+
+	f.run(storageClass.Name)
+}
+
+func TestStorageProfileOfDeletedStorageClass(t *testing.T) {
+	f := newStorageProfileFixture(t)
+	profile := newStorageProfile(createStorageClass("deleted", nil))
+	f.storageProfiles = append(f.storageProfiles, profile)
+
+	f.run(profile.Name)
+}
+
+func TestNewStorageProfile(t *testing.T) {
+	storageClass := createStorageClass("test", nil)
+	storageClass.UID = "1234"
+	profile := newStorageProfile(storageClass)
+	want := []metav1.OwnerReference{
+		{APIVersion: "storage.k8s.io/v1", Kind: "StorageClass", Name: "test", UID: "1234"},
+	}
+	if profile.Name != "test" || !reflect.DeepEqual(profile.OwnerReferences, want) {
+		t.Errorf("newStorageProfile() = %s", diff.ObjectGoPrintDiff(profile.ObjectMeta, metav1.ObjectMeta{Name: "test", OwnerReferences: want}))
+	}
+}
@@ -36,6 +36,8 @@ import (
 	"k8s.io/klog"
 
 	clientset "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
+	listers "kubevirt.io/containerized-data-importer/pkg/client/listers/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/keys"
 	"kubevirt.io/containerized-data-importer/pkg/util"
@@ -75,6 +77,7 @@ type UploadController struct {
 	pvcLister                                 corelisters.PersistentVolumeClaimLister
 	podLister                                 corelisters.PodLister
 	serviceLister                             corelisters.ServiceLister
+	configLister                              listers.CDIConfigLister
 	pvcsSynced                                cache.InformerSynced
 	podsSynced                                cache.InformerSynced
 	servicesSynced                            cache.InformerSynced
@@ -82,6 +85,7 @@ type UploadController struct {
 	pullPolicy                                string // Options: IfNotPresent, Always, or Never
 	verbose                                   string // verbose levels: 1, 2, ...
 	uploadProxyServiceName                    string
+	scheduler                                 *WorkerScheduler
 }
 
 // GetUploadResourceName returns the name given to upload resources
@@ -109,6 +113,8 @@ func NewUploadController(client kubernetes.Interface,
 	pvcInformer coreinformers.PersistentVolumeClaimInformer,
 	podInformer coreinformers.PodInformer,
 	serviceInformer coreinformers.ServiceInformer,
+	configInformer informers.CDIConfigInformer,
+	scheduler *WorkerScheduler,
 	uploadServiceImage string,
 	uploadProxyServiceName string,
 	pullPolicy string,
@@ -123,6 +129,7 @@ func NewUploadController(client kubernetes.Interface,
 		pvcLister:              pvcInformer.Lister(),
 		podLister:              podInformer.Lister(),
 		serviceLister:          serviceInformer.Lister(),
+		configLister:           configInformer.Lister(),
 		pvcsSynced:             pvcInformer.Informer().HasSynced,
 		podsSynced:             podInformer.Informer().HasSynced,
 		servicesSynced:         serviceInformer.Informer().HasSynced,
@@ -130,6 +137,7 @@ func NewUploadController(client kubernetes.Interface,
 		uploadProxyServiceName: uploadProxyServiceName,
 		pullPolicy:             pullPolicy,
 		verbose:                verbose,
+		scheduler:              scheduler,
 	}
 
 	c.pvcInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -408,11 +416,16 @@ func (c *UploadController) syncHandler(key string) error {
 
 	resourceName := GetUploadResourceName(pvc.Name)
 
-	pod, err := c.getOrCreateUploadPod(pvc, resourceName, scratchPVCName, uploadClientName)
+	pod, err := c.getOrCreateUploadPod(pvc, pvcCopy, resourceName, scratchPVCName, uploadClientName)
 	if err != nil {
 		return err
 	}
 
+	if pod == nil {
+		return c.updatePvc(pvc, pvcCopy)
+	}
+	delete(pvcCopy.Annotations, AnnQueuePosition)
+
 	if _, err = c.getOrCreateUploadService(pvc, resourceName); err != nil {
 		return err
 	}
@@ -420,14 +433,25 @@ func (c *UploadController) syncHandler(key string) error {
 	podPhase := pod.Status.Phase
 	pvcCopy.Annotations[AnnPodPhase] = string(podPhase)
 	pvcCopy.Annotations[AnnPodReady] = strconv.FormatBool(isPodReady(pod))
-
-	if !reflect.DeepEqual(pvc, pvcCopy) {
-		pvc, err = c.client.CoreV1().PersistentVolumeClaims(pvcCopy.Namespace).Update(pvcCopy)
-		if err != nil {
-			return errors.Wrapf(err, "error updating pvc %s, pod phase %s", key, podPhase)
+	if podPhase == v1.PodSucceeded {
+		if message := podResult(pod); message != nil {
+			addResultAnnotations(pvcCopy.Annotations, message)
 		}
+	} else if message := podFailure(pod); message != nil {
+		addFailureAnnotations(pvcCopy.Annotations, message)
 	}
 
+	return c.updatePvc(pvc, pvcCopy)
+}
+
+// updatePvc updates pvc to pvcCopy if it was modified
+func (c *UploadController) updatePvc(pvc, pvcCopy *v1.PersistentVolumeClaim) error {
+	if reflect.DeepEqual(pvc, pvcCopy) {
+		return nil
+	}
+	if _, err := c.client.CoreV1().PersistentVolumeClaims(pvcCopy.Namespace).Update(pvcCopy); err != nil {
+		return errors.Wrapf(err, "error updating pvc %s/%s, pod phase %s", pvc.Namespace, pvc.Name, pvcCopy.Annotations[AnnPodPhase])
+	}
 	return nil
 }
 
@@ -456,15 +480,42 @@ func (c *UploadController) cleanup(pvc *v1.PersistentVolumeClaim) error {
 	return nil
 }
 
-func (c *UploadController) getOrCreateUploadPod(pvc *v1.PersistentVolumeClaim, podName, scratchPVCName, clientName string) (*v1.Pod, error) {
+// getOrCreateUploadPod returns the upload pod of pvc, the pod is created if it does not exist. It returns nil while
+// the pod waits for the first pod consuming pvc or is queued, the reason is annotated on pvcCopy.
+func (c *UploadController) getOrCreateUploadPod(pvc, pvcCopy *v1.PersistentVolumeClaim, podName, scratchPVCName, clientName string) (*v1.Pod, error) {
 	pod, err := c.podLister.Pods(pvc.Namespace).Get(podName)
 	if err != nil {
 		if !k8serrors.IsNotFound(err) {
 			return nil, errors.Wrapf(err, "error getting upload pod %s/%s", pvc.Namespace, podName)
 		}
 
+		config, err := getCDIConfig(c.configLister)
+		if err != nil {
+			return nil, err
+		}
+		waiting, err := waitsForFirstConsumer(c.client, config, pvc)
+		if err != nil {
+			return nil, err
+		}
+		if waiting {
+			klog.V(3).Infof("pvc %s/%s waits for its first consumer", pvc.Namespace, pvc.Name)
+			pvcCopy.Annotations[AnnWaitForFirstConsumer] = "true"
+			return nil, nil
+		}
+		position, err := c.scheduler.admit(pvc, config)
+		if err != nil {
+			return nil, err
+		}
+		if position > 0 {
+			klog.V(3).Infof("pvc %s/%s is queued at position %d", pvc.Namespace, pvc.Name, position)
+			c.queue.AddAfter(pvc.Namespace+"/"+pvc.Name, queueRecheckInterval)
+			pvcCopy.Annotations[AnnQueuePosition] = strconv.Itoa(position)
+			return nil, nil
+		}
+
 		args := UploadPodArgs{
 			Client:         c.client,
+			Config:         config,
 			Image:          c.uploadServiceImage,
 			Verbose:        c.verbose,
 			PullPolicy:     c.pullPolicy,
@@ -30,6 +30,7 @@ import (
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	cdifake "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned/fake"
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/keys/keystest"
@@ -106,12 +107,18 @@ func (f *uploadFixture) newController() (*UploadController, kubeinformers.Shared
 	pvcInformer := i.Core().V1().PersistentVolumeClaims()
 	podInformer := i.Core().V1().Pods()
 	serviceInformer := i.Core().V1().Services()
+	scheduler, err := NewWorkerScheduler(pvcInformer, podInformer, newDataVolumeInformer(f.cdiclient))
+	if err != nil {
+		f.t.Fatalf("Error creating worker scheduler: %v", err)
+	}
 
 	c := NewUploadController(f.kubeclient,
 		f.cdiclient,
 		pvcInformer,
 		podInformer,
 		serviceInformer,
+		newConfigInformer(f.cdiclient, f.cdiobjects),
+		scheduler,
 		"test/myimage",
 		"cdi-uploadproxy",
 		"Always",
@@ -275,6 +282,8 @@ func TestCreatesUploadPodAndService(t *testing.T) {
 	pvc := createPvcInStorageClass("testPvc1", "default", &storageClassName, map[string]string{uploadRequestAnnotation: ""}, nil)
 	pod := createUploadPod(pvc)
 	pod.Namespace = ""
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, corev1.EnvVar{Name: common.FilesystemOverheadVar, Value: "0.055"})
+	pod.Spec.Containers[0].Resources = *createDefaultPodResourceRequirements()
 	scratchPvc := createScratchPvc(pvc, pod, storageClassName)
 	f.expectCreatePodAction(pod)
 
@@ -307,6 +316,8 @@ func TestCreatesCloneTargetPodAndService(t *testing.T) {
 	clientName := fmt.Sprintf("%s/%s-%s/%s", source.Namespace, source.Name, pvc.Namespace, pvc.Name)
 	pod := createUploadClonePod(pvc, clientName)
 	pod.Namespace = ""
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, corev1.EnvVar{Name: common.FilesystemOverheadVar, Value: "0.055"})
+	pod.Spec.Containers[0].Resources = *createDefaultPodResourceRequirements()
 	f.expectCreatePodAction(pod)
 
 	f.podLister = append(f.podLister, pod)
@@ -330,6 +341,44 @@ func TestCreatesCloneTargetPodAndService(t *testing.T) {
 	f.run(getPvcKey(pvc, t))
 }
 
+func TestUploadWaitsForFirstConsumer(t *testing.T) {
+	f := newUploadFixture(t)
+	storageClass := createWaitForFirstConsumerStorageClass("wffc")
+	pvc := createPvcInStorageClass("testPvc1", "default", &storageClass.Name, map[string]string{uploadRequestAnnotation: ""}, nil)
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.kubeobjects = append(f.kubeobjects, pvc, storageClass)
+	f.cdiobjects = append(f.cdiobjects, createHonorWaitForFirstConsumerConfig())
+
+	f.kubeactions = append(f.kubeactions, core.NewRootGetAction(schema.GroupVersionResource{Resource: "storageclasses", Version: "v1"}, storageClass.Name))
+	pvcUpdate := pvc.DeepCopy()
+	pvcUpdate.Annotations[AnnWaitForFirstConsumer] = "true"
+	f.expectUpdatePvcAction(pvcUpdate)
+
+	f.run(getPvcKey(pvc, t))
+}
+
+func TestUploadQueued(t *testing.T) {
+	f := newUploadFixture(t)
+	running := createPvc("running", "default", map[string]string{uploadRequestAnnotation: ""}, nil)
+	pod := createUploadPod(running)
+	pvc := createPvc("testPvc1", "default", map[string]string{uploadRequestAnnotation: ""}, nil)
+	limit := int32(1)
+	config := createCDIConfig(common.ConfigName)
+	config.Spec.ConcurrencyLimits = &cdiv1.ConcurrencyLimits{Namespace: &limit}
+
+	f.podLister = append(f.podLister, pod)
+	f.pvcLister = append(f.pvcLister, running, pvc)
+	f.kubeobjects = append(f.kubeobjects, running, pvc, pod)
+	f.cdiobjects = append(f.cdiobjects, config)
+
+	pvcUpdate := pvc.DeepCopy()
+	pvcUpdate.Annotations[AnnQueuePosition] = "1"
+	f.expectUpdatePvcAction(pvcUpdate)
+
+	f.run(getPvcKey(pvc, t))
+}
+
 func TestCloneFailNoSource(t *testing.T) {
 	f := newUploadFixture(t)
 	storageClassName := "test"
@@ -406,6 +455,49 @@ func TestUpdatePodPhase(t *testing.T) {
 	f.run(getPvcKey(pvc, t))
 }
 
+func TestUploadCompleteWithImageInfo(t *testing.T) {
+	f := newUploadFixture(t)
+	storageClassName := "test"
+	pvc := createPvcInStorageClass("testPvc1", "default", &storageClassName, map[string]string{uploadRequestAnnotation: "", podPhaseAnnotation: "Running"}, nil)
+	pod := createUploadPod(pvc)
+	scratchPvc := createScratchPvc(pvc, pod, storageClassName)
+	service := createUploadService(pvc)
+
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			Ready: false,
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"message":"Upload Complete","bytesTransferred":11,"format":"raw","virtualSize":11,"digest":"sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}`,
+				},
+			},
+		},
+	}
+
+	f.pvcLister = append(f.pvcLister, pvc)
+	f.pvcLister = append(f.pvcLister, scratchPvc)
+	f.kubeobjects = append(f.kubeobjects, pvc)
+	f.kubeobjects = append(f.kubeobjects, scratchPvc)
+
+	f.podLister = append(f.podLister, pod)
+	f.kubeobjects = append(f.kubeobjects, pod)
+
+	f.serviceLister = append(f.serviceLister, service)
+	f.kubeobjects = append(f.kubeobjects, service)
+
+	updatedPVC := pvc.DeepCopy()
+	updatedPVC.Annotations[podPhaseAnnotation] = string(corev1.PodSucceeded)
+	updatedPVC.Annotations[podReadyAnnotation] = "false"
+	updatedPVC.Annotations[AnnContentChecksum] = "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	updatedPVC.Annotations[AnnSourceFormat] = "raw"
+	updatedPVC.Annotations[AnnVirtualSize] = "11"
+	updatedPVC.Annotations[AnnBytesTransferred] = "11"
+
+	f.expectUpdatePvcAction(updatedPVC)
+	f.run(getPvcKey(pvc, t))
+}
+
 func TestUploadComplete(t *testing.T) {
 	f := newUploadFixture(t)
 	storageClassName := "test"
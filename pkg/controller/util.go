@@ -2,9 +2,11 @@ package controller
 
 import (
 	"crypto/rsa"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +16,10 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
 	extclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -28,6 +32,7 @@ import (
 
 	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	clientset "kubevirt.io/containerized-data-importer/pkg/client/clientset/versioned"
+	listers "kubevirt.io/containerized-data-importer/pkg/client/listers/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/keys"
 	"kubevirt.io/containerized-data-importer/pkg/operator"
@@ -43,6 +48,12 @@ const (
 	// CertVolName is the name of the volumecontaining certs
 	CertVolName = "cdi-cert-vol"
 
+	// ClientCertVolName is the name of the volume containing the tls client certificate
+	ClientCertVolName = "cdi-client-cert-vol"
+
+	// ChecksumKeyVolName is the name of the volume containing the checksum file GPG keys
+	ChecksumKeyVolName = "cdi-checksum-key-vol"
+
 	// ScratchVolName provides a const to use for creating scratch pvc volumes in pod specs
 	ScratchVolName = "cdi-scratch-vol"
 
@@ -62,6 +73,14 @@ const (
 	SourceRegistry = "registry"
 )
 
+// workloadPlacementAnnotations are the PVC annotations overriding the workload placement of the CDI config
+var workloadPlacementAnnotations = []string{
+	AnnWorkloadNodeSelector,
+	AnnWorkloadTolerations,
+	AnnWorkloadAffinity,
+	AnnWorkloadPriorityClassName,
+}
+
 type podDeleteRequest struct {
 	namespace string
 	podName   string
@@ -147,7 +166,8 @@ func getContentType(pvc *v1.PersistentVolumeClaim) string {
 	switch contentType {
 	case
 		string(cdiv1.DataVolumeKubeVirt),
-		string(cdiv1.DataVolumeArchive):
+		string(cdiv1.DataVolumeArchive),
+		string(cdiv1.DataVolumeISO):
 		klog.V(2).Infof("pvc content type annotation found for pvc \"%s/%s\", value %s\n", pvc.Namespace, pvc.Name, contentType)
 	default:
 		klog.V(2).Infof("No content type annotation found for pvc \"%s/%s\", default to kubevirt\n", pvc.Namespace, pvc.Name)
@@ -291,6 +311,15 @@ func newScratchPersistentVolumeClaimSpec(pvc *v1.PersistentVolumeClaim, pod *v1.
 	if storageClassName != "" {
 		pvcDef.Spec.StorageClassName = &storageClassName
 	}
+	// the scratch space is provisioned for the worker pod, keep the placement of the pod along with it
+	for _, annotation := range workloadPlacementAnnotations {
+		if value, ok := pvc.Annotations[annotation]; ok {
+			if pvcDef.Annotations == nil {
+				pvcDef.Annotations = map[string]string{}
+			}
+			pvcDef.Annotations[annotation] = value
+		}
+	}
 	return pvcDef
 }
 
@@ -332,21 +361,449 @@ func GetScratchPvcStorageClass(client kubernetes.Interface, cdiclient clientset.
 	return ""
 }
 
+// getCDIConfig returns the CDI config from the cache of the lister, nil if it does not exist. The worker pod settings
+// derived from the config are computed from the one returned here, the config must not be modified.
+func getCDIConfig(configLister listers.CDIConfigLister) (*cdiv1.CDIConfig, error) {
+	config, err := configLister.Get(common.ConfigName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "error getting CDI config")
+	}
+	return config, nil
+}
+
+// getProxyEnv returns the proxy environment of the worker pods, based on the proxy configuration in the CDI config
+// status. The loopback addresses, used by the importer to serve endpoints to qemu-img, and the cluster service domain,
+// used to reach the upload server, are always excluded from proxying.
+func getProxyEnv(client kubernetes.Interface, config *cdiv1.CDIConfig) ([]v1.EnvVar, error) {
+	if config == nil || config.Status.ImportProxy == nil {
+		return nil, nil
+	}
+	proxy := config.Status.ImportProxy
+
+	var env []v1.EnvVar
+	// curl only honors the lower case http_proxy, so set both forms
+	addEnv := func(name, value string) {
+		env = append(env, v1.EnvVar{Name: name, Value: value}, v1.EnvVar{Name: strings.ToLower(name), Value: value})
+	}
+	if proxy.HTTPProxy != nil && *proxy.HTTPProxy != "" {
+		addEnv("HTTP_PROXY", *proxy.HTTPProxy)
+	}
+	if proxy.HTTPSProxy != nil && *proxy.HTTPSProxy != "" {
+		addEnv("HTTPS_PROXY", *proxy.HTTPSProxy)
+	}
+	if env == nil {
+		return nil, nil
+	}
+	noProxy := "localhost,127.0.0.1,.svc"
+	if proxy.NoProxy != nil && *proxy.NoProxy != "" {
+		noProxy = *proxy.NoProxy + "," + noProxy
+	}
+	addEnv("NO_PROXY", noProxy)
+
+	if proxy.TrustedCAProxy != nil {
+		configMap, err := client.CoreV1().ConfigMaps(util.GetNamespace()).Get(*proxy.TrustedCAProxy, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting proxy CA ConfigMap %s", *proxy.TrustedCAProxy)
+		}
+		var keys []string
+		for key := range configMap.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var certs []string
+		for _, key := range keys {
+			certs = append(certs, configMap.Data[key])
+		}
+		env = append(env, v1.EnvVar{
+			Name:  common.ProxyCACertVar,
+			Value: strings.Join(certs, "\n"),
+		})
+	}
+	return env, nil
+}
+
+// getValidationPolicyEnv returns the environment passing the image validation policy in the CDI config status to the
+// importer and upload server pods.
+func getValidationPolicyEnv(config *cdiv1.CDIConfig) ([]v1.EnvVar, error) {
+	if config == nil || config.Status.ImageValidation == nil {
+		return nil, nil
+	}
+	policy, err := json.Marshal(config.Status.ImageValidation)
+	if err != nil {
+		return nil, errors.Wrap(err, "error encoding image validation policy")
+	}
+	return []v1.EnvVar{{Name: common.ImageValidationPolicyVar, Value: string(policy)}}, nil
+}
+
+// getTransferRateLimit returns the bytes per second the worker pod populating pvc may transfer, 0 if the rate is not
+// limited. The annotation of the pvc takes precedence over the limit of its namespace in the CDI config status, which
+// takes precedence over the default.
+func getTransferRateLimit(config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) (int64, error) {
+	if value, ok := pvc.Annotations[AnnTransferRateLimit]; ok {
+		limit, err := resource.ParseQuantity(value)
+		if err == nil {
+			return limit.Value(), nil
+		}
+		klog.Warningf("Ignoring invalid transfer rate limit %q of pvc %s/%s: %v", value, pvc.Namespace, pvc.Name, err)
+	}
+	if config == nil || config.Status.TransferRateLimit == nil {
+		return 0, nil
+	}
+	limits := config.Status.TransferRateLimit
+	if limit, ok := limits.Namespaces[pvc.Namespace]; ok {
+		return limit.Value(), nil
+	}
+	if limits.Default != nil {
+		return limits.Default.Value(), nil
+	}
+	return 0, nil
+}
+
+// addTransferRateLimit passes the transfer rate limit of the worker pod populating pvc in its environment, and records
+// it in an annotation of the pod.
+func addTransferRateLimit(pod *v1.Pod, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) error {
+	limit, err := getTransferRateLimit(config, pvc)
+	if err != nil || limit <= 0 {
+		return err
+	}
+	value := strconv.FormatInt(limit, 10)
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, v1.EnvVar{Name: common.TransferRateLimitVar, Value: value})
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[AnnTransferRateLimit] = value
+	return nil
+}
+
+// GetFilesystemOverhead returns the fraction of the volume of pvc a disk image may not use, the filesystem overhead in
+// effect for the storage class of a Filesystem mode pvc. A Block mode pvc has no overhead, neither has any pvc without
+// a CDI config.
+func GetFilesystemOverhead(client kubernetes.Interface, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) (float64, error) {
+	if config == nil || getVolumeMode(pvc) == v1.PersistentVolumeBlock {
+		return 0, nil
+	}
+	storageClass := ""
+	if overhead := config.Status.FilesystemOverhead; overhead != nil && len(overhead.StorageClass) > 0 {
+		var err error
+		storageClass, err = GetStorageClassName(client, pvc.Spec.StorageClassName)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return util.ParseFilesystemOverhead(storageClassFilesystemOverhead(config, storageClass))
+}
+
+// storageClassFilesystemOverhead returns the filesystem overhead the status of the CDI config sets for the storage
+// class.
+func storageClassFilesystemOverhead(config *cdiv1.CDIConfig, storageClass string) cdiv1.Percent {
+	overhead := config.Status.FilesystemOverhead
+	if overhead == nil {
+		return util.DefaultFilesystemOverhead
+	}
+	if value, ok := overhead.StorageClass[storageClass]; ok {
+		return value
+	}
+	if overhead.Global != "" {
+		return overhead.Global
+	}
+	return util.DefaultFilesystemOverhead
+}
+
+// GetStorageClassName returns the storage class name of a PVC spec, the default storage class if the spec does not
+// name one, or an empty string if there is no default storage class.
+func GetStorageClassName(client kubernetes.Interface, storageClassName *string) (string, error) {
+	if storageClassName != nil {
+		return *storageClassName, nil
+	}
+	storageClasses, err := client.StorageV1().StorageClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "error listing storage classes")
+	}
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Annotations[AnnDefaultStorageClass] == "true" {
+			return storageClass.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// addFilesystemOverhead passes the filesystem overhead of pvc to the worker pod populating it, so the disk image is
+// sized to leave room for the file system metadata.
+func addFilesystemOverhead(pod *v1.Pod, client kubernetes.Interface, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) error {
+	overhead, err := GetFilesystemOverhead(client, config, pvc)
+	if err != nil || overhead <= 0 {
+		return err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, v1.EnvVar{
+		Name:  common.FilesystemOverheadVar,
+		Value: strconv.FormatFloat(overhead, 'f', -1, 64),
+	})
+	return nil
+}
+
+// getWorkloadPlacement returns the node placement of the worker pods populating pvc, the workloads placement of the
+// CDI config with the fields the annotations of pvc override replaced. Invalid annotations are ignored.
+func getWorkloadPlacement(config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) *cdiv1.NodePlacement {
+	placement := &cdiv1.NodePlacement{}
+	if config != nil && config.Spec.Workloads != nil {
+		placement = config.Spec.Workloads.DeepCopy()
+	}
+
+	var nodeSelector map[string]string
+	if unmarshalPlacementAnnotation(pvc, AnnWorkloadNodeSelector, &nodeSelector) {
+		placement.NodeSelector = nodeSelector
+	}
+	var tolerations []v1.Toleration
+	if unmarshalPlacementAnnotation(pvc, AnnWorkloadTolerations, &tolerations) {
+		placement.Tolerations = tolerations
+	}
+	var affinity *v1.Affinity
+	if unmarshalPlacementAnnotation(pvc, AnnWorkloadAffinity, &affinity) {
+		placement.Affinity = affinity
+	}
+	if priorityClassName, ok := pvc.Annotations[AnnWorkloadPriorityClassName]; ok {
+		placement.PriorityClassName = priorityClassName
+	}
+	return placement
+}
+
+// unmarshalPlacementAnnotation decodes the JSON value of the annotation of pvc, it returns false if pvc does not have
+// the annotation or its value is invalid.
+func unmarshalPlacementAnnotation(pvc *v1.PersistentVolumeClaim, annotation string, value interface{}) bool {
+	data, ok := pvc.Annotations[annotation]
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal([]byte(data), value); err != nil {
+		klog.Warningf("Ignoring invalid %s annotation %q of pvc %s/%s: %v", annotation, data, pvc.Namespace, pvc.Name, err)
+		return false
+	}
+	return true
+}
+
+// addWorkloadPlacement schedules the worker pod populating pvc according to the workload placement in effect for pvc.
+func addWorkloadPlacement(pod *v1.Pod, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) {
+	placement := getWorkloadPlacement(config, pvc)
+	pod.Spec.NodeSelector = placement.NodeSelector
+	pod.Spec.Tolerations = placement.Tolerations
+	pod.Spec.Affinity = placement.Affinity
+	pod.Spec.PriorityClassName = placement.PriorityClassName
+}
+
+// addPodResourceRequirements sets the resource requirements of the worker pods in the CDI config status on the
+// containers of pod. The defaults are used if the config controller has not filled in the status yet.
+func addPodResourceRequirements(pod *v1.Pod, config *cdiv1.CDIConfig) {
+	if config == nil {
+		return
+	}
+	requirements := config.Status.PodResourceRequirements
+	if requirements == nil {
+		requirements = podResourceRequirementsStatus(config)
+	}
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].Resources = *requirements.DeepCopy()
+	}
+}
+
+// isFeatureGateEnabled returns true if the CDI config enables the feature gate.
+func isFeatureGateEnabled(config *cdiv1.CDIConfig, featureGate string) bool {
+	for _, gate := range config.Spec.FeatureGates {
+		if gate == featureGate {
+			return true
+		}
+	}
+	return false
+}
+
+// isWaitForFirstConsumerHonored returns true if the HonorWaitForFirstConsumer feature gate is enabled and the storage
+// class of pvc binds volumes only once a pod consuming them is scheduled.
+func isWaitForFirstConsumerHonored(client kubernetes.Interface, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) (bool, error) {
+	if config == nil || !isFeatureGateEnabled(config, common.HonorWaitForFirstConsumer) {
+		return false, nil
+	}
+	storageClassName, err := GetStorageClassName(client, pvc.Spec.StorageClassName)
+	if err != nil || storageClassName == "" {
+		return false, err
+	}
+	storageClass, err := client.StorageV1().StorageClasses().Get(storageClassName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error getting storage class %s", storageClassName)
+	}
+	return storageClass.VolumeBindingMode != nil && *storageClass.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer, nil
+}
+
+// waitsForFirstConsumer returns true if the worker pod populating pvc may not be created yet, because
+// WaitForFirstConsumer is honored for pvc and the scheduler did not select a node for a pod consuming it. CDI creates
+// no pod using pvc before, so that pod is the one the PVC was created for, a virtual machine for instance.
+func waitsForFirstConsumer(client kubernetes.Interface, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) (bool, error) {
+	if pvc.Status.Phase == v1.ClaimBound || pvc.Annotations[AnnSelectedNode] != "" {
+		return false, nil
+	}
+	return isWaitForFirstConsumerHonored(client, config, pvc)
+}
+
+// addFirstConsumerNodeAffinity schedules the worker pod populating pvc onto the node the scheduler selected for the
+// first pod consuming pvc, if WaitForFirstConsumer is honored for pvc.
+func addFirstConsumerNodeAffinity(pod *v1.Pod, client kubernetes.Interface, config *cdiv1.CDIConfig, pvc *v1.PersistentVolumeClaim) error {
+	node := pvc.Annotations[AnnSelectedNode]
+	if node == "" {
+		return nil
+	}
+	honored, err := isWaitForFirstConsumerHonored(client, config, pvc)
+	if err != nil || !honored {
+		return err
+	}
+	requirement := v1.NodeSelectorRequirement{
+		Key:      "metadata.name",
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{node},
+	}
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &v1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	required := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{MatchFields: []v1.NodeSelectorRequirement{requirement}},
+			},
+		}
+		return nil
+	}
+	// the node must match any of the terms of the workload placement, and be the selected one
+	for i := range required.NodeSelectorTerms {
+		required.NodeSelectorTerms[i].MatchFields = append(required.NodeSelectorTerms[i].MatchFields, requirement)
+	}
+	return nil
+}
+
 // CreateImporterPod creates and returns a pointer to a pod which is created based on the passed-in endpoint, secret
 // name, and pvc. A nil secret means the endpoint credentials are not passed to the
-// importer pod.
-func CreateImporterPod(client kubernetes.Interface, image, verbose, pullPolicy string, podEnvVar *importPodEnvVar, pvc *v1.PersistentVolumeClaim, scratchPvcName *string) (*v1.Pod, error) {
+// importer pod. The worker pod settings of the CDI config are applied, config is nil if there is none.
+func CreateImporterPod(client kubernetes.Interface, config *cdiv1.CDIConfig, image, verbose, pullPolicy string, podEnvVar *importPodEnvVar, pvc *v1.PersistentVolumeClaim, scratchPvcName *string) (*v1.Pod, error) {
 	ns := pvc.Namespace
 	pod := MakeImporterPodSpec(image, verbose, pullPolicy, podEnvVar, pvc, scratchPvcName)
 
-	pod, err := client.CoreV1().Pods(ns).Create(pod)
+	proxyEnv, err := getProxyEnv(client, config)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, proxyEnv...)
+	policyEnv, err := getValidationPolicyEnv(config)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, policyEnv...)
+	if err = addTransferRateLimit(pod, config, pvc); err != nil {
+		return nil, err
+	}
+	if err = addFilesystemOverhead(pod, client, config, pvc); err != nil {
+		return nil, err
+	}
+	progressSecret, err := addProgressReporting(client, pod, pvc)
+	if err != nil {
+		return nil, err
+	}
+	addWorkloadPlacement(pod, config, pvc)
+	addPodResourceRequirements(pod, config)
+	if err = addFirstConsumerNodeAffinity(pod, client, config, pvc); err != nil {
+		return nil, err
+	}
+
+	pod, err = client.CoreV1().Pods(ns).Create(pod)
 	if err != nil {
 		return nil, errors.Wrap(err, "importer pod API create errored")
 	}
+	if err = createProgressSecret(client, ns, pod, progressSecret); err != nil {
+		return nil, err
+	}
 	klog.V(3).Infof("importer pod \"%s/%s\" (image: %q) created\n", pod.Namespace, pod.Name, image)
 	return pod, nil
 }
 
+// CreateSizeProbePod creates the pod determining the virtual size of the source of the data volume, before the PVC of
+// the data volume is created. The pod is owned by the data volume, the source is only inspected or read from the
+// endpoint, nothing is written to disk.
+func CreateSizeProbePod(client kubernetes.Interface, config *cdiv1.CDIConfig, image, verbose, pullPolicy string, dataVolume *cdiv1.DataVolume) (*v1.Pod, error) {
+	pvc, err := newPersistentVolumeClaim(dataVolume)
+	if err != nil {
+		return nil, err
+	}
+	podEnvVar, err := createSourceEnvVar(client, pvc)
+	if err != nil {
+		return nil, err
+	}
+	pod := makeSizeProbePodSpec(image, verbose, pullPolicy, podEnvVar, pvc, dataVolume)
+
+	proxyEnv, err := getProxyEnv(client, config)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, proxyEnv...)
+	addWorkloadPlacement(pod, config, pvc)
+	addPodResourceRequirements(pod, config)
+
+	pod, err = client.CoreV1().Pods(dataVolume.Namespace).Create(pod)
+	if err != nil {
+		return nil, errors.Wrap(err, "size probe pod API create errored")
+	}
+	klog.V(3).Infof("size probe pod \"%s/%s\" (image: %q) created\n", pod.Namespace, pod.Name, image)
+	return pod, nil
+}
+
+// sizeProbePodName returns the name of the size probe pod of the data volume.
+func sizeProbePodName(dataVolume *cdiv1.DataVolume) string {
+	return fmt.Sprintf("%s-%s", common.SizeProbePodName, dataVolume.Name)
+}
+
+// makeSizeProbePodSpec returns the spec of an importer pod for the pvc of the data volume that only determines the
+// virtual size of the source. The pvc is not mounted, it does not exist yet.
+func makeSizeProbePodSpec(image, verbose, pullPolicy string, podEnvVar *importPodEnvVar, pvc *v1.PersistentVolumeClaim, dataVolume *cdiv1.DataVolume) *v1.Pod {
+	pod := MakeImporterPodSpec(image, verbose, pullPolicy, podEnvVar, pvc, nil)
+
+	pod.GenerateName = ""
+	pod.Name = sizeProbePodName(dataVolume)
+	pod.Labels = map[string]string{
+		common.CDILabelKey:       common.CDILabelValue,
+		common.CDIComponentLabel: common.SizeProbePodName,
+	}
+	pod.OwnerReferences = pvc.OwnerReferences
+	pod.Spec.RestartPolicy = v1.RestartPolicyNever
+
+	var volumes []v1.Volume
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name != DataVolName {
+			volumes = append(volumes, volume)
+		}
+	}
+	pod.Spec.Volumes = volumes
+
+	container := &pod.Spec.Containers[0]
+	var volumeMounts []v1.VolumeMount
+	for _, volumeMount := range container.VolumeMounts {
+		if volumeMount.Name != DataVolName {
+			volumeMounts = append(volumeMounts, volumeMount)
+		}
+	}
+	container.VolumeMounts = volumeMounts
+	container.VolumeDevices = nil
+	container.Ports = nil
+	container.Env = append(container.Env, v1.EnvVar{
+		Name:  common.ImporterSizeProbe,
+		Value: "true",
+	})
+	return pod
+}
+
 // MakeImporterPodSpec creates and return the importer pod spec based on the passed-in endpoint, secret and pvc.
 func MakeImporterPodSpec(image, verbose, pullPolicy string, podEnvVar *importPodEnvVar, pvc *v1.PersistentVolumeClaim, scratchPvcName *string) *v1.Pod {
 	// importer pod name contains the pvc name
@@ -433,6 +890,11 @@ func MakeImporterPodSpec(image, verbose, pullPolicy string, podEnvVar *importPod
 		ownerUID = pvc.OwnerReferences[0].UID
 	}
 
+	// with a retry policy the import controller replaces the failed pod itself, after a backoff
+	if getRetryPolicy(pvc) != nil {
+		pod.Spec.RestartPolicy = v1.RestartPolicyNever
+	}
+
 	if getVolumeMode(pvc) == v1.PersistentVolumeBlock {
 		pod.Spec.Containers[0].VolumeDevices = addVolumeDevices()
 		pod.Spec.SecurityContext = &v1.PodSecurityContext{
@@ -471,6 +933,47 @@ func MakeImporterPodSpec(image, verbose, pullPolicy string, podEnvVar *importPod
 		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, vm)
 		pod.Spec.Volumes = append(pod.Spec.Volumes, vol)
 	}
+
+	if podEnvVar.clientCertSecret != "" {
+		vm := v1.VolumeMount{
+			Name:      ClientCertVolName,
+			MountPath: common.ImporterClientCertDir,
+			ReadOnly:  true,
+		}
+
+		vol := v1.Volume{
+			Name: ClientCertVolName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: podEnvVar.clientCertSecret,
+				},
+			},
+		}
+
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, vm)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, vol)
+	}
+
+	if podEnvVar.checksumKeyConfigMap != "" {
+		vm := v1.VolumeMount{
+			Name:      ChecksumKeyVolName,
+			MountPath: common.ImporterChecksumKeyDir,
+		}
+
+		vol := v1.Volume{
+			Name: ChecksumKeyVolName,
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: podEnvVar.checksumKeyConfigMap,
+					},
+				},
+			},
+		}
+
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, vm)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, vol)
+	}
 	return pod
 }
 
@@ -525,6 +1028,8 @@ func makeEnv(podEnvVar *importPodEnvVar, uid types.UID) []v1.EnvVar {
 		},
 	}
 	if podEnvVar.secretName != "" {
+		// The token and extra headers are optional, the basic auth credentials are required
+		optional := true
 		env = append(env, v1.EnvVar{
 			Name: common.ImporterAccessKeyID,
 			ValueFrom: &v1.EnvVarSource{
@@ -545,6 +1050,28 @@ func makeEnv(podEnvVar *importPodEnvVar, uid types.UID) []v1.EnvVar {
 					Key: common.KeySecret,
 				},
 			},
+		}, v1.EnvVar{
+			Name: common.ImporterToken,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: podEnvVar.secretName,
+					},
+					Key:      common.KeyToken,
+					Optional: &optional,
+				},
+			},
+		}, v1.EnvVar{
+			Name: common.ImporterExtraHeaders,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: podEnvVar.secretName,
+					},
+					Key:      common.KeyExtraHeaders,
+					Optional: &optional,
+				},
+			},
 		})
 
 	}
@@ -554,6 +1081,54 @@ func makeEnv(podEnvVar *importPodEnvVar, uid types.UID) []v1.EnvVar {
 			Value: common.ImporterCertDir,
 		})
 	}
+	if podEnvVar.clientCertSecret != "" {
+		env = append(env, v1.EnvVar{
+			Name:  common.ImporterClientCertDirVar,
+			Value: common.ImporterClientCertDir,
+		})
+	}
+	if podEnvVar.checksumURL != "" {
+		env = append(env, v1.EnvVar{
+			Name:  common.ImporterChecksumURL,
+			Value: podEnvVar.checksumURL,
+		})
+		if podEnvVar.checksumSignatureURL != "" {
+			env = append(env, v1.EnvVar{
+				Name:  common.ImporterChecksumSignatureURL,
+				Value: podEnvVar.checksumSignatureURL,
+			})
+		}
+		if podEnvVar.checksumKeyConfigMap != "" {
+			env = append(env, v1.EnvVar{
+				Name:  common.ImporterChecksumKeyDirVar,
+				Value: common.ImporterChecksumKeyDir,
+			})
+		}
+	}
+	if podEnvVar.archiveSubPath != "" {
+		env = append(env, v1.EnvVar{
+			Name:  common.ImporterArchiveSubPath,
+			Value: podEnvVar.archiveSubPath,
+		})
+	}
+	if podEnvVar.archiveStripComponents != "" {
+		env = append(env, v1.EnvVar{
+			Name:  common.ImporterArchiveStripComponents,
+			Value: podEnvVar.archiveStripComponents,
+		})
+	}
+	if podEnvVar.archiveOwnership != "" {
+		env = append(env, v1.EnvVar{
+			Name:  common.ImporterArchiveOwnership,
+			Value: podEnvVar.archiveOwnership,
+		})
+	}
+	if podEnvVar.verify {
+		env = append(env, v1.EnvVar{
+			Name:  common.ImporterVerify,
+			Value: strconv.FormatBool(podEnvVar.verify),
+		})
+	}
 	return env
 }
 
@@ -569,6 +1144,83 @@ func addToMap(m1, m2 map[string]string) map[string]string {
 	return m1
 }
 
+// podResult returns the result the worker container of a successful pod reported in its termination message, or nil
+// if the container did not terminate.
+func podResult(pod *v1.Pod) *util.TerminationMessage {
+	if len(pod.Status.ContainerStatuses) == 0 || pod.Status.ContainerStatuses[0].State.Terminated == nil {
+		return nil
+	}
+	return util.ParseTerminationMessage(pod.Status.ContainerStatuses[0].State.Terminated.Message)
+}
+
+// podTermination returns the state of the worker container the last time it failed, or nil if it did not fail.
+func podTermination(pod *v1.Pod) *v1.ContainerStateTerminated {
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return nil
+	}
+	status := pod.Status.ContainerStatuses[0]
+	for _, terminated := range []*v1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+		if terminated != nil && terminated.ExitCode > 0 {
+			return terminated
+		}
+	}
+	return nil
+}
+
+// podFailure returns the failure the worker container reported in its termination message the last time it failed,
+// or nil if it did not fail.
+func podFailure(pod *v1.Pod) *util.TerminationMessage {
+	if terminated := podTermination(pod); terminated != nil {
+		return util.ParseTerminationMessage(terminated.Message)
+	}
+	return nil
+}
+
+// addResultAnnotations records the result a worker pod reported after populating a PVC in the PVC annotations anno.
+func addResultAnnotations(anno map[string]string, message *util.TerminationMessage) {
+	if message.Digest != "" {
+		anno[AnnContentChecksum] = message.Digest
+	}
+	if message.Format != "" {
+		anno[AnnSourceFormat] = message.Format
+	}
+	if message.VirtualSize > 0 {
+		anno[AnnVirtualSize] = strconv.FormatInt(message.VirtualSize, 10)
+	}
+	if message.BytesTransferred > 0 {
+		anno[AnnBytesTransferred] = strconv.FormatInt(message.BytesTransferred, 10)
+	}
+	if message.ISOVolumeLabel != "" {
+		anno[AnnISOVolumeLabel] = message.ISOVolumeLabel
+	}
+	if message.StartTime != nil {
+		anno[AnnTransferStartTime] = message.StartTime.UTC().Format(time.RFC3339)
+	}
+	if message.CompletionTime != nil {
+		anno[AnnTransferCompletionTime] = message.CompletionTime.UTC().Format(time.RFC3339)
+	}
+	if layout := message.DiskLayout; layout != nil {
+		anno[AnnPartitionTable] = layout.PartitionTable
+		anno[AnnEFISystemPartition] = strconv.FormatBool(layout.EFISystemPartition)
+		anno[AnnFilesystems] = strings.Join(layout.Filesystems, ",")
+	}
+}
+
+// addFailureAnnotations records the failure a worker pod reported in the PVC annotations anno. Messages of workers
+// that do not report a reason are ignored.
+func addFailureAnnotations(anno map[string]string, message *util.TerminationMessage) {
+	if !message.Failed() {
+		return
+	}
+	anno[AnnFailureReason] = message.Reason
+	anno[AnnFailureMessage] = message.Message
+	anno[AnnFailureTransient] = strconv.FormatBool(message.Transient)
+	if message.ValidationRule != "" {
+		anno[AnnImageValidationRule] = message.ValidationRule
+		anno[AnnImageValidationMessage] = message.Message
+	}
+}
+
 // returns the CloneRequest string which contains the pvc name (and namespace) from which we want to clone the image.
 func getCloneRequestSourcePVC(pvc *v1.PersistentVolumeClaim, pvcLister corelisters.PersistentVolumeClaimLister) (*v1.PersistentVolumeClaim, error) {
 	exists, namespace, name := ParseCloneRequestAnnotation(pvc)
@@ -668,7 +1320,7 @@ func DecodePublicKey(keyBytes []byte) (*rsa.PublicKey, error) {
 }
 
 // CreateCloneSourcePod creates our cloning src pod which will be used for out of band cloning to read the contents of the src PVC
-func CreateCloneSourcePod(client kubernetes.Interface, image, pullPolicy, clientName string, pvc *v1.PersistentVolumeClaim) (*v1.Pod, error) {
+func CreateCloneSourcePod(client kubernetes.Interface, config *cdiv1.CDIConfig, image, pullPolicy, clientName string, pvc *v1.PersistentVolumeClaim) (*v1.Pod, error) {
 	exists, sourcePvcNamespace, sourcePvcName := ParseCloneRequestAnnotation(pvc)
 	if !exists {
 		return nil, errors.Errorf("bad CloneRequest Annotation")
@@ -705,10 +1357,28 @@ func CreateCloneSourcePod(client kubernetes.Interface, image, pullPolicy, client
 	pod := MakeCloneSourcePodSpec(image, pullPolicy, sourcePvcName, ownerKey,
 		clientKeyBytes, clientCertBytes, serverCACertBytes.Cert, pvc)
 
+	proxyEnv, err := getProxyEnv(client, config)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, proxyEnv...)
+	if err = addTransferRateLimit(pod, config, pvc); err != nil {
+		return nil, err
+	}
+	progressSecret, err := addProgressReporting(client, pod, pvc)
+	if err != nil {
+		return nil, err
+	}
+	addWorkloadPlacement(pod, config, pvc)
+	addPodResourceRequirements(pod, config)
+
 	pod, err = client.CoreV1().Pods(sourcePvcNamespace).Create(pod)
 	if err != nil {
 		return nil, errors.Wrap(err, "source pod API create errored")
 	}
+	if err = createProgressSecret(client, sourcePvcNamespace, pod, progressSecret); err != nil {
+		return nil, err
+	}
 
 	klog.V(1).Infof("cloning source pod \"%s/%s\" (image: %q) created\n", pod.Namespace, pod.Name, image)
 
@@ -850,6 +1520,7 @@ func MakeCloneSourcePodSpec(image, pullPolicy, sourcePvcName, ownerRefAnno strin
 // UploadPodArgs are the parameters required to create an upload pod
 type UploadPodArgs struct {
 	Client         kubernetes.Interface
+	Config         *cdiv1.CDIConfig
 	Image          string
 	Verbose        string
 	PullPolicy     string
@@ -868,17 +1539,51 @@ func CreateUploadPod(args UploadPodArgs) (*v1.Pod, error) {
 	pod := makeUploadPodSpec(args.Image, args.Verbose, args.PullPolicy, args.Name,
 		args.PVC, args.ScratchPVCName, secretName, args.ClientName)
 
-	pod, err := args.Client.CoreV1().Pods(ns).Create(pod)
+	proxyEnv, err := getProxyEnv(args.Client, args.Config)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, proxyEnv...)
+	policyEnv, err := getValidationPolicyEnv(args.Config)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, policyEnv...)
+	if err = addTransferRateLimit(pod, args.Config, args.PVC); err != nil {
+		return nil, err
+	}
+	if err = addFilesystemOverhead(pod, args.Client, args.Config, args.PVC); err != nil {
+		return nil, err
+	}
+	var progressSecret *v1.Secret
+	if _, isCloneTarget := args.PVC.Annotations[AnnCloneRequest]; !isCloneTarget {
+		// the clone source pod reports the progress of a clone
+		if progressSecret, err = addProgressReporting(args.Client, pod, args.PVC); err != nil {
+			return nil, err
+		}
+	}
+	addWorkloadPlacement(pod, args.Config, args.PVC)
+	addPodResourceRequirements(pod, args.Config)
+	if err = addFirstConsumerNodeAffinity(pod, args.Client, args.Config, args.PVC); err != nil {
+		return nil, err
+	}
+
+	pod, err = args.Client.CoreV1().Pods(ns).Create(pod)
 	if err != nil {
 		if k8serrors.IsAlreadyExists(err) {
 			pod, err = args.Client.CoreV1().Pods(ns).Get(args.Name, metav1.GetOptions{})
 			if err != nil {
 				return nil, errors.Wrap(err, "upload pod should exist but couldn't retrieve it")
 			}
+			// the progress secret of the existing pod was created with it
+			progressSecret = nil
 		} else {
 			return nil, errors.Wrap(err, "upload pod API create errored")
 		}
 	}
+	if err = createProgressSecret(args.Client, ns, pod, progressSecret); err != nil {
+		return nil, err
+	}
 
 	serverCAKeyPair, err := keys.GetKeyPairAndCert(args.Client, util.GetNamespace(), uploadServerCASecret)
 	if err != nil {
@@ -944,6 +1649,10 @@ func MakePodOwnerReference(pod *v1.Pod) metav1.OwnerReference {
 func makeUploadPodSpec(image, verbose, pullPolicy, name string,
 	pvc *v1.PersistentVolumeClaim, scratchName, secretName, clientName string) *v1.Pod {
 	requestImageSize, _ := getRequestedImageSize(pvc)
+	var ownerID string
+	if pvcOwner := metav1.GetControllerOf(pvc); pvcOwner != nil && pvcOwner.Kind == "DataVolume" {
+		ownerID = string(pvcOwner.UID)
+	}
 	pod := &v1.Pod{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Pod",
@@ -958,6 +1667,8 @@ func makeUploadPodSpec(image, verbose, pullPolicy, name string,
 				common.CDILabelKey:              common.CDILabelValue,
 				common.CDIComponentLabel:        common.UploadServerCDILabel,
 				common.UploadServerServiceLabel: name,
+				// this label is used when searching for the pod to get the upload progress
+				common.PrometheusLabel: "",
 			},
 			OwnerReferences: []metav1.OwnerReference{
 				MakePVCOwnerReference(pvc),
@@ -1014,8 +1725,20 @@ func makeUploadPodSpec(image, verbose, pullPolicy, name string,
 							Name:  "CLIENT_NAME",
 							Value: clientName,
 						},
+						{
+							Name:  common.OwnerUID,
+							Value: ownerID,
+						},
 					},
 					Args: []string{"-v=" + verbose},
+					Ports: []v1.ContainerPort{
+						{
+							// served next to the health check, without TLS
+							Name:          "http-metrics",
+							ContainerPort: 8080,
+							Protocol:      v1.ProtocolTCP,
+						},
+					},
 					ReadinessProbe: &v1.Probe{
 						Handler: v1.Handler{
 							HTTPGet: &v1.HTTPGetAction{
@@ -1226,6 +1949,20 @@ func deletePod(req podDeleteRequest) error {
 }
 
 func createImportEnvVar(client kubernetes.Interface, pvc *v1.PersistentVolumeClaim) (*importPodEnvVar, error) {
+	podEnvVar, err := createSourceEnvVar(client, pvc)
+	if err != nil {
+		return nil, err
+	}
+	//get the requested image size.
+	podEnvVar.imageSize, err = getRequestedImageSize(pvc)
+	if err != nil {
+		return nil, err
+	}
+	return podEnvVar, nil
+}
+
+// createSourceEnvVar returns the importer env describing the source of the pvc, without the requested image size.
+func createSourceEnvVar(client kubernetes.Interface, pvc *v1.PersistentVolumeClaim) (*importPodEnvVar, error) {
 	podEnvVar := &importPodEnvVar{}
 	podEnvVar.source = getSource(pvc)
 	podEnvVar.contentType = getContentType(pvc)
@@ -1247,15 +1984,23 @@ func createImportEnvVar(client kubernetes.Interface, pvc *v1.PersistentVolumeCla
 		if err != nil {
 			return nil, err
 		}
+		podEnvVar.clientCertSecret = pvc.Annotations[AnnClientCertSecret]
+		podEnvVar.checksumURL = pvc.Annotations[AnnChecksumURL]
+		podEnvVar.checksumSignatureURL = pvc.Annotations[AnnChecksumSignatureURL]
+		podEnvVar.checksumKeyConfigMap, err = getChecksumKeyConfigMap(client, pvc)
+		if err != nil {
+			return nil, err
+		}
+		if podEnvVar.contentType == string(cdiv1.DataVolumeArchive) {
+			podEnvVar.archiveSubPath = pvc.Annotations[AnnArchiveSubPath]
+			podEnvVar.archiveStripComponents = pvc.Annotations[AnnArchiveStripComponents]
+			podEnvVar.archiveOwnership = pvc.Annotations[AnnArchiveOwnership]
+		}
 		podEnvVar.insecureTLS, err = isInsecureTLS(client, pvc)
 		if err != nil {
 			return nil, err
 		}
-	}
-	//get the requested image size.
-	podEnvVar.imageSize, err = getRequestedImageSize(pvc)
-	if err != nil {
-		return nil, err
+		podEnvVar.verify = pvc.Annotations[AnnVerify] == "true"
 	}
 	return podEnvVar, nil
 }
@@ -1279,6 +2024,25 @@ func getCertConfigMap(client kubernetes.Interface, pvc *v1.PersistentVolumeClaim
 	return value, nil
 }
 
+func getChecksumKeyConfigMap(client kubernetes.Interface, pvc *v1.PersistentVolumeClaim) (string, error) {
+	value, ok := pvc.Annotations[AnnChecksumKeyConfigMap]
+	if !ok || value == "" {
+		return "", nil
+	}
+
+	_, err := client.CoreV1().ConfigMaps(pvc.Namespace).Get(value, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Warningf("Configmap %s does not exist, pod will not start until it does", value)
+			return value, nil
+		}
+
+		return "", err
+	}
+
+	return value, nil
+}
+
 func getURLFromIngress(ing *extensionsv1beta1.Ingress, uploadProxyServiceName string) string {
 	if ing.Spec.Backend != nil {
 		if ing.Spec.Backend.ServiceName != uploadProxyServiceName {
@@ -33,6 +33,7 @@ import (
 	. "kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/keys"
 	"kubevirt.io/containerized-data-importer/pkg/token"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 func TestController_pvcFromKey(t *testing.T) {
@@ -690,14 +691,14 @@ func TestCreateImporterPod(t *testing.T) {
 	}{
 		{
 			name:    "expect pod to be created for PVC with VolumeMode Filesystem",
-			args:    args{k8sfake.NewSimpleClientset(pvc), "test/image", "-v=5", "Always", &importPodEnvVar{"", "", "", "", "1G", "", false}, pvc},
-			want:    MakeImporterPodSpec("test/image", "-v=5", "Always", &importPodEnvVar{"", "", "", "", "1G", "", false}, pvc, nil),
+			args:    args{k8sfake.NewSimpleClientset(pvc), "test/image", "-v=5", "Always", &importPodEnvVar{"", "", "", "", "1G", "", "", "", "", "", "", "", "", false, false}, pvc},
+			want:    MakeImporterPodSpec("test/image", "-v=5", "Always", &importPodEnvVar{"", "", "", "", "1G", "", "", "", "", "", "", "", "", false, false}, pvc, nil),
 			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := CreateImporterPod(tt.args.client, tt.args.image, tt.args.verbose, tt.args.pullPolicy, tt.args.podEnvVar, tt.args.pvc, nil)
+			got, err := CreateImporterPod(tt.args.client, nil, tt.args.image, tt.args.verbose, tt.args.pullPolicy, tt.args.podEnvVar, tt.args.pvc, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateImporterPod() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -734,12 +735,12 @@ func TestMakeImporterPodSpec(t *testing.T) {
 	}{
 		{
 			name:    "expect pod to be created for PVC with VolumeMode: Filesystem",
-			args:    args{"test/myimage", "5", "Always", &importPodEnvVar{"", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", false}, pvc},
+			args:    args{"test/myimage", "5", "Always", &importPodEnvVar{"", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "", "", "", "", "", "", "", false, false}, pvc},
 			wantPod: pod,
 		},
 		{
 			name:    "expect pod to be created for PVC with VolumeMode: Block",
-			args:    args{"test/myimage", "5", "Always", &importPodEnvVar{"", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", false}, pvc1},
+			args:    args{"test/myimage", "5", "Always", &importPodEnvVar{"", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "", "", "", "", "", "", "", false, false}, pvc1},
 			wantPod: pod1,
 		},
 	}
@@ -755,6 +756,65 @@ func TestMakeImporterPodSpec(t *testing.T) {
 	}
 }
 
+func TestMakeImporterPodSpecWithRetryPolicy(t *testing.T) {
+	pvc := createPvc("testPVC", "default", map[string]string{AnnRetryMaxAttempts: "3"}, nil)
+	pod := MakeImporterPodSpec("test/myimage", "5", "Always", &importPodEnvVar{}, pvc, nil)
+	if pod.Spec.RestartPolicy != v1.RestartPolicyNever {
+		t.Errorf("expected restart policy %s with a retry policy, got %s", v1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	}
+}
+
+func TestCreateSizeProbePod(t *testing.T) {
+	dataVolume := newImportDataVolume("test-dv")
+	dataVolume.Spec.PVC.Resources.Requests = nil
+	client := k8sfake.NewSimpleClientset()
+
+	pod, err := CreateSizeProbePod(client, nil, "test/image", "5", "Always", dataVolume)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if pod.Name != "cdi-size-probe-test-dv" || !metav1.IsControlledBy(pod, dataVolume) {
+		t.Errorf("Expected the size probe pod of the data volume, got %s owned by %+v", pod.Name, pod.OwnerReferences)
+	}
+	if pod.Spec.RestartPolicy != v1.RestartPolicyNever {
+		t.Errorf("Expected restart policy %s, got %s", v1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			t.Errorf("Expected no PVC volume, got %+v", volume)
+		}
+	}
+	if mounts := pod.Spec.Containers[0].VolumeMounts; len(mounts) != 0 {
+		t.Errorf("Expected no volume to be mounted, got %+v", mounts)
+	}
+	env := podEnv(pod)
+	if env[common.ImporterSizeProbe] != "true" || env[common.ImporterEndpoint] != "http://example.com/data" {
+		t.Errorf("Unexpected env %+v", env)
+	}
+	if _, err := client.CoreV1().Pods(dataVolume.Namespace).Get(pod.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the pod to be created, %v", err)
+	}
+}
+
+func TestCreateSizeProbePodKeepsSecurityContext(t *testing.T) {
+	dataVolume := newImportDataVolume("test-dv")
+	dataVolume.Spec.PVC.Resources.Requests = nil
+	blockMode := v1.PersistentVolumeBlock
+	dataVolume.Spec.PVC.VolumeMode = &blockMode
+
+	pod, err := CreateSizeProbePod(k8sfake.NewSimpleClientset(), nil, "test/image", "5", "Always", dataVolume)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	context := pod.Spec.SecurityContext
+	if context == nil || context.RunAsUser == nil || *context.RunAsUser != 0 {
+		t.Errorf("Expected the security context of the importer pod, got %+v", context)
+	}
+	if len(pod.Spec.Containers[0].VolumeDevices) != 0 {
+		t.Errorf("Expected no volume devices, got %+v", pod.Spec.Containers[0].VolumeDevices)
+	}
+}
+
 func Test_makeEnv(t *testing.T) {
 	const mockUID = "1111-1111-1111-1111"
 
@@ -769,8 +829,8 @@ func Test_makeEnv(t *testing.T) {
 	}{
 		{
 			name: "env should match",
-			args: args{&importPodEnvVar{"myendpoint", "mysecret", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", false}},
-			want: createEnv(&importPodEnvVar{"myendpoint", "mysecret", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", false}, mockUID),
+			args: args{&importPodEnvVar{"myendpoint", "mysecret", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "", "", "", "", "", "", "", false, false}},
+			want: createEnv(&importPodEnvVar{"myendpoint", "mysecret", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "", "", "", "", "", "", "", false, false}, mockUID),
 		},
 	}
 	for _, tt := range tests {
@@ -784,11 +844,81 @@ func Test_makeEnv(t *testing.T) {
 	}
 }
 
+func Test_makeEnvWithChecksum(t *testing.T) {
+	const mockUID = "1111-1111-1111-1111"
+
+	podEnvVar := &importPodEnvVar{"myendpoint", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "", "http://checksum/SHA256SUMS", "http://checksum/SHA256SUMS.gpg", "keys", "", "", "", false, false}
+	want := append(createEnv(podEnvVar, mockUID), v1.EnvVar{
+		Name:  ImporterChecksumURL,
+		Value: "http://checksum/SHA256SUMS",
+	}, v1.EnvVar{
+		Name:  ImporterChecksumSignatureURL,
+		Value: "http://checksum/SHA256SUMS.gpg",
+	}, v1.EnvVar{
+		Name:  ImporterChecksumKeyDirVar,
+		Value: ImporterChecksumKeyDir,
+	})
+
+	if got := makeEnv(podEnvVar, mockUID); !reflect.DeepEqual(got, want) {
+		t.Errorf("makeEnv() = %v, want %v", got, want)
+	}
+}
+
+func Test_makeEnvWithClientCert(t *testing.T) {
+	const mockUID = "1111-1111-1111-1111"
+
+	podEnvVar := &importPodEnvVar{"myendpoint", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "client-cert", "", "", "", "", "", "", false, false}
+	want := append(createEnv(podEnvVar, mockUID), v1.EnvVar{
+		Name:  ImporterClientCertDirVar,
+		Value: ImporterClientCertDir,
+	})
+
+	if got := makeEnv(podEnvVar, mockUID); !reflect.DeepEqual(got, want) {
+		t.Errorf("makeEnv() = %v, want %v", got, want)
+	}
+}
+
+func Test_makeEnvWithArchiveOptions(t *testing.T) {
+	const mockUID = "1111-1111-1111-1111"
+
+	podEnvVar := &importPodEnvVar{"myendpoint", "", SourceHTTP, string(cdiv1.DataVolumeArchive), "1G", "", "", "", "", "", "data", "1", string(cdiv1.ArchiveOwnershipDrop), false, false}
+	want := append(createEnv(podEnvVar, mockUID), v1.EnvVar{
+		Name:  ImporterArchiveSubPath,
+		Value: "data",
+	}, v1.EnvVar{
+		Name:  ImporterArchiveStripComponents,
+		Value: "1",
+	}, v1.EnvVar{
+		Name:  ImporterArchiveOwnership,
+		Value: "Drop",
+	})
+
+	if got := makeEnv(podEnvVar, mockUID); !reflect.DeepEqual(got, want) {
+		t.Errorf("makeEnv() = %v, want %v", got, want)
+	}
+}
+
+func Test_makeEnvWithVerify(t *testing.T) {
+	const mockUID = "1111-1111-1111-1111"
+
+	podEnvVar := &importPodEnvVar{"myendpoint", "", SourceHTTP, string(cdiv1.DataVolumeKubeVirt), "1G", "", "", "", "", "", "", "", "", false, true}
+	want := append(createEnv(podEnvVar, mockUID), v1.EnvVar{
+		Name:  ImporterVerify,
+		Value: "true",
+	})
+
+	if got := makeEnv(podEnvVar, mockUID); !reflect.DeepEqual(got, want) {
+		t.Errorf("makeEnv() = %v, want %v", got, want)
+	}
+}
+
 func TestMakeCDIConfigSpec(t *testing.T) {
 	type args struct {
 		name string
 	}
 	config := createCDIConfigWithStorageClass("testConfig", "")
+	config.Status.FilesystemOverhead = nil
+	config.Status.PodResourceRequirements = nil
 
 	tests := []struct {
 		name          string
@@ -892,6 +1022,50 @@ func Test_getCertConfigMap(t *testing.T) {
 	}
 }
 
+func Test_getChecksumKeyConfigMap(t *testing.T) {
+	namespace := "default"
+	configMapName := "foobar"
+
+	testPVC := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"cdi.kubevirt.io/storage.import.checksumKeyConfigMap": configMapName,
+			},
+		},
+	}
+
+	testConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: namespace,
+		},
+	}
+
+	type args struct {
+		pvc    *corev1.PersistentVolumeClaim
+		objs   []runtime.Object
+		result string
+	}
+
+	for _, arg := range []args{
+		{&corev1.PersistentVolumeClaim{}, nil, ""},
+		{testPVC, []runtime.Object{testConfigMap}, configMapName},
+		{testPVC, nil, configMapName},
+	} {
+		client := k8sfake.NewSimpleClientset(arg.objs...)
+
+		result, err := getChecksumKeyConfigMap(client, arg.pvc)
+
+		if err != nil {
+			t.Errorf("Enexpected error %+v", err)
+		}
+
+		if result != arg.result {
+			t.Errorf("Expected %s got %s", arg.result, result)
+		}
+	}
+}
+
 func Test_getInsecureTLS(t *testing.T) {
 	namespace := "cdi"
 	configMapName := "cdi-insecure-registries"
@@ -1018,6 +1192,345 @@ func Test_GetScratchPvcStorageClassPvc(t *testing.T) {
 	}
 }
 
+func Test_getProxyEnvNoProxy(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+
+	env, err := getProxyEnv(client, createCDIConfig(common.ConfigName))
+	if err != nil {
+		t.Errorf("getProxyEnv() error = %v", err)
+	}
+	if env != nil {
+		t.Errorf("getProxyEnv() = %v, want nil", env)
+	}
+}
+
+func Test_getProxyEnv(t *testing.T) {
+	httpProxy := "http://proxy:3128"
+	noProxy := "internal.example.com"
+	caConfigMap := "proxy-ca"
+	client := k8sfake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      caConfigMap,
+			Namespace: util.GetNamespace(),
+		},
+		Data: map[string]string{
+			"b.crt": "second",
+			"a.crt": "first",
+		},
+	})
+	config := createCDIConfig(common.ConfigName)
+	config.Status.ImportProxy = &cdiv1.ImportProxy{
+		HTTPProxy:      &httpProxy,
+		HTTPSProxy:     &httpProxy,
+		NoProxy:        &noProxy,
+		TrustedCAProxy: &caConfigMap,
+	}
+
+	want := []v1.EnvVar{
+		{Name: "HTTP_PROXY", Value: httpProxy},
+		{Name: "http_proxy", Value: httpProxy},
+		{Name: "HTTPS_PROXY", Value: httpProxy},
+		{Name: "https_proxy", Value: httpProxy},
+		{Name: "NO_PROXY", Value: "internal.example.com,localhost,127.0.0.1,.svc"},
+		{Name: "no_proxy", Value: "internal.example.com,localhost,127.0.0.1,.svc"},
+		{Name: ProxyCACertVar, Value: "first\nsecond"},
+	}
+	env, err := getProxyEnv(client, config)
+	if err != nil {
+		t.Errorf("getProxyEnv() error = %v", err)
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("getProxyEnv() = %v, want %v", env, want)
+	}
+}
+
+func Test_getProxyEnvMissingCA(t *testing.T) {
+	httpProxy := "http://proxy:3128"
+	caConfigMap := "proxy-ca"
+	config := createCDIConfig(common.ConfigName)
+	config.Status.ImportProxy = &cdiv1.ImportProxy{
+		HTTPProxy:      &httpProxy,
+		TrustedCAProxy: &caConfigMap,
+	}
+
+	_, err := getProxyEnv(k8sfake.NewSimpleClientset(), config)
+	if err == nil {
+		t.Error("getProxyEnv() expected error for missing CA ConfigMap")
+	}
+}
+
+func Test_getValidationPolicyEnv(t *testing.T) {
+	maxSize := resource.MustParse("10Gi")
+	config := createCDIConfig(common.ConfigName)
+	config.Status.ImageValidation = &cdiv1.ImageValidationPolicy{
+		MaxVirtualSize: &maxSize,
+		DeniedFormats:  []string{"vmdk"},
+	}
+
+	want := []v1.EnvVar{
+		{Name: common.ImageValidationPolicyVar, Value: `{"maxVirtualSize":"10Gi","deniedFormats":["vmdk"]}`},
+	}
+	env, err := getValidationPolicyEnv(config)
+	if err != nil {
+		t.Errorf("getValidationPolicyEnv() error = %v", err)
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("getValidationPolicyEnv() = %v, want %v", env, want)
+	}
+
+	env, err = getValidationPolicyEnv(createCDIConfig(common.ConfigName))
+	if err != nil {
+		t.Errorf("getValidationPolicyEnv() error = %v", err)
+	}
+	if env != nil {
+		t.Errorf("getValidationPolicyEnv() = %v, want nil", env)
+	}
+}
+
+func Test_getTransferRateLimit(t *testing.T) {
+	defaultLimit := resource.MustParse("100Mi")
+	config := createCDIConfig(common.ConfigName)
+	config.Status.TransferRateLimit = &cdiv1.TransferRateLimit{
+		Default:    &defaultLimit,
+		Namespaces: map[string]resource.Quantity{"backup": resource.MustParse("10Mi")},
+	}
+	tests := []struct {
+		name   string
+		config *cdiv1.CDIConfig
+		pvc    *v1.PersistentVolumeClaim
+		want   int64
+	}{
+		{"default limit", config, createPvc("testPVC", "default", nil, nil), 100 << 20},
+		{"namespace limit", config, createPvc("testPVC", "backup", nil, nil), 10 << 20},
+		{"pvc annotation", config, createPvc("testPVC", "backup", map[string]string{AnnTransferRateLimit: "1Gi"}, nil), 1 << 30},
+		{"invalid pvc annotation", config, createPvc("testPVC", "default", map[string]string{AnnTransferRateLimit: "fast"}, nil), 100 << 20},
+		{"no limit", createCDIConfig(common.ConfigName), createPvc("testPVC", "default", nil, nil), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getTransferRateLimit(tt.config, tt.pvc)
+			if err != nil {
+				t.Errorf("getTransferRateLimit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getTransferRateLimit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_addTransferRateLimit(t *testing.T) {
+	pvc := createPvc("testPVC", "default", map[string]string{AnnTransferRateLimit: "10Mi"}, nil)
+	pod := MakeImporterPodSpec("test/myimage", "5", "Always", &importPodEnvVar{}, pvc, nil)
+	if err := addTransferRateLimit(pod, nil, pvc); err != nil {
+		t.Errorf("addTransferRateLimit() error = %v", err)
+	}
+	env := pod.Spec.Containers[0].Env[len(pod.Spec.Containers[0].Env)-1]
+	if env.Name != common.TransferRateLimitVar || env.Value != "10485760" {
+		t.Errorf("addTransferRateLimit() env = %v", env)
+	}
+	if value := pod.Annotations[AnnTransferRateLimit]; value != "10485760" {
+		t.Errorf("addTransferRateLimit() annotation = %q", value)
+	}
+}
+
+func TestGetFilesystemOverhead(t *testing.T) {
+	config := createCDIConfig(common.ConfigName)
+	config.Status.FilesystemOverhead = &cdiv1.FilesystemOverhead{
+		Global:       "0.1",
+		StorageClass: map[string]cdiv1.Percent{"local": "0", "nfs": "0.2"},
+	}
+	localClass, nfsClass, noClass := "local", "nfs", ""
+	blockPvc := createPvcInStorageClass("testPVC", "default", &nfsClass, nil, nil)
+	blockMode := v1.PersistentVolumeBlock
+	blockPvc.Spec.VolumeMode = &blockMode
+	tests := []struct {
+		name   string
+		config *cdiv1.CDIConfig
+		pvc    *v1.PersistentVolumeClaim
+		want   float64
+	}{
+		{"storage class overhead", config, createPvcInStorageClass("testPVC", "default", &nfsClass, nil, nil), 0.2},
+		{"no storage class overhead", config, createPvcInStorageClass("testPVC", "default", &localClass, nil, nil), 0},
+		{"default storage class", config, createPvc("testPVC", "default", nil, nil), 0.2},
+		{"global overhead", config, createPvcInStorageClass("testPVC", "default", &noClass, nil, nil), 0.1},
+		{"block mode", config, blockPvc, 0},
+		{"default overhead", createCDIConfig(common.ConfigName), createPvc("testPVC", "default", nil, nil), 0.055},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := k8sfake.NewSimpleClientset(createStorageClass("nfs", map[string]string{AnnDefaultStorageClass: "true"}))
+			got, err := GetFilesystemOverhead(client, tt.config, tt.pvc)
+			if err != nil {
+				t.Errorf("GetFilesystemOverhead() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetFilesystemOverhead() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_addFilesystemOverhead(t *testing.T) {
+	pvc := createPvc("testPVC", "default", nil, nil)
+	pod := MakeImporterPodSpec("test/myimage", "5", "Always", &importPodEnvVar{}, pvc, nil)
+	if err := addFilesystemOverhead(pod, k8sfake.NewSimpleClientset(), createCDIConfig(common.ConfigName), pvc); err != nil {
+		t.Errorf("addFilesystemOverhead() error = %v", err)
+	}
+	env := pod.Spec.Containers[0].Env[len(pod.Spec.Containers[0].Env)-1]
+	if env.Name != common.FilesystemOverheadVar || env.Value != "0.055" {
+		t.Errorf("addFilesystemOverhead() env = %v", env)
+	}
+}
+
+func TestWaitsForFirstConsumer(t *testing.T) {
+	wffcClass, immediateClass := "wffc", "immediate"
+	boundPvc := createPvcInStorageClass("testPVC", "default", &wffcClass, nil, nil)
+	boundPvc.Status.Phase = v1.ClaimBound
+	tests := []struct {
+		name   string
+		config *cdiv1.CDIConfig
+		pvc    *v1.PersistentVolumeClaim
+		want   bool
+	}{
+		{"wait for first consumer", createHonorWaitForFirstConsumerConfig(), createPvcInStorageClass("testPVC", "default", &wffcClass, nil, nil), true},
+		{"default storage class", createHonorWaitForFirstConsumerConfig(), createPvc("testPVC", "default", nil, nil), true},
+		{"feature gate disabled", createCDIConfig(common.ConfigName), createPvcInStorageClass("testPVC", "default", &wffcClass, nil, nil), false},
+		{"immediate binding", createHonorWaitForFirstConsumerConfig(), createPvcInStorageClass("testPVC", "default", &immediateClass, nil, nil), false},
+		{"node selected", createHonorWaitForFirstConsumerConfig(), createPvcInStorageClass("testPVC", "default", &wffcClass, map[string]string{AnnSelectedNode: "node01"}, nil), false},
+		{"claim bound", createHonorWaitForFirstConsumerConfig(), boundPvc, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageClass := createWaitForFirstConsumerStorageClass(wffcClass)
+			storageClass.Annotations = map[string]string{AnnDefaultStorageClass: "true"}
+			client := k8sfake.NewSimpleClientset(storageClass, createStorageClass(immediateClass, nil))
+			got, err := waitsForFirstConsumer(client, tt.config, tt.pvc)
+			if err != nil {
+				t.Errorf("waitsForFirstConsumer() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("waitsForFirstConsumer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_addFirstConsumerNodeAffinity(t *testing.T) {
+	wffcClass := "wffc"
+	client := k8sfake.NewSimpleClientset(createWaitForFirstConsumerStorageClass(wffcClass))
+	config := createHonorWaitForFirstConsumerConfig()
+
+	pvc := createPvcInStorageClass("testPVC", "default", &wffcClass, nil, nil)
+	pod := MakeImporterPodSpec("test/myimage", "5", "Always", &importPodEnvVar{}, pvc, nil)
+	if err := addFirstConsumerNodeAffinity(pod, client, config, pvc); err != nil {
+		t.Errorf("addFirstConsumerNodeAffinity() error = %v", err)
+	}
+	if pod.Spec.Affinity != nil {
+		t.Errorf("addFirstConsumerNodeAffinity() set affinity %v without a selected node", pod.Spec.Affinity)
+	}
+
+	pvc.Annotations = map[string]string{AnnSelectedNode: "node01"}
+	if err := addFirstConsumerNodeAffinity(pod, client, config, pvc); err != nil {
+		t.Errorf("addFirstConsumerNodeAffinity() error = %v", err)
+	}
+	want := []v1.NodeSelectorTerm{
+		{
+			MatchFields: []v1.NodeSelectorRequirement{
+				{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"node01"}},
+			},
+		},
+	}
+	if pod.Spec.Affinity == nil || !reflect.DeepEqual(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, want) {
+		t.Errorf("addFirstConsumerNodeAffinity() affinity = %v, want node01", pod.Spec.Affinity)
+	}
+
+	// the selected node is added to the node affinity of the workload placement
+	zone := v1.NodeSelectorRequirement{Key: "topology.kubernetes.io/zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}
+	pod.Spec.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{zone}}},
+			},
+		},
+	}
+	if err := addFirstConsumerNodeAffinity(pod, client, config, pvc); err != nil {
+		t.Errorf("addFirstConsumerNodeAffinity() error = %v", err)
+	}
+	want[0].MatchExpressions = []v1.NodeSelectorRequirement{zone}
+	if !reflect.DeepEqual(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, want) {
+		t.Errorf("addFirstConsumerNodeAffinity() affinity = %v, want zone a and node01", pod.Spec.Affinity)
+	}
+}
+
+func TestGetWorkloadPlacement(t *testing.T) {
+	config := createCDIConfig(common.ConfigName)
+	config.Spec.Workloads = &cdiv1.NodePlacement{
+		NodeSelector:      map[string]string{"node-role.kubernetes.io/worker": ""},
+		Tolerations:       []v1.Toleration{{Key: "storage", Operator: v1.TolerationOpExists}},
+		PriorityClassName: "cdi-workloads",
+	}
+	affinity := &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+				{
+					Weight: 10,
+					PodAffinityTerm: v1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "containerized-data-importer"}},
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name        string
+		config      *cdiv1.CDIConfig
+		annotations map[string]string
+		want        *cdiv1.NodePlacement
+	}{
+		{"no config", nil, nil, &cdiv1.NodePlacement{}},
+		{"config", config, nil, config.Spec.Workloads},
+		{
+			name:   "annotations",
+			config: config,
+			annotations: map[string]string{
+				AnnWorkloadNodeSelector:      `{"disktype":"ssd"}`,
+				AnnWorkloadAffinity:          `{"podAntiAffinity":{"preferredDuringSchedulingIgnoredDuringExecution":[{"weight":10,"podAffinityTerm":{"labelSelector":{"matchLabels":{"app":"containerized-data-importer"}},"topologyKey":"kubernetes.io/hostname"}}]}}`,
+				AnnWorkloadPriorityClassName: "",
+			},
+			want: &cdiv1.NodePlacement{
+				NodeSelector: map[string]string{"disktype": "ssd"},
+				Tolerations:  config.Spec.Workloads.Tolerations,
+				Affinity:     affinity,
+			},
+		},
+		{
+			name:        "invalid annotation",
+			config:      config,
+			annotations: map[string]string{AnnWorkloadTolerations: `{"key":"storage"}`},
+			want:        config.Spec.Workloads,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getWorkloadPlacement(tt.config, createPvc("testPVC", "default", tt.annotations, nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getWorkloadPlacement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScratchPvcWorkloadPlacement(t *testing.T) {
+	pvc := createPvc("testPVC", "default", map[string]string{AnnEndpoint: "http://test", AnnWorkloadNodeSelector: `{"disktype":"ssd"}`}, nil)
+	pod := createPod(pvc, DataVolName, nil)
+	scratchPvc := newScratchPersistentVolumeClaimSpec(pvc, pod, "testPVC-scratch", "")
+	if !reflect.DeepEqual(scratchPvc.Annotations, map[string]string{AnnWorkloadNodeSelector: `{"disktype":"ssd"}`}) {
+		t.Errorf("newScratchPersistentVolumeClaimSpec() annotations = %v", scratchPvc.Annotations)
+	}
+}
+
 func Test_DecodePublicKey(t *testing.T) {
 	bytes, err := cert.EncodePublicKeyPEM(&getAPIServerKey().PublicKey)
 	if err != nil {
@@ -1426,6 +1939,7 @@ func createEnv(podEnvVar *importPodEnvVar, uid string) []v1.EnvVar {
 	}
 
 	if podEnvVar.secretName != "" {
+		optional := true
 		env = append(env, v1.EnvVar{
 			Name: ImporterAccessKeyID,
 			ValueFrom: &v1.EnvVarSource{
@@ -1446,6 +1960,28 @@ func createEnv(podEnvVar *importPodEnvVar, uid string) []v1.EnvVar {
 					Key: KeySecret,
 				},
 			},
+		}, v1.EnvVar{
+			Name: ImporterToken,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: podEnvVar.secretName,
+					},
+					Key:      KeyToken,
+					Optional: &optional,
+				},
+			},
+		}, v1.EnvVar{
+			Name: ImporterExtraHeaders,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: podEnvVar.secretName,
+					},
+					Key:      KeyExtraHeaders,
+					Optional: &optional,
+				},
+			},
 		})
 	}
 	return env
@@ -1472,6 +2008,10 @@ func createImportController(pvcSpec *v1.PersistentVolumeClaim, podSpec *v1.Pod,
 
 	pvcInformer := k8sI.Core().V1().PersistentVolumeClaims()
 	podInformer := k8sI.Core().V1().Pods()
+	scheduler, err := NewWorkerScheduler(pvcInformer, podInformer, newDataVolumeInformer(cdiclient))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("createImportController: failed to create worker scheduler error = %v", err)
+	}
 
 	pvcQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	pvcQueue.Add(pvc)
@@ -1487,7 +2027,7 @@ func createImportController(pvcSpec *v1.PersistentVolumeClaim, podSpec *v1.Pod,
 	cache.WaitForCacheSync(stop, pvcInformer.Informer().HasSynced)
 	defer close(stop)
 
-	c := NewImportController(myclient, cdiclient, pvcInformer, podInformer, "test/image", "Always", "-v=5")
+	c := NewImportController(myclient, cdiclient, pvcInformer, podInformer, newConfigInformer(cdiclient, nil), scheduler, "test/image", "Always", "-v=5")
 	return c, pvc, pod, nil
 }
 
@@ -1656,9 +2196,10 @@ func createUploadClonePod(pvc *v1.PersistentVolumeClaim, clientName string) *v1.
 				annCreatedByUpload: "yes",
 			},
 			Labels: map[string]string{
-				"app":             "containerized-data-importer",
-				"cdi.kubevirt.io": "cdi-upload-server",
-				"service":         name,
+				"app":                  "containerized-data-importer",
+				"cdi.kubevirt.io":      "cdi-upload-server",
+				"service":              name,
+				common.PrometheusLabel: "",
 			},
 			OwnerReferences: []metav1.OwnerReference{
 				MakePVCOwnerReference(pvc),
@@ -1721,8 +2262,19 @@ func createUploadClonePod(pvc *v1.PersistentVolumeClaim, clientName string) *v1.
 							Name:  "CLIENT_NAME",
 							Value: clientName,
 						},
+						{
+							Name:  common.OwnerUID,
+							Value: "",
+						},
 					},
 					Args: []string{"-v=" + "5"},
+					Ports: []v1.ContainerPort{
+						{
+							Name:          "http-metrics",
+							ContainerPort: 8080,
+							Protocol:      v1.ProtocolTCP,
+						},
+					},
 					ReadinessProbe: &v1.Probe{
 						Handler: v1.Handler{
 							HTTPGet: &v1.HTTPGetAction{
@@ -1819,6 +2371,19 @@ func createCDIConfigWithStorageClass(name string, storageClass string) *cdiv1.CD
 		},
 		Status: cdiv1.CDIConfigStatus{
 			ScratchSpaceStorageClass: storageClass,
+			FilesystemOverhead: &cdiv1.FilesystemOverhead{
+				Global: util.DefaultFilesystemOverhead,
+			},
+			PodResourceRequirements: createDefaultPodResourceRequirements(),
+		},
+	}
+}
+
+func createDefaultPodResourceRequirements() *corev1.ResourceRequirements {
+	return &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("60M"),
 		},
 	}
 }
@@ -1841,6 +2406,19 @@ func createStorageClassWithProvisioner(name string, annotations map[string]strin
 		},
 	}
 }
+func createWaitForFirstConsumerStorageClass(name string) *storagev1.StorageClass {
+	storageClass := createStorageClass(name, nil)
+	bindingMode := storagev1.VolumeBindingWaitForFirstConsumer
+	storageClass.VolumeBindingMode = &bindingMode
+	return storageClass
+}
+
+func createHonorWaitForFirstConsumerConfig() *cdiv1.CDIConfig {
+	config := createCDIConfig(common.ConfigName)
+	config.Spec.FeatureGates = []string{common.HonorWaitForFirstConsumer}
+	return config
+}
+
 func createSnapshotClass(name string, annotations map[string]string, snapshotter string) *snapshotv1.VolumeSnapshotClass {
 	return &snapshotv1.VolumeSnapshotClass{
 		TypeMeta: metav1.TypeMeta{
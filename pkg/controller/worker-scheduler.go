@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	informers "kubevirt.io/containerized-data-importer/pkg/client/informers/externalversions/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+const (
+	// admittedPodTimeout is how long an admitted PVC counts as running while its worker pod is not in the cache
+	admittedPodTimeout = time.Minute
+	// queueRecheckInterval is how often a queued PVC checks whether its worker pod may be created
+	queueRecheckInterval = 10 * time.Second
+
+	// workerPodIndex indexes the importer, upload server and size probe pods by their component label
+	workerPodIndex = "workerPod"
+	// queuedPvcIndex indexes the PVCs with a queue position under queuedIndexValue
+	queuedPvcIndex = "queuedPvc"
+	// queuedDataVolumeIndex indexes the data volumes queued for a size probe pod under queuedIndexValue
+	queuedDataVolumeIndex = "queuedDataVolume"
+	queuedIndexValue      = "queued"
+)
+
+// WorkerScheduler enforces the concurrency limits of the CDI config on the importer, upload server and size probe
+// pods. A PVC whose worker pod may not be created yet is queued, the queued PVCs are admitted by descending priority,
+// then in the order they were created. A clone is limited by its upload server pod, the clone source pod is only
+// created once the upload server runs. A data volume waiting for its size probe pod is queued under the key of the PVC
+// it creates, see sizeProbeQueueEntry.
+type WorkerScheduler struct {
+	pvcIndexer        cache.Indexer
+	podIndexer        cache.Indexer
+	dataVolumeIndexer cache.Indexer
+
+	mutex sync.Mutex
+	// admitted are the keys of the admitted PVCs whose worker pod is not in the cache yet, with the admission time
+	admitted map[string]time.Time
+}
+
+// NewWorkerScheduler returns the WorkerScheduler shared by the controllers creating worker pods. It adds the indexes
+// it looks up the worker pods and the queued PVCs with to the informers, which must not have started.
+func NewWorkerScheduler(pvcInformer coreinformers.PersistentVolumeClaimInformer,
+	podInformer coreinformers.PodInformer,
+	dataVolumeInformer informers.DataVolumeInformer) (*WorkerScheduler, error) {
+	err := pvcInformer.Informer().AddIndexers(cache.Indexers{queuedPvcIndex: queuedPvcIndexFunc})
+	if err != nil {
+		return nil, errors.Wrap(err, "error adding queued pvc index")
+	}
+	err = podInformer.Informer().AddIndexers(cache.Indexers{workerPodIndex: workerPodIndexFunc})
+	if err != nil {
+		return nil, errors.Wrap(err, "error adding worker pod index")
+	}
+	err = dataVolumeInformer.Informer().AddIndexers(cache.Indexers{queuedDataVolumeIndex: queuedDataVolumeIndexFunc})
+	if err != nil {
+		return nil, errors.Wrap(err, "error adding queued data volume index")
+	}
+	return newWorkerScheduler(pvcInformer.Informer().GetIndexer(), podInformer.Informer().GetIndexer(),
+		dataVolumeInformer.Informer().GetIndexer()), nil
+}
+
+func newWorkerScheduler(pvcIndexer, podIndexer, dataVolumeIndexer cache.Indexer) *WorkerScheduler {
+	return &WorkerScheduler{
+		pvcIndexer:        pvcIndexer,
+		podIndexer:        podIndexer,
+		dataVolumeIndexer: dataVolumeIndexer,
+		admitted:          map[string]time.Time{},
+	}
+}
+
+// workerComponents are the component labels of the worker pods the concurrency limits apply to.
+var workerComponents = []string{common.ImporterPodName, common.UploadServerCDILabel, common.SizeProbePodName}
+
+// workerPodIndexFunc indexes the worker pods by their component.
+func workerPodIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return nil, nil
+	}
+	component := pod.Labels[common.CDIComponentLabel]
+	for _, workerComponent := range workerComponents {
+		if component == workerComponent {
+			return []string{component}, nil
+		}
+	}
+	return nil, nil
+}
+
+// queuedPvcIndexFunc indexes the PVCs with a queue position.
+func queuedPvcIndexFunc(obj interface{}) ([]string, error) {
+	pvc, ok := obj.(*v1.PersistentVolumeClaim)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := pvc.Annotations[AnnQueuePosition]; !ok {
+		return nil, nil
+	}
+	return []string{queuedIndexValue}, nil
+}
+
+// queuedDataVolumeIndexFunc indexes the data volumes queued for a size probe pod.
+func queuedDataVolumeIndexFunc(obj interface{}) ([]string, error) {
+	dataVolume, ok := obj.(*cdiv1.DataVolume)
+	if !ok {
+		return nil, nil
+	}
+	if dataVolume.Status.Phase != cdiv1.Queued || !needsSizeProbe(dataVolume) {
+		return nil, nil
+	}
+	return []string{queuedIndexValue}, nil
+}
+
+// sizeProbeQueueEntry returns the PVC a data volume waiting for its size probe pod is queued as. The size probe pod
+// runs before the PVC of the data volume is created, the entry has the namespace, name and annotations of the PVC and
+// the creation time of the data volume.
+func sizeProbeQueueEntry(dataVolume *cdiv1.DataVolume) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         dataVolume.Namespace,
+			Name:              dataVolume.Name,
+			Annotations:       dataVolume.Annotations,
+			CreationTimestamp: dataVolume.CreationTimestamp,
+		},
+	}
+}
+
+// admit returns 0 if the worker pod populating pvc may be created under the concurrency limits of config, otherwise
+// the position of pvc in the queue, starting at 1. An admitted PVC counts as running until its worker pod is in the
+// cache.
+func (s *WorkerScheduler) admit(pvc *v1.PersistentVolumeClaim, config *cdiv1.CDIConfig) (int, error) {
+	if config == nil || config.Spec.ConcurrencyLimits == nil {
+		return 0, nil
+	}
+	limits := config.Spec.ConcurrencyLimits
+	key, err := cache.MetaNamespaceKeyFunc(pvc)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	running, err := s.runningWorkers()
+	if err != nil {
+		return 0, err
+	}
+	if running.Has(key) {
+		return 0, nil
+	}
+	queued, err := s.queuedPvcs(pvc, running)
+	if err != nil {
+		return 0, err
+	}
+
+	global := running.Len()
+	namespaces := map[string]int{}
+	for _, runningKey := range running.UnsortedList() {
+		namespace, _, _ := cache.SplitMetaNamespaceKey(runningKey)
+		namespaces[namespace]++
+	}
+	// the queued PVCs ahead of pvc that fit within the limits are admitted first
+	for i, claim := range queued {
+		admissible := (limits.Global == nil || global < int(*limits.Global)) &&
+			(limits.Namespace == nil || namespaces[claim.Namespace] < int(*limits.Namespace))
+		if claim.Namespace == pvc.Namespace && claim.Name == pvc.Name {
+			if !admissible {
+				return i + 1, nil
+			}
+			s.admitted[key] = time.Now()
+			return 0, nil
+		}
+		if admissible {
+			global++
+			namespaces[claim.Namespace]++
+		}
+	}
+	return 0, errors.Errorf("pvc %s missing from the queue", key)
+}
+
+// runningWorkers returns the keys of the PVCs populated by an importer or upload server pod, or sized by a size probe
+// pod, that has not completed, and of the admitted PVCs whose pod is not in the cache yet.
+func (s *WorkerScheduler) runningWorkers() (sets.String, error) {
+	var pods []interface{}
+	for _, component := range workerComponents {
+		objs, err := s.podIndexer.ByIndex(workerPodIndex, component)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing worker pods")
+		}
+		pods = append(pods, objs...)
+	}
+	running := sets.NewString()
+	for _, obj := range pods {
+		pod := obj.(*v1.Pod)
+		owner := metav1.GetControllerOf(pod)
+		// a size probe pod is owned by the data volume, its PVC has the same name
+		if owner == nil || (owner.Kind != "PersistentVolumeClaim" && owner.Kind != "DataVolume") {
+			continue
+		}
+		key := pod.Namespace + "/" + owner.Name
+		delete(s.admitted, key)
+		if pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			running.Insert(key)
+		}
+	}
+	for key, admitted := range s.admitted {
+		if time.Since(admitted) > admittedPodTimeout {
+			klog.V(3).Infof("worker pod of pvc %s not seen since its admission, releasing its slot", key)
+			delete(s.admitted, key)
+			continue
+		}
+		running.Insert(key)
+	}
+	return running, nil
+}
+
+// queuedPvcs returns the queued PVCs, the queue entries of the data volumes queued for a size probe pod, and pvc in
+// the order they are admitted.
+func (s *WorkerScheduler) queuedPvcs(pvc *v1.PersistentVolumeClaim, running sets.String) ([]*v1.PersistentVolumeClaim, error) {
+	pvcs, err := s.pvcIndexer.ByIndex(queuedPvcIndex, queuedIndexValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing queued pvcs")
+	}
+	dataVolumes, err := s.dataVolumeIndexer.ByIndex(queuedDataVolumeIndex, queuedIndexValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing queued data volumes")
+	}
+	queued := []*v1.PersistentVolumeClaim{pvc}
+	seen := sets.NewString(pvc.Namespace + "/" + pvc.Name)
+	add := func(claim *v1.PersistentVolumeClaim) {
+		key := claim.Namespace + "/" + claim.Name
+		if claim.DeletionTimestamp != nil || seen.Has(key) || running.Has(key) {
+			return
+		}
+		seen.Insert(key)
+		queued = append(queued, claim)
+	}
+	for _, obj := range pvcs {
+		add(obj.(*v1.PersistentVolumeClaim))
+	}
+	for _, obj := range dataVolumes {
+		dataVolume := obj.(*cdiv1.DataVolume)
+		if dataVolume.DeletionTimestamp != nil {
+			continue
+		}
+		add(sizeProbeQueueEntry(dataVolume))
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		a, b := queued[i], queued[j]
+		if priorityA, priorityB := queuePriority(a), queuePriority(b); priorityA != priorityB {
+			return priorityA > priorityB
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return queued, nil
+}
+
+// queuePriority returns the priority annotation of pvc, 0 if it has none or it is invalid.
+func queuePriority(pvc *v1.PersistentVolumeClaim) int {
+	value, ok := pvc.Annotations[AnnQueuePriority]
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		klog.V(3).Infof("Ignoring invalid queue priority %q of pvc %s/%s", value, pvc.Namespace, pvc.Name)
+		return 0
+	}
+	return priority
+}
+
+// removeQueuePosition removes the queue position annotation of an admitted pvc, so it is no longer queued.
+func removeQueuePosition(client kubernetes.Interface, pvc *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+	if _, ok := pvc.Annotations[AnnQueuePosition]; !ok {
+		return pvc, nil
+	}
+	pvcCopy := pvc.DeepCopy()
+	delete(pvcCopy.Annotations, AnnQueuePosition)
+	pvc, err := client.CoreV1().PersistentVolumeClaims(pvcCopy.Namespace).Update(pvcCopy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error dequeuing pvc %s/%s", pvcCopy.Namespace, pvcCopy.Name)
+	}
+	return pvc, nil
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+func createWorkerScheduler(pvcs []*v1.PersistentVolumeClaim, pods []*v1.Pod, dataVolumes []*cdiv1.DataVolume) *WorkerScheduler {
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{queuedPvcIndex: queuedPvcIndexFunc})
+	for _, pvc := range pvcs {
+		pvcIndexer.Add(pvc)
+	}
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{workerPodIndex: workerPodIndexFunc})
+	for _, pod := range pods {
+		podIndexer.Add(pod)
+	}
+	dataVolumeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{queuedDataVolumeIndex: queuedDataVolumeIndexFunc})
+	for _, dataVolume := range dataVolumes {
+		dataVolumeIndexer.Add(dataVolume)
+	}
+	return newWorkerScheduler(pvcIndexer, podIndexer, dataVolumeIndexer)
+}
+
+func createConcurrencyLimitsConfig(global, namespace *int32) *cdiv1.CDIConfig {
+	config := createCDIConfig(common.ConfigName)
+	config.Spec.ConcurrencyLimits = &cdiv1.ConcurrencyLimits{Global: global, Namespace: namespace}
+	return config
+}
+
+func createRunningImporterPod(pvc *v1.PersistentVolumeClaim) *v1.Pod {
+	pod := createPod(pvc, DataVolName, nil)
+	pod.Name = "importer-" + pvc.Name
+	pod.Namespace = pvc.Namespace
+	pod.Status.Phase = v1.PodRunning
+	return pod
+}
+
+func createQueuedPvc(name, ns string, created time.Time, annotations map[string]string) *v1.PersistentVolumeClaim {
+	pvc := createPvc(name, ns, map[string]string{AnnEndpoint: "http://test", AnnQueuePosition: "1"}, nil)
+	for k, v := range annotations {
+		pvc.Annotations[k] = v
+	}
+	pvc.CreationTimestamp = metav1.NewTime(created)
+	return pvc
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func TestWorkerSchedulerAdmit(t *testing.T) {
+	now := time.Now()
+	running := createPvc("running", "ns1", nil, nil)
+	completed := createPvc("completed", "ns1", nil, nil)
+	completedPod := createRunningImporterPod(completed)
+	completedPod.Status.Phase = v1.PodSucceeded
+
+	tests := []struct {
+		name     string
+		config   *cdiv1.CDIConfig
+		pvcs     []*v1.PersistentVolumeClaim
+		pods     []*v1.Pod
+		pvc      *v1.PersistentVolumeClaim
+		position int
+	}{
+		{
+			name:     "no limits",
+			config:   createCDIConfig(common.ConfigName),
+			pods:     []*v1.Pod{createRunningImporterPod(running)},
+			pvc:      createPvc("test", "ns1", nil, nil),
+			position: 0,
+		},
+		{
+			name:     "no config",
+			pods:     []*v1.Pod{createRunningImporterPod(running)},
+			pvc:      createPvc("test", "ns1", nil, nil),
+			position: 0,
+		},
+		{
+			name:     "global limit reached",
+			config:   createConcurrencyLimitsConfig(int32Ptr(1), nil),
+			pods:     []*v1.Pod{createRunningImporterPod(running)},
+			pvc:      createPvc("test", "ns2", nil, nil),
+			position: 1,
+		},
+		{
+			name:     "completed pods do not count",
+			config:   createConcurrencyLimitsConfig(int32Ptr(1), nil),
+			pods:     []*v1.Pod{completedPod},
+			pvc:      createPvc("test", "ns1", nil, nil),
+			position: 0,
+		},
+		{
+			name:     "namespace limit reached",
+			config:   createConcurrencyLimitsConfig(int32Ptr(2), int32Ptr(1)),
+			pods:     []*v1.Pod{createRunningImporterPod(running)},
+			pvc:      createPvc("test", "ns1", nil, nil),
+			position: 1,
+		},
+		{
+			name:     "namespace limit of another namespace reached",
+			config:   createConcurrencyLimitsConfig(int32Ptr(2), int32Ptr(1)),
+			pods:     []*v1.Pod{createRunningImporterPod(running)},
+			pvc:      createPvc("test", "ns2", nil, nil),
+			position: 0,
+		},
+		{
+			name:     "older pvc first",
+			config:   createConcurrencyLimitsConfig(int32Ptr(1), nil),
+			pvcs:     []*v1.PersistentVolumeClaim{createQueuedPvc("older", "ns1", now.Add(-time.Minute), nil)},
+			pvc:      createQueuedPvc("test", "ns1", now, nil),
+			position: 2,
+		},
+		{
+			name:   "higher priority first",
+			config: createConcurrencyLimitsConfig(int32Ptr(1), nil),
+			pvcs: []*v1.PersistentVolumeClaim{
+				createQueuedPvc("older", "ns1", now.Add(-time.Minute), nil),
+			},
+			pvc:      createQueuedPvc("test", "ns1", now, map[string]string{AnnQueuePriority: "10"}),
+			position: 0,
+		},
+		{
+			name:   "queued pvc blocked by its namespace limit",
+			config: createConcurrencyLimitsConfig(int32Ptr(2), int32Ptr(1)),
+			pvcs: []*v1.PersistentVolumeClaim{
+				createQueuedPvc("older", "ns1", now.Add(-time.Minute), nil),
+			},
+			pods:     []*v1.Pod{createRunningImporterPod(running)},
+			pvc:      createQueuedPvc("test", "ns2", now, nil),
+			position: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := createWorkerScheduler(append(test.pvcs, test.pvc), test.pods, nil)
+			position, err := s.admit(test.pvc, test.config)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if position != test.position {
+				t.Errorf("Expected queue position %d, got %d", test.position, position)
+			}
+		})
+	}
+}
+
+func TestWorkerSchedulerSizeProbes(t *testing.T) {
+	now := time.Now()
+	config := createConcurrencyLimitsConfig(int32Ptr(1), nil)
+
+	probing := newSizeProbeDataVolume("probing")
+	probing.Namespace = "ns1"
+	probePod := newSizeProbePod(probing, v1.PodRunning, nil)
+	probePod.Labels = map[string]string{common.CDIComponentLabel: common.SizeProbePodName}
+	s := createWorkerScheduler(nil, []*v1.Pod{probePod}, nil)
+	if position, err := s.admit(createPvc("test", "ns2", nil, nil), config); err != nil || position != 1 {
+		t.Errorf("Expected the running size probe pod to take the slot, got position %d, error %v", position, err)
+	}
+	// the PVC created once the size is known is populated by the next worker pod of the data volume
+	if position, err := s.admit(createPvc("probing", "ns1", nil, nil), config); err != nil || position != 0 {
+		t.Errorf("Expected the pvc of the probed data volume to be admitted, got position %d, error %v", position, err)
+	}
+
+	queued := newSizeProbeDataVolume("queued")
+	queued.Namespace = "ns1"
+	queued.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+	queued.Status.Phase = cdiv1.Queued
+	s = createWorkerScheduler([]*v1.PersistentVolumeClaim{createQueuedPvc("newer", "ns1", now, nil)}, nil, []*cdiv1.DataVolume{queued})
+	if position, err := s.admit(createQueuedPvc("newer", "ns1", now, nil), config); err != nil || position != 2 {
+		t.Errorf("Expected the older data volume queued for its size probe first, got position %d, error %v", position, err)
+	}
+	if position, err := s.admit(sizeProbeQueueEntry(queued), config); err != nil || position != 0 {
+		t.Errorf("Expected the size probe of the older data volume to be admitted, got position %d, error %v", position, err)
+	}
+}
+
+func TestWorkerSchedulerCountsAdmittedPvcs(t *testing.T) {
+	first := createPvc("first", "ns1", nil, nil)
+	second := createPvc("second", "ns1", nil, nil)
+	config := createConcurrencyLimitsConfig(int32Ptr(1), nil)
+	s := createWorkerScheduler([]*v1.PersistentVolumeClaim{first, second}, nil, nil)
+
+	if position, err := s.admit(first, config); err != nil || position != 0 {
+		t.Fatalf("Expected first pvc to be admitted, got position %d, error %v", position, err)
+	}
+	// the worker pod of the first pvc is not in the cache yet
+	if position, err := s.admit(second, config); err != nil || position != 1 {
+		t.Errorf("Expected second pvc to be queued at position 1, got position %d, error %v", position, err)
+	}
+	// the slot is released if the pod never shows up
+	s.admitted["ns1/first"] = time.Now().Add(-2 * admittedPodTimeout)
+	if position, err := s.admit(second, config); err != nil || position != 0 {
+		t.Errorf("Expected second pvc to be admitted, got position %d, error %v", position, err)
+	}
+}
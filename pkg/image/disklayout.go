@@ -0,0 +1,222 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PartitionTableNone is reported for disks without partition table
+	PartitionTableNone = "none"
+	// PartitionTableMBR is reported for disks with a MBR partition table
+	PartitionTableMBR = "mbr"
+	// PartitionTableGPT is reported for disks with a GUID partition table
+	PartitionTableGPT = "gpt"
+
+	mbrPartitionOffset  = 446
+	mbrPartitionCount   = 4
+	mbrTypeGPTProtect   = 0xee
+	mbrTypeEFISystem    = 0xef
+	gptMaxEntries       = 128
+	gptMaxEntrySize     = 4096
+	fsSignatureSize     = 4096
+	extSuperblockOffset = 1024
+	extMagic            = 0xef53
+)
+
+// the on disk encoding of the EFI system partition type GUID C12A7328-F81F-11D2-BA4B-00A0C93EC93B
+var gptTypeEFISystem = []byte{0x28, 0x73, 0x2a, 0xc1, 0x1f, 0xf8, 0xd2, 0x11, 0xba, 0x4b, 0x00, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b}
+
+// DiskLayout describes the partitioning and file systems of a raw disk image.
+type DiskLayout struct {
+	// PartitionTable is the type of the partition table, none, mbr or gpt
+	PartitionTable string `json:"partitionTable"`
+	// EFISystemPartition is true if the disk contains an EFI system partition, so it boots with UEFI
+	EFISystemPartition bool `json:"efiSystemPartition,omitempty"`
+	// Filesystems are the recognized file systems, in partition order
+	Filesystems []string `json:"filesystems,omitempty"`
+}
+
+// diskPartition is the type and start of a partition.
+type diskPartition struct {
+	start int64
+	efi   bool
+}
+
+// InspectDiskLayout detects the partition table of the raw disk image in r, and the file systems of its partitions.
+// Only the partition tables and the first sectors of every partition are read. The logical partitions of extended MBR
+// partitions are not inspected.
+func InspectDiskLayout(r io.ReaderAt) (*DiskLayout, error) {
+	layout := &DiskLayout{PartitionTable: PartitionTableNone}
+	partitions, err := gptPartitions(r)
+	if err != nil {
+		return nil, err
+	}
+	if partitions != nil {
+		layout.PartitionTable = PartitionTableGPT
+	} else {
+		fs, err := detectFilesystem(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		if fs != "" {
+			// a file system on the whole disk, the boot sector of vfat and ntfs looks like a MBR
+			layout.Filesystems = []string{fs}
+			return layout, nil
+		}
+		if partitions, err = mbrPartitions(r); err != nil {
+			return nil, err
+		}
+		if partitions != nil {
+			layout.PartitionTable = PartitionTableMBR
+		}
+	}
+	for _, p := range partitions {
+		layout.EFISystemPartition = layout.EFISystemPartition || p.efi
+		fs, err := detectFilesystem(r, p.start)
+		if err != nil {
+			return nil, err
+		}
+		if fs != "" {
+			layout.Filesystems = append(layout.Filesystems, fs)
+		}
+	}
+	return layout, nil
+}
+
+// readAt reads len(buf) bytes at off, a read past the end of the image is not an error, the rest of buf is zeroed.
+func readAt(r io.ReaderAt, buf []byte, off int64) error {
+	n, err := r.ReadAt(buf, off)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return errors.Wrap(err, "unable to read disk image")
+	}
+	for i := n; i < len(buf); i++ {
+		buf[i] = 0
+	}
+	return nil
+}
+
+// gptPartitions returns the partitions of the GUID partition table, or nil if there is none. Both 512 byte and 4KiB
+// logical sectors are checked for the header.
+func gptPartitions(r io.ReaderAt) ([]diskPartition, error) {
+	le := binary.LittleEndian
+	header := make([]byte, 512)
+	for _, sectorSize := range []int64{512, 4096} {
+		if err := readAt(r, header, sectorSize); err != nil {
+			return nil, err
+		}
+		if string(header[:8]) != "EFI PART" {
+			continue
+		}
+		entriesLBA := int64(le.Uint64(header[72:]))
+		count := le.Uint32(header[80:])
+		entrySize := le.Uint32(header[84:])
+		if entrySize < 128 || entrySize > gptMaxEntrySize {
+			return nil, errors.Errorf("invalid GPT partition entry size %d", entrySize)
+		}
+		if count > gptMaxEntries {
+			count = gptMaxEntries
+		}
+		entries := make([]byte, int64(count)*int64(entrySize))
+		if err := readAt(r, entries, entriesLBA*sectorSize); err != nil {
+			return nil, err
+		}
+		partitions := []diskPartition{}
+		for i := uint32(0); i < count; i++ {
+			entry := entries[i*entrySize : (i+1)*entrySize]
+			if bytes.Equal(entry[:16], make([]byte, 16)) {
+				continue
+			}
+			partitions = append(partitions, diskPartition{
+				start: int64(le.Uint64(entry[32:])) * sectorSize,
+				efi:   bytes.Equal(entry[:16], gptTypeEFISystem),
+			})
+		}
+		return partitions, nil
+	}
+	return nil, nil
+}
+
+// mbrPartitions returns the primary partitions of the MBR partition table, or nil if there is none.
+func mbrPartitions(r io.ReaderAt) ([]diskPartition, error) {
+	sector := make([]byte, 512)
+	if err := readAt(r, sector, 0); err != nil {
+		return nil, err
+	}
+	if sector[510] != 0x55 || sector[511] != 0xaa {
+		return nil, nil
+	}
+	partitions := []diskPartition{}
+	for i := 0; i < mbrPartitionCount; i++ {
+		entry := sector[mbrPartitionOffset+16*i : mbrPartitionOffset+16*(i+1)]
+		if entry[0] != 0 && entry[0] != 0x80 {
+			// not a boot indicator, so this is no partition table
+			return nil, nil
+		}
+		partitionType := entry[4]
+		switch partitionType {
+		case 0:
+			continue
+		case 0x05, 0x0f, 0x85:
+			// extended partitions only hold logical partitions
+			continue
+		case mbrTypeGPTProtect:
+			// a protective MBR without valid GPT header
+			continue
+		}
+		partitions = append(partitions, diskPartition{
+			start: int64(binary.LittleEndian.Uint32(entry[8:])) * 512,
+			efi:   partitionType == mbrTypeEFISystem,
+		})
+	}
+	return partitions, nil
+}
+
+// detectFilesystem returns the name of the file system starting at off, or an empty string if it is not recognized.
+func detectFilesystem(r io.ReaderAt, off int64) (string, error) {
+	buf := make([]byte, fsSignatureSize)
+	if err := readAt(r, buf, off); err != nil {
+		return "", err
+	}
+	le := binary.LittleEndian
+	switch {
+	case string(buf[:4]) == "XFSB":
+		return "xfs", nil
+	case string(buf[3:11]) == "NTFS    ":
+		return "ntfs", nil
+	case buf[510] == 0x55 && buf[511] == 0xaa && (string(buf[82:87]) == "FAT32" || string(buf[54:57]) == "FAT"):
+		return "vfat", nil
+	case le.Uint16(buf[extSuperblockOffset+56:]) == extMagic:
+		compat := le.Uint32(buf[extSuperblockOffset+92:])
+		incompat := le.Uint32(buf[extSuperblockOffset+96:])
+		// extents, 64bit or flex_bg
+		if incompat&(0x40|0x80|0x200) != 0 {
+			return "ext4", nil
+		}
+		// has_journal
+		if compat&0x4 != 0 {
+			return "ext3", nil
+		}
+		return "ext2", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+const testDiskSize = 8 << 20
+
+var testGPTTypeLinux = []byte{0xaf, 0x3d, 0xc6, 0x0f, 0x83, 0x84, 0x72, 0x47, 0x8e, 0x79, 0x3d, 0x69, 0xd8, 0x47, 0x7d, 0xe4}
+
+// writeFilesystem writes the signature of a file system starting at off.
+func writeFilesystem(disk []byte, off int64, fs string) {
+	b := disk[off:]
+	le := binary.LittleEndian
+	switch fs {
+	case "xfs":
+		copy(b, "XFSB")
+	case "ntfs":
+		copy(b[3:], "NTFS    ")
+		b[510], b[511] = 0x55, 0xaa
+	case "vfat":
+		copy(b[82:], "FAT32   ")
+		b[510], b[511] = 0x55, 0xaa
+	case "ext4":
+		le.PutUint16(b[extSuperblockOffset+56:], extMagic)
+		le.PutUint32(b[extSuperblockOffset+96:], 0x40)
+	case "ext2":
+		le.PutUint16(b[extSuperblockOffset+56:], extMagic)
+	}
+}
+
+func writeMBR(disk []byte, partitions ...[2]int64) {
+	for i, p := range partitions {
+		entry := disk[mbrPartitionOffset+16*i:]
+		entry[4] = byte(p[0])
+		binary.LittleEndian.PutUint32(entry[8:], uint32(p[1]/512))
+	}
+	disk[510], disk[511] = 0x55, 0xaa
+}
+
+func writeGPT(disk []byte, sectorSize int64, partitions map[int64][]byte) {
+	le := binary.LittleEndian
+	writeMBR(disk, [2]int64{mbrTypeGPTProtect, sectorSize})
+	header := disk[sectorSize:]
+	copy(header, "EFI PART")
+	le.PutUint64(header[72:], 2)
+	le.PutUint32(header[80:], 128)
+	le.PutUint32(header[84:], 128)
+	i := int64(0)
+	for _, start := range []int64{1 << 20, 3 << 20} {
+		partitionType, ok := partitions[start]
+		if !ok {
+			continue
+		}
+		entry := disk[2*sectorSize+i*128:]
+		copy(entry, partitionType)
+		le.PutUint64(entry[32:], uint64(start/sectorSize))
+		i++
+	}
+}
+
+var _ = Describe("Disk layout", func() {
+	table.DescribeTable("should be detected", func(build func([]byte), expected *DiskLayout) {
+		disk := make([]byte, testDiskSize)
+		build(disk)
+		layout, err := InspectDiskLayout(bytes.NewReader(disk))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(layout).To(Equal(expected))
+	},
+		table.Entry("on an empty disk", func(disk []byte) {},
+			&DiskLayout{PartitionTable: PartitionTableNone}),
+		table.Entry("with a file system on the whole disk", func(disk []byte) {
+			writeFilesystem(disk, 0, "vfat")
+		}, &DiskLayout{PartitionTable: PartitionTableNone, Filesystems: []string{"vfat"}}),
+		table.Entry("with a MBR partition table", func(disk []byte) {
+			writeMBR(disk, [2]int64{0x83, 1 << 20}, [2]int64{0x07, 3 << 20})
+			writeFilesystem(disk, 1<<20, "ext2")
+			writeFilesystem(disk, 3<<20, "ntfs")
+		}, &DiskLayout{PartitionTable: PartitionTableMBR, Filesystems: []string{"ext2", "ntfs"}}),
+		table.Entry("with an EFI system partition in a MBR partition table", func(disk []byte) {
+			writeMBR(disk, [2]int64{mbrTypeEFISystem, 1 << 20})
+			writeFilesystem(disk, 1<<20, "vfat")
+		}, &DiskLayout{PartitionTable: PartitionTableMBR, EFISystemPartition: true, Filesystems: []string{"vfat"}}),
+		table.Entry("with a GUID partition table", func(disk []byte) {
+			writeGPT(disk, 512, map[int64][]byte{1 << 20: gptTypeEFISystem, 3 << 20: testGPTTypeLinux})
+			writeFilesystem(disk, 1<<20, "vfat")
+			writeFilesystem(disk, 3<<20, "xfs")
+		}, &DiskLayout{PartitionTable: PartitionTableGPT, EFISystemPartition: true, Filesystems: []string{"vfat", "xfs"}}),
+		table.Entry("with a GUID partition table of 4KiB sectors", func(disk []byte) {
+			writeGPT(disk, 4096, map[int64][]byte{1 << 20: testGPTTypeLinux})
+			writeFilesystem(disk, 1<<20, "ext4")
+		}, &DiskLayout{PartitionTable: PartitionTableGPT, Filesystems: []string{"ext4"}}),
+	)
+
+	It("should fail on an invalid GPT entry size", func() {
+		disk := make([]byte, testDiskSize)
+		writeGPT(disk, 512, nil)
+		binary.LittleEndian.PutUint32(disk[512+84:], 16)
+		_, err := InspectDiskLayout(bytes.NewReader(disk))
+		Expect(err).To(HaveOccurred())
+	})
+})
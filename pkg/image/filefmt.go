@@ -46,6 +46,20 @@ var knownHeaders = Headers{
 		SizeOff: 0,
 		SizeLen: 0,
 	},
+	"zip": Header{
+		Format:      "zip",
+		magicNumber: []byte{0x50, 0x4B, 0x03, 0x04},
+		// size of the first entry only
+		SizeOff: 0,
+		SizeLen: 0,
+	},
+	"zst": Header{
+		Format:      "zst",
+		magicNumber: []byte{0x28, 0xB5, 0x2F, 0xFD},
+		// TODO: size not in hdr
+		SizeOff: 0,
+		SizeLen: 0,
+	},
 }
 
 // Header represents our parameters for a file format header
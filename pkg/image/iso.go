@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	isoSectorSize = 2048
+	// the volume recognition sequence starts after the 32KiB system area
+	isoFirstDescriptorSector = 16
+	// upper bound of the descriptors read, real images have a handful
+	isoMaxDescriptors        = 64
+	isoPrimaryDescriptor     = 1
+	isoPrimaryVolumeIDOffset = 40
+	isoPrimaryVolumeIDLength = 32
+
+	// the anchor volume descriptor pointer of UDF is found at sector 256
+	udfAnchorSector            = 256
+	udfTagAnchor               = 2
+	udfTagLogicalVolume        = 6
+	udfTagTerminating          = 8
+	udfLogicalVolumeIDOffset   = 84
+	udfLogicalVolumeIDLength   = 128
+	udfMaxVolumeDescriptorSize = 1024 * isoSectorSize
+)
+
+// ErrNotISO is returned if an image does not contain an ISO 9660 or UDF file system.
+var ErrNotISO = errors.New("image is not an ISO 9660 or UDF image")
+
+// ISOVolumeLabel checks that r contains an ISO 9660 or UDF image and returns its volume label. The label of the ISO
+// 9660 primary volume descriptor is preferred, as that is what most tools show for hybrid images. ErrNotISO is returned
+// if no volume descriptor is found.
+func ISOVolumeLabel(r io.ReaderAt) (string, error) {
+	sector := make([]byte, isoSectorSize)
+	isISO9660, isUDF := false, false
+	label := ""
+descriptors:
+	for i := int64(isoFirstDescriptorSector); i < isoFirstDescriptorSector+isoMaxDescriptors; i++ {
+		if _, err := r.ReadAt(sector, i*isoSectorSize); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return "", errors.Wrap(err, "unable to read volume descriptor")
+		}
+		switch string(sector[1:6]) {
+		case "CD001":
+			isISO9660 = true
+			if sector[0] == isoPrimaryDescriptor && label == "" {
+				label = strings.TrimRight(string(sector[isoPrimaryVolumeIDOffset:isoPrimaryVolumeIDOffset+isoPrimaryVolumeIDLength]), " \x00")
+			}
+		case "NSR02", "NSR03":
+			isUDF = true
+		case "BEA01", "TEA01", "BOOT2", "CDW02":
+		default:
+			// end of the volume recognition sequence
+			break descriptors
+		}
+	}
+	if !isISO9660 && !isUDF {
+		return "", ErrNotISO
+	}
+	if label == "" && isUDF {
+		return udfVolumeLabel(r)
+	}
+	return label, nil
+}
+
+// udfVolumeLabel returns the logical volume identifier found in the main volume descriptor sequence.
+func udfVolumeLabel(r io.ReaderAt) (string, error) {
+	le := binary.LittleEndian
+	sector := make([]byte, isoSectorSize)
+	if _, err := r.ReadAt(sector, udfAnchorSector*isoSectorSize); err != nil {
+		return "", errors.Wrap(err, "unable to read UDF anchor volume descriptor pointer")
+	}
+	if le.Uint16(sector) != udfTagAnchor {
+		return "", errors.New("UDF anchor volume descriptor pointer not found")
+	}
+	length := int64(le.Uint32(sector[16:]))
+	location := int64(le.Uint32(sector[20:]))
+	if length > udfMaxVolumeDescriptorSize {
+		length = udfMaxVolumeDescriptorSize
+	}
+	for i := int64(0); i < length/isoSectorSize; i++ {
+		if _, err := r.ReadAt(sector, (location+i)*isoSectorSize); err != nil {
+			return "", errors.Wrap(err, "unable to read UDF volume descriptor")
+		}
+		switch le.Uint16(sector) {
+		case udfTagLogicalVolume:
+			return decodeUDFString(sector[udfLogicalVolumeIDOffset : udfLogicalVolumeIDOffset+udfLogicalVolumeIDLength]), nil
+		case udfTagTerminating:
+			return "", nil
+		}
+	}
+	return "", nil
+}
+
+// decodeUDFString decodes a dstring, in which the first byte is the compression id of the characters and the last byte
+// the length of the used part.
+func decodeUDFString(d []byte) string {
+	length := int(d[len(d)-1])
+	if length == 0 || length > len(d)-1 {
+		return ""
+	}
+	d = d[:length]
+	switch d[0] {
+	case 8:
+		runes := make([]rune, 0, len(d)-1)
+		for _, b := range d[1:] {
+			runes = append(runes, rune(b))
+		}
+		return string(runes)
+	case 16:
+		chars := make([]uint16, 0, (len(d)-1)/2)
+		for i := 1; i+1 < len(d); i += 2 {
+			chars = append(chars, binary.BigEndian.Uint16(d[i:]))
+		}
+		return string(utf16.Decode(chars))
+	}
+	return ""
+}
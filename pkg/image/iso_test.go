@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// testISO builds the volume descriptors of an image, the file systems themselves are left out.
+type testISO struct {
+	image []byte
+	next  int64
+}
+
+func newTestISO() *testISO {
+	return &testISO{
+		image: make([]byte, 300*isoSectorSize),
+		next:  isoFirstDescriptorSector,
+	}
+}
+
+func (t *testISO) addDescriptor(descriptorType byte, id string) []byte {
+	sector := t.image[t.next*isoSectorSize : (t.next+1)*isoSectorSize]
+	sector[0] = descriptorType
+	copy(sector[1:], id)
+	sector[6] = 1
+	t.next++
+	return sector
+}
+
+func (t *testISO) addISO9660(label string) {
+	pvd := t.addDescriptor(isoPrimaryDescriptor, "CD001")
+	copy(pvd[isoPrimaryVolumeIDOffset:], bytes.Repeat([]byte{' '}, isoPrimaryVolumeIDLength))
+	copy(pvd[isoPrimaryVolumeIDOffset:], label)
+	t.addDescriptor(255, "CD001")
+}
+
+func (t *testISO) addUDF(label string) {
+	t.addDescriptor(0, "BEA01")
+	t.addDescriptor(0, "NSR03")
+	t.addDescriptor(0, "TEA01")
+
+	le := binary.LittleEndian
+	vdsLocation := int64(udfAnchorSector + 1)
+	anchor := t.image[udfAnchorSector*isoSectorSize:]
+	le.PutUint16(anchor, udfTagAnchor)
+	le.PutUint32(anchor[16:], 3*isoSectorSize)
+	le.PutUint32(anchor[20:], uint32(vdsLocation))
+
+	// a primary volume descriptor, followed by the logical volume and the terminating descriptor
+	le.PutUint16(t.image[vdsLocation*isoSectorSize:], 1)
+	lvd := t.image[(vdsLocation+1)*isoSectorSize:]
+	le.PutUint16(lvd, udfTagLogicalVolume)
+	id := lvd[udfLogicalVolumeIDOffset : udfLogicalVolumeIDOffset+udfLogicalVolumeIDLength]
+	id[0] = 16
+	chars := utf16.Encode([]rune(label))
+	for i, c := range chars {
+		binary.BigEndian.PutUint16(id[1+2*i:], c)
+	}
+	id[len(id)-1] = byte(1 + 2*len(chars))
+	le.PutUint16(t.image[(vdsLocation+2)*isoSectorSize:], udfTagTerminating)
+}
+
+var _ = Describe("ISO volume label", func() {
+	table.DescribeTable("should be read", func(build func(*testISO), expected string) {
+		iso := newTestISO()
+		build(iso)
+		label, err := ISOVolumeLabel(bytes.NewReader(iso.image))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(label).To(Equal(expected))
+	},
+		table.Entry("from an ISO 9660 image", func(iso *testISO) {
+			iso.addISO9660("CENTOS 7 X86_64")
+		}, "CENTOS 7 X86_64"),
+		table.Entry("from a UDF image", func(iso *testISO) {
+			iso.addUDF("Windows Ünicode")
+		}, "Windows Ünicode"),
+		table.Entry("from the ISO 9660 descriptor of a hybrid image", func(iso *testISO) {
+			iso.addISO9660("HYBRID")
+			iso.addUDF("Hybrid UDF")
+		}, "HYBRID"),
+		table.Entry("from the UDF descriptor of a hybrid image without ISO 9660 label", func(iso *testISO) {
+			iso.addISO9660("")
+			iso.addUDF("Hybrid UDF")
+		}, "Hybrid UDF"),
+	)
+
+	It("should fail on an image without volume descriptors", func() {
+		_, err := ISOVolumeLabel(bytes.NewReader(make([]byte, 300*isoSectorSize)))
+		Expect(err).To(Equal(ErrNotISO))
+	})
+
+	It("should fail on an image smaller than the system area", func() {
+		_, err := ISOVolumeLabel(bytes.NewReader(make([]byte, 1024)))
+		Expect(err).To(Equal(ErrNotISO))
+	})
+})
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"fmt"
+	"io"
+
+	"kubevirt.io/containerized-data-importer/pkg/system"
+)
+
+// The rules of the validation policy, reported in ValidationError.
+const (
+	// RuleMaxVirtualSize rejects images with a virtual size above the maximum
+	RuleMaxVirtualSize = "MaxVirtualSize"
+	// RuleAllowedFormats rejects images with a format that is not allowed
+	RuleAllowedFormats = "AllowedFormats"
+	// RuleDeniedFormats rejects images with a denied format
+	RuleDeniedFormats = "DeniedFormats"
+	// RuleRejectEncrypted rejects encrypted images
+	RuleRejectEncrypted = "RejectEncrypted"
+	// RuleRejectCompressed rejects qcow2 images with compressed clusters
+	RuleRejectCompressed = "RejectCompressed"
+)
+
+// ValidationPolicy defines which images are accepted.
+type ValidationPolicy struct {
+	// MaxVirtualSize is the largest accepted virtual size in bytes, 0 means no limit
+	MaxVirtualSize int64
+	// AllowedFormats are the accepted formats, raw and qcow2 if empty
+	AllowedFormats []string
+	// DeniedFormats are the rejected formats, they take precedence over AllowedFormats
+	DeniedFormats []string
+	// RejectEncrypted rejects encrypted images
+	RejectEncrypted bool
+	// RejectCompressed rejects qcow2 images with compressed clusters
+	RejectCompressed bool
+	// InfoMemoryLimit is the address space limit of qemu-img when inspecting images
+	InfoMemoryLimit uint64
+	// InfoCPUTimeLimit is the CPU time limit in seconds of qemu-img when inspecting images
+	InfoCPUTimeLimit uint64
+}
+
+// ValidationError is returned when an image violates a rule of the validation policy.
+type ValidationError struct {
+	// Rule is the violated rule
+	Rule string
+	// Message describes the violation
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+var (
+	defaultAllowedFormats = []string{"raw", "qcow2"}
+	validationPolicy      = DefaultValidationPolicy()
+)
+
+// DefaultValidationPolicy returns the policy used if none is configured.
+func DefaultValidationPolicy() *ValidationPolicy {
+	return &ValidationPolicy{
+		InfoMemoryLimit:  maxMemory,
+		InfoCPUTimeLimit: maxCPUSecs,
+	}
+}
+
+// SetValidationPolicy replaces the policy images are validated against, including the limits of qemu-img info.
+func SetValidationPolicy(policy *ValidationPolicy) {
+	validationPolicy = policy
+	qemuInfoLimits = &system.ProcessLimitValues{AddressSpaceLimit: policy.InfoMemoryLimit, CPUTimeLimit: policy.InfoCPUTimeLimit}
+}
+
+func containsFormat(formats []string, format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ValidationPolicy) checkFormat(format, image string) error {
+	if containsFormat(p.DeniedFormats, format) {
+		return &ValidationError{Rule: RuleDeniedFormats, Message: fmt.Sprintf("Format %s of image %s is denied", format, image)}
+	}
+	allowed := p.AllowedFormats
+	if len(allowed) == 0 {
+		allowed = defaultAllowedFormats
+	}
+	if !containsFormat(allowed, format) {
+		return &ValidationError{Rule: RuleAllowedFormats, Message: fmt.Sprintf("Invalid format %s for image %s", format, image)}
+	}
+	return nil
+}
+
+func (p *ValidationPolicy) checkVirtualSize(size int64, image string) error {
+	if p.MaxVirtualSize > 0 && size > p.MaxVirtualSize {
+		return &ValidationError{
+			Rule:    RuleMaxVirtualSize,
+			Message: fmt.Sprintf("Virtual size %d of image %s is larger than the maximum %d", size, image, p.MaxVirtualSize),
+		}
+	}
+	return nil
+}
+
+func (p *ValidationPolicy) checkEncrypted(encrypted bool, image string) error {
+	if p.RejectEncrypted && encrypted {
+		return &ValidationError{Rule: RuleRejectEncrypted, Message: fmt.Sprintf("Image %s is encrypted", image)}
+	}
+	return nil
+}
+
+func (p *ValidationPolicy) checkCompressed(compressed bool, image string) error {
+	if p.RejectCompressed && compressed {
+		return &ValidationError{Rule: RuleRejectCompressed, Message: fmt.Sprintf("Image %s has compressed clusters", image)}
+	}
+	return nil
+}
+
+// rawValidationReader fails once more data than the maximum virtual size is read.
+type rawValidationReader struct {
+	r      io.Reader
+	policy *ValidationPolicy
+	read   int64
+}
+
+// NewRawValidationReader validates a raw image that is written as is, without qemu-img inspecting it. It fails
+// right away if raw images are not accepted, and the returned reader fails once the image exceeds the maximum size.
+func NewRawValidationReader(r io.Reader) (io.Reader, error) {
+	if err := validationPolicy.checkFormat("raw", "stream"); err != nil {
+		return nil, err
+	}
+	return &rawValidationReader{r: r, policy: validationPolicy}, nil
+}
+
+func (r *rawValidationReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if sizeErr := r.policy.checkVirtualSize(r.read, "stream"); sizeErr != nil {
+		return n, sizeErr
+	}
+	return n, err
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	"kubevirt.io/containerized-data-importer/pkg/system"
+)
+
+const encryptedValidateJSON = `
+{
+    "virtual-size": 4294967296,
+    "filename": "myimage.qcow2",
+    "format": "qcow2",
+    "encrypted": true
+}
+`
+
+const compressedCheckJSON = `
+{
+    "image-end-offset": 262144,
+    "total-clusters": 65536,
+    "allocated-clusters": 4,
+    "fragmented-clusters": 0,
+    "compressed-clusters": 3,
+    "filename": "myimage.qcow2",
+    "format": "qcow2",
+    "check-errors": 0
+}
+`
+
+// mockInfoAndCheck returns the info output for qemu-img info and the check output for qemu-img check.
+func mockInfoAndCheck(info, check string) execFunctionType {
+	return func(limits *system.ProcessLimitValues, f func(string), cmd string, args ...string) ([]byte, error) {
+		if args[0] == "check" {
+			return []byte(check), nil
+		}
+		return []byte(info), nil
+	}
+}
+
+func expectValidationError(err error, rule string) {
+	Expect(err).To(HaveOccurred())
+	validationErr, ok := errors.Cause(err).(*ValidationError)
+	Expect(ok).To(BeTrue(), "unexpected error %v", err)
+	Expect(validationErr.Rule).To(Equal(rule))
+}
+
+const testClusterSize = int64(1) << testClusterBits
+
+var _ = Describe("Validation policy", func() {
+	imageName, _ := url.Parse("myimage.qcow2")
+
+	AfterEach(func() {
+		SetValidationPolicy(DefaultValidationPolicy())
+	})
+
+	It("should set the qemu-img info limits", func() {
+		policy := DefaultValidationPolicy()
+		policy.InfoMemoryLimit = 2 << 30
+		policy.InfoCPUTimeLimit = 60
+		SetValidationPolicy(policy)
+		Expect(qemuInfoLimits).To(Equal(&system.ProcessLimitValues{AddressSpaceLimit: 2 << 30, CPUTimeLimit: 60}))
+	})
+
+	table.DescribeTable("Validate should reject", func(policy *ValidationPolicy, exec execFunctionType, rule string) {
+		SetValidationPolicy(policy)
+		replaceExecFunction(exec, func() {
+			expectValidationError(Validate(imageName, 42949672960), rule)
+		})
+	},
+		table.Entry("a format that is not allowed", &ValidationPolicy{AllowedFormats: []string{"raw"}},
+			mockInfoAndCheck(goodValidateJSON, ""), RuleAllowedFormats),
+		table.Entry("a denied format", &ValidationPolicy{DeniedFormats: []string{"qcow2"}},
+			mockInfoAndCheck(goodValidateJSON, ""), RuleDeniedFormats),
+		table.Entry("an image larger than the maximum size", &ValidationPolicy{MaxVirtualSize: 1 << 30},
+			mockInfoAndCheck(goodValidateJSON, ""), RuleMaxVirtualSize),
+		table.Entry("an encrypted image", &ValidationPolicy{RejectEncrypted: true},
+			mockInfoAndCheck(encryptedValidateJSON, ""), RuleRejectEncrypted),
+		table.Entry("an image with compressed clusters", &ValidationPolicy{RejectCompressed: true},
+			mockInfoAndCheck(goodValidateJSON, compressedCheckJSON), RuleRejectCompressed),
+	)
+
+	It("Validate should accept a format that is allowed by the policy", func() {
+		SetValidationPolicy(&ValidationPolicy{AllowedFormats: []string{"raw2"}, MaxVirtualSize: 4294967296})
+		replaceExecFunction(mockInfoAndCheck(badFormatValidateJSON, ""), func() {
+			Expect(Validate(imageName, 42949672960)).To(Succeed())
+		})
+	})
+
+	Context("with qcow2 streams", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "policy")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		It("should reject compressed clusters", func() {
+			q := newTestQcow2(testClusterSize)
+			q.addL2(0)
+			q.addCompressed(0, testCluster(1))
+			SetValidationPolicy(&ValidationPolicy{RejectCompressed: true})
+			err := ConvertQcow2Stream(bytes.NewReader(q.bytes()), filepath.Join(tmpDir, "disk.img"), testClusterSize)
+			expectValidationError(err, RuleRejectCompressed)
+		})
+
+		It("should reject images larger than the maximum size", func() {
+			q := newTestQcow2(4 * testClusterSize)
+			SetValidationPolicy(&ValidationPolicy{MaxVirtualSize: testClusterSize})
+			err := ConvertQcow2Stream(bytes.NewReader(q.bytes()), filepath.Join(tmpDir, "disk.img"), 4*testClusterSize)
+			expectValidationError(err, RuleMaxVirtualSize)
+		})
+	})
+
+	It("should reject raw streams if raw is not allowed", func() {
+		SetValidationPolicy(&ValidationPolicy{DeniedFormats: []string{"raw"}})
+		_, err := NewRawValidationReader(bytes.NewReader(nil))
+		expectValidationError(err, RuleDeniedFormats)
+	})
+
+	It("should reject raw streams larger than the maximum size", func() {
+		SetValidationPolicy(&ValidationPolicy{MaxVirtualSize: 1024})
+		r, err := NewRawValidationReader(bytes.NewReader(make([]byte, 2048)))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ioutil.ReadAll(r)
+		expectValidationError(err, RuleMaxVirtualSize)
+	})
+
+	It("should pass raw streams within the maximum size", func() {
+		SetValidationPolicy(&ValidationPolicy{MaxVirtualSize: 1024})
+		r, err := NewRawValidationReader(bytes.NewReader(make([]byte, 1024)))
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(1024))
+	})
+})
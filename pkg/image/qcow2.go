@@ -0,0 +1,582 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+const (
+	qcow2Magic          = 0x514649fb
+	qcow2V2HeaderLength = 72
+	qcow2V3HeaderLength = 104
+
+	// L1 and L2 table entry bits
+	qcow2FlagCompressed = uint64(1) << 62
+	qcow2FlagZero       = uint64(1)
+	qcow2OffsetMask     = uint64(0x00fffffffffffe00)
+
+	// incompatible feature bits
+	qcow2IncompatDirty       = uint64(1) << 0
+	qcow2IncompatCorrupt     = uint64(1) << 1
+	qcow2IncompatCompression = uint64(1) << 3
+)
+
+// ErrQcow2NotStreamable indicates that the clusters of a qcow2 image cannot be converted in a single pass, and the image
+// has to be staged in scratch space so qemu-img can read it.
+var ErrQcow2NotStreamable = errors.New("qcow2 image layout requires random access")
+
+// may be overridden in tests
+var qcow2StreamBufferLimit = int64(64 * 1024 * 1024)
+
+type qcow2Header struct {
+	version               uint32
+	backingFileOffset     uint64
+	clusterBits           uint32
+	size                  uint64
+	cryptMethod           uint32
+	l1Size                uint32
+	l1TableOffset         uint64
+	refcountTableOffset   uint64
+	refcountTableClusters uint32
+	incompatibleFeatures  uint64
+	compressionType       uint8
+}
+
+// qcow2CompressedRef is a compressed guest cluster, stored at length bytes starting at host offset.
+type qcow2CompressedRef struct {
+	host   int64
+	length int64
+	guest  int64
+}
+
+// qcow2Stream converts a qcow2 image to RAW while reading it front to back. Clusters are matched to guest offsets with
+// the L1 and L2 tables as those arrive. Clusters read before the table mapping them are held in memory up to
+// qcow2StreamBufferLimit, so only images whose metadata is far behind their data need scratch space.
+type qcow2Stream struct {
+	hdr         *qcow2Header
+	clusterSize int64
+	target      *os.File
+	// pos is the host offset of the next cluster read from the stream
+	pos int64
+	// fillZeros is set when unwritten guest clusters are not implicitly zero, like on block devices
+	fillZeros bool
+	written   []uint64
+
+	l1Data         []byte
+	l1Parsed       bool
+	refcountData   []byte
+	l2Tables       map[int64]int64
+	refcountBlocks map[int64]bool
+
+	// clusters which have been read, but may still be referenced by tables not read yet
+	buffered     map[int64][]byte
+	bufferedSize int64
+
+	dataRefs         map[int64][]int64
+	compressedByEnd  map[int64][]qcow2CompressedRef
+	compressedNeeded map[int64]int
+	lastCompressed   int64
+	decompressed     []byte
+}
+
+// ConvertQcow2Stream converts the qcow2 image read from r to a RAW image in dest, without seeking. It returns
+// ErrQcow2NotStreamable if the image uses features or a cluster layout that the stream cannot handle.
+func ConvertQcow2Stream(r io.Reader, dest string, availableSize int64) error {
+	hdr, err := readQcow2Header(r)
+	if err != nil {
+		return err
+	}
+	if err := validationPolicy.checkFormat("qcow2", "stream"); err != nil {
+		return err
+	}
+	if err := validationPolicy.checkEncrypted(hdr.cryptMethod != 0, "stream"); err != nil {
+		return err
+	}
+	if err := hdr.checkStreamable(); err != nil {
+		return err
+	}
+	if err := validationPolicy.checkVirtualSize(int64(hdr.size), "stream"); err != nil {
+		return err
+	}
+	if availableSize < int64(hdr.size) {
+		return errors.Errorf("Virtual image size %d is larger than available size %d, shrink not yet supported.", hdr.size, availableSize)
+	}
+	target, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return errors.Wrapf(err, "could not open file %q", dest)
+	}
+	defer target.Close()
+	info, err := target.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "could not stat file %q", dest)
+	}
+	klog.V(1).Infof("Converting qcow2 stream with virtual size %d and cluster size %d", hdr.size, int64(1)<<hdr.clusterBits)
+	if err := newQcow2Stream(hdr, target, !info.Mode().IsRegular()).convert(r); err != nil {
+		return err
+	}
+	return target.Sync()
+}
+
+func readQcow2Header(r io.Reader) (*qcow2Header, error) {
+	// The smallest cluster is 512 bytes, the header and its extensions always fit in the first cluster.
+	buf := make([]byte, 512)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, errors.Wrap(err, "unable to read qcow2 header")
+	}
+	hdr, err := parseQcow2Header(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, r, (int64(1)<<hdr.clusterBits)-int64(len(buf))); err != nil {
+		return nil, errors.Wrap(err, "unable to read qcow2 header")
+	}
+	return hdr, nil
+}
+
+func parseQcow2Header(buf []byte) (*qcow2Header, error) {
+	be := binary.BigEndian
+	if len(buf) < qcow2V2HeaderLength || be.Uint32(buf[0:4]) != qcow2Magic {
+		return nil, errors.New("not a qcow2 image")
+	}
+	hdr := &qcow2Header{
+		version:               be.Uint32(buf[4:8]),
+		backingFileOffset:     be.Uint64(buf[8:16]),
+		clusterBits:           be.Uint32(buf[20:24]),
+		size:                  be.Uint64(buf[24:32]),
+		cryptMethod:           be.Uint32(buf[32:36]),
+		l1Size:                be.Uint32(buf[36:40]),
+		l1TableOffset:         be.Uint64(buf[40:48]),
+		refcountTableOffset:   be.Uint64(buf[48:56]),
+		refcountTableClusters: be.Uint32(buf[56:60]),
+	}
+	switch hdr.version {
+	case 2:
+	case 3:
+		if len(buf) < qcow2V3HeaderLength {
+			return nil, errors.New("qcow2 header is truncated")
+		}
+		hdr.incompatibleFeatures = be.Uint64(buf[72:80])
+		if headerLength := be.Uint32(buf[100:104]); headerLength > qcow2V3HeaderLength && len(buf) > qcow2V3HeaderLength {
+			hdr.compressionType = buf[qcow2V3HeaderLength]
+		}
+	default:
+		return nil, errors.Errorf("unsupported qcow2 version %d", hdr.version)
+	}
+	if hdr.clusterBits < 9 || hdr.clusterBits > 21 {
+		return nil, errors.Errorf("invalid qcow2 cluster bits %d", hdr.clusterBits)
+	}
+	return hdr, nil
+}
+
+// checkStreamable errors on images which are invalid, or need qemu-img for the conversion.
+func (hdr *qcow2Header) checkStreamable() error {
+	if hdr.backingFileOffset != 0 {
+		return errors.New("Image is invalid because it has a backing file")
+	}
+	if hdr.cryptMethod != 0 {
+		klog.V(1).Infof("qcow2 image is encrypted")
+		return ErrQcow2NotStreamable
+	}
+	if hdr.incompatibleFeatures&qcow2IncompatCorrupt != 0 {
+		return errors.New("qcow2 image is marked corrupt")
+	}
+	if hdr.incompatibleFeatures&^(qcow2IncompatDirty|qcow2IncompatCompression) != 0 {
+		klog.V(1).Infof("qcow2 image has unsupported incompatible features %#x", hdr.incompatibleFeatures)
+		return ErrQcow2NotStreamable
+	}
+	if hdr.incompatibleFeatures&qcow2IncompatCompression != 0 && hdr.compressionType != 0 {
+		klog.V(1).Infof("qcow2 image has unsupported compression type %d", hdr.compressionType)
+		return ErrQcow2NotStreamable
+	}
+	return nil
+}
+
+func newQcow2Stream(hdr *qcow2Header, target *os.File, fillZeros bool) *qcow2Stream {
+	clusterSize := int64(1) << hdr.clusterBits
+	s := &qcow2Stream{
+		hdr:              hdr,
+		clusterSize:      clusterSize,
+		target:           target,
+		pos:              clusterSize,
+		fillZeros:        fillZeros,
+		l1Parsed:         hdr.l1Size == 0,
+		l2Tables:         make(map[int64]int64),
+		refcountBlocks:   make(map[int64]bool),
+		buffered:         make(map[int64][]byte),
+		dataRefs:         make(map[int64][]int64),
+		compressedByEnd:  make(map[int64][]qcow2CompressedRef),
+		compressedNeeded: make(map[int64]int),
+		lastCompressed:   -1,
+		decompressed:     make([]byte, clusterSize),
+	}
+	if fillZeros {
+		s.written = make([]uint64, (s.guestClusters()+63)/64)
+	}
+	return s
+}
+
+// convert reads the clusters following the header from r, and writes the guest data to the target.
+func (s *qcow2Stream) convert(r io.Reader) error {
+	buf := make([]byte, s.clusterSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return errors.Wrap(err, "unable to read qcow2 stream")
+		}
+		for i := n; i < len(buf); i++ {
+			buf[i] = 0
+		}
+		off := s.pos
+		s.pos += s.clusterSize
+		retained, perr := s.processCluster(off, buf)
+		if perr != nil {
+			return perr
+		}
+		if retained {
+			buf = make([]byte, s.clusterSize)
+		}
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	return s.finish()
+}
+
+func (s *qcow2Stream) processCluster(off int64, buf []byte) (bool, error) {
+	l1Off, l1Len := int64(s.hdr.l1TableOffset), int64(s.hdr.l1Size)*8
+	refcountOff, refcountLen := int64(s.hdr.refcountTableOffset), int64(s.hdr.refcountTableClusters)*s.clusterSize
+	switch {
+	case !s.l1Parsed && off >= l1Off && off < l1Off+l1Len:
+		s.l1Data = append(s.l1Data, buf...)
+		if int64(len(s.l1Data)) >= l1Len {
+			return false, s.parseL1(s.l1Data[:l1Len])
+		}
+		return false, nil
+	case off >= refcountOff && off < refcountOff+refcountLen:
+		s.refcountData = append(s.refcountData, buf...)
+		if int64(len(s.refcountData)) >= refcountLen {
+			s.parseRefcountTable(s.refcountData)
+		}
+		return false, nil
+	}
+	if guestBase, ok := s.l2Tables[off]; ok {
+		delete(s.l2Tables, off)
+		return false, s.parseL2(buf, guestBase)
+	}
+	if s.refcountBlocks[off] {
+		delete(s.refcountBlocks, off)
+		return false, nil
+	}
+	if guests, ok := s.dataRefs[off]; ok {
+		delete(s.dataRefs, off)
+		for _, guest := range guests {
+			if err := s.writeGuest(buf, guest); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+	retained := false
+	if s.compressedNeeded[off] > 0 || !s.allL2Known() {
+		// Either compressed clusters are stored here, or a table that was not read yet may still point here.
+		s.buffered[off] = buf
+		s.bufferedSize += s.clusterSize
+		if s.bufferedSize > qcow2StreamBufferLimit {
+			klog.V(1).Infof("qcow2 stream buffer limit of %d bytes reached at offset %d", qcow2StreamBufferLimit, off)
+			return false, ErrQcow2NotStreamable
+		}
+		retained = true
+	}
+	refs := s.compressedByEnd[off]
+	delete(s.compressedByEnd, off)
+	for _, ref := range refs {
+		if err := s.resolveCompressed(ref); err != nil {
+			return retained, err
+		}
+	}
+	return retained, nil
+}
+
+func (s *qcow2Stream) parseL1(data []byte) error {
+	s.l1Data = nil
+	l2Span := s.clusterSize / 8 * s.clusterSize
+	for i := 0; i < len(data)/8; i++ {
+		l2Off := int64(binary.BigEndian.Uint64(data[i*8:]) & qcow2OffsetMask)
+		guestBase := int64(i) * l2Span
+		if l2Off == 0 || guestBase >= int64(s.hdr.size) {
+			continue
+		}
+		if l2Off >= s.pos {
+			s.l2Tables[l2Off] = guestBase
+			continue
+		}
+		table, ok := s.takeBuffered(l2Off)
+		if !ok {
+			klog.V(1).Infof("L2 table at offset %d precedes the L1 table", l2Off)
+			return ErrQcow2NotStreamable
+		}
+		if err := s.parseL2(table, guestBase); err != nil {
+			return err
+		}
+	}
+	s.l1Parsed = true
+	s.releaseUnreferenced()
+	return nil
+}
+
+func (s *qcow2Stream) parseRefcountTable(data []byte) {
+	for i := 0; i < len(data)/8; i++ {
+		blockOff := int64(binary.BigEndian.Uint64(data[i*8:]) &^ 0x1ff)
+		if blockOff == 0 {
+			continue
+		}
+		if blockOff >= s.pos {
+			s.refcountBlocks[blockOff] = true
+		} else {
+			s.takeBuffered(blockOff)
+		}
+	}
+	s.refcountData = nil
+}
+
+func (s *qcow2Stream) parseL2(table []byte, guestBase int64) error {
+	compressedShift := 62 - (s.hdr.clusterBits - 8)
+	sectorMask := (uint64(1) << (s.hdr.clusterBits - 8)) - 1
+	lastCompressed := s.lastCompressed
+	// Compressed clusters already read are resolved after all references of the table are known, so clusters holding
+	// several of them are not released early.
+	var readCompressed []qcow2CompressedRef
+	for i := int64(0); i < s.clusterSize/8; i++ {
+		guest := guestBase + i*s.clusterSize
+		if guest >= int64(s.hdr.size) {
+			break
+		}
+		entry := binary.BigEndian.Uint64(table[i*8:])
+		if entry&qcow2FlagCompressed != 0 {
+			if err := validationPolicy.checkCompressed(true, "stream"); err != nil {
+				return err
+			}
+			host := int64(entry & ((uint64(1) << compressedShift) - 1))
+			sectors := int64((entry >> compressedShift) & sectorMask)
+			ref := qcow2CompressedRef{
+				host:   host,
+				length: (sectors+1)*512 - host&511,
+				guest:  guest,
+			}
+			if s.addCompressed(ref) {
+				readCompressed = append(readCompressed, ref)
+			}
+			continue
+		}
+		host := int64(entry & qcow2OffsetMask)
+		if host == 0 || (s.hdr.version >= 3 && entry&qcow2FlagZero != 0) {
+			// Unallocated and zero clusters read as zeros.
+			continue
+		}
+		if host >= s.pos {
+			s.dataRefs[host] = append(s.dataRefs[host], guest)
+			continue
+		}
+		data, ok := s.takeBuffered(host)
+		if !ok {
+			klog.V(1).Infof("Data cluster at offset %d precedes its L2 table", host)
+			return ErrQcow2NotStreamable
+		}
+		if err := s.writeGuest(data, guest); err != nil {
+			return err
+		}
+	}
+	for _, ref := range readCompressed {
+		if err := s.resolveCompressed(ref); err != nil {
+			return err
+		}
+	}
+	if lastCompressed >= 0 && lastCompressed != s.lastCompressed {
+		s.releaseCompressed(lastCompressed)
+	}
+	if s.allL2Known() {
+		s.releaseUnreferenced()
+	}
+	return nil
+}
+
+// addCompressed records the clusters holding a compressed guest cluster, and returns true if they have all been read.
+func (s *qcow2Stream) addCompressed(ref qcow2CompressedRef) bool {
+	first := s.clusterStart(ref.host)
+	last := s.clusterStart(ref.host + ref.length - 1)
+	for c := first; c <= last; c += s.clusterSize {
+		s.compressedNeeded[c]++
+	}
+	if last > s.lastCompressed {
+		s.lastCompressed = last
+	}
+	if last < s.pos {
+		return true
+	}
+	s.compressedByEnd[last] = append(s.compressedByEnd[last], ref)
+	return false
+}
+
+// resolveCompressed decompresses a guest cluster from the buffered clusters holding it. At the end of the stream the
+// compressed data may be shorter than the length of the reference.
+func (s *qcow2Stream) resolveCompressed(ref qcow2CompressedRef) error {
+	end := ref.host + ref.length
+	data := make([]byte, 0, ref.length)
+	for c := s.clusterStart(ref.host); c < end && c < s.pos; c += s.clusterSize {
+		buf, ok := s.buffered[c]
+		if !ok {
+			klog.V(1).Infof("Compressed cluster at offset %d precedes its L2 table", ref.host)
+			return ErrQcow2NotStreamable
+		}
+		from, to := int64(0), s.clusterSize
+		if ref.host > c {
+			from = ref.host - c
+		}
+		if end < c+s.clusterSize {
+			to = end - c
+		}
+		data = append(data, buf[from:to]...)
+	}
+	if _, err := io.ReadFull(flate.NewReader(bytes.NewReader(data)), s.decompressed); err != nil {
+		return errors.Wrapf(err, "unable to decompress qcow2 cluster at offset %d", ref.host)
+	}
+	if err := s.writeGuest(s.decompressed, ref.guest); err != nil {
+		return err
+	}
+	for c := s.clusterStart(ref.host); c < end; c += s.clusterSize {
+		s.compressedNeeded[c]--
+		if s.compressedNeeded[c] <= 0 {
+			delete(s.compressedNeeded, c)
+			s.releaseCompressed(c)
+		}
+	}
+	return nil
+}
+
+// releaseCompressed drops a buffered cluster holding compressed data once all its known references are resolved.
+// The cluster compressed data was last written to is kept, as qemu fills it up with clusters of the next L2 table.
+func (s *qcow2Stream) releaseCompressed(off int64) {
+	if s.compressedNeeded[off] > 0 || (off == s.lastCompressed && !s.allL2Known()) {
+		return
+	}
+	s.takeBuffered(off)
+}
+
+// releaseUnreferenced drops the buffered clusters no table points to, once all L2 tables have been read.
+func (s *qcow2Stream) releaseUnreferenced() {
+	if !s.allL2Known() {
+		return
+	}
+	for off := range s.buffered {
+		if s.compressedNeeded[off] == 0 {
+			s.takeBuffered(off)
+		}
+	}
+}
+
+func (s *qcow2Stream) takeBuffered(off int64) ([]byte, bool) {
+	buf, ok := s.buffered[off]
+	if ok {
+		delete(s.buffered, off)
+		s.bufferedSize -= s.clusterSize
+	}
+	return buf, ok
+}
+
+func (s *qcow2Stream) allL2Known() bool {
+	return s.l1Parsed && len(s.l2Tables) == 0
+}
+
+func (s *qcow2Stream) clusterStart(off int64) int64 {
+	return off &^ (s.clusterSize - 1)
+}
+
+func (s *qcow2Stream) guestClusters() int64 {
+	return (int64(s.hdr.size) + s.clusterSize - 1) / s.clusterSize
+}
+
+// writeGuest writes a cluster to the target, all zero clusters are skipped to keep the target sparse.
+func (s *qcow2Stream) writeGuest(data []byte, guest int64) error {
+	if remaining := int64(s.hdr.size) - guest; remaining < int64(len(data)) {
+		data = data[:remaining]
+	}
+	if isZero(data) {
+		return nil
+	}
+	if _, err := s.target.WriteAt(data, guest); err != nil {
+		return errors.Wrap(err, "unable to write to target")
+	}
+	if s.fillZeros {
+		index := guest / s.clusterSize
+		s.written[index/64] |= uint64(1) << uint(index%64)
+	}
+	return nil
+}
+
+// finish resolves the compressed clusters at the end of the stream, and makes sure all guest clusters are accounted for.
+func (s *qcow2Stream) finish() error {
+	for end, refs := range s.compressedByEnd {
+		delete(s.compressedByEnd, end)
+		for _, ref := range refs {
+			if err := s.resolveCompressed(ref); err != nil {
+				return err
+			}
+		}
+	}
+	if !s.l1Parsed || len(s.l2Tables) > 0 || len(s.dataRefs) > 0 {
+		return errors.New("qcow2 image is truncated")
+	}
+	if !s.fillZeros {
+		return s.target.Truncate(int64(s.hdr.size))
+	}
+	zeros := make([]byte, s.clusterSize)
+	for index := int64(0); index < s.guestClusters(); index++ {
+		if s.written[index/64]&(uint64(1)<<uint(index%64)) != 0 {
+			continue
+		}
+		guest := index * s.clusterSize
+		data := zeros
+		if remaining := int64(s.hdr.size) - guest; remaining < int64(len(data)) {
+			data = data[:remaining]
+		}
+		if _, err := s.target.WriteAt(data, guest); err != nil {
+			return errors.Wrap(err, "unable to write to target")
+		}
+	}
+	return nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const testClusterBits = 12
+
+// testQcow2 builds qcow2 images the way qemu-img lays them out: header, refcount table, refcount block and L1 table
+// first, followed by L2 tables and data clusters in the order they are added.
+type testQcow2 struct {
+	size       int64
+	image      []byte
+	l1         []uint64
+	l2         map[int64]int64
+	entries    map[int64]uint64
+	compressed int64
+	backing    bool
+}
+
+func newTestQcow2(size int64) *testQcow2 {
+	cs := int64(1) << testClusterBits
+	l2Span := cs / 8 * cs
+	q := &testQcow2{
+		size:    size,
+		image:   make([]byte, 4*cs),
+		l1:      make([]uint64, (size+l2Span-1)/l2Span),
+		l2:      make(map[int64]int64),
+		entries: make(map[int64]uint64),
+	}
+	return q
+}
+
+func (q *testQcow2) clusterSize() int64 {
+	return int64(1) << testClusterBits
+}
+
+func (q *testQcow2) allocCluster() int64 {
+	off := int64(len(q.image))
+	q.image = append(q.image, make([]byte, q.clusterSize())...)
+	return off
+}
+
+// addL2 places the L2 table for the guest cluster at the end of the image.
+func (q *testQcow2) addL2(guestCluster int64) {
+	index := guestCluster / (q.clusterSize() / 8)
+	off := q.allocCluster()
+	q.l1[index] = uint64(off) | uint64(1)<<63
+	q.l2[index] = off
+}
+
+func (q *testQcow2) addData(guestCluster int64, data []byte) {
+	off := q.allocCluster()
+	copy(q.image[off:], data)
+	q.entries[guestCluster] = uint64(off) | uint64(1)<<63
+}
+
+func (q *testQcow2) addZero(guestCluster int64) {
+	q.entries[guestCluster] = qcow2FlagZero
+}
+
+// addCompressed packs the compressed cluster behind the previous one, like qemu does.
+func (q *testQcow2) addCompressed(guestCluster int64, data []byte) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = w.Write(data)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+	cs := q.clusterSize()
+	length := int64(buf.Len())
+	off := q.compressed
+	fitsCluster := off%cs != 0 && off%cs+length <= cs
+	atEnd := off > int64(len(q.image))-cs && off <= int64(len(q.image))
+	if !fitsCluster && !atEnd {
+		off = int64(len(q.image))
+	}
+	for int64(len(q.image)) < off+length {
+		q.allocCluster()
+	}
+	copy(q.image[off:], buf.Bytes())
+	q.compressed = off + length
+
+	shift := uint(62 - (testClusterBits - 8))
+	sectors := uint64(((off+length-1)>>9)-(off>>9)) << shift
+	q.entries[guestCluster] = qcow2FlagCompressed | sectors | uint64(off)
+}
+
+func (q *testQcow2) bytes() []byte {
+	cs := q.clusterSize()
+	be := binary.BigEndian
+	hdr := q.image[:cs]
+	be.PutUint32(hdr[0:], qcow2Magic)
+	be.PutUint32(hdr[4:], 3)
+	if q.backing {
+		be.PutUint64(hdr[8:], 512)
+		be.PutUint32(hdr[16:], 4)
+		copy(hdr[512:], "base")
+	}
+	be.PutUint32(hdr[20:], testClusterBits)
+	be.PutUint64(hdr[24:], uint64(q.size))
+	be.PutUint32(hdr[36:], uint32(len(q.l1)))
+	be.PutUint64(hdr[40:], uint64(3*cs))
+	be.PutUint64(hdr[48:], uint64(cs))
+	be.PutUint32(hdr[56:], 1)
+	be.PutUint32(hdr[96:], 4)
+	be.PutUint32(hdr[100:], qcow2V3HeaderLength)
+	be.PutUint64(q.image[cs:], uint64(2*cs))
+	for i, entry := range q.l1 {
+		be.PutUint64(q.image[3*cs+int64(i)*8:], entry)
+	}
+	for guestCluster, entry := range q.entries {
+		index := guestCluster / (cs / 8)
+		l2Off, ok := q.l2[index]
+		Expect(ok).To(BeTrue())
+		be.PutUint64(q.image[l2Off+(guestCluster%(cs/8))*8:], entry)
+	}
+	return q.image
+}
+
+func testCluster(seed byte) []byte {
+	data := make([]byte, 1<<testClusterBits)
+	for i := range data {
+		data[i] = seed + byte(i%7)
+	}
+	return data
+}
+
+var _ = Describe("qcow2 stream conversion", func() {
+	var (
+		tmpDir       string
+		dest         string
+		origLimit    int64
+		clusterSize  = int64(1) << testClusterBits
+		guestPerL2   = clusterSize / 8
+		expectedData []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "qcow2")
+		Expect(err).ToNot(HaveOccurred())
+		dest = filepath.Join(tmpDir, "disk.img")
+		origLimit = qcow2StreamBufferLimit
+	})
+
+	AfterEach(func() {
+		qcow2StreamBufferLimit = origLimit
+		os.RemoveAll(tmpDir)
+	})
+
+	setExpected := func(size int64, guestCluster int64, data []byte) {
+		if expectedData == nil {
+			expectedData = make([]byte, size)
+		}
+		copy(expectedData[guestCluster*clusterSize:], data)
+	}
+
+	expectTarget := func() {
+		actual, err := ioutil.ReadFile(dest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(actual)).To(Equal(len(expectedData)))
+		Expect(bytes.Equal(actual, expectedData)).To(BeTrue())
+	}
+
+	BeforeEach(func() {
+		expectedData = nil
+	})
+
+	It("should convert standard, zero and unallocated clusters", func() {
+		size := 2*guestPerL2*clusterSize + 1000
+		q := newTestQcow2(size)
+		q.addL2(0)
+		for _, c := range []int64{0, 1, 5, guestPerL2 - 1} {
+			q.addData(c, testCluster(byte(c)))
+			setExpected(size, c, testCluster(byte(c)))
+		}
+		q.addZero(2)
+		q.addL2(2 * guestPerL2)
+		q.addData(2*guestPerL2, testCluster(42))
+		setExpected(size, 2*guestPerL2, testCluster(42)[:1000])
+		Expect(ConvertQcow2Stream(bytes.NewReader(q.bytes()), dest, size)).To(Succeed())
+		expectTarget()
+	})
+
+	It("should convert compressed clusters, including ones sharing a cluster with the next L2 table", func() {
+		size := 2 * guestPerL2 * clusterSize
+		q := newTestQcow2(size)
+		q.addL2(0)
+		for c := int64(0); c < 8; c++ {
+			q.addCompressed(c, testCluster(byte(c)))
+			setExpected(size, c, testCluster(byte(c)))
+		}
+		q.addL2(guestPerL2)
+		for c := guestPerL2; c < guestPerL2+8; c++ {
+			q.addCompressed(c, testCluster(byte(c)))
+			setExpected(size, c, testCluster(byte(c)))
+		}
+		q.addData(guestPerL2+10, testCluster(99))
+		setExpected(size, guestPerL2+10, testCluster(99))
+		Expect(ConvertQcow2Stream(bytes.NewReader(q.bytes()), dest, size)).To(Succeed())
+		expectTarget()
+	})
+
+	It("should convert a gzip compressed image", func() {
+		size := guestPerL2 * clusterSize
+		q := newTestQcow2(size)
+		q.addL2(0)
+		q.addData(3, testCluster(3))
+		setExpected(size, 3, testCluster(3))
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(q.bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		r, err := gzip.NewReader(&buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ConvertQcow2Stream(r, dest, size)).To(Succeed())
+		expectTarget()
+	})
+
+	It("should buffer data clusters preceding their L2 table", func() {
+		size := guestPerL2 * clusterSize
+		q := newTestQcow2(size)
+		q.addData(1, testCluster(1))
+		q.addCompressed(2, testCluster(2))
+		q.addL2(0)
+		setExpected(size, 1, testCluster(1))
+		setExpected(size, 2, testCluster(2))
+		Expect(ConvertQcow2Stream(bytes.NewReader(q.bytes()), dest, size)).To(Succeed())
+		expectTarget()
+	})
+
+	It("should require random access if the buffered clusters exceed the limit", func() {
+		qcow2StreamBufferLimit = clusterSize
+		size := guestPerL2 * clusterSize
+		q := newTestQcow2(size)
+		q.addData(1, testCluster(1))
+		q.addData(2, testCluster(2))
+		q.addL2(0)
+		err := ConvertQcow2Stream(bytes.NewReader(q.bytes()), dest, size)
+		Expect(err).To(Equal(ErrQcow2NotStreamable))
+	})
+
+	It("should fill unwritten clusters with zeros if the target is not sparse", func() {
+		size := guestPerL2 * clusterSize
+		q := newTestQcow2(size)
+		q.addL2(0)
+		q.addData(1, testCluster(1))
+		setExpected(size, 1, testCluster(1))
+		Expect(ioutil.WriteFile(dest, bytes.Repeat([]byte{0xff}, int(size)), 0644)).To(Succeed())
+		data := q.bytes()
+		r := bytes.NewReader(data)
+		hdr, err := readQcow2Header(r)
+		Expect(err).ToNot(HaveOccurred())
+		target, err := os.OpenFile(dest, os.O_WRONLY, 0644)
+		Expect(err).ToNot(HaveOccurred())
+		defer target.Close()
+		Expect(newQcow2Stream(hdr, target, true).convert(r)).To(Succeed())
+		expectTarget()
+	})
+
+	It("should fail on images with a backing file", func() {
+		q := newTestQcow2(clusterSize)
+		q.backing = true
+		err := ConvertQcow2Stream(bytes.NewReader(q.bytes()), dest, clusterSize)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("backing file"))
+	})
+
+	It("should fail if the virtual size is larger than the available size", func() {
+		q := newTestQcow2(guestPerL2 * clusterSize)
+		err := ConvertQcow2Stream(bytes.NewReader(q.bytes()), dest, clusterSize)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("larger than available size"))
+	})
+
+	It("should fail on a truncated image", func() {
+		size := guestPerL2 * clusterSize
+		q := newTestQcow2(size)
+		q.addL2(0)
+		q.addData(1, testCluster(1))
+		data := q.bytes()
+		err := ConvertQcow2Stream(bytes.NewReader(data[:len(data)-int(clusterSize)]), dest, size)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("truncated"))
+	})
+
+	It("should fail on data that is not qcow2", func() {
+		err := ConvertQcow2Stream(bytes.NewReader(make([]byte, clusterSize)), dest, clusterSize)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -23,6 +23,7 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -51,6 +52,14 @@ type ImgInfo struct {
 	VirtualSize int64 `json:"virtual-size"`
 	// ActualSize is the size of the qcow2 image
 	ActualSize int64 `json:"actual-size"`
+	// Encrypted is true if the image is encrypted
+	Encrypted bool `json:"encrypted"`
+}
+
+// imgCheck contains the result of checking an image.
+type imgCheck struct {
+	// CompressedClusters is the number of compressed clusters of a qcow2 image
+	CompressedClusters int64 `json:"compressed-clusters"`
 }
 
 // QEMUOperations defines the interface for executing qemu subprocesses
@@ -60,8 +69,12 @@ type QEMUOperations interface {
 	Info(url *url.URL) (*ImgInfo, error)
 	Validate(*url.URL, int64) error
 	CreateBlankImage(string, resource.Quantity) error
+	Compare(*url.URL, string) error
 }
 
+// ErrImageMismatch is returned when the content of the target image differs from its source.
+var ErrImageMismatch = errors.New("target image does not match the source image")
+
 type qemuOperations struct{}
 
 var (
@@ -143,17 +156,16 @@ func (o *qemuOperations) Resize(image string, size resource.Quantity) error {
 	return nil
 }
 
-func (o *qemuOperations) Info(url *url.URL) (*ImgInfo, error) {
-	var output []byte
-	var err error
-
+// imageArg returns the qemu-img argument opening the image at url, network images get a long enough timeout.
+func imageArg(url *url.URL) string {
 	if len(url.Scheme) > 0 {
-		// Image is a URL, make sure the timeout is long enough.
-		jsonArg := fmt.Sprintf("json: {\"file.driver\": \"%s\", \"file.url\": \"%s\", \"file.timeout\": %d}", url.Scheme, url, networkTimeoutSecs)
-		output, err = qemuExecFunction(qemuInfoLimits, nil, "qemu-img", "info", "--output=json", jsonArg)
-	} else {
-		output, err = qemuExecFunction(qemuInfoLimits, nil, "qemu-img", "info", "--output=json", url.String())
+		return fmt.Sprintf("json: {\"file.driver\": \"%s\", \"file.url\": \"%s\", \"file.timeout\": %d}", url.Scheme, url, networkTimeoutSecs)
 	}
+	return url.String()
+}
+
+func (o *qemuOperations) Info(url *url.URL) (*ImgInfo, error) {
+	output, err := qemuExecFunction(qemuInfoLimits, nil, "qemu-img", "info", "--output=json", imageArg(url))
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error getting info on image %s", url.String())
 	}
@@ -166,13 +178,18 @@ func (o *qemuOperations) Info(url *url.URL) (*ImgInfo, error) {
 	return &info, nil
 }
 
-func isSupportedFormat(value string) bool {
-	switch value {
-	case "raw", "qcow2":
-		return true
-	default:
-		return false
+// compressedClusters returns the number of compressed clusters of a qcow2 image.
+func compressedClusters(url *url.URL) (int64, error) {
+	output, err := qemuExecFunction(qemuInfoLimits, nil, "qemu-img", "check", "--output=json", "-f", "qcow2", imageArg(url))
+	if err != nil {
+		return 0, errors.Wrapf(err, "Error checking image %s", url.String())
+	}
+	var check imgCheck
+	if err := json.Unmarshal(output, &check); err != nil {
+		klog.Errorf("Invalid JSON:\n%s\n", string(output))
+		return 0, errors.Wrapf(err, "Invalid json for image %s", url.String())
 	}
+	return check.CompressedClusters, nil
 }
 
 func (o *qemuOperations) Validate(url *url.URL, availableSize int64) error {
@@ -181,17 +198,33 @@ func (o *qemuOperations) Validate(url *url.URL, availableSize int64) error {
 		return err
 	}
 
-	if !isSupportedFormat(info.Format) {
-		return errors.Errorf("Invalid format %s for image %s", info.Format, url.String())
+	if err := validationPolicy.checkFormat(info.Format, url.String()); err != nil {
+		return err
 	}
 
 	if len(info.BackingFile) > 0 {
 		return errors.Errorf("Image %s is invalid because it has backing file %s", url.String(), info.BackingFile)
 	}
 
+	if err := validationPolicy.checkEncrypted(info.Encrypted, url.String()); err != nil {
+		return err
+	}
+
+	if err := validationPolicy.checkVirtualSize(info.VirtualSize, url.String()); err != nil {
+		return err
+	}
+
 	if availableSize < info.VirtualSize {
 		return errors.Errorf("Virtual image size %d is larger than available size %d, shrink not yet supported.", info.VirtualSize, availableSize)
 	}
+
+	if validationPolicy.RejectCompressed && info.Format == "qcow2" {
+		compressed, err := compressedClusters(url)
+		if err != nil {
+			return err
+		}
+		return validationPolicy.checkCompressed(compressed > 0, url.String())
+	}
 	return nil
 }
 
@@ -236,3 +269,18 @@ func (o *qemuOperations) CreateBlankImage(dest string, size resource.Quantity) e
 	}
 	return nil
 }
+
+// Compare checks that the raw image dest has the same content as the source image at url. Data past the end of the
+// shorter image has to be zero.
+func (o *qemuOperations) Compare(url *url.URL, dest string) error {
+	output, err := qemuExecFunction(nil, nil, "qemu-img", "compare", "-F", "raw", imageArg(url), dest)
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "Content mismatch at offset") {
+			return errors.Wrap(ErrImageMismatch, strings.TrimSuffix(line, "!"))
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Error comparing image %s with %s", url.String(), dest)
+	}
+	return nil
+}
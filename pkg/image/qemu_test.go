@@ -175,6 +175,37 @@ var _ = Describe("Convert to Raw", func() {
 
 })
 
+var _ = Describe("Compare", func() {
+	It("should succeed if the images are identical", func() {
+		ep, err := url.Parse("/scratch/disk.qcow2")
+		Expect(err).NotTo(HaveOccurred())
+		replaceExecFunction(mockExecFunction("Images are identical.", "", nil, "compare", "-F", "raw", "/scratch/disk.qcow2", "dest"), func() {
+			err = NewQEMUOperations().Compare(ep, "dest")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	It("should return a mismatch error if the content differs", func() {
+		ep, err := url.Parse("/scratch/disk.qcow2")
+		Expect(err).NotTo(HaveOccurred())
+		replaceExecFunction(mockExecFunction("Content mismatch at offset 65536!", "exit 1", nil, "compare"), func() {
+			err = NewQEMUOperations().Compare(ep, "dest")
+			Expect(errors.Cause(err)).To(Equal(ErrImageMismatch))
+			Expect(err.Error()).To(ContainSubstring("offset 65536"))
+		})
+	})
+
+	It("should return an error if the images cannot be compared", func() {
+		ep, err := url.Parse("/scratch/disk.qcow2")
+		Expect(err).NotTo(HaveOccurred())
+		replaceExecFunction(mockExecFunction("qemu-img: Could not open '/scratch/disk.qcow2'", "exit 2", nil, "compare"), func() {
+			err = NewQEMUOperations().Compare(ep, "dest")
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Cause(err)).ToNot(Equal(ErrImageMismatch))
+		})
+	})
+})
+
 var _ = Describe("Resize", func() {
 	It("Should complete successfully if qemu-img resize succeeds", func() {
 		quantity, err := resource.ParseQuantity("10Gi")
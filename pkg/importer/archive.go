@@ -0,0 +1,461 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/klog"
+)
+
+const (
+	zipLocalHeaderSignature    = 0x04034b50
+	zipDataDescriptorSignature = 0x08074b50
+	zipMethodStore             = 0
+	zipMethodDeflate           = 8
+	zipFlagEncrypted           = 0x1
+	zipFlagDataDescriptor      = 0x8
+	zip64ExtraID               = 0x0001
+	zipExtTimeExtraID          = 0x5455
+	zipUnixExtraID             = 0x7875
+)
+
+// ArchiveOptions controls which entries of an archive are extracted, and how.
+type ArchiveOptions struct {
+	// SubPath is the directory or file inside the archive that is extracted, to the root of the target.
+	SubPath string
+	// StripComponents is the number of leading path components removed from the entry names.
+	StripComponents int
+	// PreserveOwnership sets the owner of the extracted entries to the user and group recorded in the archive.
+	PreserveOwnership bool
+}
+
+type archiveExtractor struct {
+	destDir string
+	options ArchiveOptions
+	subPath string
+	dirs    map[string]dirAttributes
+}
+
+// dirAttributes are the mode and modification time of an extracted directory, which are set after all entries are
+// extracted.
+type dirAttributes struct {
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// extractArchive extracts the tar or zip archive read from r to destDir. The archive is read once, front to back, and
+// the rest of the stream is drained, so the stream can be verified against a checksum afterwards.
+func extractArchive(r io.Reader, format, destDir string, options *ArchiveOptions) error {
+	x := &archiveExtractor{
+		destDir: destDir,
+		dirs:    make(map[string]dirAttributes),
+	}
+	if options != nil {
+		x.options = *options
+	}
+	if x.options.SubPath != "" {
+		x.subPath = strings.TrimPrefix(path.Clean("/"+x.options.SubPath), "/")
+	}
+	klog.V(1).Infof("begin extracting %s archive to %s...\n", format, destDir)
+	var err error
+	if format == "zip" {
+		err = x.extractZip(r)
+	} else {
+		err = x.extractTar(r)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return errors.Wrap(err, "unable to read archive")
+	}
+	// Extracting entries into a directory changes its modification time, and a read only directory would prevent
+	// extracting its entries, so both are set last. Subdirectories come first, before the mode of a parent can make
+	// them inaccessible.
+	dirs := make([]string, 0, len(x.dirs))
+	for dir := range x.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		attrs := x.dirs[dir]
+		if err := os.Chmod(dir, attrs.mode); err != nil {
+			return errors.Wrapf(err, "unable to set mode of %q", dir)
+		}
+		if err := os.Chtimes(dir, attrs.modTime, attrs.modTime); err != nil {
+			return errors.Wrapf(err, "unable to set times of %q", dir)
+		}
+	}
+	return nil
+}
+
+func (x *archiveExtractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "unable to read tar archive")
+		}
+		if err := x.extract(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip reads the entries of a zip archive through their local headers, the central directory at the end of the
+// archive is not needed.
+func (x *archiveExtractor) extractZip(r io.Reader) error {
+	// The flate reader reads exactly up to the end of the compressed data when given a io.ByteReader.
+	br := bufio.NewReader(r)
+	for {
+		var sig [4]byte
+		if _, err := io.ReadFull(br, sig[:]); err != nil {
+			return errors.Wrap(err, "unable to read zip archive")
+		}
+		if binary.LittleEndian.Uint32(sig[:]) != zipLocalHeaderSignature {
+			// The central directory follows the last entry.
+			return nil
+		}
+		if err := x.extractZipEntry(br); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *archiveExtractor) extractZipEntry(br *bufio.Reader) error {
+	le := binary.LittleEndian
+	var buf [26]byte
+	if _, err := io.ReadFull(br, buf[:]); err != nil {
+		return errors.Wrap(err, "unable to read zip entry header")
+	}
+	flags := le.Uint16(buf[2:])
+	method := le.Uint16(buf[4:])
+	crc := le.Uint32(buf[10:])
+	compressedSize := uint64(le.Uint32(buf[14:]))
+	nameAndExtra := make([]byte, int(le.Uint16(buf[22:]))+int(le.Uint16(buf[24:])))
+	if _, err := io.ReadFull(br, nameAndExtra); err != nil {
+		return errors.Wrap(err, "unable to read zip entry header")
+	}
+	name := string(nameAndExtra[:le.Uint16(buf[22:])])
+	hdr := &tar.Header{
+		Name:     name,
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Uid:      -1,
+		Gid:      -1,
+		ModTime:  msDosTimeToTime(le.Uint16(buf[8:]), le.Uint16(buf[6:])),
+	}
+	if strings.HasSuffix(name, "/") {
+		hdr.Typeflag = tar.TypeDir
+		hdr.Mode = 0755
+	}
+	zip64 := false
+	extra := nameAndExtra[len(name):]
+	for len(extra) >= 4 {
+		id, size := le.Uint16(extra), int(le.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		field := extra[:size]
+		extra = extra[size:]
+		switch id {
+		case zip64ExtraID:
+			zip64 = true
+			if le.Uint32(buf[18:]) == 0xffffffff && len(field) >= 8 {
+				field = field[8:]
+			}
+			if compressedSize == 0xffffffff && len(field) >= 8 {
+				compressedSize = le.Uint64(field)
+			}
+		case zipExtTimeExtraID:
+			if len(field) >= 5 && field[0]&1 != 0 {
+				hdr.ModTime = time.Unix(int64(le.Uint32(field[1:])), 0)
+			}
+		case zipUnixExtraID:
+			if uid, gid, ok := parseZipUnixExtra(field); ok {
+				hdr.Uid, hdr.Gid = uid, gid
+			}
+		}
+	}
+	if flags&zipFlagEncrypted != 0 {
+		return errors.Errorf("zip entry %s is encrypted", name)
+	}
+	var content io.Reader
+	switch method {
+	case zipMethodStore:
+		if flags&zipFlagDataDescriptor != 0 && hdr.Typeflag != tar.TypeDir {
+			return errors.Errorf("zip entry %s is stored without its size, which cannot be streamed", name)
+		}
+		content = io.LimitReader(br, int64(compressedSize))
+	case zipMethodDeflate:
+		fr := flate.NewReader(br)
+		defer fr.Close()
+		content = fr
+	default:
+		return errors.Errorf("zip entry %s uses unsupported compression method %d", name, method)
+	}
+	hash := crc32.NewIEEE()
+	content = io.TeeReader(content, hash)
+	if err := x.extract(hdr, content); err != nil {
+		return err
+	}
+	// Entries that are not extracted still have to be read.
+	if _, err := io.Copy(ioutil.Discard, content); err != nil {
+		return errors.Wrapf(err, "unable to read zip entry %s", name)
+	}
+	if flags&zipFlagDataDescriptor != 0 {
+		var err error
+		if crc, err = readZipDataDescriptor(br, zip64); err != nil {
+			return err
+		}
+	}
+	if hash.Sum32() != crc {
+		return errors.Errorf("zip entry %s has an invalid checksum", name)
+	}
+	return nil
+}
+
+// readZipDataDescriptor reads the descriptor following the entry data, and returns the crc32 it contains.
+func readZipDataDescriptor(br *bufio.Reader, zip64 bool) (uint32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(br, buf[:]); err != nil {
+		return 0, errors.Wrap(err, "unable to read zip data descriptor")
+	}
+	// The signature is optional.
+	if binary.LittleEndian.Uint32(buf[:]) == zipDataDescriptorSignature {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return 0, errors.Wrap(err, "unable to read zip data descriptor")
+		}
+	}
+	sizes := int64(8)
+	if zip64 {
+		sizes = 16
+	}
+	if _, err := io.CopyN(ioutil.Discard, br, sizes); err != nil {
+		return 0, errors.Wrap(err, "unable to read zip data descriptor")
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+// parseZipUnixExtra reads the user and group from the Info-ZIP unix extra field.
+func parseZipUnixExtra(field []byte) (int, int, bool) {
+	if len(field) < 2 || field[0] != 1 {
+		return 0, 0, false
+	}
+	ids := make([]int, 0, 2)
+	field = field[1:]
+	for len(ids) < 2 {
+		if len(field) < 1 || int(field[0]) > len(field)-1 {
+			return 0, 0, false
+		}
+		size := int(field[0])
+		id := 0
+		for i := size; i > 0; i-- {
+			id = id<<8 | int(field[i])
+		}
+		ids = append(ids, id)
+		field = field[size+1:]
+	}
+	return ids[0], ids[1], true
+}
+
+func msDosTimeToTime(dosDate, dosTime uint16) time.Time {
+	return time.Date(
+		int(dosDate>>9)+1980, time.Month(dosDate>>5&0xf), int(dosDate&0x1f),
+		int(dosTime>>11), int(dosTime>>5&0x3f), int(dosTime&0x1f)*2, 0,
+		time.UTC)
+}
+
+// extract writes a single archive entry below the destination directory.
+func (x *archiveExtractor) extract(hdr *tar.Header, content io.Reader) error {
+	target, ok := x.targetPath(hdr.Name, hdr.Typeflag == tar.TypeDir)
+	if !ok {
+		klog.V(3).Infof("Skipping archive entry %s", hdr.Name)
+		return nil
+	}
+	if err := x.checkParents(target); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return errors.Wrapf(err, "unable to create directory for %q", target)
+	}
+	mode := hdr.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return errors.Wrapf(err, "unable to create directory %q", target)
+		}
+		x.dirs[target] = dirAttributes{mode: mode, modTime: hdr.ModTime}
+		return x.setOwner(target, hdr)
+	case tar.TypeReg, tar.TypeRegA:
+		if err := removeExisting(target); err != nil {
+			return err
+		}
+		if err := writeArchiveFile(target, content); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		if err := removeExisting(target); err != nil {
+			return err
+		}
+		if err := os.Symlink(hdr.Linkname, target); err != nil {
+			return errors.Wrapf(err, "unable to create symlink %q", target)
+		}
+		return x.setOwner(target, hdr)
+	case tar.TypeLink:
+		linkTarget, ok := x.targetPath(hdr.Linkname, false)
+		if !ok {
+			klog.V(1).Infof("Skipping archive entry %s, its link target %s is not extracted", hdr.Name, hdr.Linkname)
+			return nil
+		}
+		if err := removeExisting(target); err != nil {
+			return err
+		}
+		if err := os.Link(linkTarget, target); err != nil {
+			return errors.Wrapf(err, "unable to create link %q", target)
+		}
+		return nil
+	default:
+		klog.V(1).Infof("Skipping archive entry %s of unsupported type %q", hdr.Name, hdr.Typeflag)
+		return nil
+	}
+	// The owner is changed first, as it clears the setuid and setgid bits.
+	if err := x.setOwner(target, hdr); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, mode); err != nil {
+		return errors.Wrapf(err, "unable to set mode of %q", target)
+	}
+	if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+		return errors.Wrapf(err, "unable to set times of %q", target)
+	}
+	return nil
+}
+
+// targetPath maps the name of an archive entry to a path below the destination directory, applying the sub path and
+// the stripped components. It returns false for entries that are not extracted.
+func (x *archiveExtractor) targetPath(name string, isDir bool) (string, bool) {
+	// Cleaning the name as an absolute path removes any .. that would leave the destination directory.
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if x.subPath != "" {
+		switch {
+		case name == x.subPath && isDir:
+			name = ""
+		case name == x.subPath:
+			name = path.Base(name)
+		case strings.HasPrefix(name, x.subPath+"/"):
+			name = strings.TrimPrefix(name, x.subPath+"/")
+		default:
+			return "", false
+		}
+	}
+	if x.options.StripComponents > 0 {
+		parts := strings.Split(name, "/")
+		if name == "" || len(parts) <= x.options.StripComponents {
+			return "", false
+		}
+		name = strings.Join(parts[x.options.StripComponents:], "/")
+	}
+	if name == "" && !isDir {
+		return "", false
+	}
+	return filepath.Join(x.destDir, filepath.FromSlash(name)), true
+}
+
+// checkParents makes sure none of the directories between the destination directory and target is a symlink, so an
+// archive cannot write outside the destination through a symlink it extracted earlier.
+func (x *archiveExtractor) checkParents(target string) error {
+	rel, err := filepath.Rel(x.destDir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+	current := x.destDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to stat %q", current)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return errors.Errorf("archive entry %q is below the symlink %q", target, current)
+		}
+	}
+	return nil
+}
+
+func (x *archiveExtractor) setOwner(target string, hdr *tar.Header) error {
+	if !x.options.PreserveOwnership {
+		return nil
+	}
+	if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+		return errors.Wrapf(err, "unable to set owner of %q", target)
+	}
+	return nil
+}
+
+// removeExisting removes a file or symlink the entry replaces, directories are kept.
+func removeExisting(target string) error {
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to stat %q", target)
+	}
+	if info.IsDir() {
+		return nil
+	}
+	return os.Remove(target)
+}
+
+func writeArchiveFile(target string, content io.Reader) error {
+	// O_EXCL makes sure no symlink is followed.
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "could not open file %q", target)
+	}
+	_, err = io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to write to file %q", target)
+	}
+	return nil
+}
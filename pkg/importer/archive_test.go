@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type testArchiveEntry struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+	mode     int64
+	uid      int
+}
+
+// The format readers need a full header, so the archives contain data that does not compress well.
+var testRandomContent = func() string {
+	data := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(data)
+	return string(data)
+}()
+
+var testArchiveEntries = []testArchiveEntry{
+	{name: "random.bin", content: testRandomContent, mode: 0644},
+	{name: "a/", typeflag: tar.TypeDir, mode: 0750},
+	{name: "a/b/", typeflag: tar.TypeDir, mode: 0755},
+	{name: "a/b/c.txt", content: "c", mode: 0600},
+	{name: "a/d.txt", content: "d", mode: 0644},
+	{name: "e.txt", content: "e", mode: 0755},
+}
+
+func createTestTar(entries []testArchiveEntry) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: typeflag,
+			Linkname: e.linkname,
+			Mode:     e.mode,
+			Size:     int64(len(e.content)),
+			Uid:      e.uid,
+			Gid:      e.uid,
+			ModTime:  time.Unix(1500000000, 0),
+		}
+		Expect(w.WriteHeader(hdr)).To(Succeed())
+		_, err := w.Write([]byte(e.content))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func createTestZip(entries []testArchiveEntry) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		f, err := w.Create(e.name)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write([]byte(e.content))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func zstdCompressed(data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = w.Write(data)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func gzipped(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+// onlyReader hides any other interfaces of the reader, like io.Seeker.
+type onlyReader struct {
+	io.Reader
+}
+
+var _ = Describe("Archive extraction", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "archive")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	expectFile := func(name, content string) {
+		data, err := ioutil.ReadFile(filepath.Join(tmpDir, name))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+	}
+
+	extractThroughReaders := func(data []byte, options *ArchiveOptions) error {
+		fr, err := NewFormatReaders(ioutil.NopCloser(onlyReader{bytes.NewReader(data)}), uint64(len(data)))
+		Expect(err).ToNot(HaveOccurred())
+		defer fr.Close()
+		Expect(fr.ArchiveFormat).ToNot(BeEmpty())
+		return extractArchive(fr.TopReader(), fr.ArchiveFormat, tmpDir, options)
+	}
+
+	table.DescribeTable("should extract", func(createArchive func() []byte, checkModes bool) {
+		Expect(extractThroughReaders(createArchive(), nil)).To(Succeed())
+		expectFile("a/b/c.txt", "c")
+		expectFile("a/d.txt", "d")
+		expectFile("e.txt", "e")
+		if checkModes {
+			info, err := os.Stat(filepath.Join(tmpDir, "a"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+			info, err = os.Stat(filepath.Join(tmpDir, "a/b/c.txt"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			Expect(info.ModTime()).To(Equal(time.Unix(1500000000, 0)))
+		}
+	},
+		table.Entry("a tar archive", func() []byte {
+			return createTestTar(testArchiveEntries)
+		}, true),
+		table.Entry("a gzip compressed tar archive", func() []byte {
+			return gzipped(createTestTar(testArchiveEntries))
+		}, true),
+		table.Entry("a zstd compressed tar archive", func() []byte {
+			return zstdCompressed(createTestTar(testArchiveEntries))
+		}, true),
+		table.Entry("a zip archive", func() []byte {
+			return createTestZip(testArchiveEntries)
+		}, false),
+	)
+
+	It("should extract stored zip entries", func() {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		content := []byte(testRandomContent)
+		f, err := w.CreateRaw(&zip.FileHeader{
+			Name:               "stored.txt",
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE(content),
+			CompressedSize64:   uint64(len(content)),
+			UncompressedSize64: uint64(len(content)),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write(content)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		Expect(extractThroughReaders(buf.Bytes(), nil)).To(Succeed())
+		expectFile("stored.txt", testRandomContent)
+	})
+
+	It("should fail on a zip entry with an invalid checksum", func() {
+		data := createTestZip([]testArchiveEntry{{name: "file.txt", content: testRandomContent}})
+		// The crc32 is in the data descriptor following the deflated data, right before the central directory.
+		cdOffset := bytes.Index(data, []byte{0x50, 0x4b, 0x01, 0x02})
+		Expect(cdOffset).To(BeNumerically(">", 12))
+		crc := binary.LittleEndian.Uint32(data[cdOffset-12:])
+		binary.LittleEndian.PutUint32(data[cdOffset-12:], crc+1)
+		err := extractThroughReaders(data, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid checksum"))
+	})
+
+	table.DescribeTable("should select entries", func(options *ArchiveOptions, expected map[string]string) {
+		Expect(extractThroughReaders(createTestTar(testArchiveEntries), options)).To(Succeed())
+		var files []string
+		err := filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(tmpDir, path)
+				files = append(files, rel)
+			}
+			return err
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(len(expected)))
+		for name, content := range expected {
+			expectFile(name, content)
+		}
+	},
+		table.Entry("with a sub directory", &ArchiveOptions{SubPath: "a/b"}, map[string]string{"c.txt": "c"}),
+		table.Entry("with a sub path to a file", &ArchiveOptions{SubPath: "a/d.txt"}, map[string]string{"d.txt": "d"}),
+		table.Entry("with stripped components", &ArchiveOptions{StripComponents: 1}, map[string]string{"b/c.txt": "c", "d.txt": "d"}),
+		table.Entry("with a sub directory and stripped components", &ArchiveOptions{SubPath: "a", StripComponents: 1}, map[string]string{"c.txt": "c"}),
+	)
+
+	It("should keep entries inside the target directory", func() {
+		data := createTestTar([]testArchiveEntry{
+			{name: "../../escaped.txt", content: "x", mode: 0644},
+		})
+		Expect(extractThroughReaders(data, nil)).To(Succeed())
+		expectFile("escaped.txt", "x")
+	})
+
+	It("should not write through an extracted symlink", func() {
+		outside, err := ioutil.TempDir("", "outside")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(outside)
+		data := createTestTar([]testArchiveEntry{
+			{name: "link", typeflag: tar.TypeSymlink, linkname: outside},
+			{name: "link/file.txt", content: "x", mode: 0644},
+		})
+		err = extractThroughReaders(data, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("symlink"))
+		_, err = os.Stat(filepath.Join(outside, "file.txt"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should extract entries into read only directories", func() {
+		data := createTestTar([]testArchiveEntry{
+			{name: "ro/", typeflag: tar.TypeDir, mode: 0555},
+			{name: "ro/sub/", typeflag: tar.TypeDir, mode: 0500},
+			{name: "ro/sub/file.txt", content: "x", mode: 0644},
+			{name: "ro/file.txt", content: "y", mode: 0644},
+		})
+		defer os.Chmod(filepath.Join(tmpDir, "ro/sub"), 0755)
+		defer os.Chmod(filepath.Join(tmpDir, "ro"), 0755)
+		Expect(extractThroughReaders(data, nil)).To(Succeed())
+		expectFile("ro/sub/file.txt", "x")
+		expectFile("ro/file.txt", "y")
+		info, err := os.Stat(filepath.Join(tmpDir, "ro"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0555)))
+		Expect(info.ModTime()).To(Equal(time.Unix(1500000000, 0)))
+		info, err = os.Stat(filepath.Join(tmpDir, "ro/sub"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0500)))
+	})
+
+	It("should extract hard links", func() {
+		data := createTestTar([]testArchiveEntry{
+			{name: "file.txt", content: "x", mode: 0644},
+			{name: "hardlink.txt", typeflag: tar.TypeLink, linkname: "file.txt"},
+		})
+		Expect(extractThroughReaders(data, nil)).To(Succeed())
+		expectFile("hardlink.txt", "x")
+	})
+
+	table.DescribeTable("should handle ownership", func(preserve bool, expectedUID int) {
+		if os.Geteuid() != 0 {
+			Skip("changing the owner of files requires root")
+		}
+		data := createTestTar([]testArchiveEntry{
+			{name: "file.txt", content: "x", mode: 0644, uid: 1234},
+		})
+		Expect(extractThroughReaders(data, &ArchiveOptions{PreserveOwnership: preserve})).To(Succeed())
+		info, err := os.Stat(filepath.Join(tmpDir, "file.txt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(int(info.Sys().(*syscall.Stat_t).Uid)).To(Equal(expectedUID))
+	},
+		table.Entry("by preserving it", true, 1234),
+		table.Entry("by dropping it", false, 0),
+	)
+})
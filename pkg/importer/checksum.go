@@ -0,0 +1,224 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+
+	"k8s.io/klog"
+)
+
+const (
+	// maxChecksumFileSize limits how much of a checksum or signature file is read into memory.
+	maxChecksumFileSize = 10 * 1024 * 1024
+)
+
+var (
+	// ErrChecksumMismatch is returned when the digest of the downloaded data does not match the published checksum.
+	ErrChecksumMismatch = errors.New("checksum of the downloaded data does not match the published checksum")
+
+	// <hex digest>  <file name> or <hex digest> *<file name> as written by sha256sum.
+	gnuChecksumLine = regexp.MustCompile(`^([0-9a-fA-F]{64})\s+\*?(.+)$`)
+	// SHA256 (<file name>) = <hex digest> as written by sha256sum --tag and used by Fedora.
+	bsdChecksumLine = regexp.MustCompile(`^SHA256 \((.+)\) = ([0-9a-fA-F]{64})$`)
+)
+
+// HTTPChecksum describes the published checksum file an http source is verified against.
+type HTTPChecksum struct {
+	// URL is the location of the SHA256SUMS style checksum file.
+	URL string
+	// SignatureURL is the optional location of a detached GPG signature of the checksum file.
+	SignatureURL string
+	// KeyDir is the optional directory containing the GPG public keys used to verify the checksum file.
+	KeyDir string
+}
+
+// digestReader computes the digest of all the data read through it.
+type digestReader struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func newDigestReader(r io.ReadCloser) *digestReader {
+	return &digestReader{
+		ReadCloser: r,
+		hash:       sha256.New(),
+	}
+}
+
+// Read reads from the underlying reader and adds the data read to the digest.
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	return n, err
+}
+
+// Sum drains the underlying reader and returns the hex encoded digest of all the data.
+func (r *digestReader) Sum() (string, error) {
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return "", errors.Wrap(err, "unable to read remaining data for checksum")
+	}
+	return hex.EncodeToString(r.hash.Sum(nil)), nil
+}
+
+// getExpectedChecksum retrieves the checksum file, verifies its signature if keys are provided, and returns the
+// digest listed for fileName.
+func getExpectedChecksum(client *http.Client, checksum *HTTPChecksum, fileName string, endpoint *url.URL, creds httpCredentials) (string, error) {
+	data, err := fetchChecksumData(client, checksum.URL, endpoint, creds)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to retrieve checksum file")
+	}
+	if checksum.KeyDir != "" {
+		var signature []byte
+		if checksum.SignatureURL != "" {
+			signature, err = fetchChecksumData(client, checksum.SignatureURL, endpoint, creds)
+			if err != nil {
+				return "", errors.Wrap(err, "unable to retrieve checksum signature")
+			}
+		}
+		data, err = verifyChecksumSignature(data, signature, checksum.KeyDir)
+		if err != nil {
+			return "", err
+		}
+	} else if checksum.SignatureURL != "" {
+		return "", errors.New("checksum signature provided without public keys to verify it")
+	}
+	return findChecksum(data, fileName)
+}
+
+// fetchChecksumData retrieves a checksum or signature file. The credentials of the endpoint are only sent if the file
+// is served from the same scheme and host as the endpoint, so they do not leak to another server.
+func fetchChecksumData(client *http.Client, u string, endpoint *url.URL, creds httpCredentials) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create HTTP request")
+	}
+	if req.URL.Scheme == endpoint.Scheme && req.URL.Host == endpoint.Host {
+		creds.apply(req)
+	} else {
+		klog.V(2).Infof("Not sending the endpoint credentials to %q, it is on another host\n", req.URL.Host)
+	}
+	klog.V(2).Infof("Attempting to get checksum data %q via http client\n", u)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "HTTP request errored")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize))
+}
+
+// verifyChecksumSignature checks the checksum data against the keys in keyDir. If no detached signature is
+// passed the data is expected to be clearsigned. The signed content is returned.
+func verifyChecksumSignature(data, signature []byte, keyDir string) ([]byte, error) {
+	keyring, err := readKeyRing(keyDir)
+	if err != nil {
+		return nil, err
+	}
+	if signature == nil {
+		block, _ := clearsign.Decode(data)
+		if block == nil {
+			return nil, errors.New("checksum file is not clearsigned and no signature was provided")
+		}
+		if _, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+			return nil, errors.Wrap(err, "invalid checksum file signature")
+		}
+		return block.Plaintext, nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(signature))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(signature))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid checksum file signature")
+	}
+	return data, nil
+}
+
+func readKeyRing(keyDir string) (openpgp.EntityList, error) {
+	files, err := ioutil.ReadDir(keyDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error listing files in %s", keyDir)
+	}
+	var keyring openpgp.EntityList
+	for _, file := range files {
+		if file.IsDir() || file.Name()[0] == '.' {
+			continue
+		}
+		fp := path.Join(keyDir, file.Name())
+		klog.Infof("Attempting to get GPG keys from %s", fp)
+		keys, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error reading file %s", fp)
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keys))
+		if err != nil {
+			entities, err = openpgp.ReadKeyRing(bytes.NewReader(keys))
+		}
+		if err != nil {
+			klog.Warningf("No GPG keys in %s: %v", fp, err)
+			continue
+		}
+		keyring = append(keyring, entities...)
+	}
+	if len(keyring) == 0 {
+		return nil, errors.Errorf("no GPG keys found in %s", keyDir)
+	}
+	return keyring, nil
+}
+
+// findChecksum returns the digest listed for fileName in the checksum data. Both the GNU and BSD style
+// sha256sum output formats are understood.
+func findChecksum(data []byte, fileName string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var sum, name string
+		if m := gnuChecksumLine.FindStringSubmatch(line); m != nil {
+			sum, name = m[1], m[2]
+		} else if m := bsdChecksumLine.FindStringSubmatch(line); m != nil {
+			name, sum = m[1], m[2]
+		} else {
+			continue
+		}
+		if path.Base(strings.TrimSpace(name)) == fileName {
+			return strings.ToLower(sum), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrap(err, "unable to parse checksum file")
+	}
+	return "", errors.Errorf("no checksum found for %q", fileName)
+}
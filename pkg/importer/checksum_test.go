@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+const (
+	testChecksum      = "2b1d0c9ee0a73c9c8be6fd8e1bcd0d3e0ab3f3ae3b3e0b4bd0e1a5fe1f1b5a0d"
+	otherTestChecksum = "d6b2e2c52e2c5cd1b01b7e0b4bd6e3e0ab3f3ae3b3e0b4bd0e1a5fe1f1b5a0d4"
+)
+
+var _ = Describe("Checksum file", func() {
+	table.DescribeTable("findChecksum should", func(data, fileName, expected string, wantErr bool) {
+		sum, err := findChecksum([]byte(data), fileName)
+		if wantErr {
+			Expect(err).To(HaveOccurred())
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sum).To(Equal(expected))
+		}
+	},
+		table.Entry("find GNU style entry", otherTestChecksum+"  other.img\n"+testChecksum+"  disk.img\n", "disk.img", testChecksum, false),
+		table.Entry("find GNU style binary entry", testChecksum+" *disk.img\n", "disk.img", testChecksum, false),
+		table.Entry("find GNU style entry with path", testChecksum+"  ./images/disk.img\n", "disk.img", testChecksum, false),
+		table.Entry("find BSD style entry", "# comment\nSHA256 (other.img) = "+otherTestChecksum+"\nSHA256 (disk.img) = "+testChecksum+"\n", "disk.img", testChecksum, false),
+		table.Entry("lower case the digest", "SHA256 (disk.img) = 2B1D0C9EE0A73C9C8BE6FD8E1BCD0D3E0AB3F3AE3B3E0B4BD0E1A5FE1F1B5A0D\n", "disk.img", testChecksum, false),
+		table.Entry("fail when the entry is missing", otherTestChecksum+"  other.img\n", "disk.img", "", true),
+		table.Entry("fail on an empty file", "", "disk.img", "", true),
+	)
+})
+
+var _ = Describe("Checksum file credentials", func() {
+	var (
+		endpointServer, otherServer *httptest.Server
+		authorization               map[string]string
+	)
+
+	BeforeEach(func() {
+		authorization = map[string]string{}
+		handler := func(name string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				authorization[name] = r.Header.Get("Authorization")
+				w.Write([]byte(testChecksum + "  disk.img\n"))
+			}
+		}
+		endpointServer = httptest.NewServer(handler("endpoint"))
+		otherServer = httptest.NewServer(handler("other"))
+	})
+
+	AfterEach(func() {
+		endpointServer.Close()
+		otherServer.Close()
+	})
+
+	table.DescribeTable("should send the endpoint credentials", func(server string, expected string) {
+		ep, err := url.Parse(endpointServer.URL + "/disk.img")
+		Expect(err).ToNot(HaveOccurred())
+		checksumURL := endpointServer.URL + "/SHA256SUMS"
+		if server == "other" {
+			checksumURL = otherServer.URL + "/SHA256SUMS"
+		}
+		_, err = fetchChecksumData(http.DefaultClient, checksumURL, ep, httpCredentials{token: "mytoken"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authorization[server]).To(Equal(expected))
+	},
+		table.Entry("to the endpoint host", "endpoint", "Bearer mytoken"),
+		table.Entry("not to another host", "other", ""),
+	)
+})
+
+var _ = Describe("Checksum signature", func() {
+	var (
+		keyDir string
+		entity *openpgp.Entity
+		data   = []byte(testChecksum + "  disk.img\n")
+	)
+
+	BeforeEach(func() {
+		var err error
+		keyDir, err = ioutil.TempDir("", "checksum-keys")
+		Expect(err).ToNot(HaveOccurred())
+		entity, err = openpgp.NewEntity("test", "", "test@example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+		writePublicKey(entity, filepath.Join(keyDir, "key.asc"))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(keyDir)
+	})
+
+	It("Should accept a valid armored detached signature", func() {
+		signature := &bytes.Buffer{}
+		Expect(openpgp.ArmoredDetachSign(signature, entity, bytes.NewReader(data), nil)).To(Succeed())
+		result, err := verifyChecksumSignature(data, signature.Bytes(), keyDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(data))
+	})
+
+	It("Should accept a valid binary detached signature", func() {
+		signature := &bytes.Buffer{}
+		Expect(openpgp.DetachSign(signature, entity, bytes.NewReader(data), nil)).To(Succeed())
+		_, err := verifyChecksumSignature(data, signature.Bytes(), keyDir)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should accept a valid clearsigned checksum file and return the plaintext", func() {
+		signed := &bytes.Buffer{}
+		w, err := clearsign.Encode(signed, entity.PrivateKey, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		result, err := verifyChecksumSignature(signed.Bytes(), nil, keyDir)
+		Expect(err).ToNot(HaveOccurred())
+		sum, err := findChecksum(result, "disk.img")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sum).To(Equal(testChecksum))
+	})
+
+	It("Should reject tampered data", func() {
+		signature := &bytes.Buffer{}
+		Expect(openpgp.ArmoredDetachSign(signature, entity, bytes.NewReader(data), nil)).To(Succeed())
+		_, err := verifyChecksumSignature([]byte(otherTestChecksum+"  disk.img\n"), signature.Bytes(), keyDir)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should reject a signature from an unknown key", func() {
+		other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+		signature := &bytes.Buffer{}
+		Expect(openpgp.ArmoredDetachSign(signature, other, bytes.NewReader(data), nil)).To(Succeed())
+		_, err = verifyChecksumSignature(data, signature.Bytes(), keyDir)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should reject an unsigned checksum file when no signature is provided", func() {
+		_, err := verifyChecksumSignature(data, nil, keyDir)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should fail when there are no keys", func() {
+		emptyDir, err := ioutil.TempDir("", "empty-keys")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(emptyDir)
+		_, err = verifyChecksumSignature(data, nil, emptyDir)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Http data source with checksum", func() {
+	var (
+		ts      *httptest.Server
+		dp      *HTTPDataSource
+		content []byte
+		srvDir  string
+		tmpDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		srvDir, err = ioutil.TempDir("", "checksum-server")
+		Expect(err).ToNot(HaveOccurred())
+		tmpDir, err = ioutil.TempDir("", "checksum-target")
+		Expect(err).ToNot(HaveOccurred())
+		content = make([]byte, 1024*1024)
+		_, err = rand.Read(content)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(srvDir, "disk.img"), content, 0644)).To(Succeed())
+		sum := sha256.Sum256(content)
+		checksums := fmt.Sprintf("%s  other.img\n%s  disk.img\n", otherTestChecksum, hex.EncodeToString(sum[:]))
+		Expect(ioutil.WriteFile(filepath.Join(srvDir, "SHA256SUMS"), []byte(checksums), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(srvDir, "BADSUMS"), []byte(otherTestChecksum+"  disk.img\n"), 0644)).To(Succeed())
+		ts = createTestServer(srvDir)
+		dp = nil
+	})
+
+	AfterEach(func() {
+		if dp != nil {
+			dp.Close()
+		}
+		ts.Close()
+		os.RemoveAll(srvDir)
+		os.RemoveAll(tmpDir)
+	})
+
+	It("Should not convert directly from the endpoint", func() {
+		var err error
+		dp, err = NewHTTPDataSource(ts.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, &HTTPChecksum{URL: ts.URL + "/SHA256SUMS"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		phase, err := dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseTransferDataFile))
+	})
+
+	It("Should succeed when the checksum matches", func() {
+		var err error
+		dp, err = NewHTTPDataSource(ts.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, &HTTPChecksum{URL: ts.URL + "/SHA256SUMS"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		target := filepath.Join(tmpDir, "disk.img")
+		phase, err := dp.TransferFile(target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseResize))
+		written, err := ioutil.ReadFile(target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(written).To(Equal(content))
+	})
+
+	It("Should fail when the checksum does not match", func() {
+		var err error
+		dp, err = NewHTTPDataSource(ts.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, &HTTPChecksum{URL: ts.URL + "/BADSUMS"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		phase, err := dp.TransferFile(filepath.Join(tmpDir, "disk.img"))
+		Expect(err).To(Equal(ErrChecksumMismatch))
+		Expect(phase).To(Equal(ProcessingPhaseError))
+	})
+
+	It("Should fail when the checksum file cannot be retrieved", func() {
+		_, err := NewHTTPDataSource(ts.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, &HTTPChecksum{URL: ts.URL + "/MISSING"}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should fail when a signature is given without keys", func() {
+		_, err := NewHTTPDataSource(ts.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, &HTTPChecksum{URL: ts.URL + "/SHA256SUMS", SignatureURL: ts.URL + "/SHA256SUMS.gpg"}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func writePublicKey(entity *openpgp.Entity, fileName string) {
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(entity.Serialize(w)).To(Succeed())
+	Expect(w.Close()).To(Succeed())
+	Expect(ioutil.WriteFile(fileName, buf.Bytes(), 0644)).To(Succeed())
+}
@@ -18,7 +18,10 @@ package importer
 
 import (
 	"fmt"
+	"hash"
+	"io"
 	"net/url"
+	"os"
 
 	"github.com/pkg/errors"
 
@@ -47,8 +50,14 @@ const (
 	// ProcessingPhaseConvert is the phase in which the data is taken from the url provided by the source, and it is converted to the target RAW disk image format.
 	// The url can be an http end point or file system end point.
 	ProcessingPhaseConvert ProcessingPhase = "Convert"
+	// ProcessingPhaseVerify is the phase in which the converted target disk image is compared with the source it was converted from.
+	ProcessingPhaseVerify ProcessingPhase = "Verify"
 	// ProcessingPhaseResize the disk image, this is only needed when the target contains a file system (block device do not need a resize)
 	ProcessingPhaseResize ProcessingPhase = "Resize"
+	// ProcessingPhaseInspect is the phase in which the partition table and file systems of the target disk image are detected.
+	ProcessingPhaseInspect ProcessingPhase = "Inspect"
+	// ProcessingPhaseValidateISO is the phase in which the target file is checked to contain an ISO image, which is neither converted nor resized.
+	ProcessingPhaseValidateISO ProcessingPhase = "ValidateISO"
 	// ProcessingPhaseComplete is the phase where the entire process completed successfully and we can exit gracefully.
 	ProcessingPhaseComplete ProcessingPhase = "Complete"
 	// ProcessingPhaseError is the phase in which we encountered an error and need to exit ungracefully.
@@ -81,6 +90,13 @@ type DataSourceInterface interface {
 	Close() error
 }
 
+// StreamingDataSource is implemented by data sources that can convert a qcow2 image while reading it, so it does not
+// have to be staged in scratch space.
+type StreamingDataSource interface {
+	// TransferStream is called to convert the data from the source to the RAW file passed in.
+	TransferStream(fileName string, availableSize int64) (ProcessingPhase, error)
+}
+
 // DataProcessor holds the fields needed to process data from a data provider.
 type DataProcessor struct {
 	// currentPhase is the phase the processing is in currently.
@@ -97,6 +113,54 @@ type DataProcessor struct {
 	requestImageSize string
 	// available space is the available space before downloading the image
 	availableSpace int64
+	// filesystemOverhead is the fraction of a file system volume not available to the image.
+	filesystemOverhead float64
+	// isoVolumeLabel is the volume label of an imported ISO image.
+	isoVolumeLabel string
+	// diskLayout is the partitioning and file systems of the imported disk image.
+	diskLayout *image.DiskLayout
+	// verify enables comparing the converted disk image with its source.
+	verify bool
+	// written hashes the data a WrittenDataSource writes to the data file as is, nil for other data sources.
+	written *writtenData
+	// digest is the content checksum of the disk image written to the data file.
+	digest string
+	// sourceFormat is the detected format of the source image.
+	sourceFormat string
+	// virtualSize is the virtual size of the source image in bytes.
+	virtualSize int64
+}
+
+// VerifiableDataSource is implemented by data sources whose import can be verified without reading the source a second
+// time.
+type VerifiableDataSource interface {
+	// EnableVerification is called before Info. The data source must convert images from the scratch space rather
+	// than let qemu-img read the endpoint.
+	EnableVerification()
+}
+
+// WrittenDataSource is implemented by data sources that can copy the data they write to the data file as is, so it is
+// hashed while it is written instead of reading the data file again.
+type WrittenDataSource interface {
+	// CopyWrittenData is called before Info. The data source must copy the data TransferFile writes to w.
+	CopyWrittenData(w io.Writer)
+}
+
+// writtenData hashes and counts the data written to the data file as is.
+type writtenData struct {
+	hash hash.Hash
+	size int64
+}
+
+func (w *writtenData) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return w.hash.Write(p)
+}
+
+// TransferCounter is implemented by data sources that count the bytes read from the source.
+type TransferCounter interface {
+	// BytesTransferred returns the number of bytes read from the source.
+	BytesTransferred() int64
 }
 
 // NewDataProcessor create a new instance of a data processor using the passed in data provider.
@@ -109,6 +173,10 @@ func NewDataProcessor(dataSource DataSourceInterface, dataFile, dataDir, scratch
 		scratchDataDir:   scratchDataDir,
 		requestImageSize: requestImageSize,
 	}
+	if source, ok := dataSource.(WrittenDataSource); ok {
+		dp.written = &writtenData{hash: util.NewContentHash()}
+		source.CopyWrittenData(dp.written)
+	}
 	// Calculate available space before doing anything.
 	dp.availableSpace = dp.calculateTargetSize()
 	return dp
@@ -141,6 +209,21 @@ func (dp *DataProcessor) ProcessData() error {
 				err = errors.Wrap(err, "Unable to obtain information about data source")
 			}
 		case ProcessingPhaseTransferScratch:
+			streamer, ok := dp.source.(StreamingDataSource)
+			if ok && !dp.verify && util.GetAvailableSpace(dp.scratchDataDir) <= int64(0) {
+				// No scratch space, try to convert while streaming before asking for scratch space. A verified
+				// conversion is compared with its source in the scratch space.
+				dp.currentPhase, err = streamer.TransferStream(dp.dataFile, dp.availableSpace)
+				if errors.Cause(err) == image.ErrQcow2NotStreamable {
+					err = ErrRequiresScratchSpace
+				} else if err != nil {
+					err = errors.Wrap(err, "Unable to convert source data stream to target file")
+				} else {
+					dp.setSourceFormat("qcow2")
+					dp.checksumConvertedImage()
+				}
+				break
+			}
 			dp.currentPhase, err = dp.source.Transfer(dp.scratchDataDir)
 			if err == ErrInvalidPath {
 				// Passed in invalid scratch space path, return scratch space needed error.
@@ -157,6 +240,14 @@ func (dp *DataProcessor) ProcessData() error {
 			dp.currentPhase, err = dp.source.TransferFile(dp.dataFile)
 			if err != nil {
 				err = errors.Wrap(err, "Unable to transfer source data to target file")
+			} else {
+				dp.setSourceFormat("raw")
+				if dp.written != nil {
+					dp.digest = util.FormatContentChecksum(dp.written.hash)
+				}
+				if err = dp.verifyWrittenData(); err != nil {
+					err = errors.Wrap(err, "Unable to verify target file")
+				}
 			}
 		case ProcessingPhaseProcess:
 			dp.currentPhase, err = dp.source.Process()
@@ -168,11 +259,23 @@ func (dp *DataProcessor) ProcessData() error {
 			if err != nil {
 				err = errors.Wrap(err, "Unable to convert source data to target format")
 			}
+		case ProcessingPhaseVerify:
+			dp.currentPhase, err = dp.verifyConversion(dp.source.GetURL())
+			if err != nil {
+				err = errors.Wrap(err, "Unable to verify target disk image")
+			}
 		case ProcessingPhaseResize:
 			dp.currentPhase, err = dp.resize()
 			if err != nil {
 				err = errors.Wrap(err, "Unable to resize disk image to requested size")
 			}
+		case ProcessingPhaseInspect:
+			dp.currentPhase, err = dp.inspect()
+		case ProcessingPhaseValidateISO:
+			dp.currentPhase, err = dp.validateISO()
+			if err != nil {
+				err = errors.Wrap(err, "Unable to validate ISO image")
+			}
 		default:
 			return errors.Errorf("Unknown processing phase %s", dp.currentPhase)
 		}
@@ -191,9 +294,49 @@ func (dp *DataProcessor) validate(url *url.URL) error {
 	if err != nil {
 		return errors.Wrap(err, "Image validation failed")
 	}
+	// The format and size are informational, so the import does not fail if they cannot be read.
+	if info, err := qemuOperations.Info(url); err == nil {
+		dp.sourceFormat = info.Format
+		dp.virtualSize = info.VirtualSize
+	} else {
+		klog.Warningf("Unable to read the format of the source image: %v\n", err)
+	}
 	return nil
 }
 
+// setSourceFormat records the format of a source image written to the data file without qemu-img. The virtual size is
+// the size of the data file, if it is not a block device.
+func (dp *DataProcessor) setSourceFormat(format string) {
+	dp.sourceFormat = format
+	if fi, err := os.Stat(dp.dataFile); err == nil && fi.Mode().IsRegular() {
+		dp.virtualSize = fi.Size()
+	}
+}
+
+// SourceFormat returns the detected format of the source image, or an empty string if it is not known.
+func (dp *DataProcessor) SourceFormat() string {
+	return dp.sourceFormat
+}
+
+// VirtualSize returns the virtual size of the source image in bytes, or 0 if it is not known.
+func (dp *DataProcessor) VirtualSize() int64 {
+	return dp.virtualSize
+}
+
+// Digest returns the content checksum of the disk image written to the data file, before it is resized, or an empty
+// string if it is not known.
+func (dp *DataProcessor) Digest() string {
+	return dp.digest
+}
+
+// BytesTransferred returns the number of bytes read from the data source, or 0 if the data source does not count them.
+func (dp *DataProcessor) BytesTransferred() int64 {
+	if counter, ok := dp.source.(TransferCounter); ok {
+		return counter.BytesTransferred()
+	}
+	return 0
+}
+
 // convert is called when convert the image from the url to a RAW disk image. Source formats include RAW/QCOW2 (Raw to raw conversion is a copy)
 func (dp *DataProcessor) convert(url *url.URL) (ProcessingPhase, error) {
 	err := dp.validate(url)
@@ -205,10 +348,79 @@ func (dp *DataProcessor) convert(url *url.URL) (ProcessingPhase, error) {
 	if err != nil {
 		return ProcessingPhaseError, errors.Wrap(err, "Conversion to Raw failed")
 	}
+	dp.checksumConvertedImage()
+
+	if dp.verify {
+		return ProcessingPhaseVerify, nil
+	}
+	return ProcessingPhaseResize, nil
+}
+
+// SetVerify enables or disables comparing the converted disk image with its source before it is resized. An image
+// written as is is compared with the checksum of the data written, if the data source is a WrittenDataSource.
+func (dp *DataProcessor) SetVerify(verify bool) {
+	dp.verify = verify
+	if source, ok := dp.source.(VerifiableDataSource); ok && verify {
+		source.EnableVerification()
+	}
+}
+
+// checksumConvertedImage computes the content checksum of an image converted by qemu-img, which writes the data file
+// itself. Only the virtual size of the image is read, if it is known. The checksum is informational, so the import
+// does not fail if it cannot be computed.
+func (dp *DataProcessor) checksumConvertedImage() {
+	size := int64(-1)
+	if dp.virtualSize > 0 {
+		size = dp.virtualSize
+	}
+	checksum, err := util.ContentChecksum(dp.dataFile, size)
+	if err != nil {
+		klog.Warningf("Unable to compute the content checksum of %s: %v", dp.dataFile, err)
+		return
+	}
+	dp.digest = checksum
+}
+
+// SetFilesystemOverhead sets the fraction of a file system volume reserved for file system metadata, the image is
+// sized to the rest of the volume.
+func (dp *DataProcessor) SetFilesystemOverhead(overhead float64) {
+	dp.filesystemOverhead = overhead
+	dp.availableSpace = dp.calculateTargetSize()
+}
 
+// verifyConversion compares the converted disk image with the source at url. The source is read a second time, from
+// the scratch space or the remote endpoint.
+func (dp *DataProcessor) verifyConversion(url *url.URL) (ProcessingPhase, error) {
+	klog.V(1).Infoln("Verifying converted image")
+	err := qemuOperations.Compare(url, dp.dataFile)
+	if err != nil {
+		return ProcessingPhaseError, err
+	}
+	klog.V(1).Infoln("Converted image matches the source")
 	return ProcessingPhaseResize, nil
 }
 
+// verifyWrittenData compares the data file with the checksum of the data the data source wrote to it as is.
+func (dp *DataProcessor) verifyWrittenData() error {
+	if !dp.verify {
+		return nil
+	}
+	if dp.written == nil {
+		klog.Warningf("The data source can't verify the data written as is, not verifying the target file")
+		return nil
+	}
+	klog.V(1).Infoln("Verifying written data")
+	checksum, err := util.ContentChecksum(dp.dataFile, dp.written.size)
+	if err != nil {
+		return err
+	}
+	if expected := util.FormatContentChecksum(dp.written.hash); checksum != expected {
+		return errors.Wrapf(image.ErrImageMismatch, "checksum %s of the target file, expected %s", checksum, expected)
+	}
+	klog.V(1).Infoln("Written data matches the source")
+	return nil
+}
+
 func (dp *DataProcessor) resize() (ProcessingPhase, error) {
 	// Resize only if we have a resize request, and if the image is on a file system pvc.
 	klog.V(3).Infof("Available space in dataFile: %d", getAvailableSpaceBlockFunc(dp.dataFile))
@@ -219,9 +431,54 @@ func (dp *DataProcessor) resize() (ProcessingPhase, error) {
 			return ProcessingPhaseError, errors.Wrap(err, "Resize of image failed")
 		}
 	}
+	return ProcessingPhaseInspect, nil
+}
+
+// inspect detects the partition table and file systems of the disk image. The result is informational only, so the
+// import does not fail if the image cannot be inspected.
+func (dp *DataProcessor) inspect() (ProcessingPhase, error) {
+	f, err := os.Open(dp.dataFile)
+	if err != nil {
+		klog.Warningf("Unable to open %q to inspect the disk layout: %v\n", dp.dataFile, err)
+		return ProcessingPhaseComplete, nil
+	}
+	defer f.Close()
+	layout, err := image.InspectDiskLayout(f)
+	if err != nil {
+		klog.Warningf("Unable to inspect the disk layout: %v\n", err)
+		return ProcessingPhaseComplete, nil
+	}
+	klog.V(1).Infof("Disk layout: %+v\n", *layout)
+	dp.diskLayout = layout
+	return ProcessingPhaseComplete, nil
+}
+
+// DiskLayout returns the partitioning and file systems of the imported disk image, or nil if it was not inspected.
+func (dp *DataProcessor) DiskLayout() *image.DiskLayout {
+	return dp.diskLayout
+}
+
+func (dp *DataProcessor) validateISO() (ProcessingPhase, error) {
+	f, err := os.Open(dp.dataFile)
+	if err != nil {
+		return ProcessingPhaseError, errors.Wrapf(err, "could not open file %q", dp.dataFile)
+	}
+	defer f.Close()
+	label, err := image.ISOVolumeLabel(f)
+	if err != nil {
+		return ProcessingPhaseError, err
+	}
+	klog.V(1).Infof("ISO volume label: %q\n", label)
+	dp.isoVolumeLabel = label
+	dp.setSourceFormat("iso")
 	return ProcessingPhaseComplete, nil
 }
 
+// ISOVolumeLabel returns the volume label of the imported ISO image, or an empty string if no ISO image was imported.
+func (dp *DataProcessor) ISOVolumeLabel() string {
+	return dp.isoVolumeLabel
+}
+
 // ResizeImage resizes the images to match the requested size. Sometimes provisioners misbehave and the available space
 // is not the same as the requested space. For those situations we compare the available space to the requested space and
 // use the smallest of the two values.
@@ -252,7 +509,8 @@ func ResizeImage(dataFile, imageSize string, totalTargetSpace int64) error {
 func (dp *DataProcessor) calculateTargetSize() int64 {
 	klog.V(1).Infof("Calculating available size\n")
 	var targetQuantity *resource.Quantity
-	if getAvailableSpaceBlockFunc(dp.dataFile) >= int64(0) {
+	block := getAvailableSpaceBlockFunc(dp.dataFile) >= int64(0)
+	if block {
 		// Block volume.
 		klog.V(1).Infof("Checking out block volume size.\n")
 		targetQuantity = resource.NewScaledQuantity(getAvailableSpaceBlockFunc(dp.dataFile), 0)
@@ -267,7 +525,11 @@ func (dp *DataProcessor) calculateTargetSize() int64 {
 		minQuantity := util.MinQuantity(targetQuantity, &newImageSizeQuantity)
 		targetQuantity = &minQuantity
 	}
-	klog.V(1).Infof("Target size %s.\n", targetQuantity.String())
 	targetSize, _ := targetQuantity.AsInt64()
+	if !block && dp.filesystemOverhead > 0 {
+		targetSize = util.UsableSpace(targetSize, dp.filesystemOverhead)
+		targetQuantity = resource.NewScaledQuantity(targetSize, 0)
+	}
+	klog.V(1).Infof("Target size %s.\n", targetQuantity.String())
 	return targetSize
 }
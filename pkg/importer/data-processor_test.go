@@ -1,9 +1,11 @@
 package importer
 
 import (
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	"github.com/onsi/ginkgo/extensions/table"
@@ -14,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 
 	"kubevirt.io/containerized-data-importer/pkg/image"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 type fakeInfoOpRetVal struct {
@@ -41,6 +44,7 @@ type fakeQEMUOperations struct {
 	e5             error
 	e6             error
 	resizeQuantity *resource.Quantity
+	compareErr     error
 }
 
 type MockDataProvider struct {
@@ -105,6 +109,47 @@ func (m *MockDataProvider) Close() error {
 	return nil
 }
 
+// MockVerifiableDataProvider writes data to the data file, and copies written to the writer of the written data.
+type MockVerifiableDataProvider struct {
+	MockDataProvider
+	data    []byte
+	written []byte
+	w       io.Writer
+}
+
+// CopyWrittenData records the writer the written data is copied to.
+func (m *MockVerifiableDataProvider) CopyWrittenData(w io.Writer) {
+	m.w = w
+}
+
+// TransferFile writes the data to the file passed in.
+func (m *MockVerifiableDataProvider) TransferFile(fileName string) (ProcessingPhase, error) {
+	m.calledPhases = append(m.calledPhases, ProcessingPhaseTransferDataFile)
+	if err := ioutil.WriteFile(fileName, m.data, 0644); err != nil {
+		return ProcessingPhaseError, err
+	}
+	if m.w != nil {
+		m.w.Write(m.written)
+	}
+	return m.transferResponse, nil
+}
+
+type MockStreamingDataProvider struct {
+	MockDataProvider
+	streamFile  string
+	streamError error
+}
+
+// TransferStream is called to convert the data from the source to the file passed in.
+func (m *MockStreamingDataProvider) TransferStream(fileName string, availableSize int64) (ProcessingPhase, error) {
+	m.calledPhases = append(m.calledPhases, ProcessingPhaseTransferScratch)
+	m.streamFile = fileName
+	if m.streamError != nil {
+		return ProcessingPhaseError, m.streamError
+	}
+	return m.transferResponse, nil
+}
+
 var _ = Describe("Data Processor", func() {
 	It("should call the right phases based on the responses from the provider, Transfer should pass the scratch data dir as a path", func() {
 		mdp := &MockDataProvider{
@@ -166,6 +211,68 @@ var _ = Describe("Data Processor", func() {
 		Expect(ProcessingPhaseTransferScratch).To(Equal(mdp.calledPhases[1]))
 	})
 
+	It("should convert the stream to the data file without scratch space", func() {
+		mdp := &MockStreamingDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse:     ProcessingPhaseTransferScratch,
+				transferResponse: ProcessingPhaseComplete,
+			},
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		err := dp.ProcessData()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(2).To(Equal(len(mdp.calledPhases)))
+		Expect(ProcessingPhaseInfo).To(Equal(mdp.calledPhases[0]))
+		Expect(ProcessingPhaseTransferScratch).To(Equal(mdp.calledPhases[1]))
+		Expect("dest").To(Equal(mdp.streamFile))
+		Expect("").To(Equal(mdp.transferPath))
+	})
+
+	It("should require scratch space if the stream cannot be converted", func() {
+		mdp := &MockStreamingDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse: ProcessingPhaseTransferScratch,
+			},
+			streamError: image.ErrQcow2NotStreamable,
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		err := dp.ProcessData()
+		Expect(err).To(HaveOccurred())
+		Expect(ErrRequiresScratchSpace).To(Equal(err))
+	})
+
+	It("should error if the stream conversion fails", func() {
+		mdp := &MockStreamingDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse: ProcessingPhaseTransferScratch,
+			},
+			streamError: errors.New("Stream errored"),
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		err := dp.ProcessData()
+		Expect(err).To(HaveOccurred())
+		Expect(ErrRequiresScratchSpace).ToNot(Equal(err))
+	})
+
+	It("should use the scratch space instead of streaming if there is scratch space", func() {
+		scratchDir, err := ioutil.TempDir("", "scratch")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(scratchDir)
+		mdp := &MockStreamingDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse:     ProcessingPhaseTransferScratch,
+				transferResponse: ProcessingPhaseProcess,
+				processResponse:  ProcessingPhaseComplete,
+			},
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", scratchDir, "1G")
+		err = dp.ProcessData()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(3).To(Equal(len(mdp.calledPhases)))
+		Expect(scratchDir).To(Equal(mdp.transferPath))
+		Expect("").To(Equal(mdp.streamFile))
+	})
+
 	It("should call the right phases based on the responses from the provider, TransferDataFile should pass the data file", func() {
 		mdp := &MockDataProvider{
 			infoResponse:     ProcessingPhaseTransferDataFile,
@@ -235,6 +342,69 @@ var _ = Describe("Data Processor", func() {
 			Expect(tmpDir).To(Equal(mdp.transferPath))
 		})
 	})
+
+	It("should validate an ISO image and skip the resize", func() {
+		tmpDir, err := ioutil.TempDir("", "data")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		dataFile := filepath.Join(tmpDir, "disk.img")
+		iso := make([]byte, 18*2048)
+		copy(iso[16*2048:], "\x01CD001\x01")
+		copy(iso[16*2048+40:], "INSTALLER                       ")
+		Expect(ioutil.WriteFile(dataFile, iso, 0644)).To(Succeed())
+		mdp := &MockDataProvider{
+			infoResponse:     ProcessingPhaseTransferDataFile,
+			transferResponse: ProcessingPhaseValidateISO,
+		}
+		dp := NewDataProcessor(mdp, dataFile, "dataDir", "scratchDataDir", "1G")
+		err = dp.ProcessData()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mdp.calledPhases).To(Equal([]ProcessingPhase{ProcessingPhaseInfo, ProcessingPhaseTransferDataFile}))
+		Expect(dp.ISOVolumeLabel()).To(Equal("INSTALLER"))
+	})
+
+	It("should inspect the disk layout of the imported image", func() {
+		tmpDir, err := ioutil.TempDir("", "data")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		dataFile := filepath.Join(tmpDir, "disk.img")
+		disk := make([]byte, 1<<20)
+		copy(disk, "XFSB")
+		Expect(ioutil.WriteFile(dataFile, disk, 0644)).To(Succeed())
+		mdp := &MockDataProvider{
+			infoResponse:     ProcessingPhaseTransferDataFile,
+			transferResponse: ProcessingPhaseInspect,
+		}
+		dp := NewDataProcessor(mdp, dataFile, "dataDir", "scratchDataDir", "1G")
+		err = dp.ProcessData()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dp.DiskLayout()).To(Equal(&image.DiskLayout{PartitionTable: image.PartitionTableNone, Filesystems: []string{"xfs"}}))
+	})
+
+	It("should complete if the disk layout cannot be inspected", func() {
+		mdp := &MockDataProvider{
+			infoResponse:     ProcessingPhaseTransferDataFile,
+			transferResponse: ProcessingPhaseInspect,
+		}
+		dp := NewDataProcessor(mdp, "/invalid/disk.img", "dataDir", "scratchDataDir", "1G")
+		Expect(dp.ProcessData()).To(Succeed())
+		Expect(dp.DiskLayout()).To(BeNil())
+	})
+
+	It("should fail if the ISO image has no volume descriptor", func() {
+		tmpDir, err := ioutil.TempDir("", "data")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		dataFile := filepath.Join(tmpDir, "disk.img")
+		Expect(ioutil.WriteFile(dataFile, make([]byte, 18*2048), 0644)).To(Succeed())
+		mdp := &MockDataProvider{
+			infoResponse:     ProcessingPhaseTransferDataFile,
+			transferResponse: ProcessingPhaseValidateISO,
+		}
+		dp := NewDataProcessor(mdp, dataFile, "dataDir", "scratchDataDir", "1G")
+		err = dp.ProcessData()
+		Expect(errors.Cause(err)).To(Equal(image.ErrNotISO))
+	})
 })
 
 var _ = Describe("Convert", func() {
@@ -253,6 +423,23 @@ var _ = Describe("Convert", func() {
 		})
 	})
 
+	It("Should record the format and virtual size of the source image", func() {
+		url, err := url.Parse("http://fakeurl-notreal.fake")
+		Expect(err).ToNot(HaveOccurred())
+		mdp := &MockDataProvider{
+			url: url,
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		info := image.ImgInfo{Format: "qcow2", VirtualSize: SmallVirtualSize}
+		qemuOperations := NewFakeQEMUOperations(nil, nil, fakeInfoOpRetVal{&info, nil}, nil, nil, nil)
+		replaceQEMUOperations(qemuOperations, func() {
+			_, err := dp.convert(mdp.GetURL())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dp.SourceFormat()).To(Equal("qcow2"))
+			Expect(dp.VirtualSize()).To(Equal(int64(SmallVirtualSize)))
+		})
+	})
+
 	It("Should fail when validation fails and return Error", func() {
 		url, err := url.Parse("http://fakeurl-notreal.fake")
 		Expect(err).ToNot(HaveOccurred())
@@ -284,8 +471,142 @@ var _ = Describe("Convert", func() {
 	})
 })
 
+var _ = Describe("Verify", func() {
+	It("Should return verify after convert if verification is enabled", func() {
+		url, err := url.Parse("http://fakeurl-notreal.fake")
+		Expect(err).ToNot(HaveOccurred())
+		mdp := &MockDataProvider{
+			url: url,
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		dp.SetVerify(true)
+		qemuOperations := NewFakeQEMUOperations(nil, nil, fakeInfoOpRetVal{&fakeZeroImageInfo, nil}, nil, nil, nil)
+		replaceQEMUOperations(qemuOperations, func() {
+			nextPhase, err := dp.convert(mdp.GetURL())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ProcessingPhaseVerify).To(Equal(nextPhase))
+		})
+	})
+
+	It("Should return resize if the images match", func() {
+		url, err := url.Parse("http://fakeurl-notreal.fake")
+		Expect(err).ToNot(HaveOccurred())
+		mdp := &MockDataProvider{
+			url: url,
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		qemuOperations := NewFakeQEMUOperations(nil, nil, fakeInfoOpRetVal{&fakeZeroImageInfo, nil}, nil, nil, nil)
+		replaceQEMUOperations(qemuOperations, func() {
+			nextPhase, err := dp.verifyConversion(mdp.GetURL())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ProcessingPhaseResize).To(Equal(nextPhase))
+		})
+	})
+
+	It("Should require scratch space instead of converting a stream", func() {
+		mdp := &MockStreamingDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse:     ProcessingPhaseTransferScratch,
+				transferResponse: ProcessingPhaseError,
+				needsScratch:     true,
+			},
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		dp.SetVerify(true)
+		err := dp.ProcessData()
+		Expect(err).To(Equal(ErrRequiresScratchSpace))
+		Expect(mdp.streamFile).To(BeEmpty())
+	})
+
+	table.DescribeTable("Should compare the data written as is with the data file", func(written []byte, wantErr bool) {
+		tmpDir, err := ioutil.TempDir("", "verify")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		mdp := &MockVerifiableDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse:     ProcessingPhaseTransferDataFile,
+				transferResponse: ProcessingPhaseComplete,
+			},
+			data:    []byte("raw disk image"),
+			written: written,
+		}
+		dp := NewDataProcessor(mdp, filepath.Join(tmpDir, "disk.img"), "dataDir", "scratchDataDir", "")
+		dp.SetVerify(true)
+		err = dp.ProcessData()
+		if wantErr {
+			Expect(errors.Cause(err)).To(Equal(image.ErrImageMismatch))
+		} else {
+			Expect(err).NotTo(HaveOccurred())
+		}
+	},
+		table.Entry("and succeed if they match", []byte("raw disk image"), false),
+		table.Entry("and fail if they differ", []byte("raw disk imagE"), true),
+	)
+
+	It("Should record the checksum of the data written as is without reading the data file", func() {
+		tmpDir, err := ioutil.TempDir("", "digest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		mdp := &MockVerifiableDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse:     ProcessingPhaseTransferDataFile,
+				transferResponse: ProcessingPhaseComplete,
+			},
+			data:    []byte("raw disk image"),
+			written: []byte("data written"),
+		}
+		dp := NewDataProcessor(mdp, filepath.Join(tmpDir, "disk.img"), "dataDir", "scratchDataDir", "")
+		err = dp.ProcessData()
+		Expect(err).NotTo(HaveOccurred())
+		h := util.NewContentHash()
+		h.Write([]byte("data written"))
+		Expect(dp.Digest()).To(Equal(util.FormatContentChecksum(h)))
+	})
+
+	It("Should record the checksum of the virtual size of a converted image", func() {
+		tmpDir, err := ioutil.TempDir("", "digest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		dataFile := filepath.Join(tmpDir, "disk.img")
+		data := make([]byte, SmallVirtualSize+512)
+		data[0] = 1
+		Expect(ioutil.WriteFile(dataFile, data, 0644)).To(Succeed())
+		url, err := url.Parse("http://fakeurl-notreal.fake")
+		Expect(err).ToNot(HaveOccurred())
+		mdp := &MockDataProvider{
+			infoResponse: ProcessingPhaseConvert,
+			url:          url,
+		}
+		dp := NewDataProcessor(mdp, dataFile, "dataDir", "scratchDataDir", "")
+		qemuOperations := NewFakeQEMUOperations(nil, nil, fakeInfoRet, nil, nil, nil)
+		replaceQEMUOperations(qemuOperations, func() {
+			nextPhase, err := dp.convert(mdp.GetURL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nextPhase).To(Equal(ProcessingPhaseResize))
+		})
+		h := util.NewContentHash()
+		h.Write(data[:SmallVirtualSize])
+		Expect(dp.Digest()).To(Equal(util.FormatContentChecksum(h)))
+	})
+
+	It("Should fail when the images do not match and return Error", func() {
+		url, err := url.Parse("http://fakeurl-notreal.fake")
+		Expect(err).ToNot(HaveOccurred())
+		mdp := &MockDataProvider{
+			url: url,
+		}
+		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
+		qemuOperations := &fakeQEMUOperations{compareErr: errors.Wrap(image.ErrImageMismatch, "Content mismatch at offset 0")}
+		replaceQEMUOperations(qemuOperations, func() {
+			nextPhase, err := dp.verifyConversion(mdp.GetURL())
+			Expect(errors.Cause(err)).To(Equal(image.ErrImageMismatch))
+			Expect(ProcessingPhaseError).To(Equal(nextPhase))
+		})
+	})
+})
+
 var _ = Describe("Resize", func() {
-	It("Should not resize and return complete, when requestedSize is blank", func() {
+	It("Should not resize and return inspect, when requestedSize is blank", func() {
 		url, err := url.Parse("http://fakeurl-notreal.fake")
 		Expect(err).ToNot(HaveOccurred())
 		mdp := &MockDataProvider{
@@ -294,10 +615,10 @@ var _ = Describe("Resize", func() {
 		dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "")
 		nextPhase, err := dp.resize()
 		Expect(err).ToNot(HaveOccurred())
-		Expect(ProcessingPhaseComplete).To(Equal(nextPhase))
+		Expect(ProcessingPhaseInspect).To(Equal(nextPhase))
 	})
 
-	It("Should not resize and return complete, when requestedSize is valid, but datadir doesn't exist (block device)", func() {
+	It("Should not resize and return inspect, when requestedSize is valid, but datadir doesn't exist (block device)", func() {
 		replaceAvailableSpaceBlockFunc(func(dataDir string) int64 {
 			Expect("dest").To(Equal(dataDir))
 			return int64(100000)
@@ -310,11 +631,11 @@ var _ = Describe("Resize", func() {
 			dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "1G")
 			nextPhase, err := dp.resize()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(ProcessingPhaseComplete).To(Equal(nextPhase))
+			Expect(ProcessingPhaseInspect).To(Equal(nextPhase))
 		})
 	})
 
-	It("Should resize and return complete, when requestedSize is valid, and datadir exists", func() {
+	It("Should resize and return inspect, when requestedSize is valid, and datadir exists", func() {
 		tmpDir, err := ioutil.TempDir("", "data")
 		Expect(err).ToNot(HaveOccurred())
 		url, err := url.Parse("http://fakeurl-notreal.fake")
@@ -327,7 +648,7 @@ var _ = Describe("Resize", func() {
 		replaceQEMUOperations(qemuOperations, func() {
 			nextPhase, err := dp.resize()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(ProcessingPhaseComplete).To(Equal(nextPhase))
+			Expect(ProcessingPhaseInspect).To(Equal(nextPhase))
 		})
 	})
 
@@ -357,6 +678,32 @@ var _ = Describe("Resize", func() {
 			Expect(int64(100000)).To(Equal(dp.calculateTargetSize()))
 		})
 	})
+
+	It("Should reserve the filesystem overhead of a file system volume", func() {
+		replaceAvailableSpaceBlockFunc(func(dataDir string) int64 {
+			return int64(-1)
+		}, func() {
+			replaceAvailableSpaceFunc(func(dataDir string) int64 {
+				return int64(1024 * 1024 * 1024)
+			}, func() {
+				mdp := &MockDataProvider{}
+				dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "")
+				dp.SetFilesystemOverhead(0.1)
+				Expect(dp.availableSpace).To(Equal(int64(921 * 1024 * 1024)))
+			})
+		})
+	})
+
+	It("Should not reserve a filesystem overhead on a block volume", func() {
+		replaceAvailableSpaceBlockFunc(func(dataDir string) int64 {
+			return int64(1024 * 1024 * 1024)
+		}, func() {
+			mdp := &MockDataProvider{}
+			dp := NewDataProcessor(mdp, "dest", "dataDir", "scratchDataDir", "")
+			dp.SetFilesystemOverhead(0.1)
+			Expect(dp.availableSpace).To(Equal(int64(1024 * 1024 * 1024)))
+		})
+	})
 })
 
 var _ = Describe("ResizeImage", func() {
@@ -389,7 +736,7 @@ func replaceQEMUOperations(replacement image.QEMUOperations, f func()) {
 }
 
 func NewFakeQEMUOperations(e2, e3 error, ret4 fakeInfoOpRetVal, e5 error, e6 error, targetResize *resource.Quantity) image.QEMUOperations {
-	return &fakeQEMUOperations{e2, e3, ret4, e5, e6, targetResize}
+	return &fakeQEMUOperations{e2, e3, ret4, e5, e6, targetResize, nil}
 }
 
 func (o *fakeQEMUOperations) ConvertToRawStream(*url.URL, string) error {
@@ -415,6 +762,10 @@ func (o *fakeQEMUOperations) CreateBlankImage(dest string, size resource.Quantit
 	return o.e6
 }
 
+func (o *fakeQEMUOperations) Compare(*url.URL, string) error {
+	return o.compareErr
+}
+
 func NewQEMUAllErrors() image.QEMUOperations {
 	err := errors.New("qemu should not be called from this test override with replaceQEMUOperations")
 	return NewFakeQEMUOperations(err, err, fakeInfoOpRetVal{nil, err}, err, err, nil)
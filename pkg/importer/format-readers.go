@@ -24,8 +24,10 @@ import (
 	"io/ioutil"
 	"strconv"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
 	"github.com/ulikunitz/xz"
+	"golang.org/x/time/rate"
 
 	"k8s.io/klog"
 
@@ -44,7 +46,10 @@ var (
 		},
 		[]string{"ownerUID"},
 	)
-	ownerUID string
+	transferStats = prometheusutil.NewTransferStatsVec("import_transfer_stats", "The statistics of the import transfer")
+	ownerUID      string
+	// transferRateLimiter limits the rate the input stream is read, nil if the rate is not limited
+	transferRateLimiter *rate.Limiter
 )
 
 func init() {
@@ -57,6 +62,13 @@ func init() {
 			klog.Errorf("Unable to create prometheus progress counter")
 		}
 	}
+	if err := prometheus.Register(transferStats); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			transferStats = are.ExistingCollector.(*prometheus.GaugeVec)
+		} else {
+			klog.Errorf("Unable to create prometheus transfer statistics gauge")
+		}
+	}
 	ownerUID, _ = util.ParseEnvVar(common.OwnerUID, false)
 }
 
@@ -71,7 +83,10 @@ type FormatReaders struct {
 	buf            []byte // holds file headers
 	Convert        bool
 	Archived       bool
+	ArchiveFormat  string // "tar" or "zip" if the stream is a recognized archive
+	qcow2Size      int64  // the virtual size in the qcow2 header, if Convert
 	progressReader *prometheusutil.ProgressReader
+	countingReader *util.CountingReader
 }
 
 const (
@@ -79,6 +94,7 @@ const (
 	rdrMulti
 	rdrXz
 	rdrStream
+	rdrZst
 )
 
 // map scheme and format to rdrType
@@ -86,20 +102,24 @@ var rdrTypM = map[string]int{
 	"gz":     rdrGz,
 	"xz":     rdrXz,
 	"stream": rdrStream,
+	"zst":    rdrZst,
+}
+
+// SetTransferRateLimit limits the bytes per second read from the source of the import, 0 removes the limit. The limit is
+// shared by all readers, including the loopback proxy qemu-img reads the endpoint through.
+func SetTransferRateLimit(bytesPerSecond int64) {
+	transferRateLimiter = util.NewRateLimiter(bytesPerSecond)
 }
 
 // NewFormatReaders creates a new instance of FormatReaders using the input stream and content type passed in.
 func NewFormatReaders(stream io.ReadCloser, total uint64) (*FormatReaders, error) {
 	var err error
 	readers := &FormatReaders{
-		buf: make([]byte, image.MaxExpectedHdrSize),
-	}
-	if total > uint64(0) {
-		readers.progressReader = prometheusutil.NewProgressReader(stream, total, progress, ownerUID)
-		err = readers.constructReaders(readers.progressReader)
-	} else {
-		err = readers.constructReaders(stream)
+		buf:            make([]byte, image.MaxExpectedHdrSize),
+		countingReader: &util.CountingReader{Reader: util.NewRateLimitedReader(stream, transferRateLimiter)},
 	}
+	readers.progressReader = prometheusutil.NewProgressReader(readers.countingReader, total, progress, ownerUID)
+	err = readers.constructReaders(readers.progressReader)
 	return readers, err
 }
 
@@ -153,6 +173,24 @@ func (fr *FormatReaders) TopReader() io.ReadCloser {
 	return fr.readers[len(fr.readers)-1].rdr
 }
 
+// BytesRead returns the number of bytes read from the input stream.
+func (fr *FormatReaders) BytesRead() int64 {
+	return int64(fr.countingReader.Current)
+}
+
+// VirtualSize returns the virtual size of the image in the stream. The size of a qcow2 image is read from its header,
+// any other image is read to the end and discarded, its size is the number of bytes read after decompression.
+func (fr *FormatReaders) VirtualSize() (int64, error) {
+	if fr.Convert {
+		return fr.qcow2Size, nil
+	}
+	size, err := io.Copy(ioutil.Discard, fr.TopReader())
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to read the image")
+	}
+	return size, nil
+}
+
 // Based on the passed in header, append the format-specific reader to the readers stack,
 // and update the receiver Size field. Note: a bool is set in the receiver for qcow2 files.
 func (fr *FormatReaders) fileFormatSelector(hdr *image.Header) {
@@ -173,6 +211,13 @@ func (fr *FormatReaders) fileFormatSelector(hdr *image.Header) {
 		if err == nil {
 			fr.Archived = true
 		}
+	case "zst":
+		r, err = fr.zstReader()
+		if err == nil {
+			fr.Archived = true
+		}
+	case "tar", "zip":
+		fr.ArchiveFormat = fFmt
 	}
 	if err == nil && r != nil {
 		fr.appendReader(rdrTypM[fFmt], r)
@@ -198,10 +243,11 @@ func (fr *FormatReaders) gzReader() (io.ReadCloser, error) {
 // Note: size is stored at offset 24 in the qcow2 header.
 func (fr *FormatReaders) qcow2NopReader(h *image.Header) (io.Reader, error) {
 	s := hex.EncodeToString(fr.buf[h.SizeOff : h.SizeOff+h.SizeLen])
-	_, err := strconv.ParseInt(s, 16, 64)
+	size, err := strconv.ParseInt(s, 16, 64)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to determine original qcow2 file size from %+v", s)
 	}
+	fr.qcow2Size = size
 	return nil, nil
 }
 
@@ -219,6 +265,17 @@ func (fr *FormatReaders) xzReader() (io.Reader, error) {
 	return xz, nil
 }
 
+// Return the zstd reader. Like xz, the size is not stored in the header. Closing the reader stops the goroutines
+// of the decoder.
+func (fr *FormatReaders) zstReader() (io.ReadCloser, error) {
+	zst, err := zstd.NewReader(fr.TopReader())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create zstd reader")
+	}
+	klog.V(2).Infof("zstd: extracting stream\n")
+	return zst.IOReadCloser(), nil
+}
+
 // Return the matching header, if one is found, from the passed-in map of known headers. After a
 // successful read append a multi-reader to the receiver's reader stack.
 // Note: .iso files are not detected here but rather in the Size() function.
@@ -260,7 +317,14 @@ func (fr *FormatReaders) Close() (rtnerr error) {
 	return rtnerr
 }
 
-// StartProgressUpdate starts the go routine to automatically update the progress on a set interval.
+// StartProgressUpdate starts the go routine to automatically update the progress and the transfer statistics on a set
+// interval, and to push them to the progress server if the pod is configured to.
 func (fr *FormatReaders) StartProgressUpdate() {
+	fr.progressReader.ReportTransferStats(transferStats)
+	reporter, err := util.NewProgressReporterFromEnv()
+	if err != nil {
+		klog.Warningf("Not pushing progress reports: %v", err)
+	}
+	fr.progressReader.PushReports(reporter)
 	fr.progressReader.StartTimedUpdate()
 }
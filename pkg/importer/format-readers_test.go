@@ -1,8 +1,12 @@
 package importer
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,6 +33,14 @@ var (
 	stringRdr                 = strings.NewReader("test data for reader 1")
 )
 
+// qcow2Header returns the start of a qcow2 image of the virtual size.
+func qcow2Header(virtualSize uint64) []byte {
+	header := make([]byte, 64*1024)
+	copy(header, []byte{'Q', 'F', 'I', 0xfb, 0, 0, 0, 3})
+	binary.BigEndian.PutUint64(header[24:], virtualSize)
+	return header
+}
+
 var _ = Describe("Format Readers", func() {
 	var fr *FormatReaders
 	BeforeEach(func() {
@@ -89,4 +101,40 @@ var _ = Describe("Format Readers", func() {
 		table.Entry("should append io.reader", rdrGz, stringRdr, 3, false),
 		table.Entry("should append io.Multireader", rdrMulti, stringRdr, 3, false),
 	)
+
+	It("should read the virtual size of a qcow2 image from its header", func() {
+		var err error
+		fr, err = NewFormatReaders(ioutil.NopCloser(bytes.NewReader(qcow2Header(10737418240))), uint64(0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fr.Convert).To(BeTrue())
+		size, err := fr.VirtualSize()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(size).To(Equal(int64(10737418240)))
+	})
+
+	It("should read the virtual size of a compressed raw image by decompressing it", func() {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		_, err := gz.Write(make([]byte, 1024*1024))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gz.Close()).To(Succeed())
+		fr, err = NewFormatReaders(ioutil.NopCloser(&compressed), uint64(0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fr.Archived).To(BeTrue())
+		size, err := fr.VirtualSize()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(size).To(Equal(int64(1024 * 1024)))
+	})
+
+	It("should decompress a zstd compressed raw image", func() {
+		data := []byte(testRandomContent)
+		var err error
+		fr, err = NewFormatReaders(ioutil.NopCloser(bytes.NewReader(zstdCompressed(data))), uint64(0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fr.Archived).To(BeTrue())
+		read, err := ioutil.ReadAll(fr.TopReader())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read).To(Equal(data))
+		Expect(fr.Close()).To(Succeed())
+	})
 })
@@ -28,6 +28,7 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,18 +37,26 @@ import (
 	"k8s.io/klog"
 
 	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/image"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
 const (
 	tempFile = "tmpimage"
+
+	// names of the certificate and key in a kubernetes.io/tls secret
+	clientCertFile = "tls.crt"
+	clientKeyFile  = "tls.key"
 )
 
 // HTTPDataSource is the data provider for http(s) endpoints.
 // Sequence of phases:
-// 1a. Info -> Convert (In Info phase the format readers are configured), if the source Reader image is not archived, and no custom CA is used, and can be converted by QEMU-IMG (RAW/QCOW2)
+// 1a. Info -> Convert (In Info phase the format readers are configured), if the source Reader image is not archived, no checksum
+//     is used, and can be converted by QEMU-IMG (RAW/QCOW2). If a custom CA, client certificate or request headers are used
+//     QEMU-IMG reads the endpoint through a loopback proxy.
 // 1b. Info -> TransferArchive if the content type is archive
-// 1c. Info -> Transfer in all other cases.
+// 1c. Info -> TransferDataFile -> ValidateISO if the content type is iso
+// 1d. Info -> Transfer in all other cases.
 // 2a. Transfer -> Process if content type is kube virt
 // 2b. Transfer -> Complete if content type is archive (Transfer is called with the target instead of the scratch space). Non block PVCs only.
 // 3. Process -> Convert
@@ -64,25 +73,74 @@ type HTTPDataSource struct {
 	endpoint *url.URL
 	// url the url to report to the caller of getURL, could be the endpoint, or a file in scratch space.
 	url *url.URL
-	// true if we are using a custom CA (and thus have to use scratch storage)
-	customCA bool
+	// proxy used by qemu-img to read the endpoint, nil if qemu-img can read the endpoint itself.
+	proxy *loopbackProxy
+	// true if qemu-img converts the image straight from the endpoint.
+	convertFromEndpoint bool
 	// the content length reported by the http server.
 	contentLength uint64
+	// digest of the data read from the http server, nil if no checksum was requested.
+	digestReader *digestReader
+	// the checksum published for the endpoint.
+	expectedChecksum string
+	// the entries extracted from archive content.
+	archive *ArchiveOptions
+	// true if images are converted from the scratch space, so the target can be compared with it.
+	verify bool
+	// receives a copy of the data TransferFile writes.
+	writtenWriter io.Writer
 }
 
-// NewHTTPDataSource creates a new instance of the http data provider.
-func NewHTTPDataSource(endpoint, accessKey, secKey, certDir string, contentType cdiv1.DataVolumeContentType) (*HTTPDataSource, error) {
+// NewHTTPDataSource creates a new instance of the http data provider. The token is sent as a bearer token, and the
+// extraHeaders, in "Name: value" form, are added to every request. If clientCertDir is set the tls.crt and tls.key in it
+// are presented as client certificate. If checksum is not nil the downloaded data is verified against the published
+// checksum file. The archive options select what is extracted from archive content.
+func NewHTTPDataSource(endpoint, accessKey, secKey, token, certDir, clientCertDir string, extraHeaders []string, contentType cdiv1.DataVolumeContentType, checksum *HTTPChecksum, archive *ArchiveOptions) (*HTTPDataSource, error) {
 	ep, err := ParseEndpoint(endpoint)
 	if err != nil {
 		return nil, errors.Wrapf(err, fmt.Sprintf("unable to parse endpoint %q", endpoint))
 	}
+	headers, err := parseHTTPHeaders(extraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	creds := httpCredentials{
+		accessKey: accessKey,
+		secKey:    secKey,
+		token:     token,
+		headers:   headers,
+	}
+	expectedChecksum := ""
+	if checksum != nil && checksum.URL != "" {
+		client, err := createHTTPClient(certDir, clientCertDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error creating http client")
+		}
+		expectedChecksum, err = getExpectedChecksum(client, checksum, path.Base(ep.Path), ep, creds)
+		if err != nil {
+			return nil, err
+		}
+		klog.V(1).Infof("Expecting sha256 checksum %s", expectedChecksum)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
-	httpReader, contentLength, err := createHTTPReader(ctx, ep, accessKey, secKey, certDir)
+	httpReader, contentLength, err := createHTTPReader(ctx, ep, creds, certDir, clientCertDir)
 	if err != nil {
 		cancel()
 		return nil, err
 	}
 
+	var proxy *loopbackProxy
+	// qemu-img cannot be given a custom CA, a client certificate or extra request headers, and cannot be rate limited
+	needsProxy := certDir != "" || clientCertDir != "" || token != "" || len(headers) > 0 || transferRateLimiter != nil
+	if needsProxy && expectedChecksum == "" && contentType == cdiv1.DataVolumeKubeVirt {
+		proxy, err = newLoopbackProxy(ep, creds, certDir, clientCertDir)
+		if err != nil {
+			httpReader.Close()
+			cancel()
+			return nil, err
+		}
+	}
+
 	if accessKey != "" && secKey != "" {
 		ep.User = url.UserPassword(accessKey, secKey)
 	}
@@ -92,11 +150,17 @@ func NewHTTPDataSource(endpoint, accessKey, secKey, certDir string, contentType
 		httpReader:    httpReader,
 		contentType:   contentType,
 		endpoint:      ep,
-		customCA:      certDir != "",
+		proxy:         proxy,
 		contentLength: contentLength,
+		archive:       archive,
 	}
 	// We know this is a counting reader, so no need to check.
 	countingReader := httpReader.(*util.CountingReader)
+	if expectedChecksum != "" {
+		httpSource.digestReader = newDigestReader(httpReader)
+		httpSource.httpReader = httpSource.digestReader
+		httpSource.expectedChecksum = expectedChecksum
+	}
 	go httpSource.pollProgress(countingReader, 10*time.Minute, time.Second)
 	return httpSource, nil
 }
@@ -112,11 +176,19 @@ func (hs *HTTPDataSource) Info() (ProcessingPhase, error) {
 		klog.Errorf("Error creating readers: %v", err)
 		return ProcessingPhaseError, err
 	}
+	if hs.contentType == cdiv1.DataVolumeISO {
+		// ISO images are written as is, qemu-img would treat them as raw disks.
+		return ProcessingPhaseTransferDataFile, nil
+	}
 	// The readers now contain all the information needed to determine if we can stream directly or if we need scratch space to download
 	// the file to, before converting.
-	if !hs.readers.Archived && !hs.customCA {
+	if !hs.readers.Archived && hs.digestReader == nil && !hs.verify {
 		// We can pass straight to conversion from the endpoint. No scratch required.
 		hs.url = hs.endpoint
+		if hs.proxy != nil {
+			hs.url = hs.proxy.URL()
+		}
+		hs.convertFromEndpoint = true
 		return ProcessingPhaseConvert, nil
 	}
 	if !hs.readers.Convert {
@@ -137,12 +209,18 @@ func (hs *HTTPDataSource) Transfer(path string) (ProcessingPhase, error) {
 		if err != nil {
 			return ProcessingPhaseError, err
 		}
+		if err := hs.verifyChecksum(); err != nil {
+			return ProcessingPhaseError, err
+		}
 		// If we successfully wrote to the file, then the parse will succeed.
 		hs.url, _ = url.Parse(file)
 		return ProcessingPhaseProcess, nil
 	} else if hs.contentType == cdiv1.DataVolumeArchive {
-		if err := util.UnArchiveTar(hs.readers.TopReader(), path); err != nil {
-			return ProcessingPhaseError, errors.Wrap(err, "unable to untar files from endpoint")
+		if err := extractArchive(hs.readers.TopReader(), hs.readers.ArchiveFormat, path, hs.archive); err != nil {
+			return ProcessingPhaseError, errors.Wrap(err, "unable to extract files from endpoint")
+		}
+		if err := hs.verifyChecksum(); err != nil {
+			return ProcessingPhaseError, err
 		}
 		hs.url = nil
 		return ProcessingPhaseComplete, nil
@@ -153,13 +231,60 @@ func (hs *HTTPDataSource) Transfer(path string) (ProcessingPhase, error) {
 // TransferFile is called to transfer the data from the source to the passed in file.
 func (hs *HTTPDataSource) TransferFile(fileName string) (ProcessingPhase, error) {
 	hs.readers.StartProgressUpdate()
-	err := util.StreamDataToFile(hs.readers.TopReader(), fileName)
+	var reader io.Reader = hs.readers.TopReader()
+	if hs.contentType == cdiv1.DataVolumeKubeVirt {
+		var err error
+		if reader, err = image.NewRawValidationReader(reader); err != nil {
+			return ProcessingPhaseError, err
+		}
+	}
+	if hs.writtenWriter != nil {
+		reader = io.TeeReader(reader, hs.writtenWriter)
+	}
+	err := util.StreamDataToFile(reader, fileName)
 	if err != nil {
 		return ProcessingPhaseError, err
 	}
+	if err := hs.verifyChecksum(); err != nil {
+		return ProcessingPhaseError, err
+	}
+	if hs.contentType == cdiv1.DataVolumeISO {
+		return ProcessingPhaseValidateISO, nil
+	}
 	return ProcessingPhaseResize, nil
 }
 
+// TransferStream is called to convert the qcow2 data from the source to the passed in RAW file, without scratch space.
+func (hs *HTTPDataSource) TransferStream(fileName string, availableSize int64) (ProcessingPhase, error) {
+	if !hs.readers.Convert {
+		return ProcessingPhaseError, image.ErrQcow2NotStreamable
+	}
+	hs.readers.StartProgressUpdate()
+	if err := image.ConvertQcow2Stream(hs.readers.TopReader(), fileName, availableSize); err != nil {
+		return ProcessingPhaseError, err
+	}
+	if err := hs.verifyChecksum(); err != nil {
+		return ProcessingPhaseError, err
+	}
+	return ProcessingPhaseResize, nil
+}
+
+// EnableVerification makes qemu-img convert the image from the scratch space, so the target can be compared with it.
+func (hs *HTTPDataSource) EnableVerification() {
+	hs.verify = true
+}
+
+// CopyWrittenData copies the data TransferFile writes to w.
+func (hs *HTTPDataSource) CopyWrittenData(w io.Writer) {
+	hs.writtenWriter = w
+}
+
+// VirtualSize returns the virtual size of the image, read from the qcow2 header or by reading the whole stream, so the
+// size probe does not need scratch space.
+func (hs *HTTPDataSource) VirtualSize() (int64, error) {
+	return hs.readers.VirtualSize()
+}
+
 // Process is called to do any special processing before giving the URI to the data back to the processor
 func (hs *HTTPDataSource) Process() (ProcessingPhase, error) {
 	return ProcessingPhaseConvert, nil
@@ -170,12 +295,27 @@ func (hs *HTTPDataSource) GetURL() *url.URL {
 	return hs.url
 }
 
+// BytesTransferred returns the number of bytes read from the endpoint. If qemu-img read the endpoint itself that is
+// the content length reported by the http server.
+func (hs *HTTPDataSource) BytesTransferred() int64 {
+	if hs.convertFromEndpoint {
+		return int64(hs.contentLength)
+	}
+	if hs.readers == nil {
+		return 0
+	}
+	return hs.readers.BytesRead()
+}
+
 // Close all readers.
 func (hs *HTTPDataSource) Close() error {
 	var err error
 	if hs.readers != nil {
 		err = hs.readers.Close()
 	}
+	if hs.proxy != nil {
+		hs.proxy.Close()
+	}
 	hs.cancelLock.Lock()
 	if hs.cancel != nil {
 		hs.cancel()
@@ -185,15 +325,62 @@ func (hs *HTTPDataSource) Close() error {
 	return err
 }
 
-func createHTTPClient(certDir string) (*http.Client, error) {
+// verifyChecksum compares the digest of the data received from the endpoint with the published checksum.
+func (hs *HTTPDataSource) verifyChecksum() error {
+	if hs.digestReader == nil {
+		return nil
+	}
+	sum, err := hs.digestReader.Sum()
+	if err != nil {
+		return err
+	}
+	if sum != hs.expectedChecksum {
+		klog.Errorf("Checksum mismatch, expected %s, got %s", hs.expectedChecksum, sum)
+		return ErrChecksumMismatch
+	}
+	klog.V(1).Infof("Checksum %s verified", sum)
+	return nil
+}
+
+func createHTTPClient(certDir, clientCertDir string) (*http.Client, error) {
 	client := &http.Client{
 		// Don't set timeout here, since that will be an absolute timeout, we need a relative to last progress timeout.
 	}
 
-	if certDir == "" {
+	if certDir == "" && clientCertDir == "" {
 		return client, nil
 	}
 
+	tlsConfig := &tls.Config{}
+	if certDir != "" {
+		certPool, err := loadCertPool(certDir)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if clientCertDir != "" {
+		certFile := path.Join(clientCertDir, clientCertFile)
+		keyFile := path.Join(clientCertDir, clientKeyFile)
+		klog.Infof("Attempting to get client certificate from %s", certFile)
+		clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error loading client certificate from %s", clientCertDir)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+
+	return client, nil
+}
+
+// loadCertPool returns the system certs with the certs found in certDir added.
+func loadCertPool(certDir string) (*x509.CertPool, error) {
 	// let's get system certs as well
 	certPool, err := x509.SystemCertPool()
 	if err != nil {
@@ -224,29 +411,70 @@ func createHTTPClient(certDir string) (*http.Client, error) {
 		}
 	}
 
-	client.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs: certPool,
-		},
+	return certPool, nil
+}
+
+// httpCredentials are applied to every request sent to the http endpoint, including redirects.
+type httpCredentials struct {
+	accessKey, secKey, token string
+	headers                  http.Header
+}
+
+// apply sets the basic auth, bearer token and extra headers on the request. The token takes precedence over basic
+// auth, and the extra headers override both.
+func (c httpCredentials) apply(req *http.Request) {
+	if len(c.accessKey) > 0 && len(c.secKey) > 0 {
+		req.SetBasicAuth(c.accessKey, c.secKey)
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+}
 
-	return client, nil
+// parseHTTPHeaders parses headers in "Name: value" form, empty lines are ignored.
+func parseHTTPHeaders(lines []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, errors.Errorf("invalid http header %q, expected \"Name: value\"", line)
+		}
+		headers.Add(name, strings.TrimSpace(parts[1]))
+	}
+	return headers, nil
+}
+
+// HTTPStatusError is returned when the http server does not respond with 200 OK.
+type HTTPStatusError struct {
+	// StatusCode is the status code of the response
+	StatusCode int
+	// Status is the status line of the response
+	Status string
 }
 
-func createHTTPReader(ctx context.Context, ep *url.URL, accessKey, secKey, certDir string) (io.ReadCloser, uint64, error) {
-	client, err := createHTTPClient(certDir)
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("expected status code 200, got %d. Status: %s", e.StatusCode, e.Status)
+}
+
+func createHTTPReader(ctx context.Context, ep *url.URL, creds httpCredentials, certDir, clientCertDir string) (io.ReadCloser, uint64, error) {
+	client, err := createHTTPClient(certDir, clientCertDir)
 	if err != nil {
 		return nil, uint64(0), errors.Wrap(err, "Error creating http client")
 	}
 
 	client.CheckRedirect = func(r *http.Request, via []*http.Request) error {
-		if len(accessKey) > 0 && len(secKey) > 0 {
-			r.SetBasicAuth(accessKey, secKey) // Redirects will lose basic auth, so reset them manually
-		}
+		creds.apply(r) // Redirects will lose auth headers, so reset them manually
 		return nil
 	}
 
-	total, err := getContentLength(client, ep, accessKey, secKey)
+	total, err := getContentLength(client, ep, creds)
 	if err != nil {
 		return nil, total, err
 	}
@@ -254,9 +482,7 @@ func createHTTPReader(ctx context.Context, ep *url.URL, accessKey, secKey, certD
 	req, _ := http.NewRequest("GET", ep.String(), nil)
 
 	req = req.WithContext(ctx)
-	if len(accessKey) > 0 && len(secKey) > 0 {
-		req.SetBasicAuth(accessKey, secKey)
-	}
+	creds.apply(req)
 	klog.V(2).Infof("Attempting to get object %q via http client\n", ep.String())
 	resp, err := client.Do(req)
 	if err != nil {
@@ -264,7 +490,7 @@ func createHTTPReader(ctx context.Context, ep *url.URL, accessKey, secKey, certD
 	}
 	if resp.StatusCode != 200 {
 		klog.Errorf("http: expected status code 200, got %d", resp.StatusCode)
-		return nil, uint64(0), errors.Errorf("expected status code 200, got %d. Status: %s", resp.StatusCode, resp.Status)
+		return nil, uint64(0), &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 	countingReader := &util.CountingReader{
 		Reader:  resp.Body,
@@ -300,14 +526,12 @@ func (hs *HTTPDataSource) pollProgress(reader *util.CountingReader, idleTime, po
 	}
 }
 
-func getContentLength(client *http.Client, ep *url.URL, accessKey, secKey string) (uint64, error) {
+func getContentLength(client *http.Client, ep *url.URL, creds httpCredentials) (uint64, error) {
 	req, err := http.NewRequest("HEAD", ep.String(), nil)
 	if err != nil {
 		return uint64(0), errors.Wrap(err, "could not create HTTP request")
 	}
-	if len(accessKey) > 0 && len(secKey) > 0 {
-		req.SetBasicAuth(accessKey, secKey)
-	}
+	creds.apply(req)
 
 	klog.V(2).Infof("Attempting to HEAD %q via http client\n", ep.String())
 	resp, err := client.Do(req)
@@ -317,7 +541,7 @@ func getContentLength(client *http.Client, ep *url.URL, accessKey, secKey string
 
 	if resp.StatusCode != 200 {
 		klog.Errorf("http: expected status code 200, got %d", resp.StatusCode)
-		return uint64(0), errors.Errorf("expected status code 200, got %d. Status: %s", resp.StatusCode, resp.Status)
+		return uint64(0), &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	for k, v := range resp.Header {
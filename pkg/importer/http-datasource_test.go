@@ -1,7 +1,9 @@
 package importer
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"io"
 	"io/ioutil"
@@ -71,14 +73,14 @@ var _ = Describe("Http data source", func() {
 	})
 
 	It("NewHTTPDataSource should fail when called with an invalid endpoint", func() {
-		_, err = NewHTTPDataSource("httpd://!@#$%^&*()dgsdd&3r53/invalid", "", "", "", cdiv1.DataVolumeKubeVirt)
+		_, err = NewHTTPDataSource("httpd://!@#$%^&*()dgsdd&3r53/invalid", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).To(HaveOccurred())
 		Expect(strings.Contains(err.Error(), "unable to parse endpoint")).To(BeTrue())
 	})
 
 	It("endpoint User object should be set when accessKey and secKey are not blank", func() {
 		image := ts.URL + "/" + cirrosFileName
-		dp, err = NewHTTPDataSource(image, "user", "password", "", cdiv1.DataVolumeKubeVirt)
+		dp, err = NewHTTPDataSource(image, "user", "password", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		user := dp.endpoint.User
 		Expect("user").To(Equal(user.Username()))
@@ -89,7 +91,7 @@ var _ = Describe("Http data source", func() {
 
 	It("NewHTTPDataSource should fail when called with an invalid certdir", func() {
 		image := ts.URL + "/" + cirrosFileName
-		_, err = NewHTTPDataSource(image, "", "", "/invaliddir", cdiv1.DataVolumeKubeVirt)
+		_, err = NewHTTPDataSource(image, "", "", "", "/invaliddir", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -98,7 +100,7 @@ var _ = Describe("Http data source", func() {
 		if image != "" {
 			image = ts.URL + "/" + image
 		}
-		dp, err = NewHTTPDataSource(image, "", "", "", contentType)
+		dp, err = NewHTTPDataSource(image, "", "", "", "", "", nil, contentType, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		newPhase, err := dp.Info()
 		if !wantErr {
@@ -119,13 +121,45 @@ var _ = Describe("Http data source", func() {
 	)
 
 	It("calling info with raw image should return TransferDataFile", func() {
-		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreGz, "", "", "", cdiv1.DataVolumeKubeVirt)
+		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreGz, "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		newPhase, err := dp.Info()
 		Expect(err).NotTo(HaveOccurred())
 		Expect(ProcessingPhaseTransferDataFile).To(Equal(newPhase))
 	})
 
+	It("calling info with verification enabled should convert a qcow2 image from the scratch space", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(qcow2Header(1073741824))
+		}))
+		defer server.Close()
+		dp, err = NewHTTPDataSource(server.URL+"/disk.qcow2", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		dp.EnableVerification()
+		newPhase, err := dp.Info()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newPhase).To(Equal(ProcessingPhaseTransferScratch))
+	})
+
+	It("TransferFile with verification enabled should copy the data written to the file", func() {
+		data := bytes.Repeat([]byte("raw disk"), 128*1024)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(data)
+		}))
+		defer server.Close()
+		dp, err = NewHTTPDataSource(server.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		var written bytes.Buffer
+		dp.EnableVerification()
+		dp.CopyWrittenData(&written)
+		newPhase, err := dp.Info()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newPhase).To(Equal(ProcessingPhaseTransferDataFile))
+		_, err = dp.TransferFile(filepath.Join(tmpDir, "disk.img"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written.Bytes()).To(Equal(data))
+	})
+
 	table.DescribeTable("calling transfer should", func(image string, contentType cdiv1.DataVolumeContentType, expectedPhase ProcessingPhase, scratchPath string, want []byte, wantErr bool) {
 		flushRead = want
 		if scratchPath == "" {
@@ -134,7 +168,7 @@ var _ = Describe("Http data source", func() {
 		if image != "" {
 			image = ts.URL + "/" + image
 		}
-		dp, err = NewHTTPDataSource(image, "", "", "", contentType)
+		dp, err = NewHTTPDataSource(image, "", "", "", "", "", nil, contentType, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		_, err := dp.Info()
 		Expect(err).NotTo(HaveOccurred())
@@ -165,7 +199,7 @@ var _ = Describe("Http data source", func() {
 	)
 
 	It("TransferFile should succeed when writing to valid file, and reading raw gz", func() {
-		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreGz, "", "", "", cdiv1.DataVolumeKubeVirt)
+		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreGz, "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		result, err := dp.Info()
 		Expect(err).NotTo(HaveOccurred())
@@ -176,7 +210,7 @@ var _ = Describe("Http data source", func() {
 	})
 
 	It("TransferFile should succeed when writing to valid file and reading raw xz", func() {
-		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreXz, "", "", "", cdiv1.DataVolumeKubeVirt)
+		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreXz, "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		result, err := dp.Info()
 		Expect(err).NotTo(HaveOccurred())
@@ -187,7 +221,7 @@ var _ = Describe("Http data source", func() {
 	})
 
 	It("TransferFile should fail on streaming error", func() {
-		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreGz, "", "", "", cdiv1.DataVolumeKubeVirt)
+		dp, err = NewHTTPDataSource(ts.URL+"/"+tinyCoreGz, "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		result, err := dp.Info()
 		Expect(err).NotTo(HaveOccurred())
@@ -199,7 +233,7 @@ var _ = Describe("Http data source", func() {
 
 	It("calling Process should return Convert", func() {
 		flushRead = cirrosData
-		dp, err = NewHTTPDataSource(ts.URL+"/"+cirrosFileName, "", "", "", cdiv1.DataVolumeKubeVirt)
+		dp, err = NewHTTPDataSource(ts.URL+"/"+cirrosFileName, "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 		_, err := dp.Info()
 		Expect(err).NotTo(HaveOccurred())
@@ -234,7 +268,7 @@ var _ = Describe("Http client", func() {
 	})
 
 	It("should load the cert", func() {
-		client, err := createHTTPClient(tempDir)
+		client, err := createHTTPClient(tempDir, "")
 		Expect(err).ToNot(HaveOccurred())
 
 		transport := client.Transport.(*http.Transport)
@@ -249,11 +283,78 @@ var _ = Describe("Http client", func() {
 		Expect(len(activeCAs.Subjects())).Should(Equal(len(systemCAs.Subjects()) + 1))
 	})
 
+	It("should load the client cert", func() {
+		clientCertDir := writeClientKeyPair(tempDir)
+		client, err := createHTTPClient("", clientCertDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		transport := client.Transport.(*http.Transport)
+		Expect(transport.TLSClientConfig.RootCAs).To(BeNil())
+		Expect(transport.TLSClientConfig.Certificates).To(HaveLen(1))
+	})
+
+	It("should fail when the client key is missing", func() {
+		clientCertDir := writeClientKeyPair(tempDir)
+		Expect(os.Remove(path.Join(clientCertDir, "tls.key"))).To(Succeed())
+		_, err := createHTTPClient("", clientCertDir)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should present the client cert to a server requiring it", func() {
+		caKeyPair, err := triple.NewCA("client-ca")
+		Expect(err).ToNot(HaveOccurred())
+		clientCertDir := writeClientKeyPairSignedBy(tempDir, caKeyPair)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(caKeyPair.Cert)
+
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("data"))
+		}))
+		ts.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+		ts.StartTLS()
+		defer ts.Close()
+
+		certDir := path.Join(tempDir, "server-ca")
+		Expect(os.Mkdir(certDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path.Join(certDir, "ca.crt"), cert.EncodeCertPEM(ts.Certificate()), 0644)).To(Succeed())
+		ep, err := url.Parse(ts.URL)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = createHTTPReader(context.Background(), ep, httpCredentials{}, certDir, "")
+		Expect(err).To(HaveOccurred())
+
+		r, total, err := createHTTPReader(context.Background(), ep, httpCredentials{}, certDir, clientCertDir)
+		Expect(err).ToNot(HaveOccurred())
+		defer r.Close()
+		Expect(total).To(Equal(uint64(4)))
+		data, err := ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("data"))
+	})
 })
 
+func writeClientKeyPair(dir string) string {
+	caKeyPair, err := triple.NewCA("client-ca")
+	Expect(err).ToNot(HaveOccurred())
+	return writeClientKeyPairSignedBy(dir, caKeyPair)
+}
+
+func writeClientKeyPairSignedBy(dir string, caKeyPair *triple.KeyPair) string {
+	keyPair, err := triple.NewClientKeyPair(caKeyPair, "importer", nil)
+	Expect(err).ToNot(HaveOccurred())
+	clientCertDir := path.Join(dir, "client")
+	Expect(os.Mkdir(clientCertDir, 0755)).To(Succeed())
+	Expect(ioutil.WriteFile(path.Join(clientCertDir, "tls.crt"), cert.EncodeCertPEM(keyPair.Cert), 0644)).To(Succeed())
+	Expect(ioutil.WriteFile(path.Join(clientCertDir, "tls.key"), cert.EncodePrivateKeyPEM(keyPair.Key), 0600)).To(Succeed())
+	return clientCertDir
+}
+
 var _ = Describe("Http reader", func() {
 	It("should fail when passed an invalid cert directory", func() {
-		_, total, err := createHTTPReader(context.Background(), nil, "", "", "/invalid")
+		_, total, err := createHTTPReader(context.Background(), nil, httpCredentials{}, "/invalid", "")
 		Expect(err).To(HaveOccurred())
 		Expect(uint64(0)).To(Equal(total))
 	})
@@ -270,7 +371,7 @@ var _ = Describe("Http reader", func() {
 		defer ts.Close()
 		ep, err := url.Parse(ts.URL)
 		Expect(err).ToNot(HaveOccurred())
-		r, total, err := createHTTPReader(context.Background(), ep, "user", "password", "")
+		r, total, err := createHTTPReader(context.Background(), ep, httpCredentials{accessKey: "user", secKey: "password"}, "", "")
 		Expect(err).ToNot(HaveOccurred())
 		Expect(uint64(25)).To(Equal(total))
 		err = r.Close()
@@ -293,13 +394,73 @@ var _ = Describe("Http reader", func() {
 		defer ts.Close()
 		ep, err := url.Parse(ts.URL)
 		Expect(err).ToNot(HaveOccurred())
-		r, total, err := createHTTPReader(context.Background(), ep, "user", "password", "")
+		r, total, err := createHTTPReader(context.Background(), ep, httpCredentials{accessKey: "user", secKey: "password"}, "", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(uint64(25)).To(Equal(total))
+		err = r.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should pass bearer token and extra headers in HEAD and GET requests if set", func() {
+		methods := []string{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer w.WriteHeader(http.StatusOK)
+			methods = append(methods, r.Method)
+			Expect("Bearer mytoken").To(Equal(r.Header.Get("Authorization")))
+			Expect("myapikey").To(Equal(r.Header.Get("X-JFrog-Art-Api")))
+			w.Header().Add("Content-Length", "25")
+		}))
+		defer ts.Close()
+		ep, err := url.Parse(ts.URL)
+		Expect(err).ToNot(HaveOccurred())
+		creds := httpCredentials{token: "mytoken", headers: http.Header{"X-Jfrog-Art-Api": []string{"myapikey"}}}
+		r, total, err := createHTTPReader(context.Background(), ep, creds, "", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(uint64(25)).To(Equal(total))
+		Expect(methods).To(Equal([]string{"HEAD", "GET"}))
+		err = r.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should pass bearer token and extra headers if set and redirected", func() {
+		redirTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer w.WriteHeader(http.StatusOK)
+			Expect("Bearer mytoken").To(Equal(r.Header.Get("Authorization")))
+			Expect("myapikey").To(Equal(r.Header.Get("X-JFrog-Art-Api")))
+			w.Header().Add("Content-Length", "25")
+		}))
+		defer redirTs.Close()
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, redirTs.URL, http.StatusFound)
+		}))
+		defer ts.Close()
+		ep, err := url.Parse(ts.URL)
+		Expect(err).ToNot(HaveOccurred())
+		creds := httpCredentials{token: "mytoken", headers: http.Header{"X-Jfrog-Art-Api": []string{"myapikey"}}}
+		r, total, err := createHTTPReader(context.Background(), ep, creds, "", "")
 		Expect(err).ToNot(HaveOccurred())
 		Expect(uint64(25)).To(Equal(total))
 		err = r.Close()
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("should prefer the bearer token over basic auth", func() {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer w.WriteHeader(http.StatusOK)
+			_, _, ok := r.BasicAuth()
+			Expect(ok).To(BeFalse())
+			Expect("Bearer mytoken").To(Equal(r.Header.Get("Authorization")))
+			w.Header().Add("Content-Length", "25")
+		}))
+		defer ts.Close()
+		ep, err := url.Parse(ts.URL)
+		Expect(err).ToNot(HaveOccurred())
+		r, _, err := createHTTPReader(context.Background(), ep, httpCredentials{accessKey: "user", secKey: "password", token: "mytoken"}, "", "")
+		Expect(err).ToNot(HaveOccurred())
+		err = r.Close()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
 	It("should redirect properly without auth if not set", func() {
 		redirTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			_, _, ok := r.BasicAuth()
@@ -314,7 +475,7 @@ var _ = Describe("Http reader", func() {
 		defer ts.Close()
 		ep, err := url.Parse(ts.URL)
 		Expect(err).ToNot(HaveOccurred())
-		r, total, err := createHTTPReader(context.Background(), ep, "", "", "")
+		r, total, err := createHTTPReader(context.Background(), ep, httpCredentials{}, "", "")
 		Expect(err).ToNot(HaveOccurred())
 		Expect(uint64(25)).To(Equal(total))
 		err = r.Close()
@@ -328,13 +489,86 @@ var _ = Describe("Http reader", func() {
 		defer ts.Close()
 		ep, err := url.Parse(ts.URL)
 		Expect(err).ToNot(HaveOccurred())
-		_, total, err := createHTTPReader(context.Background(), ep, "", "", "")
+		_, total, err := createHTTPReader(context.Background(), ep, httpCredentials{}, "", "")
 		Expect(err).To(HaveOccurred())
 		Expect(uint64(0)).To(Equal(total))
 		Expect("expected status code 200, got 500. Status: 500 Internal Server Error").To(Equal(err.Error()))
 	})
 })
 
+var _ = Describe("Http headers", func() {
+	table.DescribeTable("parseHTTPHeaders should", func(lines []string, expected http.Header, wantErr bool) {
+		headers, err := parseHTTPHeaders(lines)
+		if wantErr {
+			Expect(err).To(HaveOccurred())
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headers).To(Equal(expected))
+		}
+	},
+		table.Entry("return empty headers for no lines", nil, http.Header{}, false),
+		table.Entry("ignore empty lines", []string{"", "  "}, http.Header{}, false),
+		table.Entry("parse a header", []string{"X-JFrog-Art-Api: myapikey"}, http.Header{"X-Jfrog-Art-Api": []string{"myapikey"}}, false),
+		table.Entry("keep colons in the value", []string{"X-Time: 12:00"}, http.Header{"X-Time": []string{"12:00"}}, false),
+		table.Entry("add repeated headers", []string{"X-A: 1", "X-A: 2"}, http.Header{"X-A": []string{"1", "2"}}, false),
+		table.Entry("fail without a colon", []string{"X-JFrog-Art-Api myapikey"}, nil, true),
+		table.Entry("fail without a name", []string{": myapikey"}, nil, true),
+	)
+
+	It("Should convert through the loopback proxy when a token is used", func() {
+		content := make([]byte, 1024*1024)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer mytoken" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.ServeContent(w, r, "disk.img", time.Now(), bytes.NewReader(content))
+		}))
+		defer ts.Close()
+		dp, err := NewHTTPDataSource(ts.URL+"/disk.img", "", "", "mytoken", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer dp.Close()
+		phase, err := dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseConvert))
+		Expect(dp.GetURL().Hostname()).To(Equal("127.0.0.1"))
+		resp, err := http.Get(dp.GetURL().String())
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.ContentLength).To(Equal(int64(len(content))))
+	})
+
+	It("Should write an ISO image without conversion and validate it", func() {
+		content := make([]byte, 18*2048)
+		copy(content[16*2048:], "\x01CD001\x01")
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "installer.iso", time.Now(), bytes.NewReader(content))
+		}))
+		defer ts.Close()
+		tmpDir, err := ioutil.TempDir("", "iso")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		dp, err := NewHTTPDataSource(ts.URL+"/installer.iso", "", "", "", "", "", nil, cdiv1.DataVolumeISO, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer dp.Close()
+		phase, err := dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseTransferDataFile))
+		phase, err = dp.TransferFile(filepath.Join(tmpDir, "disk.img"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseValidateISO))
+		written, err := ioutil.ReadFile(filepath.Join(tmpDir, "disk.img"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bytes.Equal(written, content)).To(BeTrue())
+	})
+
+	It("Should fail with invalid extra headers", func() {
+		_, err := NewHTTPDataSource("http://localhost/disk.img", "", "", "", "", "", []string{"invalid"}, cdiv1.DataVolumeKubeVirt, nil, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
 var _ = Describe("http pollprogress", func() {
 	It("Should properly finish with valid reader", func() {
 		By("Creating context for the transfer, we have the ability to cancel it")
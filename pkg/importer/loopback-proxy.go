@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/klog"
+
+	"kubevirt.io/containerized-data-importer/pkg/util"
+)
+
+var (
+	// request headers qemu-img sends that are passed on to the endpoint
+	forwardedRequestHeaders = []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match"}
+	// response headers of the endpoint that are passed back to qemu-img
+	forwardedResponseHeaders = []string{"Content-Length", "Content-Range", "Content-Type", "Accept-Ranges", "Last-Modified", "ETag"}
+)
+
+// loopbackProxy serves a single http endpoint on the loopback interface. This allows qemu-img to stream from endpoints
+// that need a custom CA, a client certificate or request headers, which it cannot be configured with, and limits the
+// rate qemu-img reads with. The requests are forwarded with the importer's http client, which does the TLS and adds the
+// credentials.
+type loopbackProxy struct {
+	endpoint *url.URL
+	client   *http.Client
+	creds    httpCredentials
+	listener net.Listener
+	server   *http.Server
+}
+
+// newLoopbackProxy starts a proxy forwarding to endpoint. Close must be called to stop it.
+func newLoopbackProxy(endpoint *url.URL, creds httpCredentials, certDir, clientCertDir string) (*loopbackProxy, error) {
+	client, err := createHTTPClient(certDir, clientCertDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating http client")
+	}
+	client.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		creds.apply(r) // Redirects will lose auth headers, so reset them manually
+		return nil
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to listen on loopback interface")
+	}
+	p := &loopbackProxy{
+		endpoint: endpoint,
+		client:   client,
+		creds:    creds,
+		listener: listener,
+	}
+	p.server = &http.Server{Handler: p}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("Loopback proxy failed: %v", err)
+		}
+	}()
+	klog.V(1).Infof("Serving %s on %s", endpoint.Host, listener.Addr())
+	return p, nil
+}
+
+// URL returns the loopback url of the endpoint.
+func (p *loopbackProxy) URL() *url.URL {
+	return &url.URL{
+		Scheme: "http",
+		Host:   p.listener.Addr().String(),
+		Path:   p.endpoint.Path,
+	}
+}
+
+// ServeHTTP forwards GET and HEAD requests to the endpoint, regardless of the requested path.
+func (p *loopbackProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := http.NewRequest(r.Method, p.endpoint.String(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req = req.WithContext(r.Context())
+	for _, name := range forwardedRequestHeaders {
+		if value := r.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	// qemu-img needs the exact bytes and content length of the image
+	req.Header.Set("Accept-Encoding", "identity")
+	p.creds.apply(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		klog.Errorf("Loopback proxy request errored: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, name := range forwardedResponseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, util.NewRateLimitedReader(resp.Body, transferRateLimiter)); err != nil {
+		klog.V(3).Infof("Loopback proxy response interrupted: %v", err)
+	}
+}
+
+// Close stops the proxy.
+func (p *loopbackProxy) Close() error {
+	return p.server.Close()
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/util/cert"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+var _ = Describe("Loopback proxy", func() {
+	var (
+		ts      *httptest.Server
+		certDir string
+		content []byte
+		ep      *url.URL
+	)
+
+	BeforeEach(func() {
+		var err error
+		content = make([]byte, 64*1024)
+		_, err = rand.Read(content)
+		Expect(err).ToNot(HaveOccurred())
+		ts = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer mytoken" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.ServeContent(w, r, "disk.img", time.Now(), bytes.NewReader(content))
+		}))
+		certDir, err = ioutil.TempDir("", "proxy-cert")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(path.Join(certDir, "ca.crt"), cert.EncodeCertPEM(ts.Certificate()), 0644)).To(Succeed())
+		ep, err = url.Parse(ts.URL + "/disk.img")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(certDir)
+	})
+
+	It("Should forward range requests using the custom CA and credentials", func() {
+		proxy, err := newLoopbackProxy(ep, httpCredentials{token: "mytoken"}, certDir, "")
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		Expect(proxy.URL().Scheme).To(Equal("http"))
+		Expect(proxy.URL().Path).To(Equal("/disk.img"))
+		req, err := http.NewRequest(http.MethodGet, proxy.URL().String(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Range", "bytes=1024-2047")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusPartialContent))
+		Expect(resp.Header.Get("Content-Range")).To(Equal("bytes 1024-2047/65536"))
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(content[1024:2048]))
+	})
+
+	It("Should report the content length on HEAD requests", func() {
+		proxy, err := newLoopbackProxy(ep, httpCredentials{token: "mytoken"}, certDir, "")
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		resp, err := http.Head(proxy.URL().String())
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.ContentLength).To(Equal(int64(len(content))))
+	})
+
+	It("Should reject other methods", func() {
+		proxy, err := newLoopbackProxy(ep, httpCredentials{}, certDir, "")
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		resp, err := http.Post(proxy.URL().String(), "text/plain", bytes.NewReader(nil))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("Should let the http data source convert a custom CA endpoint without scratch space", func() {
+		dp, err := NewHTTPDataSource(ep.String(), "", "", "mytoken", certDir, "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer dp.Close()
+		phase, err := dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseConvert))
+		Expect(dp.GetURL().Scheme).To(Equal("http"))
+		Expect(dp.GetURL().Hostname()).To(Equal("127.0.0.1"))
+	})
+
+	It("Should let qemu-img read a plain endpoint through the proxy if the rate is limited", func() {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "disk.img", time.Now(), bytes.NewReader(content))
+		}))
+		defer plain.Close()
+		SetTransferRateLimit(1 << 20)
+		defer SetTransferRateLimit(0)
+
+		dp, err := NewHTTPDataSource(plain.URL+"/disk.img", "", "", "", "", "", nil, cdiv1.DataVolumeKubeVirt, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer dp.Close()
+		phase, err := dp.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(phase).To(Equal(ProcessingPhaseConvert))
+		Expect(dp.GetURL().Hostname()).To(Equal("127.0.0.1"))
+		resp, err := http.Get(dp.GetURL().String())
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(content))
+	})
+})
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The CDI Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importer
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+// probeFile is the file in the scratch space a source that is neither converted nor processed is written to.
+const probeFile = "probe.img"
+
+// SizeProbingDataSource is implemented by data sources that can determine the virtual size of their image while reading
+// it, so the image does not have to be transferred to the scratch space to be probed.
+type SizeProbingDataSource interface {
+	// VirtualSize returns the virtual size of the image, it is called after Info.
+	VirtualSize() (int64, error)
+}
+
+// ProbeVirtualSize returns the virtual size of the disk image the data source provides, so the volume it is imported
+// to can be sized. An image qemu-img can read from the endpoint is only inspected. Any other image is read by the data
+// source if it implements SizeProbingDataSource, otherwise it is first transferred to the scratch space in scratchDir.
+func ProbeVirtualSize(source DataSourceInterface, scratchDir string) (int64, error) {
+	var err error
+	phase := ProcessingPhaseInfo
+	for {
+		klog.V(1).Infof("Probing the virtual size in phase %s", phase)
+		if prober, ok := source.(SizeProbingDataSource); ok && (phase == ProcessingPhaseTransferScratch || phase == ProcessingPhaseTransferDataFile) {
+			return prober.VirtualSize()
+		}
+		switch phase {
+		case ProcessingPhaseInfo:
+			phase, err = source.Info()
+		case ProcessingPhaseTransferScratch:
+			phase, err = source.Transfer(scratchDir)
+		case ProcessingPhaseProcess:
+			phase, err = source.Process()
+		case ProcessingPhaseConvert:
+			info, err := qemuOperations.Info(source.GetURL())
+			if err != nil {
+				return 0, errors.Wrap(err, "unable to read the image info")
+			}
+			return info.VirtualSize, nil
+		case ProcessingPhaseTransferDataFile:
+			// the image is written as is, its size is the size of the file
+			file := filepath.Join(scratchDir, probeFile)
+			if _, err = source.TransferFile(file); err != nil {
+				return 0, err
+			}
+			fileInfo, err := os.Stat(file)
+			if err != nil {
+				return 0, errors.Wrap(err, "unable to determine the image size")
+			}
+			return fileInfo.Size(), nil
+		default:
+			return 0, errors.Errorf("the virtual size can't be determined in phase %s", phase)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/pkg/errors"
+
+	"kubevirt.io/containerized-data-importer/pkg/image"
+)
+
+// MockSizeProbingDataProvider is a MockDataProvider that reads the virtual size of its image itself.
+type MockSizeProbingDataProvider struct {
+	MockDataProvider
+	virtualSize int64
+}
+
+// VirtualSize returns the virtual size of the image.
+func (m *MockSizeProbingDataProvider) VirtualSize() (int64, error) {
+	return m.virtualSize, nil
+}
+
+var _ = Describe("Size probe", func() {
+	var scratchDir string
+
+	BeforeEach(func() {
+		var err error
+		scratchDir, err = ioutil.TempDir("", "probe")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(scratchDir)
+	})
+
+	It("should read the virtual size of an image qemu-img can convert", func() {
+		mdp := &MockDataProvider{
+			infoResponse: ProcessingPhaseConvert,
+		}
+		info := &image.ImgInfo{Format: "qcow2", VirtualSize: 10737418240}
+		replaceQEMUOperations(NewFakeQEMUOperations(nil, nil, fakeInfoOpRetVal{info, nil}, nil, nil, nil), func() {
+			size, err := ProbeVirtualSize(mdp, scratchDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(10737418240)))
+		})
+		Expect(mdp.calledPhases).To(Equal([]ProcessingPhase{ProcessingPhaseInfo}))
+	})
+
+	It("should transfer an image to the scratch space before reading its virtual size", func() {
+		mdp := &MockDataProvider{
+			infoResponse:     ProcessingPhaseTransferScratch,
+			transferResponse: ProcessingPhaseProcess,
+			processResponse:  ProcessingPhaseConvert,
+		}
+		info := &image.ImgInfo{Format: "qcow2", VirtualSize: 1073741824}
+		replaceQEMUOperations(NewFakeQEMUOperations(nil, nil, fakeInfoOpRetVal{info, nil}, nil, nil, nil), func() {
+			size, err := ProbeVirtualSize(mdp, scratchDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(1073741824)))
+		})
+		Expect(mdp.transferPath).To(Equal(scratchDir))
+		Expect(mdp.calledPhases).To(Equal([]ProcessingPhase{ProcessingPhaseInfo, ProcessingPhaseTransferScratch, ProcessingPhaseProcess}))
+	})
+
+	It("should use the size of an image transferred as is", func() {
+		mdp := &MockDataProvider{
+			infoResponse:     ProcessingPhaseTransferDataFile,
+			transferResponse: ProcessingPhaseComplete,
+		}
+		Expect(ioutil.WriteFile(filepath.Join(scratchDir, probeFile), make([]byte, 4096), 0644)).To(Succeed())
+		size, err := ProbeVirtualSize(mdp, scratchDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(int64(4096)))
+		Expect(mdp.transferFile).To(Equal(filepath.Join(scratchDir, probeFile)))
+	})
+
+	table.DescribeTable("should let a source that can read the virtual size itself skip the scratch space", func(phase ProcessingPhase) {
+		mdp := &MockSizeProbingDataProvider{
+			MockDataProvider: MockDataProvider{
+				infoResponse: phase,
+			},
+			virtualSize: 2147483648,
+		}
+		size, err := ProbeVirtualSize(mdp, scratchDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(int64(2147483648)))
+		Expect(mdp.calledPhases).To(Equal([]ProcessingPhase{ProcessingPhaseInfo}))
+		Expect(mdp.transferPath).To(BeEmpty())
+		Expect(mdp.transferFile).To(BeEmpty())
+	},
+		table.Entry("instead of transferring it to the scratch space", ProcessingPhaseTransferScratch),
+		table.Entry("instead of writing it to a file", ProcessingPhaseTransferDataFile),
+	)
+
+	It("should fail if the source fails", func() {
+		mdp := &MockDataProvider{
+			infoResponse: ProcessingPhaseError,
+		}
+		_, err := ProbeVirtualSize(mdp, scratchDir)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail if the image info can't be read", func() {
+		mdp := &MockDataProvider{
+			infoResponse: ProcessingPhaseConvert,
+		}
+		replaceQEMUOperations(NewFakeQEMUOperations(nil, nil, fakeInfoOpRetVal{nil, errors.New("invalid image")}, nil, nil, nil), func() {
+			_, err := ProbeVirtualSize(mdp, scratchDir)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("should fail in a phase the size can't be determined in", func() {
+		mdp := &MockDataProvider{
+			infoResponse: ProcessingPhaseResize,
+		}
+		_, err := ProbeVirtualSize(mdp, scratchDir)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -13,6 +13,7 @@ import (
 	"k8s.io/klog"
 
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/image"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
@@ -42,15 +43,18 @@ type S3DataSource struct {
 	readers *FormatReaders
 	// The image file in scratch space.
 	url *url.URL
+	// receives a copy of the data TransferFile writes.
+	writtenWriter io.Writer
 }
 
-// NewS3DataSource creates a new instance of the S3DataSource
-func NewS3DataSource(endpoint, accessKey, secKey string) (*S3DataSource, error) {
+// NewS3DataSource creates a new instance of the S3DataSource, the certs in certDir, such as the proxy CA, are trusted in
+// addition to the system certs.
+func NewS3DataSource(endpoint, accessKey, secKey, certDir string) (*S3DataSource, error) {
 	ep, err := ParseEndpoint(endpoint)
 	if err != nil {
 		return nil, errors.Wrapf(err, fmt.Sprintf("unable to parse endpoint %q", endpoint))
 	}
-	s3Reader, err := createS3Reader(ep, accessKey, secKey)
+	s3Reader, err := createS3Reader(ep, accessKey, secKey, certDir)
 	if err != nil {
 		return nil, err
 	}
@@ -96,13 +100,46 @@ func (sd *S3DataSource) Transfer(path string) (ProcessingPhase, error) {
 
 // TransferFile is called to transfer the data from the source to the passed in file.
 func (sd *S3DataSource) TransferFile(fileName string) (ProcessingPhase, error) {
-	err := util.StreamDataToFile(sd.readers.TopReader(), fileName)
+	reader, err := image.NewRawValidationReader(sd.readers.TopReader())
 	if err != nil {
 		return ProcessingPhaseError, err
 	}
+	if sd.writtenWriter != nil {
+		reader = io.TeeReader(reader, sd.writtenWriter)
+	}
+	err = util.StreamDataToFile(reader, fileName)
+	if err != nil {
+		return ProcessingPhaseError, err
+	}
+	return ProcessingPhaseResize, nil
+}
+
+// TransferStream is called to convert the qcow2 data from the source to the passed in RAW file, without scratch space.
+func (sd *S3DataSource) TransferStream(fileName string, availableSize int64) (ProcessingPhase, error) {
+	if !sd.readers.Convert {
+		return ProcessingPhaseError, image.ErrQcow2NotStreamable
+	}
+	if err := image.ConvertQcow2Stream(sd.readers.TopReader(), fileName, availableSize); err != nil {
+		return ProcessingPhaseError, err
+	}
 	return ProcessingPhaseResize, nil
 }
 
+// EnableVerification does nothing, images are always converted from the scratch space.
+func (sd *S3DataSource) EnableVerification() {
+}
+
+// CopyWrittenData copies the data TransferFile writes to w.
+func (sd *S3DataSource) CopyWrittenData(w io.Writer) {
+	sd.writtenWriter = w
+}
+
+// VirtualSize returns the virtual size of the image, read from the qcow2 header or by reading the whole stream, so the
+// size probe does not need scratch space.
+func (sd *S3DataSource) VirtualSize() (int64, error) {
+	return sd.readers.VirtualSize()
+}
+
 // Process is called to do any special processing before giving the url to the data back to the processor
 func (sd *S3DataSource) Process() (ProcessingPhase, error) {
 	return ProcessingPhaseConvert, nil
@@ -113,6 +150,14 @@ func (sd *S3DataSource) GetURL() *url.URL {
 	return sd.url
 }
 
+// BytesTransferred returns the number of bytes read from the s3 object.
+func (sd *S3DataSource) BytesTransferred() int64 {
+	if sd.readers == nil {
+		return 0
+	}
+	return sd.readers.BytesRead()
+}
+
 // Close closes any readers or other open resources.
 func (sd *S3DataSource) Close() error {
 	var err error
@@ -122,11 +167,11 @@ func (sd *S3DataSource) Close() error {
 	return err
 }
 
-func createS3Reader(ep *url.URL, accessKey, secKey string) (io.ReadCloser, error) {
+func createS3Reader(ep *url.URL, accessKey, secKey, certDir string) (io.ReadCloser, error) {
 	klog.V(3).Infoln("Using S3 client to get data")
 	bucket := ep.Host
 	object := strings.Trim(ep.Path, "/")
-	mc, err := newClientFunc(accessKey, secKey, false)
+	mc, err := newClientFunc(accessKey, secKey, certDir, false)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not build minio client for %q", ep.Host)
 	}
@@ -138,6 +183,17 @@ func createS3Reader(ep *url.URL, accessKey, secKey string) (io.ReadCloser, error
 	return objectReader, nil
 }
 
-func getS3Client(accessKey, secKey string, secure bool) (S3Client, error) {
-	return minio.NewV4(common.ImporterS3Host, accessKey, secKey, secure)
+func getS3Client(accessKey, secKey, certDir string, secure bool) (S3Client, error) {
+	client, err := minio.NewV4(common.ImporterS3Host, accessKey, secKey, secure)
+	if err != nil {
+		return nil, err
+	}
+	if certDir != "" {
+		httpClient, err := createHTTPClient(certDir, "")
+		if err != nil {
+			return nil, err
+		}
+		client.SetCustomTransport(httpClient.Transport)
+	}
+	return client, nil
 }
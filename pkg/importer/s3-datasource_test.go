@@ -12,6 +12,8 @@ import (
 
 	minio "github.com/minio/minio-go"
 	"github.com/pkg/errors"
+
+	"kubevirt.io/containerized-data-importer/pkg/image"
 )
 
 var _ = Describe("S3 data source", func() {
@@ -37,19 +39,19 @@ var _ = Describe("S3 data source", func() {
 	})
 
 	It("NewS3DataSource should Error, when passed in an invalid endpoint", func() {
-		sd, err = NewS3DataSource("thisisinvalid#$%#ep", "", "")
+		sd, err = NewS3DataSource("thisisinvalid#$%#ep", "", "", "")
 		Expect(err).To(HaveOccurred())
 	})
 
 	It("NewS3DataSource should Error, when failing to create minio client", func() {
 		newClientFunc = failMockS3Client
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).To(HaveOccurred())
 	})
 
 	It("NewS3DataSource should Error, when failing to get object", func() {
 		newClientFunc = createErrMockS3Client
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -59,7 +61,7 @@ var _ = Describe("S3 data source", func() {
 		Expect(err).NotTo(HaveOccurred())
 		err = file.Close()
 		Expect(err).NotTo(HaveOccurred())
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = file
@@ -72,7 +74,7 @@ var _ = Describe("S3 data source", func() {
 		// Don't need to defer close, since ud.Close will close the reader
 		file, err := os.Open(cirrosFilePath)
 		Expect(err).NotTo(HaveOccurred())
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = file
@@ -85,7 +87,7 @@ var _ = Describe("S3 data source", func() {
 		// Don't need to defer close, since ud.Close will close the reader
 		file, err := os.Open(tinyCoreFilePath)
 		Expect(err).NotTo(HaveOccurred())
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = file
@@ -101,7 +103,7 @@ var _ = Describe("S3 data source", func() {
 		sourceFile, err := os.Open(fileName)
 		Expect(err).NotTo(HaveOccurred())
 
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = sourceFile
@@ -135,7 +137,7 @@ var _ = Describe("S3 data source", func() {
 		sourceFile, err := os.Open(cirrosFilePath)
 		Expect(err).NotTo(HaveOccurred())
 
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = sourceFile
@@ -153,7 +155,7 @@ var _ = Describe("S3 data source", func() {
 		// Don't need to defer close, since ud.Close will close the reader
 		file, err := os.Open(tinyCoreFilePath)
 		Expect(err).NotTo(HaveOccurred())
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = file
@@ -169,7 +171,7 @@ var _ = Describe("S3 data source", func() {
 		// Don't need to defer close, since ud.Close will close the reader
 		file, err := os.Open(tinyCoreFilePath)
 		Expect(err).NotTo(HaveOccurred())
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = file
@@ -181,11 +183,42 @@ var _ = Describe("S3 data source", func() {
 		Expect(ProcessingPhaseError).To(Equal(result))
 	})
 
+	It("TransferStream should convert a qcow2 image without scratch space", func() {
+		sourceFile, err := os.Open(cirrosFilePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		// Replace minio.Object with a reader we can use.
+		sd.s3Reader = sourceFile
+		nextPhase, err := sd.Info()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ProcessingPhaseTransferScratch).To(Equal(nextPhase))
+		result, err := sd.TransferStream(filepath.Join(tmpDir, "disk.img"), int64(1024*1024*1024))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ProcessingPhaseResize).To(Equal(result))
+	})
+
+	It("TransferStream should refuse an image that is not qcow2", func() {
+		sourceFile, err := os.Open(tinyCoreFilePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		// Replace minio.Object with a reader we can use.
+		sd.s3Reader = sourceFile
+		_, err = sd.Info()
+		Expect(err).NotTo(HaveOccurred())
+		result, err := sd.TransferStream(filepath.Join(tmpDir, "disk.img"), int64(1024*1024*1024))
+		Expect(err).To(Equal(image.ErrQcow2NotStreamable))
+		Expect(ProcessingPhaseError).To(Equal(result))
+	})
+
 	It("Process should return Convert", func() {
 		// Don't need to defer close, since ud.Close will close the reader
 		file, err := os.Open(cirrosFilePath)
 		Expect(err).NotTo(HaveOccurred())
-		sd, err = NewS3DataSource("http://amazon.com", "", "")
+		sd, err = NewS3DataSource("http://amazon.com", "", "", "")
 		Expect(err).NotTo(HaveOccurred())
 		// Replace minio.Object with a reader we can use.
 		sd.s3Reader = file
@@ -195,33 +228,54 @@ var _ = Describe("S3 data source", func() {
 	})
 
 	It("GetS3Client should return a real client", func() {
-		_, err := getS3Client("", "", false)
+		_, err := getS3Client("", "", "", false)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("GetS3Client should trust the certs in the cert dir", func() {
+		Expect(ioutil.WriteFile(filepath.Join(tmpDir, "ca.crt"), []byte("proxy"), 0644)).To(Succeed())
+		_, err := getS3Client("", "", tmpDir, false)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = getS3Client("", "", "/invalid", false)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("NewS3DataSource should pass the cert dir to the S3 client", func() {
+		var clientCertDir string
+		newClientFunc = func(accKey, secKey, certDir string, secure bool) (S3Client, error) {
+			clientCertDir = certDir
+			return createMockS3Client(accKey, secKey, certDir, secure)
+		}
+		sd, err = NewS3DataSource("http://amazon.com", "", "", tmpDir)
 		Expect(err).NotTo(HaveOccurred())
+		Expect(clientCertDir).To(Equal(tmpDir))
 	})
 })
 
 // MockMinioClient is a mock minio client
 type MockMinioClient struct {
-	accKey string
-	secKey string
-	secure bool
-	doErr  bool
+	accKey  string
+	secKey  string
+	certDir string
+	secure  bool
+	doErr   bool
 }
 
-func failMockS3Client(accKey, secKey string, secure bool) (S3Client, error) {
+func failMockS3Client(accKey, secKey, certDir string, secure bool) (S3Client, error) {
 	return nil, errors.New("Failed to create client")
 }
 
-func createMockS3Client(accKey, secKey string, secure bool) (S3Client, error) {
+func createMockS3Client(accKey, secKey, certDir string, secure bool) (S3Client, error) {
 	return &MockMinioClient{
-		accKey: accKey,
-		secKey: secKey,
-		secure: secure,
-		doErr:  false,
+		accKey:  accKey,
+		secKey:  secKey,
+		certDir: certDir,
+		secure:  secure,
+		doErr:   false,
 	}, nil
 }
 
-func createErrMockS3Client(accKey, secKey string, secure bool) (S3Client, error) {
+func createErrMockS3Client(accKey, secKey, certDir string, secure bool) (S3Client, error) {
 	return &MockMinioClient{
 		doErr: true,
 	}, nil
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"k8s.io/klog"
+	"kubevirt.io/containerized-data-importer/pkg/image"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
@@ -66,7 +67,11 @@ func (ud *UploadDataSource) Transfer(path string) (ProcessingPhase, error) {
 
 // TransferFile is called to transfer the data from the source to the passed in file.
 func (ud *UploadDataSource) TransferFile(fileName string) (ProcessingPhase, error) {
-	err := util.StreamDataToFile(ud.readers.TopReader(), fileName)
+	reader, err := image.NewRawValidationReader(ud.readers.TopReader())
+	if err != nil {
+		return ProcessingPhaseError, err
+	}
+	err = util.StreamDataToFile(reader, fileName)
 	if err != nil {
 		return ProcessingPhaseError, err
 	}
@@ -83,6 +88,14 @@ func (ud *UploadDataSource) GetURL() *url.URL {
 	return ud.url
 }
 
+// BytesTransferred returns the number of bytes read from the upload stream.
+func (ud *UploadDataSource) BytesTransferred() int64 {
+	if ud.readers == nil {
+		return 0
+	}
+	return ud.readers.BytesRead()
+}
+
 // Close closes any readers or other open resources.
 func (ud *UploadDataSource) Close() error {
 	if ud.stream != nil {
@@ -1,18 +1,28 @@
 package importer
 
 import (
+	"encoding/json"
+	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 
+	minio "github.com/minio/minio-go"
 	"github.com/pkg/errors"
 	"k8s.io/klog"
 
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/image"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
+// the registry import only picks up certificates with the .crt extension
+const proxyCAFile = "cdi-proxy-ca.crt"
+
 // ParseEndpoint parses the required endpoint and return the url struct.
 func ParseEndpoint(endpt string) (*url.URL, error) {
 	if endpt == "" {
@@ -25,6 +35,65 @@ func ParseEndpoint(endpt string) (*url.URL, error) {
 	return url.Parse(endpt)
 }
 
+// AddProxyCA returns a new temporary directory containing the certificates in certDir and the PEM encoded proxy CA,
+// so the proxy CA is trusted in addition to any custom CA. The caller is responsible for removing the directory.
+func AddProxyCA(certDir string, proxyCA []byte) (string, error) {
+	dir, err := ioutil.TempDir("", "proxy-certs")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create cert directory")
+	}
+	if certDir != "" {
+		files, err := ioutil.ReadDir(certDir)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrapf(err, "Error listing files in %s", certDir)
+		}
+		for _, file := range files {
+			if file.IsDir() || file.Name()[0] == '.' {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(certDir, file.Name()))
+			if err != nil {
+				os.RemoveAll(dir)
+				return "", errors.Wrapf(err, "Error reading file %s", file.Name())
+			}
+			if err = ioutil.WriteFile(filepath.Join(dir, file.Name()), data, 0644); err != nil {
+				os.RemoveAll(dir)
+				return "", errors.Wrapf(err, "Error writing file %s", file.Name())
+			}
+		}
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, proxyCAFile), proxyCA, 0644); err != nil {
+		os.RemoveAll(dir)
+		return "", errors.Wrap(err, "Error writing proxy CA")
+	}
+	return dir, nil
+}
+
+// ParseValidationPolicy parses the JSON encoded image validation policy passed to the importer and upload server. Limits
+// missing from the policy keep their defaults.
+func ParseValidationPolicy(value string) (*image.ValidationPolicy, error) {
+	var policy cdiv1.ImageValidationPolicy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return nil, errors.Wrap(err, "unable to parse image validation policy")
+	}
+	result := image.DefaultValidationPolicy()
+	result.AllowedFormats = policy.AllowedFormats
+	result.DeniedFormats = policy.DeniedFormats
+	result.RejectEncrypted = policy.RejectEncrypted
+	result.RejectCompressed = policy.RejectCompressed
+	if policy.MaxVirtualSize != nil {
+		result.MaxVirtualSize = policy.MaxVirtualSize.Value()
+	}
+	if policy.InfoMemoryLimit != nil {
+		result.InfoMemoryLimit = uint64(policy.InfoMemoryLimit.Value())
+	}
+	if policy.InfoCPUTimeLimit != nil {
+		result.InfoCPUTimeLimit = uint64(*policy.InfoCPUTimeLimit)
+	}
+	return result, nil
+}
+
 // CleanDir cleans the contents of a directory including its sub directories, but does NOT remove the
 // directory itself.
 func CleanDir(dest string) error {
@@ -43,3 +112,41 @@ func CleanDir(dest string) error {
 	}
 	return nil
 }
+
+// ClassifyError returns the reason the import failed with err, and whether retrying the import may succeed.
+func ClassifyError(err error) (string, bool) {
+	switch cause := errors.Cause(err).(type) {
+	case *image.ValidationError:
+		return util.ReasonValidationFailed, false
+	case *HTTPStatusError:
+		return classifyStatusCode(cause.StatusCode)
+	case minio.ErrorResponse:
+		return classifyStatusCode(cause.StatusCode)
+	case net.Error:
+		return util.ReasonTransferFailed, true
+	}
+	switch errors.Cause(err) {
+	case ErrRequiresScratchSpace:
+		return util.ReasonScratchSpaceRequired, true
+	case ErrChecksumMismatch:
+		return util.ReasonChecksumMismatch, false
+	case image.ErrImageMismatch:
+		return util.ReasonVerificationFailed, true
+	case io.ErrUnexpectedEOF:
+		return util.ReasonTransferFailed, true
+	}
+	return util.ReasonProcessingFailed, true
+}
+
+// classifyStatusCode returns the reason and whether a retry may succeed for an unexpected http status code.
+func classifyStatusCode(code int) (string, bool) {
+	switch {
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return util.ReasonNotFound, false
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return util.ReasonUnauthorized, false
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500:
+		return util.ReasonTransferFailed, true
+	}
+	return util.ReasonTransferFailed, false
+}
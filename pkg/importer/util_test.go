@@ -11,8 +11,10 @@ import (
 	. "github.com/onsi/ginkgo"
 	"github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/image"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
@@ -120,3 +122,93 @@ var _ = Describe("Clean dir", func() {
 		Expect(0).To(Equal(len(dir)))
 	})
 })
+
+var _ = Describe("Add proxy CA", func() {
+	var (
+		err     error
+		certDir string
+		dir     string
+	)
+
+	BeforeEach(func() {
+		certDir, err = ioutil.TempDir("", "certs")
+		Expect(err).NotTo(HaveOccurred())
+		dir = ""
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(certDir)
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	})
+
+	It("Should combine the custom CA and the proxy CA", func() {
+		Expect(ioutil.WriteFile(filepath.Join(certDir, "ca.pem"), []byte("custom"), 0644)).To(Succeed())
+		dir, err = AddProxyCA(certDir, []byte("proxy"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dir).ToNot(Equal(certDir))
+		data, err := ioutil.ReadFile(filepath.Join(dir, "ca.pem"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("custom"))
+		data, err = ioutil.ReadFile(filepath.Join(dir, proxyCAFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("proxy"))
+	})
+
+	It("Should only contain the proxy CA without a custom CA", func() {
+		dir, err = AddProxyCA("", []byte("proxy"))
+		Expect(err).NotTo(HaveOccurred())
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+	})
+
+	It("Should fail with an invalid cert directory", func() {
+		_, err = AddProxyCA("/invalid", []byte("proxy"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Parse validation policy", func() {
+	It("Should convert the policy and keep the default limits", func() {
+		policy, err := ParseValidationPolicy(`{"maxVirtualSize":"10Gi","deniedFormats":["vmdk"],"rejectEncrypted":true}`)
+		Expect(err).NotTo(HaveOccurred())
+		expected := image.DefaultValidationPolicy()
+		expected.MaxVirtualSize = 10 * 1024 * 1024 * 1024
+		expected.DeniedFormats = []string{"vmdk"}
+		expected.RejectEncrypted = true
+		Expect(policy).To(Equal(expected))
+	})
+
+	It("Should convert the limits", func() {
+		policy, err := ParseValidationPolicy(`{"infoMemoryLimit":"2Gi","infoCPUTimeLimit":60}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy.InfoMemoryLimit).To(Equal(uint64(2 * 1024 * 1024 * 1024)))
+		Expect(policy.InfoCPUTimeLimit).To(Equal(uint64(60)))
+	})
+
+	It("Should fail on invalid JSON", func() {
+		_, err := ParseValidationPolicy("{")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Classify error", func() {
+	table.DescribeTable("should return the reason and whether retrying may succeed", func(err error, reason string, transient bool) {
+		r, t := ClassifyError(errors.Wrap(err, "Unable to process data"))
+		Expect(r).To(Equal(reason))
+		Expect(t).To(Equal(transient))
+	},
+		table.Entry("for a rejected image", &image.ValidationError{Rule: image.RuleMaxVirtualSize}, util.ReasonValidationFailed, false),
+		table.Entry("for a checksum mismatch", ErrChecksumMismatch, util.ReasonChecksumMismatch, false),
+		table.Entry("for a failed verification", image.ErrImageMismatch, util.ReasonVerificationFailed, true),
+		table.Entry("for missing scratch space", ErrRequiresScratchSpace, util.ReasonScratchSpaceRequired, true),
+		table.Entry("for a missing http source", &HTTPStatusError{StatusCode: 404}, util.ReasonNotFound, false),
+		table.Entry("for rejected credentials", &HTTPStatusError{StatusCode: 403}, util.ReasonUnauthorized, false),
+		table.Entry("for an unavailable http server", &HTTPStatusError{StatusCode: 503}, util.ReasonTransferFailed, true),
+		table.Entry("for a bad http request", &HTTPStatusError{StatusCode: 400}, util.ReasonTransferFailed, false),
+		table.Entry("for a truncated transfer", io.ErrUnexpectedEOF, util.ReasonTransferFailed, true),
+		table.Entry("for any other error", errors.New("disk full"), util.ReasonProcessingFailed, true),
+	)
+})
@@ -76,6 +76,18 @@ func GetAPIServerClusterRolePermissions() []rbacv1.PolicyRule {
 				"list",
 			},
 		},
+		{
+			APIGroups: []string{
+				"storage.k8s.io",
+			},
+			Resources: []string{
+				"storageclasses",
+			},
+			Verbs: []string{
+				"get",
+				"list",
+			},
+		},
 		{
 			APIGroups: []string{
 				"cdi.kubevirt.io",
@@ -164,6 +164,7 @@ func GetControllerPermissions() []rbacv1.PolicyRule {
 			Verbs: []string{
 				"get",
 				"list",
+				"watch",
 			},
 		},
 		{
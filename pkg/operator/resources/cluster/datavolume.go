@@ -131,9 +131,6 @@ func createDataVolumeCRD() *extv1beta1.CustomResourceDefinition {
 													},
 												},
 											},
-											Required: []string{
-												"requests",
-											},
 										},
 										"storageClassName": {
 											Type: "string",
@@ -143,11 +140,31 @@ func createDataVolumeCRD() *extv1beta1.CustomResourceDefinition {
 										},
 									},
 									Required: []string{
-										"resources",
 										"accessModes",
 									},
 								},
 							},
+							// only the size of http, s3 and registry sources can be probed, the webhook rejects archives
+							AnyOf: []extv1beta1.JSONSchemaProps{
+								{
+									Properties: map[string]extv1beta1.JSONSchemaProps{
+										"pvc": {
+											Properties: map[string]extv1beta1.JSONSchemaProps{
+												"resources": {
+													Required: []string{
+														"requests",
+													},
+												},
+											},
+											Required: []string{
+												"resources",
+											},
+										},
+									},
+								},
+								sizeProbeSourceSchema("http"),
+								sizeProbeSourceSchema("s3"),
+							},
 						},
 					},
 				},
@@ -155,3 +172,17 @@ func createDataVolumeCRD() *extv1beta1.CustomResourceDefinition {
 		},
 	}
 }
+
+// sizeProbeSourceSchema matches the data volume specs with the source whose size can be probed, so they may leave out
+// the storage request of the PVC.
+func sizeProbeSourceSchema(source string) extv1beta1.JSONSchemaProps {
+	return extv1beta1.JSONSchemaProps{
+		Properties: map[string]extv1beta1.JSONSchemaProps{
+			"source": {
+				Required: []string{
+					source,
+				},
+			},
+		},
+	}
+}
@@ -69,6 +69,7 @@ func createCRDResources(args *FactoryArgs) []runtime.Object {
 	return []runtime.Object{
 		createDataVolumeCRD(),
 		createCDIConfigCRD(),
+		createStorageProfileCRD(),
 	}
 }
 
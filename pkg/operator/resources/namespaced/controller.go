@@ -23,6 +23,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"kubevirt.io/containerized-data-importer/pkg/common"
 	"kubevirt.io/containerized-data-importer/pkg/controller"
@@ -46,9 +47,25 @@ func createControllerResources(args *FactoryArgs) []runtime.Object {
 			args.Verbosity,
 			args.PullPolicy),
 		createInsecureRegConfigMap(),
+		createProgressService(),
 	}
 }
 
+func createProgressService() *corev1.Service {
+	service := utils.CreateService(controller.ProgressServiceName, "app", "containerized-data-importer")
+	service.Spec.Ports = []corev1.ServicePort{
+		{
+			Port: 443,
+			TargetPort: intstr.IntOrString{
+				Type:   intstr.Int,
+				IntVal: controller.ProgressServerPort,
+			},
+			Protocol: corev1.ProtocolTCP,
+		},
+	}
+	return service
+}
+
 func createControllerServiceAccount() *corev1.ServiceAccount {
 	sa := utils.CreateServiceAccount(controllerServiceAccount)
 	if sa.Annotations == nil {
@@ -83,6 +100,13 @@ func createControllerDeployment(repo, controllerImage, importerImage, clonerImag
 			Value: pullPolicy,
 		},
 	}
+	container.Ports = []corev1.ContainerPort{
+		{
+			Name:          "progress",
+			ContainerPort: controller.ProgressServerPort,
+			Protocol:      corev1.ProtocolTCP,
+		},
+	}
 	container.ReadinessProbe = &corev1.Probe{
 		Handler: corev1.Handler{
 			Exec: &corev1.ExecAction{
@@ -29,15 +29,23 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/image"
 	"kubevirt.io/containerized-data-importer/pkg/importer"
 	"kubevirt.io/containerized-data-importer/pkg/util"
+	prometheusutil "kubevirt.io/containerized-data-importer/pkg/util/prometheus"
 )
 
 const (
@@ -52,11 +60,33 @@ const (
 
 	healthzPort = 8080
 	healthzPath = "/healthz"
+	metricsPath = "/metrics"
 )
 
+var (
+	progress = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "upload_progress",
+			Help: "The upload progress in percentage",
+		},
+		[]string{"ownerUID"},
+	)
+	transferStats = prometheusutil.NewTransferStatsVec("upload_transfer_stats", "The statistics of the upload transfer")
+	ownerUID      = os.Getenv(common.OwnerUID)
+)
+
+func init() {
+	prometheus.MustRegister(progress, transferStats)
+}
+
+// ErrContentChecksumMismatch is returned when the cloned data does not match the checksum sent by the clone source.
+var ErrContentChecksumMismatch = errors.New("content checksum of the target does not match the source")
+
 // UploadServer is the interface to uploadServerApp
 type UploadServer interface {
 	Run() error
+	// TerminationMessage returns the result of a successful upload, or nil if there was none
+	TerminationMessage() *util.TerminationMessage
 }
 
 type uploadServerApp struct {
@@ -75,13 +105,16 @@ type uploadServerApp struct {
 	done        bool
 	doneChan    chan struct{}
 	mutex       sync.Mutex
+	result      *util.TerminationMessage
+	rateLimiter *rate.Limiter
 }
 
 // may be overridden in tests
 var uploadProcessorFunc = newUploadStreamProcessor
 
-// NewUploadServer returns a new instance of uploadServerApp
-func NewUploadServer(bindAddress string, bindPort int, destination, tlsKey, tlsCert, clientCert, clientName, imageSize string) UploadServer {
+// NewUploadServer returns a new instance of uploadServerApp. An upload is read with at most transferRateLimit bytes
+// per second, 0 means the rate is not limited.
+func NewUploadServer(bindAddress string, bindPort int, destination, tlsKey, tlsCert, clientCert, clientName, imageSize string, transferRateLimit int64) UploadServer {
 	server := &uploadServerApp{
 		bindAddress: bindAddress,
 		bindPort:    bindPort,
@@ -95,6 +128,7 @@ func NewUploadServer(bindAddress string, bindPort int, destination, tlsKey, tlsC
 		uploading:   false,
 		done:        false,
 		doneChan:    make(chan struct{}),
+		rateLimiter: util.NewRateLimiter(transferRateLimit),
 	}
 	server.mux.HandleFunc(healthzPath, server.healthzHandler)
 	server.mux.HandleFunc(common.UploadPath, server.uploadHandler)
@@ -200,6 +234,8 @@ func (app *uploadServerApp) createUploadServer() (*http.Server, error) {
 func (app *uploadServerApp) createHealthzServer() (*http.Server, error) {
 	mux := http.NewServeMux()
 	mux.HandleFunc(healthzPath, app.healthzHandler)
+	// the upload port requires a client certificate, so the metrics are served next to the health check
+	mux.Handle(metricsPath, promhttp.Handler())
 	return &http.Server{Handler: mux}, nil
 }
 
@@ -262,34 +298,112 @@ func (app *uploadServerApp) uploadHandler(w http.ResponseWriter, r *http.Request
 
 	klog.Infof("Content type header is %q\n", cdiContentType)
 
-	err := uploadProcessorFunc(r.Body, app.destination, app.imageSize, cdiContentType)
+	startTime := metav1.Now()
+	body := &util.CountingReader{Reader: util.NewRateLimitedReader(r.Body, app.rateLimiter)}
+	total := uint64(0)
+	if r.ContentLength > 0 {
+		total = uint64(r.ContentLength)
+	}
+	progressReader := prometheusutil.NewProgressReader(body, total, progress, ownerUID)
+	progressReader.ReportTransferStats(transferStats)
+	reporter, err := util.NewProgressReporterFromEnv()
+	if err != nil {
+		klog.Warningf("Not pushing progress reports: %v", err)
+	}
+	progressReader.PushReports(reporter)
+	progressReader.StartTimedUpdate()
+	result, err := uploadProcessorFunc(progressReader, app.destination, app.imageSize, cdiContentType)
+	if err == nil {
+		result.Digest, err = app.contentChecksum(progressReader, r)
+	}
+	// stops the statistics updates, also if the upload failed before the end of the body
+	progressReader.Done = true
 
 	app.mutex.Lock()
 	defer app.mutex.Unlock()
 
 	if err != nil {
 		klog.Errorf("Saving stream failed: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		if validationErr, ok := errors.Cause(err).(*image.ValidationError); ok {
+			// the image is rejected, retrying the same upload will not help
+			http.Error(w, fmt.Sprintf("%s%s: %s", common.ImageValidationFailedMessage, validationErr.Rule, validationErr.Message), http.StatusBadRequest)
+		} else if errors.Cause(err) == ErrContentChecksumMismatch {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		app.uploading = false
 		return
 	}
 
 	app.uploading = false
 	app.done = true
+	result.Message = "Upload Complete"
+	result.BytesTransferred = int64(body.Current)
+	result.SetTransferTimes(startTime)
+	app.result = result
 
 	close(app.doneChan)
 
 	klog.Infof("Wrote data to %s", app.destination)
 }
 
-func newUploadStreamProcessor(stream io.ReadCloser, dest, imageSize, contentType string) error {
+func (app *uploadServerApp) TerminationMessage() *util.TerminationMessage {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	return app.result
+}
+
+// contentChecksum computes the checksum of the written disk image. A clone source sends the checksum of the source
+// volume in a trailer, and the written data has to match it. Otherwise failing to compute the checksum does not fail
+// the upload.
+func (app *uploadServerApp) contentChecksum(body io.Reader, r *http.Request) (string, error) {
+	// trailers are only set once the body is read completely
+	if _, err := io.Copy(ioutil.Discard, body); err != nil {
+		return "", errors.Wrap(err, "error reading request body")
+	}
+	expected := r.Trailer.Get(common.ContentChecksumTrailer)
+	size := int64(-1)
+	if s := r.Trailer.Get(common.ContentSizeTrailer); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid %s trailer", common.ContentSizeTrailer)
+		}
+		size = n
+	}
+	checksum, err := util.ContentChecksum(app.destination, size)
+	if err != nil {
+		if expected != "" {
+			return "", err
+		}
+		if !os.IsNotExist(errors.Cause(err)) {
+			klog.Warningf("Unable to compute the content checksum: %v", err)
+		}
+		return "", nil
+	}
+	if expected != "" && expected != checksum {
+		return "", errors.Wrapf(ErrContentChecksumMismatch, "source %s, target %s", expected, checksum)
+	}
+	klog.Infof("Content checksum: %s", checksum)
+	return checksum, nil
+}
+
+func newUploadStreamProcessor(stream io.ReadCloser, dest, imageSize, contentType string) (*util.TerminationMessage, error) {
 	if contentType == FilesystemCloneContentType {
-		return filesystemCloneProcessor(stream, common.ImporterVolumePath)
+		return &util.TerminationMessage{}, filesystemCloneProcessor(stream, common.ImporterVolumePath)
 	}
 
+	filesystemOverhead, err := util.FilesystemOverheadFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	uds := importer.NewUploadDataSource(stream)
 	processor := importer.NewDataProcessor(uds, dest, common.ImporterVolumePath, common.ScratchDataDir, imageSize)
-	return processor.ProcessData()
+	processor.SetFilesystemOverhead(filesystemOverhead)
+	if err := processor.ProcessData(); err != nil {
+		return nil, err
+	}
+	return &util.TerminationMessage{Format: processor.SourceFormat(), VirtualSize: processor.VirtualSize()}, nil
 }
 
 func filesystemCloneProcessor(stream io.ReadCloser, destDir string) error {
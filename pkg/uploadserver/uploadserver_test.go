@@ -24,19 +24,24 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"k8s.io/client-go/util/cert"
 	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/image"
+	"kubevirt.io/containerized-data-importer/pkg/util"
 	"kubevirt.io/containerized-data-importer/pkg/util/cert/triple"
 )
 
 func newServer() *uploadServerApp {
-	server := NewUploadServer("127.0.0.1", 0, "disk.img", "", "", "", "", "")
+	server := NewUploadServer("127.0.0.1", 0, "disk.img", "", "", "", "", "", 0)
 	return server.(*uploadServerApp)
 }
 
@@ -60,7 +65,7 @@ func newTLSServer(t *testing.T, clientCertName, expectedName string) (*uploadSer
 	tlsCert := string(cert.EncodeCertPEM(serverKeyPair.Cert))
 	clientCert := string(cert.EncodeCertPEM(clientCA.Cert))
 
-	server := NewUploadServer("127.0.0.1", 0, "disk.img", tlsKey, tlsCert, clientCert, expectedName, "").(*uploadServerApp)
+	server := NewUploadServer("127.0.0.1", 0, "disk.img", tlsKey, tlsCert, clientCert, expectedName, "", 0).(*uploadServerApp)
 
 	clientKeyPair, err := triple.NewClientKeyPair(clientCA, clientCertName, []string{})
 	if err != nil {
@@ -99,12 +104,16 @@ func newRequest(t *testing.T) *http.Request {
 	return req
 }
 
-func saveProcessorSuccess(stream io.ReadCloser, dest, imageSize, contentType string) error {
-	return nil
+func saveProcessorSuccess(stream io.ReadCloser, dest, imageSize, contentType string) (*util.TerminationMessage, error) {
+	return &util.TerminationMessage{}, nil
 }
 
-func saveProcessorFailure(stream io.ReadCloser, dest, imageSize, contentType string) error {
-	return fmt.Errorf("Error using datastream")
+func saveProcessorFailure(stream io.ReadCloser, dest, imageSize, contentType string) (*util.TerminationMessage, error) {
+	return nil, fmt.Errorf("Error using datastream")
+}
+
+func saveProcessorValidationFailure(stream io.ReadCloser, dest, imageSize, contentType string) (*util.TerminationMessage, error) {
+	return nil, errors.Wrap(&image.ValidationError{Rule: image.RuleAllowedFormats, Message: "Invalid format vmdk"}, "Image validation failed")
 }
 
 func withProcessorSuccess(f func()) {
@@ -115,7 +124,7 @@ func withProcessorFailure(f func()) {
 	replaceProcessorFunc(saveProcessorFailure, f)
 }
 
-func replaceProcessorFunc(replacement func(io.ReadCloser, string, string, string) error, f func()) {
+func replaceProcessorFunc(replacement func(io.ReadCloser, string, string, string) (*util.TerminationMessage, error), f func()) {
 	origProcessorFunc := uploadProcessorFunc
 	uploadProcessorFunc = replacement
 	defer func() {
@@ -227,6 +236,132 @@ func TestStreamFail(t *testing.T) {
 	})
 }
 
+func TestStreamValidationFail(t *testing.T) {
+	replaceProcessorFunc(saveProcessorValidationFailure, func() {
+		req := newRequest(t)
+
+		rr := httptest.NewRecorder()
+
+		server := newServer()
+		server.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusBadRequest)
+		}
+		expected := "Image validation failed, rule AllowedFormats: Invalid format vmdk"
+		if body := strings.TrimSpace(rr.Body.String()); body != expected {
+			t.Errorf("handler returned wrong body: got %q want %q", body, expected)
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	withProcessorSuccess(func() {
+		req := newRequest(t)
+
+		rr := httptest.NewRecorder()
+
+		server := NewUploadServer("127.0.0.1", 0, "disk.img", "", "", "", "", "", 1024).(*uploadServerApp)
+		server.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if limit := server.rateLimiter.Limit(); limit != 1024 {
+			t.Errorf("wrong rate limit: got %v want 1024", limit)
+		}
+	})
+}
+
+func TestTransferStats(t *testing.T) {
+	withProcessorSuccess(func() {
+		req := newRequest(t)
+
+		rr := httptest.NewRecorder()
+
+		server := newServer()
+		server.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		result := server.TerminationMessage()
+		if result.StartTime == nil || result.CompletionTime == nil {
+			t.Errorf("transfer times not reported: %+v", result)
+		}
+
+		req, err := http.NewRequest("GET", metricsPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr = httptest.NewRecorder()
+		healthzServer, _ := server.createHealthzServer()
+		healthzServer.Handler.ServeHTTP(rr, req)
+
+		expected := `upload_transfer_stats{ownerUID="",stat="totalBytes"} 4`
+		if !strings.Contains(rr.Body.String(), expected) {
+			t.Errorf("metrics do not contain %s:\n%s", expected, rr.Body.String())
+		}
+	})
+}
+
+func newChecksumServer(t *testing.T) (*uploadServerApp, func()) {
+	f, err := ioutil.TempFile("", "disk")
+	if err != nil {
+		t.Fatalf("Error creating destination: %v", err)
+	}
+	f.WriteString("hello world")
+	f.Close()
+	server := NewUploadServer("127.0.0.1", 0, f.Name(), "", "", "", "", "", 0).(*uploadServerApp)
+	return server, func() { os.Remove(f.Name()) }
+}
+
+func TestContentChecksum(t *testing.T) {
+	withProcessorSuccess(func() {
+		server, cleanup := newChecksumServer(t)
+		defer cleanup()
+		req := newRequest(t)
+		req.Trailer = http.Header{}
+		req.Trailer.Set(common.ContentChecksumTrailer, "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+		req.Trailer.Set(common.ContentSizeTrailer, "5")
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		expected := "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		if checksum := server.TerminationMessage().Digest; checksum != expected {
+			t.Errorf("wrong content checksum: got %q want %q", checksum, expected)
+		}
+		if n := server.TerminationMessage().BytesTransferred; n != 4 {
+			t.Errorf("wrong bytes transferred: got %d want 4", n)
+		}
+	})
+}
+
+func TestContentChecksumMismatch(t *testing.T) {
+	withProcessorSuccess(func() {
+		server, cleanup := newChecksumServer(t)
+		defer cleanup()
+		req := newRequest(t)
+		req.Trailer = http.Header{}
+		req.Trailer.Set(common.ContentChecksumTrailer, "sha256:0000")
+
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusInternalServerError {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+		}
+		if message := server.TerminationMessage(); message != nil {
+			t.Errorf("unexpected termination message %+v", message)
+		}
+	})
+}
+
 func TestRealUploadWithClient(t *testing.T) {
 	type testData struct {
 		certName, expectedName string
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+// DefaultFilesystemOverhead is the fraction of a Filesystem mode volume reserved for file system metadata if the CDI
+// config sets no overhead.
+const DefaultFilesystemOverhead = cdiv1.Percent("0.055")
+
+// overheadAlignment is the size the usable space of a volume is rounded down to a multiple of.
+const overheadAlignment = 1024 * 1024
+
+// ParseFilesystemOverhead returns the fraction the percent represents, an error if it is not a number between 0
+// (inclusive) and 1 (exclusive).
+func ParseFilesystemOverhead(percent cdiv1.Percent) (float64, error) {
+	overhead, err := strconv.ParseFloat(string(percent), 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid filesystem overhead %q", percent)
+	}
+	if overhead < 0 || overhead >= 1 {
+		return 0, errors.Errorf("filesystem overhead %q is not between 0 and 1", percent)
+	}
+	return overhead, nil
+}
+
+// FilesystemOverheadFromEnv returns the fraction of the volume the worker pod may not fill, 0 if there is no overhead.
+func FilesystemOverheadFromEnv() (float64, error) {
+	value := os.Getenv(common.FilesystemOverheadVar)
+	if value == "" {
+		return 0, nil
+	}
+	return ParseFilesystemOverhead(cdiv1.Percent(value))
+}
+
+// UsableSpace returns the part of space a disk image may use once the filesystem overhead is reserved, rounded down to
+// whole MiB. Without an overhead all of space is usable.
+func UsableSpace(space int64, overhead float64) int64 {
+	if overhead <= 0 || space <= 0 {
+		return space
+	}
+	usable := int64(float64(space) * (1 - overhead))
+	return usable / overheadAlignment * overheadAlignment
+}
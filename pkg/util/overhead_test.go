@@ -0,0 +1,54 @@
+package util
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+var _ = Describe("Filesystem overhead", func() {
+	AfterEach(func() {
+		os.Unsetenv(common.FilesystemOverheadVar)
+	})
+
+	table.DescribeTable("Should parse the overhead", func(percent string, overhead float64, valid bool) {
+		result, err := ParseFilesystemOverhead(cdiv1.Percent(percent))
+		if valid {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(overhead))
+		} else {
+			Expect(err).To(HaveOccurred())
+		}
+	},
+		table.Entry("default", string(DefaultFilesystemOverhead), 0.055, true),
+		table.Entry("no overhead", "0", 0.0, true),
+		table.Entry("not a number", "5%", 0.0, false),
+		table.Entry("negative", "-0.1", 0.0, false),
+		table.Entry("whole volume", "1", 0.0, false),
+	)
+
+	It("Should have no overhead without the env variable", func() {
+		overhead, err := FilesystemOverheadFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overhead).To(BeZero())
+	})
+
+	It("Should read the overhead from the env variable", func() {
+		os.Setenv(common.FilesystemOverheadVar, "0.1")
+		overhead, err := FilesystemOverheadFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overhead).To(Equal(0.1))
+	})
+
+	It("Should reserve the overhead and round down to whole MiB", func() {
+		Expect(UsableSpace(10*1024*1024*1024, 0)).To(Equal(int64(10 * 1024 * 1024 * 1024)))
+		Expect(UsableSpace(1000*1024*1024, 0.2)).To(Equal(int64(800 * 1024 * 1024)))
+		Expect(UsableSpace(1000*1024*1024+1, 0.2)).To(Equal(int64(800 * 1024 * 1024)))
+		Expect(UsableSpace(-1, 0.2)).To(Equal(int64(-1)))
+	})
+})
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+// progressReportTimeout is how long pushing a single progress report may take.
+const progressReportTimeout = 10 * time.Second
+
+// ProgressReport is the JSON encoded progress an importer, upload server or clone source pod pushes to the progress
+// server of the controller while it transfers data.
+type ProgressReport struct {
+	// Progress is the percentage of the data transferred, nil if the total is not known
+	Progress *float64 `json:"progress,omitempty"`
+	// BytesTransferred is the number of bytes read from the source so far
+	BytesTransferred int64 `json:"bytesTransferred"`
+	// TotalBytes is the number of bytes to read from the source, 0 if it is not known
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+	// Throughput is the number of bytes per second read since the previous report
+	Throughput int64 `json:"throughput,omitempty"`
+	// StartTime is when the transfer started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+}
+
+// ProgressReporter pushes progress reports to the progress server, authenticating with a client certificate.
+type ProgressReporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewProgressReporterFromEnv returns a reporter configured by the progress env variables of the pod, nil if the pod is
+// not configured to report progress.
+func NewProgressReporterFromEnv() (*ProgressReporter, error) {
+	url := os.Getenv(common.ProgressURLVar)
+	if url == "" {
+		return nil, nil
+	}
+	return NewProgressReporter(url,
+		[]byte(os.Getenv(common.ProgressClientKeyVar)),
+		[]byte(os.Getenv(common.ProgressClientCertVar)),
+		[]byte(os.Getenv(common.ProgressServerCACertVar)))
+}
+
+// NewProgressReporter returns a reporter pushing to url with the PEM encoded client key and certificate, trusting the
+// server certificate only if it is signed by the PEM encoded server CA certificate.
+func NewProgressReporter(url string, clientKey, clientCert, serverCACert []byte) (*ProgressReporter, error) {
+	clientKeyPair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid progress client key pair")
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(serverCACert) {
+		return nil, errors.New("invalid progress server CA certificate")
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{clientKeyPair},
+		RootCAs:      caCertPool,
+	}
+	// the progress server runs in cluster, reports never go through the configured proxy
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	return &ProgressReporter{
+		url:    url,
+		client: &http.Client{Transport: transport, Timeout: progressReportTimeout},
+	}, nil
+}
+
+// Report pushes the report to the progress server.
+func (r *ProgressReporter) Report(report *ProgressReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return errors.Wrap(err, "error encoding progress report")
+	}
+	resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error pushing progress report")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("progress server responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/cert"
+
+	"kubevirt.io/containerized-data-importer/pkg/common"
+	"kubevirt.io/containerized-data-importer/pkg/util/cert/triple"
+)
+
+var _ = Describe("Progress reporter", func() {
+	var (
+		server     *httptest.Server
+		received   chan *ProgressReport
+		clientKey  []byte
+		clientCert []byte
+		serverCA   []byte
+	)
+
+	BeforeEach(func() {
+		clientCA, err := triple.NewCA("client.progress.test")
+		Expect(err).NotTo(HaveOccurred())
+		clientKeyPair, err := triple.NewClientKeyPair(clientCA, "b856691e-1038-11e9-a5ab-525500d15501", []string{})
+		Expect(err).NotTo(HaveOccurred())
+		clientKey = cert.EncodePrivateKeyPEM(clientKeyPair.Key)
+		clientCert = cert.EncodeCertPEM(clientKeyPair.Cert)
+
+		received = make(chan *ProgressReport, 1)
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.TLS.PeerCertificates[0].Subject.CommonName).To(Equal("b856691e-1038-11e9-a5ab-525500d15501"))
+			report := &ProgressReport{}
+			Expect(json.NewDecoder(r.Body).Decode(report)).To(Succeed())
+			received <- report
+		}))
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(clientCA.Cert)
+		server.TLS = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+		server.StartTLS()
+		serverCA = cert.EncodeCertPEM(server.Certificate())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.Unsetenv(common.ProgressURLVar)
+	})
+
+	It("Should not report without a progress URL", func() {
+		os.Unsetenv(common.ProgressURLVar)
+		reporter, err := NewProgressReporterFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reporter).To(BeNil())
+	})
+
+	It("Should reject an invalid client key pair", func() {
+		_, err := NewProgressReporter(server.URL, []byte("invalid"), clientCert, serverCA)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should push reports authenticated with the client certificate", func() {
+		reporter, err := NewProgressReporter(server.URL, clientKey, clientCert, serverCA)
+		Expect(err).NotTo(HaveOccurred())
+		progress := 25.0
+		Expect(reporter.Report(&ProgressReport{Progress: &progress, BytesTransferred: 1024, TotalBytes: 4096})).To(Succeed())
+		report := <-received
+		Expect(*report.Progress).To(Equal(progress))
+		Expect(report.BytesTransferred).To(Equal(int64(1024)))
+		Expect(report.TotalBytes).To(Equal(int64(4096)))
+	})
+
+	It("Should fail if the server is not trusted", func() {
+		otherCA, err := triple.NewCA("other")
+		Expect(err).NotTo(HaveOccurred())
+		reporter, err := NewProgressReporter(server.URL, clientKey, clientCert, cert.EncodeCertPEM(otherCA.Cert))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reporter.Report(&ProgressReport{BytesTransferred: 1024})).NotTo(Succeed())
+	})
+})
@@ -11,16 +11,52 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"kubevirt.io/containerized-data-importer/pkg/keys"
 	"kubevirt.io/containerized-data-importer/pkg/util"
 )
 
+// The statistics of a transfer, reported in the stat label of the transfer statistics metric.
+const (
+	// StatBytesTransferred is the number of bytes read from the source so far
+	StatBytesTransferred = "bytesTransferred"
+	// StatTotalBytes is the number of bytes to read from the source, if it is known
+	StatTotalBytes = "totalBytes"
+	// StatThroughput is the number of bytes per second read since the last update
+	StatThroughput = "throughput"
+	// StatStartTime is the unix time in seconds the transfer started
+	StatStartTime = "startTime"
+)
+
+// progressReportInterval is the minimum time between the progress reports pushed to the progress server.
+var progressReportInterval = 5 * time.Second
+
 // ProgressReader is a counting reader that reports progress to prometheus.
 type ProgressReader struct {
 	util.CountingReader
 	total    uint64
 	progress *prometheus.CounterVec
 	ownerUID string
+	// stats are the transfer statistics, nil if they are not reported
+	stats *prometheus.GaugeVec
+	// reporter pushes the progress to the progress server, nil if it is not pushed
+	reporter   *util.ProgressReporter
+	lastReport time.Time
+	startTime  time.Time
+	lastUpdate time.Time
+	lastBytes  uint64
+	throughput float64
+}
+
+// NewTransferStatsVec creates the gauge the transfer statistics are reported in, the name is like import_transfer_stats.
+func NewTransferStatsVec(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: name,
+			Help: help,
+		},
+		[]string{"ownerUID", "stat"},
+	)
 }
 
 // NewProgressReader creates a new instance of a prometheus updating progress reader.
@@ -38,6 +74,35 @@ func NewProgressReader(r io.ReadCloser, total uint64, progress *prometheus.Count
 	return promReader
 }
 
+// ReportTransferStats reports the bytes transferred, the total bytes, the throughput and the start time of the transfer
+// in stats, next to the progress. The transfer is considered started when this is called.
+func (r *ProgressReader) ReportTransferStats(stats *prometheus.GaugeVec) {
+	r.stats = stats
+	r.start()
+	r.stats.WithLabelValues(r.ownerUID, StatStartTime).Set(float64(r.startTime.Unix()))
+	r.stats.WithLabelValues(r.ownerUID, StatTotalBytes).Set(float64(r.total))
+}
+
+// PushReports pushes the progress and the transfer statistics to the progress server with reporter, at most every
+// few seconds and once more when the transfer is done. Nothing is pushed if reporter is nil.
+func (r *ProgressReader) PushReports(reporter *util.ProgressReporter) {
+	if reporter == nil {
+		return
+	}
+	r.reporter = reporter
+	r.start()
+}
+
+// start marks the start of the transfer, the throughput is measured from here.
+func (r *ProgressReader) start() {
+	if !r.startTime.IsZero() {
+		return
+	}
+	r.startTime = time.Now()
+	r.lastUpdate = r.startTime
+	r.lastBytes = r.Current
+}
+
 // StartTimedUpdate starts the update timer to automatically update every second.
 func (r *ProgressReader) StartTimedUpdate() {
 	// Start the progress update thread.
@@ -54,11 +119,11 @@ func (r *ProgressReader) timedUpdateProgress() {
 }
 
 func (r *ProgressReader) updateProgress() bool {
+	now := time.Now()
+	r.updateTransferStats(now)
+	r.pushReport(now)
 	if r.total > 0 {
-		currentProgress := 100.0
-		if !r.Done && r.Current < r.total {
-			currentProgress = float64(r.Current) / float64(r.total) * 100.0
-		}
+		currentProgress := r.currentProgress()
 		metric := &dto.Metric{}
 		r.progress.WithLabelValues(r.ownerUID).Write(metric)
 		if currentProgress > *metric.Counter.Value {
@@ -67,7 +132,63 @@ func (r *ProgressReader) updateProgress() bool {
 		klog.V(1).Infoln(fmt.Sprintf("%.2f", currentProgress))
 		return !r.Done
 	}
-	return false
+	// without a total there is no progress, but the statistics are still updated
+	return (r.stats != nil || r.reporter != nil) && !r.Done
+}
+
+func (r *ProgressReader) currentProgress() float64 {
+	if r.Done || r.Current >= r.total {
+		return 100.0
+	}
+	return float64(r.Current) / float64(r.total) * 100.0
+}
+
+func (r *ProgressReader) updateTransferStats(now time.Time) {
+	if r.stats == nil && r.reporter == nil {
+		return
+	}
+	current := r.Current
+	if elapsed := now.Sub(r.lastUpdate).Seconds(); elapsed > 0 {
+		r.throughput = 0
+		if !r.Done {
+			r.throughput = float64(current-r.lastBytes) / elapsed
+		}
+		if r.stats != nil {
+			r.stats.WithLabelValues(r.ownerUID, StatThroughput).Set(r.throughput)
+		}
+	}
+	if r.stats != nil {
+		r.stats.WithLabelValues(r.ownerUID, StatBytesTransferred).Set(float64(current))
+	}
+	r.lastUpdate = now
+	r.lastBytes = current
+}
+
+// pushReport pushes the current progress with the reporter, unless the previous report is too recent. The final
+// report of a transfer is always pushed.
+func (r *ProgressReader) pushReport(now time.Time) {
+	if r.reporter == nil || (!r.Done && now.Sub(r.lastReport) < progressReportInterval) {
+		return
+	}
+	r.lastReport = now
+	if err := r.reporter.Report(r.report()); err != nil {
+		klog.Warningf("Unable to push progress report: %v", err)
+	}
+}
+
+func (r *ProgressReader) report() *util.ProgressReport {
+	startTime := metav1.NewTime(r.startTime)
+	report := &util.ProgressReport{
+		BytesTransferred: int64(r.Current),
+		TotalBytes:       int64(r.total),
+		Throughput:       int64(r.throughput),
+		StartTime:        &startTime,
+	}
+	if r.total > 0 {
+		progress := r.currentProgress()
+		report.Progress = &progress
+	}
+	return report
 }
 
 // StartPrometheusEndpoint starts an http server providing a prometheus endpoint using the passed
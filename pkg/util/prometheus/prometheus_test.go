@@ -3,6 +3,7 @@ package prometheus
 import (
 	"bytes"
 	"io/ioutil"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -107,3 +108,89 @@ var _ = Describe("Update Progress", func() {
 	})
 
 })
+
+var _ = Describe("Transfer statistics", func() {
+	var stats *prometheus.GaugeVec
+
+	BeforeEach(func() {
+		stats = NewTransferStatsVec("test_transfer_stats", "The test transfer statistics")
+	})
+
+	statValue := func(stat string) float64 {
+		metric := &dto.Metric{}
+		stats.WithLabelValues(ownerUID, stat).Write(metric)
+		return *metric.Gauge.Value
+	}
+
+	It("Should report the total, the bytes transferred and the throughput", func() {
+		promReader := &ProgressReader{
+			CountingReader: util.CountingReader{
+				Current: uint64(100),
+			},
+			total:    uint64(1000),
+			progress: progress,
+			ownerUID: ownerUID,
+		}
+		promReader.ReportTransferStats(stats)
+		startTime := promReader.lastUpdate
+		Expect(statValue(StatStartTime)).To(Equal(float64(startTime.Unix())))
+		Expect(statValue(StatTotalBytes)).To(Equal(float64(1000)))
+
+		promReader.Current = 600
+		promReader.updateTransferStats(startTime.Add(2 * time.Second))
+		Expect(statValue(StatBytesTransferred)).To(Equal(float64(600)))
+		Expect(statValue(StatThroughput)).To(Equal(float64(250)))
+
+		By("Reporting no throughput once done")
+		promReader.Current = 1000
+		promReader.Done = true
+		promReader.updateTransferStats(startTime.Add(3 * time.Second))
+		Expect(statValue(StatBytesTransferred)).To(Equal(float64(1000)))
+		Expect(statValue(StatThroughput)).To(Equal(float64(0)))
+	})
+
+	It("Should keep updating without a total until done", func() {
+		promReader := &ProgressReader{
+			CountingReader: util.CountingReader{
+				Current: uint64(45),
+			},
+			progress: progress,
+			ownerUID: ownerUID,
+		}
+		promReader.ReportTransferStats(stats)
+		Expect(promReader.updateProgress()).To(BeTrue())
+		Expect(statValue(StatBytesTransferred)).To(Equal(float64(45)))
+		promReader.Done = true
+		Expect(promReader.updateProgress()).To(BeFalse())
+	})
+
+	It("Should report the progress only with a total", func() {
+		promReader := &ProgressReader{
+			CountingReader: util.CountingReader{
+				Current: uint64(250),
+			},
+			total:    uint64(1000),
+			progress: progress,
+			ownerUID: ownerUID,
+		}
+		promReader.start()
+		report := promReader.report()
+		Expect(*report.Progress).To(Equal(25.0))
+		Expect(report.BytesTransferred).To(Equal(int64(250)))
+		Expect(report.TotalBytes).To(Equal(int64(1000)))
+		Expect(report.StartTime.Time).To(Equal(promReader.startTime))
+
+		promReader.total = 0
+		Expect(promReader.report().Progress).To(BeNil())
+	})
+
+	It("Should not push reports without a reporter", func() {
+		promReader := &ProgressReader{
+			progress: progress,
+			ownerUID: ownerUID,
+		}
+		promReader.PushReports(nil)
+		Expect(promReader.reporter).To(BeNil())
+		Expect(promReader.updateProgress()).To(BeFalse())
+	})
+})
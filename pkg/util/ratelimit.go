@@ -0,0 +1,75 @@
+package util
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+// maxRateLimitBurst caps the bytes read at once from a rate limited reader, so the transfer rate stays even.
+const maxRateLimitBurst = 1 << 20
+
+// RateLimitedReader is a reader that limits the rate data is read from the underlying reader. The limiter may be
+// shared by several readers, which then transfer at the combined rate.
+type RateLimitedReader struct {
+	Reader  io.ReadCloser
+	Limiter *rate.Limiter
+}
+
+// NewRateLimiter returns a limiter allowing bytesPerSecond bytes per second, or nil if bytesPerSecond is not positive.
+func NewRateLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := bytesPerSecond
+	if burst > maxRateLimitBurst {
+		burst = maxRateLimitBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+// NewRateLimitedReader returns a reader limiting the rate r is read with limiter, or r itself if limiter is nil.
+func NewRateLimitedReader(r io.ReadCloser, limiter *rate.Limiter) io.ReadCloser {
+	if limiter == nil {
+		return r
+	}
+	return &RateLimitedReader{Reader: r, Limiter: limiter}
+}
+
+// Read reads at most one burst of bytes from the stream, and waits until the limiter allows the bytes read.
+func (r *RateLimitedReader) Read(p []byte) (int, error) {
+	if burst := r.Limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		if waitErr := r.Limiter.WaitN(context.Background(), n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// Close closes the stream
+func (r *RateLimitedReader) Close() error {
+	return r.Reader.Close()
+}
+
+// TransferRateLimitFromEnv returns the bytes per second the worker pod may transfer, 0 if the rate is not limited.
+func TransferRateLimitFromEnv() (int64, error) {
+	value := os.Getenv(common.TransferRateLimitVar)
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid transfer rate limit %q", value)
+	}
+	return limit, nil
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rate limited reader", func() {
+	It("Should return the reader if the rate is not limited", func() {
+		r := ioutil.NopCloser(bytes.NewReader(nil))
+		Expect(NewRateLimiter(0)).To(BeNil())
+		Expect(NewRateLimitedReader(r, NewRateLimiter(0))).To(BeIdenticalTo(r))
+	})
+
+	It("Should cap the burst", func() {
+		Expect(NewRateLimiter(1024).Burst()).To(Equal(1024))
+		Expect(NewRateLimiter(1 << 30).Burst()).To(Equal(maxRateLimitBurst))
+	})
+
+	It("Should limit the rate data is read", func() {
+		data := make([]byte, 3*maxRateLimitBurst/2)
+		r := NewRateLimitedReader(ioutil.NopCloser(bytes.NewReader(data)), NewRateLimiter(maxRateLimitBurst))
+		start := time.Now()
+		result, err := ioutil.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(data))
+		// the first burst is read right away, the rest takes half a second
+		Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+	})
+})
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+	"kubevirt.io/containerized-data-importer/pkg/common"
+)
+
+// The reasons a worker pod reports in the termination message when it fails.
+const (
+	// ReasonInvalidConfiguration means the pod was started with invalid settings
+	ReasonInvalidConfiguration = "InvalidConfiguration"
+	// ReasonScratchSpaceRequired means the data has to be processed in scratch space first
+	ReasonScratchSpaceRequired = "ScratchSpaceRequired"
+	// ReasonNotFound means the source does not exist
+	ReasonNotFound = "NotFound"
+	// ReasonUnauthorized means the credentials were missing or rejected by the source
+	ReasonUnauthorized = "Unauthorized"
+	// ReasonTransferFailed means the data could not be read from the source or sent to the target
+	ReasonTransferFailed = "TransferFailed"
+	// ReasonValidationFailed means the image was rejected by the image validation policy
+	ReasonValidationFailed = "ValidationFailed"
+	// ReasonChecksumMismatch means the data does not match its expected checksum
+	ReasonChecksumMismatch = "ChecksumMismatch"
+	// ReasonVerificationFailed means the converted disk image does not match its source
+	ReasonVerificationFailed = "VerificationFailed"
+	// ReasonProcessingFailed means the data could not be written to the volume
+	ReasonProcessingFailed = "ProcessingFailed"
+)
+
+// maxTerminationMessageText is the length the text of a termination message is truncated to, so the encoded message
+// stays below the 4096 bytes kubernetes keeps of the termination message file.
+const maxTerminationMessageText = 2048
+
+// TerminationMessage is the JSON encoded result an importer, upload server or clone source pod writes to its
+// termination message file.
+type TerminationMessage struct {
+	// Message describes the result in human readable form
+	Message string `json:"message"`
+	// Reason is the cause of a failure, empty on success
+	Reason string `json:"reason,omitempty"`
+	// Transient is true if the failure may not happen again when the operation is retried
+	Transient bool `json:"transient,omitempty"`
+	// BytesTransferred is the number of bytes read from the source
+	BytesTransferred int64 `json:"bytesTransferred,omitempty"`
+	// Format is the detected format of the source image
+	Format string `json:"format,omitempty"`
+	// VirtualSize is the virtual size of the disk image in bytes
+	VirtualSize int64 `json:"virtualSize,omitempty"`
+	// Digest is the content checksum of the disk image written to the volume
+	Digest string `json:"digest,omitempty"`
+	// ISOVolumeLabel is the volume label of an imported ISO image
+	ISOVolumeLabel string `json:"isoVolumeLabel,omitempty"`
+	// DiskLayout is the partitioning and file systems of the imported disk image
+	DiskLayout *cdiv1.DataVolumeDiskLayout `json:"diskLayout,omitempty"`
+	// ValidationRule is the image validation policy rule the image violates
+	ValidationRule string `json:"validationRule,omitempty"`
+	// StartTime is the time the transfer started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the transfer completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// NewFailureMessage returns the termination message of a failed worker pod.
+func NewFailureMessage(reason string, transient bool, err error) *TerminationMessage {
+	return &TerminationMessage{Message: err.Error(), Reason: reason, Transient: transient}
+}
+
+// SetTransferTimes records that the transfer started at startTime and completed now.
+func (m *TerminationMessage) SetTransferTimes(startTime metav1.Time) {
+	now := metav1.Now()
+	m.StartTime = &startTime
+	m.CompletionTime = &now
+}
+
+// Failed returns true if the message reports a failure.
+func (m *TerminationMessage) Failed() bool {
+	return m.Reason != ""
+}
+
+// Write writes the JSON encoded message to the termination message file of the pod.
+func (m *TerminationMessage) Write() error {
+	return m.WriteToFile(common.PodTerminationMessageFile)
+}
+
+// WriteToFile writes the JSON encoded message to the passed in message file.
+func (m *TerminationMessage) WriteToFile(file string) error {
+	message := *m
+	if len(message.Message) > maxTerminationMessageText {
+		message.Message = message.Message[:maxTerminationMessageText]
+	}
+	data, err := json.Marshal(&message)
+	if err != nil {
+		return errors.Wrap(err, "could not encode termination message")
+	}
+	return WriteTerminationMessageToFile(file, string(data))
+}
+
+// ParseTerminationMessage decodes the termination message of a worker pod. A message that is not JSON encoded is
+// returned as the text of the message.
+func ParseTerminationMessage(message string) *TerminationMessage {
+	result := &TerminationMessage{}
+	if strings.HasPrefix(message, "{") && json.Unmarshal([]byte(message), result) == nil {
+		return result
+	}
+	return &TerminationMessage{Message: message}
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer/pkg/apis/core/v1alpha1"
+)
+
+var _ = Describe("Termination message", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "termination")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	writeAndParse := func(message *TerminationMessage) *TerminationMessage {
+		file := filepath.Join(tmpDir, "termination-log")
+		Expect(message.WriteToFile(file)).To(Succeed())
+		data, err := ioutil.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("\n"))
+		return ParseTerminationMessage(string(data))
+	}
+
+	It("Should encode and decode a success message", func() {
+		startTime := metav1.NewTime(time.Unix(1570000000, 0))
+		completionTime := metav1.NewTime(time.Unix(1570000060, 0))
+		message := &TerminationMessage{
+			Message:          "Import Complete",
+			BytesTransferred: 1024,
+			Format:           "qcow2",
+			VirtualSize:      4096,
+			Digest:           "sha256:1234",
+			DiskLayout:       &cdiv1.DataVolumeDiskLayout{PartitionTable: "gpt", Filesystems: []string{"ext4"}},
+			StartTime:        &startTime,
+			CompletionTime:   &completionTime,
+		}
+		result := writeAndParse(message)
+		Expect(result).To(Equal(message))
+		Expect(result.Failed()).To(BeFalse())
+	})
+
+	It("Should encode a failure on a single line", func() {
+		message := NewFailureMessage(ReasonNotFound, false, errors.New("not found\nat line 2"))
+		result := writeAndParse(message)
+		Expect(result).To(Equal(message))
+		Expect(result.Failed()).To(BeTrue())
+	})
+
+	It("Should truncate long messages", func() {
+		message := &TerminationMessage{Message: strings.Repeat("x", 8192), Reason: ReasonProcessingFailed}
+		result := writeAndParse(message)
+		Expect(result.Message).To(HaveLen(maxTerminationMessageText))
+		Expect(message.Message).To(HaveLen(8192))
+	})
+
+	It("Should return a message that is not JSON as text", func() {
+		Expect(ParseTerminationMessage("Import Complete")).To(Equal(&TerminationMessage{Message: "Import Complete"}))
+		Expect(ParseTerminationMessage("{broken")).To(Equal(&TerminationMessage{Message: "{broken"}))
+	})
+})
@@ -3,8 +3,11 @@ package util
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -291,3 +294,36 @@ func CopyDir(source string, dest string) (err error) {
 	}
 	return
 }
+
+// NewContentHash returns the hash used for content checksums.
+func NewContentHash() hash.Hash {
+	return sha256.New()
+}
+
+// FormatContentChecksum formats the digest of a hash returned by NewContentHash, like "sha256:<hex digest>".
+func FormatContentChecksum(h hash.Hash) string {
+	return common.ContentChecksumAlgorithm + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// ContentChecksum returns the content checksum of the first size bytes of the file or block device at path, or of all
+// of its contents if size is negative.
+func ContentChecksum(path string, size int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not open %s", path)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if size >= 0 {
+		r = io.LimitReader(f, size)
+	}
+	h := NewContentHash()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read %s", path)
+	}
+	if size >= 0 && n < size {
+		return "", errors.Errorf("%s holds %d bytes, expected at least %d", path, n, size)
+	}
+	return FormatContentChecksum(h), nil
+}
@@ -126,6 +126,40 @@ var _ = Describe("Copy files", func() {
 	})
 })
 
+var _ = Describe("Content checksum", func() {
+	var file string
+
+	BeforeEach(func() {
+		f, err := ioutil.TempFile("", "disk")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteString("hello world")
+		Expect(err).NotTo(HaveOccurred())
+		f.Close()
+		file = f.Name()
+	})
+
+	AfterEach(func() {
+		os.Remove(file)
+	})
+
+	It("Should hash the whole file", func() {
+		checksum, err := ContentChecksum(file, -1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).To(Equal("sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"))
+	})
+
+	It("Should hash the start of the file", func() {
+		checksum, err := ContentChecksum(file, 5)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).To(Equal("sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+	})
+
+	It("Should fail if the file is too short", func() {
+		_, err := ContentChecksum(file, 100)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
 var _ = Describe("RetryBackoffSize", func() {
 	var blockSize int64
 